@@ -4,8 +4,10 @@ package rpc
 import (
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 	"net/textproto"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -47,6 +49,7 @@ var (
 		"application/grpc+proto",
 		"application/proto",
 		"application/protobuf",
+		"application/x-protobuf",
 		"application/proto-rpc",
 		"application/octet-stream",
 	}
@@ -113,6 +116,14 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ct = ct[:idx]
 	}
 
+	// the response is returned with the request content type unless
+	// the caller accepts one there's a codec for
+	rspType := r.Header.Get("Content-Type")
+	accept := acceptContentType(r.Header.Get("Accept"))
+	if len(accept) > 0 {
+		rspType = accept
+	}
+
 	// micro client
 	c := h.opts.Client
 
@@ -161,26 +172,51 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var rsp []byte
+	var request interface{}
 
 	switch {
 	// proto codecs
 	case hasCodec(ct, protoCodecs):
-		request := &proto.Message{}
+		msg := &proto.Message{}
 		// if the extracted payload isn't empty lets use it
 		if len(br) > 0 {
-			request = proto.NewMessage(br)
+			msg = proto.NewMessage(br)
+		}
+		request = msg
+	default:
+		// if json codec is not present set to json
+		if !hasCodec(ct, jsonCodecs) {
+			ct = "application/json"
 		}
 
-		// create request/response
-		response := &proto.Message{}
+		// default to trying json
+		var msg json.RawMessage
+		// if the extracted payload isn't empty lets use it
+		if len(br) > 0 {
+			msg = json.RawMessage(br)
+		}
+		request = &msg
+	}
 
-		req := c.NewRequest(
-			service.Service,
-			service.Endpoint.Name,
-			request,
-			client.WithContentType(ct),
-		)
+	// the request is encoded with its own content type
+	// and the response with the one the caller accepts
+	if len(accept) == 0 {
+		accept = ct
+	}
+
+	req := c.NewRequest(
+		service.Service,
+		service.Endpoint.Name,
+		request,
+		client.WithContentType(ct),
+	)
+
+	cx = client.NewAcceptContext(cx, accept)
+
+	var rsp []byte
+
+	if hasCodec(accept, protoCodecs) {
+		response := &proto.Message{}
 
 		// make the call
 		if err := c.Call(cx, req, response, client.WithSelectOption(so)); err != nil {
@@ -192,35 +228,9 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		// marshall response
 		rsp, err = response.Marshal()
-		if err != nil {
-			if werr := writeError(w, r, err); werr != nil {
-				logger.Log(log.ErrorLevel, werr)
-			}
-			return
-		}
-
-	default:
-		// if json codec is not present set to json
-		if !hasCodec(ct, jsonCodecs) {
-			ct = "application/json"
-		}
-
-		// default to trying json
-		var request json.RawMessage
-		// if the extracted payload isn't empty lets use it
-		if len(br) > 0 {
-			request = json.RawMessage(br)
-		}
-
-		// create request/response
+	} else {
 		var response json.RawMessage
 
-		req := c.NewRequest(
-			service.Service,
-			service.Endpoint.Name,
-			&request,
-			client.WithContentType(ct),
-		)
 		// make the call
 		if err := c.Call(cx, req, &response, client.WithSelectOption(so)); err != nil {
 			if werr := writeError(w, r, err); werr != nil {
@@ -231,16 +241,17 @@ func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		// marshall response
 		rsp, err = response.MarshalJSON()
-		if err != nil {
-			if werr := writeError(w, r, err); werr != nil {
-				logger.Log(log.ErrorLevel, werr)
-			}
-			return
+	}
+
+	if err != nil {
+		if werr := writeError(w, r, err); werr != nil {
+			logger.Log(log.ErrorLevel, werr)
 		}
+		return
 	}
 
 	// write the response
-	if err := writeResponse(w, r, rsp); err != nil {
+	if err := writeResponse(w, r, rspType, rsp); err != nil {
 		logger.Log(log.ErrorLevel, err)
 	}
 }
@@ -249,6 +260,46 @@ func (rh *rpcHandler) String() string {
 	return "rpc"
 }
 
+// acceptContentType returns the most preferred media type in the
+// Accept header there's a codec for, or an empty string if none.
+func acceptContentType(accept string) string {
+	type mediaType struct {
+		name string
+		q    float64
+	}
+
+	var types []mediaType
+
+	for _, part := range strings.Split(accept, ",") {
+		name, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 && (hasCodec(name, jsonCodecs) || hasCodec(name, protoCodecs)) {
+			types = append(types, mediaType{name, q})
+		}
+	}
+
+	// the order of equally preferred types is kept
+	sort.SliceStable(types, func(i, j int) bool {
+		return types[i].q > types[j].q
+	})
+
+	if len(types) == 0 {
+		return ""
+	}
+
+	return types[0].name
+}
+
 func hasCodec(ct string, codecs []string) bool {
 	for _, codec := range codecs {
 		if ct == codec {
@@ -495,8 +546,8 @@ func writeError(w http.ResponseWriter, r *http.Request, err error) error {
 	return werr
 }
 
-func writeResponse(w http.ResponseWriter, r *http.Request, rsp []byte) error {
-	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+func writeResponse(w http.ResponseWriter, r *http.Request, ct string, rsp []byte) error {
+	w.Header().Set("Content-Type", ct)
 	w.Header().Set("Content-Length", strconv.Itoa(len(rsp)))
 
 	// Set trailers
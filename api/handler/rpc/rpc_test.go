@@ -2,13 +2,24 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
+	"go-micro.dev/v4/api/handler"
 	go_api "go-micro.dev/v4/api/proto"
+	"go-micro.dev/v4/api/router"
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	"go-micro.dev/v4/server"
+	"go-micro.dev/v4/transport"
 )
 
 func TestRequestPayloadFromRequest(t *testing.T) {
@@ -117,3 +128,133 @@ func TestRequestPayloadFromRequest(t *testing.T) {
 		}
 	})
 }
+
+func TestAcceptContentType(t *testing.T) {
+	testCases := []struct {
+		accept string
+		expect string
+	}{
+		{"", ""},
+		{"*/*", ""},
+		{"text/html", ""},
+		{"application/json", "application/json"},
+		{"application/x-protobuf", "application/x-protobuf"},
+		{"text/html, application/protobuf", "application/protobuf"},
+		{"application/json;q=0.5, application/x-protobuf", "application/x-protobuf"},
+		{"application/json, application/x-protobuf", "application/json"},
+		{"application/x-protobuf;q=0, application/json;q=0.1", "application/json"},
+	}
+
+	for _, tc := range testCases {
+		if ct := acceptContentType(tc.accept); ct != tc.expect {
+			t.Errorf("Accept %q: expected %q, got %q", tc.accept, tc.expect, ct)
+		}
+	}
+}
+
+type testRouter struct {
+	route *router.Route
+}
+
+func (r *testRouter) Options() router.Options          { return router.Options{} }
+func (r *testRouter) Register(_ *router.Route) error   { return nil }
+func (r *testRouter) Deregister(_ *router.Route) error { return nil }
+func (r *testRouter) Stop() error                      { return nil }
+
+func (r *testRouter) Route(_ *http.Request) (*router.Route, error) {
+	return r.route, nil
+}
+
+type EventHandler struct{}
+
+func (h *EventHandler) Call(ctx context.Context, req *go_api.Event, rsp *go_api.Event) error {
+	rsp.Name = "hello " + req.Name
+	return nil
+}
+
+func TestServeHTTPAccept(t *testing.T) {
+	reg := registry.NewMemoryRegistry()
+	tr := transport.NewMemoryTransport()
+
+	srv := server.NewServer(
+		server.Name("test.service"),
+		server.Registry(reg),
+		server.Transport(tr),
+		server.Broker(broker.NewMemoryBroker()),
+	)
+	if err := srv.Handle(srv.NewHandler(&EventHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	services, err := reg.GetService("test.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(
+		handler.WithClient(client.NewClient(
+			client.Registry(reg),
+			client.Transport(tr),
+			client.Selector(selector.NewSelector(selector.Registry(reg))),
+		)),
+		handler.WithRouter(&testRouter{&router.Route{
+			Service:  "test.service",
+			Endpoint: &router.Endpoint{Name: "EventHandler.Call"},
+			Versions: services,
+		}}),
+	)
+
+	protoReq, err := proto.Marshal(&go_api.Event{Name: "john"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        []byte
+		expect      string
+	}{
+		{"json", "application/json", "", []byte(`{"name": "john"}`), "application/json"},
+		{"json accepting proto", "application/json", "application/protobuf", []byte(`{"name": "john"}`), "application/protobuf"},
+		{"proto accepting json", "application/protobuf", "application/json", protoReq, "application/json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/event/call", bytes.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			if len(tc.accept) > 0 {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tc.expect {
+				t.Fatalf("Expected content type %s, got %s", tc.expect, ct)
+			}
+
+			var rsp go_api.Event
+			if strings.Contains(tc.expect, "json") {
+				err = json.Unmarshal(w.Body.Bytes(), &rsp)
+			} else {
+				err = proto.Unmarshal(w.Body.Bytes(), &rsp)
+			}
+			if err != nil {
+				t.Fatalf("Expected a %s response, got %v: %q", tc.expect, err, w.Body.String())
+			}
+			if rsp.Name != "hello john" {
+				t.Fatalf("Expected hello john, got %q", rsp.Name)
+			}
+		})
+	}
+}
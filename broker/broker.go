@@ -1,6 +1,8 @@
 // Package broker is an interface used for asynchronous messaging
 package broker
 
+import "time"
+
 // Broker is an interface used for asynchronous messaging.
 type Broker interface {
 	Init(...Option) error
@@ -31,6 +33,36 @@ type Event interface {
 	Error() error
 }
 
+// Nacker is implemented by events which can be negatively acknowledged.
+// Nacking a message delivered to a subscriber with auto ack disabled
+// asks the broker to redeliver it.
+type Nacker interface {
+	Nack() error
+}
+
+// Delivery is the delivery guarantee of a broker.
+type Delivery int
+
+const (
+	// AtMostOnce doesn't redeliver messages by itself, messages which
+	// fail or go unacked are dropped. Nacked messages are still
+	// redelivered as the subscriber asked for it. It's the default.
+	AtMostOnce Delivery = iota
+	// AtLeastOnce redelivers messages until they're acked. Messages
+	// are redelivered when nacked, when an auto acked handler fails or
+	// when a manually acked message isn't acked within the AckTimeout.
+	AtLeastOnce
+)
+
+func (d Delivery) String() string {
+	switch d {
+	case AtLeastOnce:
+		return "at-least-once"
+	default:
+		return "at-most-once"
+	}
+}
+
 // Subscriber is a convenience return type for the Subscribe method.
 type Subscriber interface {
 	Options() SubscribeOptions
@@ -40,6 +72,10 @@ type Subscriber interface {
 
 var (
 	DefaultBroker Broker = NewBroker()
+
+	// DefaultDrainTimeout is how long unsubscribing waits
+	// for the messages being handled to complete.
+	DefaultDrainTimeout = time.Second * 10
 )
 
 func Init(opts ...Option) error {
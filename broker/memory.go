@@ -21,6 +21,8 @@ type memoryBroker struct {
 	sync.RWMutex
 	connected   bool
 	Subscribers map[string][]*memorySubscriber
+	// timers for messages published with a delay
+	pending map[*time.Timer]bool
 }
 
 type memoryEvent struct {
@@ -28,14 +30,30 @@ type memoryEvent struct {
 	topic   string
 	err     error
 	message interface{}
+	// the broker and subscriber the event was delivered to
+	broker *memoryBroker
+	sub    *memorySubscriber
+	// number of times the message has been delivered
+	attempt int
+
+	sync.Mutex
+	// set once acked, nacked or redelivered
+	settled bool
 }
 
 type memorySubscriber struct {
 	id      string
 	topic   string
-	exit    chan bool
 	handler Handler
 	opts    SubscribeOptions
+	broker  *memoryBroker
+
+	sync.Mutex
+	// messages being handled
+	inflight int
+	closed   bool
+	// closed once unsubscribed and the in flight messages are handled
+	drained chan bool
 }
 
 func (m *memoryBroker) Options() Options {
@@ -79,6 +97,12 @@ func (m *memoryBroker) Disconnect() error {
 
 	m.connected = false
 
+	// drop any messages still waiting on a delay
+	for t := range m.pending {
+		t.Stop()
+	}
+	m.pending = make(map[*time.Timer]bool)
+
 	return nil
 }
 
@@ -90,15 +114,20 @@ func (m *memoryBroker) Init(opts ...Option) error {
 }
 
 func (m *memoryBroker) Publish(topic string, msg *Message, opts ...PublishOption) error {
+	var options PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
 	m.RLock()
 	if !m.connected {
 		m.RUnlock()
 		return errors.New("not connected")
 	}
 
-	subs, ok := m.Subscribers[topic]
+	_, ok := m.Subscribers[topic]
 	m.RUnlock()
-	if !ok {
+	if !ok && options.Delay <= 0 {
 		return nil
 	}
 
@@ -113,26 +142,124 @@ func (m *memoryBroker) Publish(topic string, msg *Message, opts ...PublishOption
 		v = msg
 	}
 
+	if options.Delay > 0 {
+		m.schedule(topic, v, options.Delay)
+		return nil
+	}
+
+	return m.deliver(topic, v)
+}
+
+// schedule holds the message until the delay elapses. Messages still
+// pending when the broker disconnects are dropped.
+func (m *memoryBroker) schedule(topic string, v interface{}, d time.Duration) {
+	m.after(d, func() {
+		if err := m.deliver(topic, v); err != nil {
+			m.opts.Logger.Logf(log.ErrorLevel, "[memory]: failed to deliver delayed message on %s: %v", topic, err)
+		}
+	})
+}
+
+// after calls fn once d elapses unless the broker disconnects first.
+func (m *memoryBroker) after(d time.Duration, fn func()) {
+	m.Lock()
+	defer m.Unlock()
+
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		m.Lock()
+		if !m.pending[t] {
+			m.Unlock()
+			return
+		}
+		delete(m.pending, t)
+		m.Unlock()
+
+		fn()
+	})
+	m.pending[t] = true
+}
+
+// deliver hands the message to the subscribers of the topic at the time of the call.
+func (m *memoryBroker) deliver(topic string, v interface{}) error {
+	m.RLock()
+	if !m.connected {
+		m.RUnlock()
+		return errors.New("not connected")
+	}
+	subs := m.Subscribers[topic]
+	m.RUnlock()
+
+	for _, sub := range subs {
+		if err := m.dispatch(sub, topic, v, 1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dispatch hands the message to the subscriber. Delivering AtLeastOnce
+// a failed auto acked message is redelivered, a manually acked one is
+// redelivered if it's not acked within the AckTimeout.
+func (m *memoryBroker) dispatch(sub *memorySubscriber, topic string, v interface{}, attempt int) error {
 	p := &memoryEvent{
 		topic:   topic,
 		message: v,
 		opts:    m.opts,
+		broker:  m,
+		sub:     sub,
+		attempt: attempt,
 	}
 
-	for _, sub := range subs {
-		if err := sub.handler(p); err != nil {
-			p.err = err
-			if eh := m.opts.ErrorHandler; eh != nil {
-				eh(p)
-				continue
-			}
-			return err
+	err := sub.handle(p)
+
+	if m.opts.Delivery == AtLeastOnce {
+		switch {
+		case !sub.opts.AutoAck:
+			p.expire(m.opts.AckTimeout)
+		case err != nil && p.settle():
+			p.retry()
+		}
+	}
+
+	if err != nil {
+		p.err = err
+		if eh := m.opts.ErrorHandler; eh != nil {
+			eh(p)
+			return nil
 		}
+		return err
 	}
 
 	return nil
 }
 
+// redeliver hands a message back to its subscriber if it's still subscribed.
+func (m *memoryBroker) redeliver(e *memoryEvent) {
+	m.RLock()
+	if !m.connected {
+		m.RUnlock()
+		return
+	}
+	var subscribed bool
+	for _, sub := range m.Subscribers[e.topic] {
+		if sub.id == e.sub.id {
+			subscribed = true
+			break
+		}
+	}
+	m.RUnlock()
+
+	if !subscribed {
+		return
+	}
+
+	if err := m.dispatch(e.sub, e.topic, e.message, e.attempt+1); err != nil {
+		m.opts.Logger.Logf(log.ErrorLevel, "[memory]: failed to redeliver message on %s: %v", e.topic, err)
+	}
+}
+
 func (m *memoryBroker) Subscribe(topic string, handler Handler, opts ...SubscribeOption) (Subscriber, error) {
 	m.RLock()
 	if !m.connected {
@@ -141,40 +268,39 @@ func (m *memoryBroker) Subscribe(topic string, handler Handler, opts ...Subscrib
 	}
 	m.RUnlock()
 
-	var options SubscribeOptions
-	for _, o := range opts {
-		o(&options)
-	}
+	options := NewSubscribeOptions(opts...)
 
 	sub := &memorySubscriber{
-		exit:    make(chan bool, 1),
 		id:      uuid.New().String(),
 		topic:   topic,
 		handler: handler,
 		opts:    options,
+		broker:  m,
+		drained: make(chan bool),
 	}
 
 	m.Lock()
 	m.Subscribers[topic] = append(m.Subscribers[topic], sub)
 	m.Unlock()
 
-	go func() {
-		<-sub.exit
-		m.Lock()
-		var newSubscribers []*memorySubscriber
-		for _, sb := range m.Subscribers[topic] {
-			if sb.id == sub.id {
-				continue
-			}
-			newSubscribers = append(newSubscribers, sb)
-		}
-		m.Subscribers[topic] = newSubscribers
-		m.Unlock()
-	}()
-
 	return sub, nil
 }
 
+// unsubscribe removes the subscriber from its topic.
+func (m *memoryBroker) unsubscribe(sub *memorySubscriber) {
+	m.Lock()
+	defer m.Unlock()
+
+	var newSubscribers []*memorySubscriber
+	for _, sb := range m.Subscribers[sub.topic] {
+		if sb.id == sub.id {
+			continue
+		}
+		newSubscribers = append(newSubscribers, sb)
+	}
+	m.Subscribers[sub.topic] = newSubscribers
+}
+
 func (m *memoryBroker) String() string {
 	return "memory"
 }
@@ -200,9 +326,62 @@ func (m *memoryEvent) Message() *Message {
 }
 
 func (m *memoryEvent) Ack() error {
+	m.settle()
+	return nil
+}
+
+// Nack redelivers the message to the subscriber if auto ack is disabled.
+func (m *memoryEvent) Nack() error {
+	if m.sub == nil || m.sub.opts.AutoAck {
+		return nil
+	}
+
+	if m.settle() {
+		m.retry()
+	}
+
 	return nil
 }
 
+// settle marks the event as handled, returning false if it already was.
+func (m *memoryEvent) settle() bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.settled {
+		return false
+	}
+	m.settled = true
+
+	return true
+}
+
+// expire redelivers the event if it's not settled within the timeout.
+func (m *memoryEvent) expire(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	m.broker.after(timeout, func() {
+		if m.settle() {
+			m.retry()
+		}
+	})
+}
+
+// retry redelivers the event after the redelivery delay
+// unless it's been delivered the max number of times.
+func (m *memoryEvent) retry() {
+	if max := m.opts.MaxDeliveries; max > 0 && m.attempt >= max {
+		m.opts.Logger.Logf(log.WarnLevel, "[memory]: dropping message on %s after %d deliveries", m.topic, m.attempt)
+		return
+	}
+
+	m.broker.after(m.opts.RedeliveryDelay, func() {
+		m.broker.redeliver(m)
+	})
+}
+
 func (m *memoryEvent) Error() error {
 	return m.err
 }
@@ -215,9 +394,56 @@ func (m *memorySubscriber) Topic() string {
 	return m.topic
 }
 
+// handle passes the event to the handler unless unsubscribed,
+// tracking it as in flight until the handler returns.
+func (m *memorySubscriber) handle(e Event) error {
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return nil
+	}
+	m.inflight++
+	m.Unlock()
+
+	defer func() {
+		m.Lock()
+		m.inflight--
+		if m.closed && m.inflight == 0 {
+			close(m.drained)
+		}
+		m.Unlock()
+	}()
+
+	return m.handler(e)
+}
+
+// Unsubscribe stops delivery to the subscriber then waits up
+// to the broker's DrainTimeout for in flight messages to be handled.
 func (m *memorySubscriber) Unsubscribe() error {
-	m.exit <- true
-	return nil
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return nil
+	}
+	m.closed = true
+	if m.inflight == 0 {
+		close(m.drained)
+	}
+	m.Unlock()
+
+	m.broker.unsubscribe(m)
+
+	timeout := m.broker.opts.DrainTimeout
+	if timeout <= 0 {
+		return nil
+	}
+
+	select {
+	case <-m.drained:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("timed out waiting for in flight messages")
+	}
 }
 
 func NewMemoryBroker(opts ...Option) Broker {
@@ -228,5 +454,6 @@ func NewMemoryBroker(opts ...Option) Broker {
 	return &memoryBroker{
 		opts:        options,
 		Subscribers: make(map[string][]*memorySubscriber),
+		pending:     make(map[*time.Timer]bool),
 	}
 }
@@ -2,7 +2,9 @@ package broker_test
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"go-micro.dev/v4/broker"
 )
@@ -48,3 +50,347 @@ func TestMemoryBroker(t *testing.T) {
 		t.Fatalf("Unexpected connect error %v", err)
 	}
 }
+
+func TestMemoryBrokerPublishDelay(t *testing.T) {
+	b := broker.NewMemoryBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	topic := "test.delay"
+	delay := 100 * time.Millisecond
+
+	received := make(chan time.Time, 1)
+	if _, err := b.Subscribe(topic, func(p broker.Event) error {
+		received <- time.Now()
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	// a subscriber which leaves before the delay elapses should not see the message
+	var early int32
+	sub, err := b.Subscribe(topic, func(p broker.Event) error {
+		atomic.AddInt32(&early, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	start := time.Now()
+	if err := b.Publish(topic, &broker.Message{Body: []byte(`hello`)}, broker.PublishDelay(delay)); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Unexpected error unsubscribing %v", err)
+	}
+
+	select {
+	case at := <-received:
+		if elapsed := at.Sub(start); elapsed < delay {
+			t.Fatalf("Message delivered after %v, expected at least %v", elapsed, delay)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for delayed message")
+	}
+
+	if n := atomic.LoadInt32(&early); n != 0 {
+		t.Fatalf("Expected unsubscribed handler to receive nothing, got %d messages", n)
+	}
+}
+
+func TestMemoryBrokerPublishDelayDisconnect(t *testing.T) {
+	b := broker.NewMemoryBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+
+	topic := "test.delay"
+	received := make(chan bool, 1)
+	if _, err := b.Subscribe(topic, func(p broker.Event) error {
+		received <- true
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	if err := b.Publish(topic, &broker.Message{Body: []byte(`hello`)}, broker.PublishDelay(50*time.Millisecond)); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	// pending messages are dropped on disconnect, even after reconnecting
+	if err := b.Disconnect(); err != nil {
+		t.Fatalf("Unexpected disconnect error %v", err)
+	}
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	select {
+	case <-received:
+		t.Fatal("Expected delayed message to be dropped on disconnect")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestMemoryBrokerNack(t *testing.T) {
+	b := broker.NewMemoryBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	var count int32
+	done := make(chan bool, 1)
+
+	fn := func(p broker.Event) error {
+		if atomic.AddInt32(&count, 1) < 3 {
+			return p.(broker.Nacker).Nack()
+		}
+		done <- true
+		return p.Ack()
+	}
+
+	if _, err := b.Subscribe("test", fn, broker.DisableAutoAck()); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	if err := b.Publish("test", &broker.Message{Body: []byte(`hello`)}); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected nacked message to be redelivered")
+	}
+
+	time.Sleep(time.Millisecond * 50)
+	if n := atomic.LoadInt32(&count); n != 3 {
+		t.Fatalf("Expected 3 deliveries got %d", n)
+	}
+}
+
+func TestMemoryBrokerUnsubscribeDrain(t *testing.T) {
+	b := broker.NewMemoryBroker()
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	started := make(chan bool)
+	var handled int32
+
+	sub, err := b.Subscribe("test", func(p broker.Event) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	go b.Publish("test", &broker.Message{Body: []byte(`hello world`)})
+	<-started
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Unexpected error unsubscribing %v", err)
+	}
+
+	if n := atomic.LoadInt32(&handled); n != 1 {
+		t.Fatal("Expected the in flight message to be handled before unsubscribing returned")
+	}
+
+	// no further messages are delivered
+	if err := b.Publish("test", &broker.Message{Body: []byte(`hello world`)}); err != nil {
+		t.Fatalf("Unexpected publish error %v", err)
+	}
+	if n := atomic.LoadInt32(&handled); n != 1 {
+		t.Fatalf("Expected no messages after unsubscribing, got %d", n)
+	}
+}
+
+func TestMemoryBrokerUnsubscribeDrainTimeout(t *testing.T) {
+	b := broker.NewMemoryBroker(broker.DrainTimeout(50 * time.Millisecond))
+
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	started := make(chan bool)
+	release := make(chan bool)
+	defer close(release)
+
+	sub, err := b.Subscribe("test", func(p broker.Event) error {
+		close(started)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	go b.Publish("test", &broker.Message{Body: []byte(`hello world`)})
+	<-started
+
+	start := time.Now()
+	if err := sub.Unsubscribe(); err == nil {
+		t.Fatal("Expected unsubscribing to time out")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the drain to be bounded, took %v", d)
+	}
+}
+
+func TestMemoryBrokerDelivery(t *testing.T) {
+	testData := []struct {
+		name string
+		opts []broker.Option
+		// subscribe with auto ack disabled
+		manual bool
+		// handles each delivery
+		handle func(p broker.Event) error
+		// number of deliveries expected
+		deliveries int32
+	}{
+		{
+			name:       "at most once nack",
+			opts:       []broker.Option{broker.MaxDeliveries(3)},
+			manual:     true,
+			handle:     func(p broker.Event) error { return p.(broker.Nacker).Nack() },
+			deliveries: 3,
+		},
+		{
+			name:       "at most once error",
+			handle:     func(p broker.Event) error { return fmt.Errorf("failed") },
+			deliveries: 1,
+		},
+		{
+			name:       "at most once unacked",
+			opts:       []broker.Option{broker.AckTimeout(time.Millisecond * 10)},
+			manual:     true,
+			handle:     func(p broker.Event) error { return nil },
+			deliveries: 1,
+		},
+		{
+			name:       "at least once nack",
+			opts:       []broker.Option{broker.DeliveryMode(broker.AtLeastOnce), broker.MaxDeliveries(3)},
+			manual:     true,
+			handle:     func(p broker.Event) error { return p.(broker.Nacker).Nack() },
+			deliveries: 3,
+		},
+		{
+			name:       "at least once error",
+			opts:       []broker.Option{broker.DeliveryMode(broker.AtLeastOnce), broker.MaxDeliveries(3)},
+			handle:     func(p broker.Event) error { return fmt.Errorf("failed") },
+			deliveries: 3,
+		},
+		{
+			name: "at least once unacked",
+			opts: []broker.Option{
+				broker.DeliveryMode(broker.AtLeastOnce),
+				broker.AckTimeout(time.Millisecond * 10),
+				broker.MaxDeliveries(2),
+			},
+			manual:     true,
+			handle:     func(p broker.Event) error { return nil },
+			deliveries: 2,
+		},
+		{
+			name:       "at least once acked",
+			opts:       []broker.Option{broker.DeliveryMode(broker.AtLeastOnce), broker.AckTimeout(time.Millisecond * 10)},
+			manual:     true,
+			handle:     func(p broker.Event) error { return p.Ack() },
+			deliveries: 1,
+		},
+	}
+
+	for _, d := range testData {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			// ignore the handler errors
+			opts := append([]broker.Option{broker.ErrorHandler(func(broker.Event) error { return nil })}, d.opts...)
+
+			b := broker.NewMemoryBroker(opts...)
+			if err := b.Connect(); err != nil {
+				t.Fatalf("Unexpected connect error %v", err)
+			}
+			defer b.Disconnect()
+
+			var count int32
+			fn := func(p broker.Event) error {
+				atomic.AddInt32(&count, 1)
+				return d.handle(p)
+			}
+
+			var sopts []broker.SubscribeOption
+			if d.manual {
+				sopts = append(sopts, broker.DisableAutoAck())
+			}
+
+			if _, err := b.Subscribe("test", fn, sopts...); err != nil {
+				t.Fatalf("Unexpected error subscribing %v", err)
+			}
+
+			if err := b.Publish("test", &broker.Message{Body: []byte(`hello`)}); err != nil {
+				t.Fatalf("Unexpected error publishing %v", err)
+			}
+
+			// wait for any redeliveries
+			time.Sleep(time.Millisecond * 100)
+
+			if n := atomic.LoadInt32(&count); n != d.deliveries {
+				t.Fatalf("Expected %d deliveries got %d", d.deliveries, n)
+			}
+		})
+	}
+}
+
+func TestMemoryBrokerRedeliveryDelay(t *testing.T) {
+	b := broker.NewMemoryBroker(
+		broker.DeliveryMode(broker.AtLeastOnce),
+		broker.RedeliveryDelay(time.Millisecond*50),
+	)
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Unexpected connect error %v", err)
+	}
+	defer b.Disconnect()
+
+	var count int32
+	times := make(chan time.Time, 2)
+	fn := func(p broker.Event) error {
+		times <- time.Now()
+		if atomic.AddInt32(&count, 1) == 1 {
+			return p.(broker.Nacker).Nack()
+		}
+		return p.Ack()
+	}
+
+	if _, err := b.Subscribe("test", fn, broker.DisableAutoAck()); err != nil {
+		t.Fatalf("Unexpected error subscribing %v", err)
+	}
+
+	if err := b.Publish("test", &broker.Message{Body: []byte(`hello`)}); err != nil {
+		t.Fatalf("Unexpected error publishing %v", err)
+	}
+
+	first := <-times
+	select {
+	case second := <-times:
+		if d := second.Sub(first); d < time.Millisecond*50 {
+			t.Fatalf("Expected the message to be redelivered after the delay, got %v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the nacked message to be redelivered")
+	}
+}
@@ -3,6 +3,7 @@ package broker
 import (
 	"context"
 	"crypto/tls"
+	"time"
 
 	"go-micro.dev/v4/codec"
 	"go-micro.dev/v4/logger"
@@ -22,14 +23,34 @@ type Options struct {
 	ErrorHandler Handler
 
 	TLSConfig *tls.Config
+	// DrainTimeout bounds how long Unsubscribe waits for the messages
+	// being handled to complete. Zero doesn't wait.
+	DrainTimeout time.Duration
 	// Registry used for clustering
 	Registry registry.Registry
+
+	// Delivery guarantee, AtMostOnce by default
+	Delivery Delivery
+	// RedeliveryDelay is how long to wait before
+	// redelivering a message, zero is immediately
+	RedeliveryDelay time.Duration
+	// MaxDeliveries bounds how many times a message is
+	// delivered before it's dropped, zero is unlimited
+	MaxDeliveries int
+	// AckTimeout is how long a message delivered to a subscriber with
+	// auto ack disabled may go unacked before it's redelivered when
+	// delivering AtLeastOnce, zero waits indefinitely
+	AckTimeout time.Duration
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
 }
 
 type PublishOptions struct {
+	// Delay holds the message for the given duration before
+	// delivering it to subscribers. Zero delivers immediately.
+	Delay time.Duration
+
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -60,12 +81,21 @@ func PublishContext(ctx context.Context) PublishOption {
 	}
 }
 
+// PublishDelay delays delivery of the message by d. Brokers which
+// do not support scheduled delivery ignore the option.
+func PublishDelay(d time.Duration) PublishOption {
+	return func(o *PublishOptions) {
+		o.Delay = d
+	}
+}
+
 type SubscribeOption func(*SubscribeOptions)
 
 func NewOptions(opts ...Option) *Options {
 	options := Options{
-		Context: context.Background(),
-		Logger:  logger.DefaultLogger,
+		Context:      context.Background(),
+		Logger:       logger.DefaultLogger,
+		DrainTimeout: DefaultDrainTimeout,
 	}
 
 	for _, o := range opts {
@@ -110,6 +140,45 @@ func DisableAutoAck() SubscribeOption {
 	}
 }
 
+// DrainTimeout sets how long Unsubscribe waits for
+// in flight messages to be handled.
+func DrainTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.DrainTimeout = d
+	}
+}
+
+// DeliveryMode sets the delivery guarantee. Brokers
+// with a fixed guarantee ignore the option.
+func DeliveryMode(d Delivery) Option {
+	return func(o *Options) {
+		o.Delivery = d
+	}
+}
+
+// RedeliveryDelay sets how long to wait before redelivering a message.
+func RedeliveryDelay(d time.Duration) Option {
+	return func(o *Options) {
+		o.RedeliveryDelay = d
+	}
+}
+
+// MaxDeliveries sets the number of times a
+// message is delivered before it's dropped.
+func MaxDeliveries(n int) Option {
+	return func(o *Options) {
+		o.MaxDeliveries = n
+	}
+}
+
+// AckTimeout sets how long a manually acked message may go
+// unacked before it's redelivered delivering AtLeastOnce.
+func AckTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.AckTimeout = d
+	}
+}
+
 // ErrorHandler will catch all broker errors that cant be handled
 // in normal way, for example Codec errors.
 func ErrorHandler(h Handler) Option {
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// AttemptHeader carries the attempt number of a call, starting at 1
+	// and incremented on each retry.
+	AttemptHeader = "X-Micro-Attempt"
+	// IdempotencyKeyHeader carries a key which is the same for every
+	// attempt of a call, so the server can recognise retries.
+	IdempotencyKeyHeader = "X-Micro-Idempotency-Key"
+)
+
+type attemptKey struct{}
+
+type idempotencyKey struct{}
+
+// withIdempotencyKey sets the idempotency key of a call, the one passed
+// with WithIdempotencyKey or a new one. It's never inherited from the
+// context metadata, e.g that of the request a handler is serving.
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	if len(key) == 0 {
+		key = uuid.New().String()
+	}
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// withAttempt sets the attempt number, i being the zero based retry.
+func withAttempt(ctx context.Context, i int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, i+1)
+}
+
+// setAttemptHeaders replaces the attempt headers forwarded
+// from the context metadata with those of the call.
+func setAttemptHeaders(ctx context.Context, header map[string]string) {
+	delete(header, AttemptHeader)
+	delete(header, IdempotencyKeyHeader)
+
+	if key, ok := ctx.Value(idempotencyKey{}).(string); ok {
+		header[IdempotencyKeyHeader] = key
+	}
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		header[AttemptHeader] = strconv.Itoa(n)
+	}
+}
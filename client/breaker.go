@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/registry"
+)
+
+// BreakerState is the state of the circuit to a node.
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects calls until the cooldown passes.
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial call through,
+	// closing the circuit if it succeeds.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerStatus is the state of the circuit to a node of a service.
+type BreakerStatus struct {
+	Service string
+	Node    string
+	Address string
+	State   BreakerState
+	// Failures is the number of consecutive failures
+	Failures int
+	// TotalFailures is the number of failures since the node was first called
+	TotalFailures int
+	// Opened is when the circuit last opened
+	Opened time.Time
+}
+
+// Breaker is a circuit breaker per node. A node's circuit opens after a
+// number of consecutive failed calls, rejecting calls to it with
+// ErrCircuitOpen, which are retried on another node like other errors.
+// After the cooldown a single trial call is let through which closes the
+// circuit if it succeeds or opens it again if not. Errors returned by the
+// service with a code below 500, other than timeouts, aren't failures.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	sync.Mutex
+	circuits map[circuitKey]*BreakerStatus
+}
+
+type circuitKey struct {
+	service string
+	node    string
+}
+
+// nodeId identifies the node, those given by address have no id.
+func nodeId(node *registry.Node) string {
+	if len(node.Id) > 0 {
+		return node.Id
+	}
+	return node.Address
+}
+
+// NewBreaker returns a breaker opening a node's circuit after
+// threshold consecutive failures for the cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[circuitKey]*BreakerStatus),
+	}
+}
+
+// WithBreaker wraps the calls of the client with the breaker.
+func WithBreaker(b *Breaker) Option {
+	return WrapCall(b.wrap)
+}
+
+func (b *Breaker) wrap(fn CallFunc) CallFunc {
+	return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+		if !b.allow(req.Service(), node) {
+			return errors.InternalServerError("go.micro.client.breaker", "circuit open for node %s of %s", nodeId(node), req.Service())
+		}
+
+		err := fn(ctx, node, req, rsp, opts)
+		b.mark(req.Service(), node, err)
+
+		return err
+	}
+}
+
+// allow reports whether a call to the node may be made.
+func (b *Breaker) allow(service string, node *registry.Node) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	c, ok := b.circuits[circuitKey{service, nodeId(node)}]
+	if !ok {
+		return true
+	}
+
+	switch c.State {
+	case BreakerOpen:
+		if time.Since(c.Opened) < b.cooldown {
+			return false
+		}
+		// let a trial call through
+		c.State = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		// the trial call is in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// mark records the outcome of a call to the node.
+func (b *Breaker) mark(service string, node *registry.Node, err error) {
+	b.Lock()
+	defer b.Unlock()
+
+	key := circuitKey{service, nodeId(node)}
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &BreakerStatus{Service: service, Node: key.node}
+		b.circuits[key] = c
+	}
+	c.Address = node.Address
+
+	if !breakerFailure(err) {
+		c.State = BreakerClosed
+		c.Failures = 0
+		return
+	}
+
+	c.Failures++
+	c.TotalFailures++
+
+	if c.State == BreakerHalfOpen || c.Failures >= b.threshold {
+		c.State = BreakerOpen
+		c.Opened = time.Now()
+	}
+}
+
+// breakerFailure reports whether the error counts against the node.
+func breakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	merr, ok := errors.As(err)
+	if !ok {
+		return true
+	}
+
+	return merr.Code >= 500 || merr.Code == 408 || merr.Code == 0
+}
+
+// States returns the state of the circuit to every node called, sorted by
+// service and node. It's a consistent snapshot taken at a single point in time.
+func (b *Breaker) States() []BreakerStatus {
+	b.Lock()
+
+	states := make([]BreakerStatus, 0, len(b.circuits))
+	for _, c := range b.circuits {
+		states = append(states, *c)
+	}
+
+	b.Unlock()
+
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Service != states[j].Service {
+			return states[i].Service < states[j].Service
+		}
+		return states[i].Node < states[j].Node
+	})
+
+	return states
+}
+
+// State returns the state of the circuit to the node of the service, nodes
+// called by address are identified by it.
+func (b *Breaker) State(service, node string) (BreakerStatus, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	c, ok := b.circuits[circuitKey{service, node}]
+	if !ok {
+		return BreakerStatus{Service: service, Node: node}, false
+	}
+
+	return *c, true
+}
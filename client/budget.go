@@ -0,0 +1,83 @@
+package client
+
+import (
+	"sync"
+)
+
+// RetryBudget limits the retries made by a client to a share of its calls
+// so a struggling service isn't overwhelmed by retry storms. It's a token
+// bucket, every call deposits ratio tokens and every retry withdraws one.
+// Once empty retries are suppressed, even if the call options allow them,
+// until enough calls have been made to refill it.
+type RetryBudget struct {
+	ratio float64
+	max   float64
+
+	sync.Mutex
+	tokens float64
+}
+
+// NewRetryBudget returns a budget allowing ratio retries per call e.g 0.1
+// for one retry every ten calls. The bucket starts with and holds up to
+// minTokens, so that many retries are allowed in a burst e.g when a client
+// is first used. It always holds at least one token once refilled.
+func NewRetryBudget(ratio float64, minTokens int) *RetryBudget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if minTokens < 0 {
+		minTokens = 0
+	}
+
+	max := float64(minTokens)
+	if max < 1 {
+		max = 1
+	}
+
+	return &RetryBudget{
+		ratio:  ratio,
+		max:    max,
+		tokens: float64(minTokens),
+	}
+}
+
+// deposit adds the tokens for a call.
+func (b *RetryBudget) deposit() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// withdraw takes a token for a retry, returning false if there's none left.
+func (b *RetryBudget) withdraw() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// deposit adds the tokens for a call to the retry budget if there is one.
+func (r *rpcClient) deposit() {
+	if b := r.opts.RetryBudget; b != nil {
+		b.deposit()
+	}
+}
+
+// withdraw reports whether the retry budget allows another retry.
+func (r *rpcClient) withdraw() bool {
+	b := r.opts.RetryBudget
+	if b == nil {
+		return true
+	}
+	return b.withdraw()
+}
@@ -117,18 +117,26 @@ var (
 	DefaultPoolSize = 100
 	// DefaultPoolTTL sets the connection pool ttl.
 	DefaultPoolTTL = time.Minute
+	// DefaultWaitInterval is how often the selector is retried while
+	// waiting for nodes, registry changes retry it sooner.
+	DefaultWaitInterval = time.Millisecond * 500
 
 	// NewClient returns a new client.
 	NewClient func(...Option) Client = newRpcClient
 )
 
+// RegionKey is the node metadata key holding the region a node runs in.
+const RegionKey = "region"
+
 // Makes a synchronous call to a service using the default client.
 func Call(ctx context.Context, request Request, response interface{}, opts ...CallOption) error {
 	return DefaultClient.Call(ctx, request, response, opts...)
 }
 
 // Publishes a publication using the default client. Using the underlying broker
-// set within the options.
+// set within the options. The payload is encoded with the codec for the message
+// content type, which is also sent as the Content-Type header so subscribers
+// decode it with the same codec.
 func Publish(ctx context.Context, msg Message, opts ...PublishOption) error {
 	return DefaultClient.Publish(ctx, msg, opts...)
 }
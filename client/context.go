@@ -14,3 +14,33 @@ func FromContext(ctx context.Context) (Client, bool) {
 func NewContext(ctx context.Context, c Client) context.Context {
 	return context.WithValue(ctx, clientKey{}, c)
 }
+
+type contentTypeKey struct{}
+
+// NewContentTypeContext returns a context carrying the content type calls
+// made with it should use, see NewAcceptContext for the response's.
+// A content type set on the request itself takes precedence.
+func NewContentTypeContext(ctx context.Context, contentType string) context.Context {
+	return context.WithValue(ctx, contentTypeKey{}, contentType)
+}
+
+// ContentTypeFromContext returns the content type carried by the context.
+func ContentTypeFromContext(ctx context.Context) (string, bool) {
+	ct, ok := ctx.Value(contentTypeKey{}).(string)
+	return ct, ok && len(ct) > 0
+}
+
+type acceptKey struct{}
+
+// NewAcceptContext returns a context carrying the content type responses to
+// calls made with it should be encoded with, e.g from an inbound Accept
+// header. Requests are still encoded with the call content type.
+func NewAcceptContext(ctx context.Context, contentType string) context.Context {
+	return context.WithValue(ctx, acceptKey{}, contentType)
+}
+
+// AcceptFromContext returns the response content type carried by the context.
+func AcceptFromContext(ctx context.Context) (string, bool) {
+	ct, ok := ctx.Value(acceptKey{}).(string)
+	return ct, ok && len(ct) > 0
+}
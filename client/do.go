@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/transport"
+	"go-micro.dev/v4/util/pool"
+)
+
+// Doer is implemented by clients which can call a node directly.
+type Doer interface {
+	// Do calls the node at the address, bypassing the selector,
+	// registry and connection pool. Retries and call wrappers
+	// don't apply.
+	Do(ctx context.Context, addr string, req Request, rsp interface{}, opts ...CallOption) error
+}
+
+// Do calls the node at the address directly using the default client,
+// e.g for tooling which already knows the node. A connection is dialed
+// for the call and closed once it's done.
+func Do(ctx context.Context, addr string, req Request, rsp interface{}, opts ...CallOption) error {
+	d, ok := DefaultClient.(Doer)
+	if !ok {
+		return fmt.Errorf("client %s can't call a node directly", DefaultClient.String())
+	}
+	return d.Do(ctx, addr, req, rsp, opts...)
+}
+
+func (r *rpcClient) Do(ctx context.Context, addr string, req Request, rsp interface{}, opts ...CallOption) error {
+	callOpts := r.opts.CallOptions
+	for _, o := range opts {
+		o(&callOpts)
+	}
+
+	if d, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callOpts.RequestTimeout)
+		defer cancel()
+	} else {
+		callOpts.RequestTimeout = time.Until(d)
+	}
+
+	// the same client without the pool
+	direct := &rpcClient{
+		opts: r.opts,
+		pool: &directPool{tr: r.opts.Transport},
+	}
+
+	node := &registry.Node{
+		Address: addr,
+		// Set the protocol
+		Metadata: map[string]string{
+			"protocol": "mucp",
+		},
+	}
+
+	return direct.call(ctx, node, req, rsp, callOpts)
+}
+
+// directPool dials a connection for every call and closes it on release.
+type directPool struct {
+	tr transport.Transport
+}
+
+type directConn struct {
+	transport.Client
+	id      string
+	created time.Time
+}
+
+func (p *directPool) Get(addr string, opts ...transport.DialOption) (pool.Conn, error) {
+	c, err := p.tr.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &directConn{Client: c, id: uuid.New().String(), created: time.Now()}, nil
+}
+
+func (p *directPool) Release(c pool.Conn, status error) error {
+	if dc, ok := c.(*directConn); ok {
+		return dc.Client.Close()
+	}
+	return c.Close()
+}
+
+func (p *directPool) Close() error {
+	return nil
+}
+
+// Close is a noop, the connection is closed once released.
+func (c *directConn) Close() error {
+	return nil
+}
+
+func (c *directConn) Id() string {
+	return c.id
+}
+
+func (c *directConn) Created() time.Time {
+	return c.created
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/errors"
+)
+
+// Limit is a rate of calls per second. It mirrors rate.Limit from
+// golang.org/x/time/rate, which isn't a dependency of this module, so the
+// limiter below is kept in place of rate.Limiter. A rate.Limit converts to
+// it with Limit(l).
+type Limit float64
+
+// Inf is an infinite rate, which allows every call.
+const Inf = Limit(math.MaxFloat64)
+
+// Every converts the minimum interval between calls to a Limit.
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+// tokenBucket allows limit calls per second in bursts of up to burst.
+type tokenBucket struct {
+	limit Limit
+	burst float64
+
+	sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit Limit, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		limit:  limit,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens since the last refill, the lock must be held.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.limit)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// wait takes a token, waiting for one if there's none left. It fails straight
+// away if the context would be done before a token is available.
+func (b *tokenBucket) wait(ctx context.Context, service string) error {
+	if b.limit == Inf {
+		return nil
+	}
+
+	b.Lock()
+
+	now := time.Now()
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.Unlock()
+		return nil
+	}
+
+	if b.limit <= 0 {
+		b.Unlock()
+		return rateLimited(service)
+	}
+
+	delay := time.Duration((1 - b.tokens) / float64(b.limit) * float64(time.Second))
+
+	if d, ok := ctx.Deadline(); ok && now.Add(delay).After(d) {
+		b.Unlock()
+		return rateLimited(service)
+	}
+
+	// reserve the token so later calls queue behind this one
+	b.tokens--
+	b.Unlock()
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// hand back the reserved token
+		b.Lock()
+		b.tokens++
+		b.Unlock()
+		return contextError(ctx.Err(), "waiting to call %s: %v", service, ctx.Err())
+	}
+}
+
+func rateLimited(service string) error {
+	return errors.New("go.micro.client.egress", "rate limited calling "+service, 429)
+}
+
+type egressClient struct {
+	Client
+
+	service string
+	bucket  *tokenBucket
+}
+
+func (e *egressClient) Call(ctx context.Context, req Request, rsp interface{}, opts ...CallOption) error {
+	if req.Service() == e.service {
+		if err := e.bucket.wait(ctx, e.service); err != nil {
+			return err
+		}
+	}
+	return e.Client.Call(ctx, req, rsp, opts...)
+}
+
+func (e *egressClient) Stream(ctx context.Context, req Request, opts ...CallOption) (Stream, error) {
+	if req.Service() == e.service {
+		if err := e.bucket.wait(ctx, e.service); err != nil {
+			return nil, err
+		}
+	}
+	return e.Client.Stream(ctx, req, opts...)
+}
+
+// EgressLimit returns a client wrapper limiting the calls and streams to the
+// service to limit per second, with bursts of up to burst. Calls over the
+// limit wait their turn, unless their context would be done first in which
+// case they fail straight away with ErrRateLimited. A call whose context is
+// done while waiting fails with a timeout or canceled error. Other services
+// aren't limited, so each can have its own wrapper. An Inf limit allows
+// every call.
+func EgressLimit(service string, limit Limit, burst int) Wrapper {
+	return func(c Client) Client {
+		return &egressClient{
+			Client:  c,
+			service: service,
+			bucket:  newTokenBucket(limit, burst),
+		}
+	}
+}
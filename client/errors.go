@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"go-micro.dev/v4/errors"
+)
+
+// The errors returned by calls match these with errors.Is, telling
+// a call which ran out of time from one the caller gave up on or
+// one which failed to reach the service.
+var (
+	// ErrTimeout matches calls which failed as their deadline passed.
+	ErrTimeout = errors.Timeout("go.micro.client", "call timeout")
+	// ErrCanceled matches calls which failed as their context was canceled.
+	ErrCanceled = errors.Canceled("go.micro.client", "call canceled")
+	// ErrTransport matches calls which failed to connect to a
+	// node or to send the request or receive the response.
+	ErrTransport = errors.InternalServerError("go.micro.client.transport", "transport error")
+	// ErrCircuitOpen matches calls rejected by a Breaker as the node is failing.
+	ErrCircuitOpen = errors.InternalServerError("go.micro.client.breaker", "circuit open")
+	// ErrRateLimited matches calls rejected by an EgressLimit as they
+	// couldn't be made within their deadline.
+	ErrRateLimited = errors.New("go.micro.client.egress", "rate limited", 429)
+	// ErrInvalidResponse matches calls whose response failed validation.
+	ErrInvalidResponse = errors.New("go.micro.client.validate", "invalid response", 502)
+)
+
+// contextError returns the error for a call whose context is done, a
+// Timeout if its deadline passed otherwise a Canceled error.
+func contextError(err error, format string, a ...interface{}) error {
+	detail := fmt.Sprintf(format, a...)
+	if err == context.Canceled {
+		return errors.Canceled("go.micro.client", detail)
+	}
+	return errors.Timeout("go.micro.client", detail)
+}
+
+// connectionError is returned when a node can't be reached. It keeps
+// the go.micro.client id the error always had while matching ErrTransport.
+type connectionError struct {
+	err *errors.Error
+}
+
+func newConnectionError(err error) error {
+	return &connectionError{
+		err: errors.InternalServerError("go.micro.client", "connection error: %v", err).(*errors.Error),
+	}
+}
+
+func (e *connectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connectionError) Unwrap() error {
+	return e.err
+}
+
+func (e *connectionError) Is(target error) bool {
+	return target == ErrTransport
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// FallbackFunc returns the response of a call which failed with err, or
+// an error to return instead. A nil response leaves the caller's as is.
+type FallbackFunc func(ctx context.Context, req Request, err error) (interface{}, error)
+
+type fallbackClient struct {
+	Client
+	fn FallbackFunc
+}
+
+// WithFallback wraps the client so a call which fails, after any retries,
+// gets its response from fn rather than returning the error, e.g to
+// degrade to a cached or empty response. It must be passed to NewClient.
+//
+// A response of the caller's response type, or a pointer to it, is copied
+// into the caller's response. Anything else is converted by encoding it to
+// json and decoding that into the caller's response.
+func WithFallback(fn FallbackFunc) Option {
+	return Wrap(func(c Client) Client {
+		return &fallbackClient{Client: c, fn: fn}
+	})
+}
+
+func (f *fallbackClient) Call(ctx context.Context, req Request, rsp interface{}, opts ...CallOption) error {
+	err := f.Client.Call(ctx, req, rsp, opts...)
+	if err == nil {
+		return nil
+	}
+
+	v, ferr := f.fn(ctx, req, err)
+	if ferr != nil {
+		return ferr
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return setResponse(rsp, v)
+}
+
+// setResponse sets the response pointed to by rsp to v.
+func setResponse(rsp, v interface{}) error {
+	dst := reflect.ValueOf(rsp)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("fallback response must be set on a pointer, got %T", rsp)
+	}
+
+	src := reflect.ValueOf(v)
+	elem := dst.Elem()
+
+	switch {
+	case src.Type().AssignableTo(elem.Type()):
+		elem.Set(src)
+		return nil
+	case src.Kind() == reflect.Ptr && !src.IsNil() && src.Elem().Type().AssignableTo(elem.Type()):
+		elem.Set(src.Elem())
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode fallback response: %v", err)
+	}
+
+	if err := json.Unmarshal(b, rsp); err != nil {
+		return fmt.Errorf("failed to decode fallback response into %T: %v", rsp, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"errors"
+
+	"go-micro.dev/v4/metadata"
+)
+
+// HeaderReader is implemented by streams which can read the header the server
+// sent ahead of its first message.
+type HeaderReader interface {
+	// Header returns the header sent by the server, blocking
+	// until it's received ahead of the first response
+	Header() (metadata.Metadata, error)
+}
+
+// StreamHeader returns the header the server sent on the stream e.g to complete
+// a handshake, blocking until it's received ahead of the first response.
+func StreamHeader(s Stream) (metadata.Metadata, error) {
+	hr, ok := s.(HeaderReader)
+	if !ok {
+		return nil, errors.New("client: stream does not support reading a header")
+	}
+	return hr.Header()
+}
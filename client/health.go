@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+// HealthProbe returns a selector.HealthProbe which calls the Debug.Health
+// endpoint of the node directly. The node is healthy if it reports status "ok".
+func HealthProbe(c Client, timeout time.Duration) selector.HealthProbe {
+	return func(service string, node *registry.Node) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		req := c.NewRequest(service, "Debug.Health", map[string]interface{}{}, WithContentType("application/json"))
+		rsp := map[string]interface{}{}
+
+		if err := c.Call(ctx, req, &rsp, WithAddress(node.Address), WithRetries(0), WithRequestTimeout(timeout)); err != nil {
+			return err
+		}
+
+		if status, _ := rsp["status"].(string); status != "ok" {
+			return fmt.Errorf("node %s reported status %q", node.Id, status)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// AdaptiveLimiter limits the number of concurrent calls, adapting
+// the limit from the latency and outcome of completed calls.
+type AdaptiveLimiter interface {
+	// Acquire is called before each call. It may block until the call
+	// is allowed or return an error to reject it, the error is returned
+	// to the caller as is. The context carries the call deadline.
+	Acquire(ctx context.Context, req Request) error
+	// Release is called once an acquired call completes, including
+	// any retries, with the total latency and the final error.
+	Release(req Request, latency time.Duration, err error)
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"fmt"
+
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+)
+
+// Nodes returns the nodes of the service known to the client's selector,
+// served from its cache where possible. The selector must implement
+// selector.NodeLister.
+func Nodes(c Client, service string) ([]*registry.Node, error) {
+	s := c.Options().Selector
+
+	l, ok := s.(selector.NodeLister)
+	if !ok {
+		return nil, fmt.Errorf("selector %s can't list nodes", s.String())
+	}
+
+	return l.Nodes(service)
+}
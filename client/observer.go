@@ -0,0 +1,27 @@
+package client
+
+import (
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+// CallEvent describes a single attempt of a call, retries
+// included, passed to the observers set with WithCallObserver.
+type CallEvent struct {
+	Service  string
+	Endpoint string
+	// Node the attempt was made to
+	Node *registry.Node
+	// Attempt number starting at 1
+	Attempt  int
+	Duration time.Duration
+	Error    error
+}
+
+// observe passes the event to each of the call observers.
+func (r *rpcClient) observe(e CallEvent) {
+	for _, fn := range r.opts.CallObservers {
+		fn(e)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/codec"
 	"go-micro.dev/v4/logger"
+	"go-micro.dev/v4/metadata"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/selector"
 	"go-micro.dev/v4/transport"
@@ -29,6 +30,15 @@ type Options struct {
 	// Connection Pool
 	PoolSize int
 	PoolTTL  time.Duration
+	// PoolSizes overrides the pool size for a service
+	PoolSizes map[string]int
+	// PoolBlocking caps the connections in use per node at the
+	// pool size, calls wait for one until their deadline
+	PoolBlocking bool
+
+	// Publish bodies larger than this many bytes are gzip
+	// compressed. Zero disables compression.
+	PublishCompressThreshold int
 
 	// Response cache
 	Cache *Cache
@@ -39,6 +49,26 @@ type Options struct {
 	// Default Call Options
 	CallOptions CallOptions
 
+	// Default request timeouts keyed by endpoint. Keys are either an
+	// exact endpoint e.g Greeter.Hello or a path.Match pattern e.g Greeter.*
+	EndpointTimeouts map[string]time.Duration
+
+	// Idempotent endpoints are the only ones retried when set. Entries
+	// are either an exact endpoint or a path.Match pattern.
+	Idempotent []string
+
+	// Limiter is consulted before each call and told its outcome
+	Limiter AdaptiveLimiter
+
+	// CallObservers are told about every call attempt
+	CallObservers []func(CallEvent)
+
+	// RetryBudget limits the retries across all calls
+	RetryBudget *RetryBudget
+
+	// AdaptiveTimeout computes request timeouts from observed latencies
+	AdaptiveTimeout *AdaptiveTimeout
+
 	// Logger is the underline logger
 	Logger logger.Logger
 
@@ -49,6 +79,8 @@ type Options struct {
 
 type CallOptions struct {
 	SelectOptions []selector.SelectOption
+	// Strategy overrides the selector strategy for the call
+	Strategy selector.Strategy
 
 	// Address of remote hosts
 	Address []string
@@ -64,10 +96,33 @@ type CallOptions struct {
 	RequestTimeout time.Duration
 	// Stream timeout for the stream
 	StreamTimeout time.Duration
+	// StreamSendBuffer is the number of messages a stream buffers
+	// before Send blocks, zero writes each message in Send
+	StreamSendBuffer int
+	// StreamSendTimeout is how long Send blocks on a full buffer
+	// before failing, zero blocks until there's space
+	StreamSendTimeout time.Duration
 	// Use the services own auth token
 	ServiceToken bool
 	// Duration to cache the response for
 	CacheExpiry time.Duration
+	// OneWay sends the request without waiting for a response
+	OneWay bool
+	// WaitForNodes is how long to wait for a node to become
+	// available before failing, zero fails immediately
+	WaitForNodes time.Duration
+	// Regions to call in order, failing over to the next
+	// on error, the first is usually the local region
+	Regions []string
+	// ContentType overrides the content type and codec of the call
+	ContentType string
+	// Trailer is set to the trailing metadata of the response
+	Trailer *metadata.Metadata
+	// ValidateResponse fails the call if the decoded response is invalid
+	ValidateResponse bool
+	// IdempotencyKey is sent with every attempt of the call, a new
+	// one is generated for each call if it's not set
+	IdempotencyKey string
 
 	// Middleware for low level call func
 	CallWrappers []CallWrapper
@@ -155,6 +210,34 @@ func PoolSize(d int) Option {
 	}
 }
 
+// PublishCompression gzips published message bodies larger than threshold
+// bytes, setting the Content-Encoding header so subscribers decompress them.
+func PublishCompression(threshold int) Option {
+	return func(o *Options) {
+		o.PublishCompressThreshold = threshold
+	}
+}
+
+// PoolSizeFor sets the connection pool size for connections to the given service.
+// Services without a size use the PoolSize.
+func PoolSizeFor(service string, size int) Option {
+	return func(o *Options) {
+		if o.PoolSizes == nil {
+			o.PoolSizes = make(map[string]int)
+		}
+		o.PoolSizes[service] = size
+	}
+}
+
+// PoolBlocking makes calls wait for a connection once the pool size
+// connections to a node are in use, failing with a timeout if none
+// is released before the call's deadline.
+func PoolBlocking(b bool) Option {
+	return func(o *Options) {
+		o.PoolBlocking = b
+	}
+}
+
 // PoolTTL sets the connection pool ttl.
 func PoolTTL(d time.Duration) Option {
 	return func(o *Options) {
@@ -230,6 +313,26 @@ func RequestTimeout(d time.Duration) Option {
 	}
 }
 
+// WaitForNodes makes calls to a service without any available nodes wait
+// up to the timeout for one to appear rather than failing immediately, e.g
+// for services which scale from zero. The call context deadline still applies.
+func WaitForNodes(d time.Duration) Option {
+	return func(o *Options) {
+		o.CallOptions.WaitForNodes = d
+	}
+}
+
+// Regions makes calls go to the nodes of the first region and fail over to
+// the others in order. Nodes advertise their region in the RegionKey metadata.
+// Each region gets an equal share of what's left of the call deadline, so
+// time a failed region didn't use is passed on to the next. Failing over is
+// decided like retries, by the RetryFunc and for idempotent endpoints only.
+func Regions(regions ...string) Option {
+	return func(o *Options) {
+		o.CallOptions.Regions = regions
+	}
+}
+
 // StreamTimeout sets the stream timeout.
 func StreamTimeout(d time.Duration) Option {
 	return func(o *Options) {
@@ -237,6 +340,84 @@ func StreamTimeout(d time.Duration) Option {
 	}
 }
 
+// EndpointTimeouts sets the default request timeout per endpoint. It's applied
+// when a call doesn't set its own timeout via WithRequestTimeout or a context deadline.
+func EndpointTimeouts(t map[string]time.Duration) Option {
+	return func(o *Options) {
+		if o.EndpointTimeouts == nil {
+			o.EndpointTimeouts = make(map[string]time.Duration)
+		}
+		for k, v := range t {
+			o.EndpointTimeouts[k] = v
+		}
+	}
+}
+
+// WithIdempotent marks the endpoints as safe to retry e.g "Greeter.Hello" or
+// "Greeter.Get*". Once any are set calls to other endpoints fail on the first
+// error rather than being retried. By default every endpoint is retried.
+func WithIdempotent(endpoints ...string) Option {
+	return func(o *Options) {
+		o.Idempotent = append(o.Idempotent, endpoints...)
+	}
+}
+
+// WithLimiter sets the limiter consulted before each call to decide
+// whether it may proceed, and informed of the result afterwards.
+func WithLimiter(l AdaptiveLimiter) Option {
+	return func(o *Options) {
+		o.Limiter = l
+	}
+}
+
+// WithCallObserver calls fn after every attempt of a call, retries
+// included, e.g to record metrics without writing a wrapper.
+func WithCallObserver(fn func(CallEvent)) Option {
+	return func(o *Options) {
+		o.CallObservers = append(o.CallObservers, fn)
+	}
+}
+
+// WithRetryBudget limits the retries made by the client to ratio retries
+// per call, allowing bursts of up to minTokens. See NewRetryBudget.
+func WithRetryBudget(ratio float64, minTokens int) Option {
+	return func(o *Options) {
+		o.RetryBudget = NewRetryBudget(ratio, minTokens)
+	}
+}
+
+// WithAdaptiveTimeout sets the request timeout of each endpoint to the
+// percentile of its recent latencies times the multiplier, bounded by min
+// and max, e.g 0.99 and 2 for twice the p99. Until enough calls have been
+// observed the request timeout is used. Timeouts set for the endpoint or
+// the call take precedence. See NewAdaptiveTimeout.
+func WithAdaptiveTimeout(percentile, multiplier float64, min, max time.Duration) Option {
+	return func(o *Options) {
+		o.AdaptiveTimeout = NewAdaptiveTimeout(percentile, multiplier, min, max)
+	}
+}
+
+// EndpointTimeoutsFromConfig loads the endpoint timeouts from a config value
+// e.g config.Get("client", "timeouts"). The value is expected to be a map of
+// endpoint to duration string e.g {"Greeter.Hello": "2s", "Greeter.*": "5s"}.
+// Entries which fail to parse as a duration are ignored.
+func EndpointTimeoutsFromConfig(v interface{ Scan(interface{}) error }) Option {
+	var m map[string]string
+	if err := v.Scan(&m); err != nil {
+		return func(o *Options) {}
+	}
+
+	t := make(map[string]time.Duration)
+	for k, v := range m {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			continue
+		}
+		t[k] = d
+	}
+	return EndpointTimeouts(t)
+}
+
 // Transport dial timeout.
 func DialTimeout(d time.Duration) Option {
 	return func(o *Options) {
@@ -273,6 +454,23 @@ func WithSelectOption(so ...selector.SelectOption) CallOption {
 	}
 }
 
+// WithoutNodes is a CallOption which excludes the nodes with the ids from
+// selection for a single call, e.g to avoid a known bad instance. If every
+// node is excluded the call fails as none are available. It doesn't apply
+// to the addresses set with WithAddress.
+func WithoutNodes(ids ...string) CallOption {
+	return WithSelectOption(selector.WithFilter(selector.ExcludeNodes(ids...)))
+}
+
+// WithStrategy is a CallOption which overrides the selector strategy for
+// a single call. When used with WithAddress the strategy picks between
+// the given addresses.
+func WithStrategy(fn selector.Strategy) CallOption {
+	return func(o *CallOptions) {
+		o.Strategy = fn
+	}
+}
+
 // WithCallWrapper is a CallOption which adds to the existing CallFunc wrappers.
 func WithCallWrapper(cw ...CallWrapper) CallOption {
 	return func(o *CallOptions) {
@@ -312,6 +510,22 @@ func WithRequestTimeout(d time.Duration) CallOption {
 	}
 }
 
+// WithWaitForNodes is a CallOption which overrides that which
+// set in Options.CallOptions.
+func WithWaitForNodes(d time.Duration) CallOption {
+	return func(o *CallOptions) {
+		o.WaitForNodes = d
+	}
+}
+
+// WithRegions is a CallOption which overrides that which
+// set in Options.CallOptions.
+func WithRegions(regions ...string) CallOption {
+	return func(o *CallOptions) {
+		o.Regions = regions
+	}
+}
+
 // WithStreamTimeout sets the stream timeout.
 func WithStreamTimeout(d time.Duration) CallOption {
 	return func(o *CallOptions) {
@@ -319,6 +533,24 @@ func WithStreamTimeout(d time.Duration) CallOption {
 	}
 }
 
+// WithStreamSendBuffer buffers up to size messages sent on the stream,
+// written in the background. Once it's full Send blocks until a message
+// is written, applying backpressure when the receiver is slow. Messages
+// must not be modified after they've been sent.
+func WithStreamSendBuffer(size int) CallOption {
+	return func(o *CallOptions) {
+		o.StreamSendBuffer = size
+	}
+}
+
+// WithStreamSendTimeout fails Send if the stream send buffer is
+// still full after the timeout.
+func WithStreamSendTimeout(d time.Duration) CallOption {
+	return func(o *CallOptions) {
+		o.StreamSendTimeout = d
+	}
+}
+
 // WithDialTimeout is a CallOption which overrides that which
 // set in Options.CallOptions.
 func WithDialTimeout(d time.Duration) CallOption {
@@ -343,6 +575,53 @@ func WithCache(c time.Duration) CallOption {
 	}
 }
 
+// WithRequestCodec is a CallOption which makes the call with the codec for
+// the content type instead of the client default, e.g application/json for
+// a legacy service. The response is decoded with the same codec.
+func WithRequestCodec(contentType string) CallOption {
+	return func(o *CallOptions) {
+		o.ContentType = contentType
+	}
+}
+
+// WithTrailer is a CallOption which sets md to the trailing metadata the
+// handler returned with its response, e.g final counts. It's set once the
+// call returns, whether or not it failed, and is empty if no response was
+// received e.g the call timed out. For streams it's set once Recv returns
+// the end of the stream or the error the handler failed with.
+func WithTrailer(md *metadata.Metadata) CallOption {
+	return func(o *CallOptions) {
+		o.Trailer = md
+	}
+}
+
+// WithIdempotencyKey is a CallOption which sets the key sent with every
+// attempt of the call, e.g to carry the key of an operation across calls.
+// By default each call gets a new key.
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *CallOptions) {
+		o.IdempotencyKey = key
+	}
+}
+
+// WithResponseValidation is a CallOption which validates the decoded
+// response if it implements Validator, failing the call with an
+// ErrInvalidResponse error rather than returning bad data from the server.
+func WithResponseValidation() CallOption {
+	return func(o *CallOptions) {
+		o.ValidateResponse = true
+	}
+}
+
+// OneWay is a CallOption which sends the request and returns as soon
+// as it's written. The server doesn't reply so the response isn't set
+// and handler errors aren't returned, only failures to send.
+func OneWay() CallOption {
+	return func(o *CallOptions) {
+		o.OneWay = true
+	}
+}
+
 func WithMessageContentType(ct string) MessageOption {
 	return func(o *MessageOptions) {
 		o.ContentType = ct
@@ -1,9 +1,12 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
+	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/transport"
 )
 
@@ -81,3 +84,71 @@ func TestCallOptions(t *testing.T) {
 		}
 	}
 }
+
+type testConfigValue []byte
+
+func (v testConfigValue) Scan(val interface{}) error {
+	return json.Unmarshal(v, val)
+}
+
+func TestEndpointTimeouts(t *testing.T) {
+	cfg := testConfigValue(`{
+		"Foo.Bar": "2s",
+		"Foo.*": "3s",
+		"Foo.Ba*": "4s",
+		"*": "5s",
+		"Foo.Invalid": "invalid"
+	}`)
+
+	testData := []struct {
+		endpoint string
+		opts     []CallOption
+		timeout  time.Duration
+	}{
+		// exact match
+		{"Foo.Bar", nil, time.Second * 2},
+		// most specific wildcard
+		{"Foo.Baz", nil, time.Second * 4},
+		{"Foo.Qux", nil, time.Second * 3},
+		{"Bar.Foo", nil, time.Second * 5},
+		// call option takes precedence
+		{"Foo.Bar", []CallOption{WithRequestTimeout(time.Second)}, time.Second},
+	}
+
+	for _, d := range testData {
+		var timeout time.Duration
+
+		wrap := func(cf CallFunc) CallFunc {
+			return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+				timeout = opts.RequestTimeout
+				return nil
+			}
+		}
+
+		c := NewClient(
+			EndpointTimeoutsFromConfig(cfg),
+			WrapCall(wrap),
+		)
+
+		if _, ok := c.Options().EndpointTimeouts["Foo.Invalid"]; ok {
+			t.Fatal("Expected invalid duration to be ignored")
+		}
+
+		req := c.NewRequest("foo", d.endpoint, nil)
+		opts := append(d.opts, WithAddress("10.1.10.1:8080"))
+
+		if err := c.Call(context.Background(), req, nil, opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		if timeout != d.timeout {
+			t.Fatalf("Expected timeout %v for %s got %v", d.timeout, d.endpoint, timeout)
+		}
+	}
+
+	// the default applies when nothing matches
+	c := NewClient(EndpointTimeouts(map[string]time.Duration{"Foo.Bar": time.Second}))
+	if d, ok := c.(*rpcClient).endpointTimeout("Bar.Foo"); ok {
+		t.Fatalf("Expected no timeout got %v", d)
+	}
+}
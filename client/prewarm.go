@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/transport"
+	"go-micro.dev/v4/util/pool"
+)
+
+// Prewarmer is implemented by clients which can open pooled
+// connections to a service ahead of its first call.
+type Prewarmer interface {
+	// Prewarm dials up to count connections to the nodes of the
+	// service, spread evenly across them, and adds them to the pool.
+	Prewarm(ctx context.Context, service string, count int) error
+}
+
+// Prewarm opens up to count pooled connections to the nodes of the service
+// using the default client, so latency critical calls don't wait to dial.
+// The pool keeps at most its size of connections per node.
+func Prewarm(ctx context.Context, service string, count int) error {
+	p, ok := DefaultClient.(Prewarmer)
+	if !ok {
+		return fmt.Errorf("client %s can't prewarm connections", DefaultClient.String())
+	}
+	return p.Prewarm(ctx, service, count)
+}
+
+func (r *rpcClient) Prewarm(ctx context.Context, service string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	nodes, err := Nodes(r, service)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return errors.InternalServerError("go.micro.client", "service %s: not found", service)
+	}
+
+	dOpts := []transport.DialOption{
+		transport.WithStream(),
+	}
+
+	if r.opts.CallOptions.DialTimeout >= 0 {
+		dOpts = append(dOpts, transport.WithTimeout(r.opts.CallOptions.DialTimeout))
+	}
+
+	p := r.getPool(service)
+
+	// hold every connection until all are dialed, releasing
+	// them early would hand the same one out again
+	conns := make([]pool.Conn, 0, count)
+	defer func() {
+		for _, c := range conns {
+			p.Release(c, nil)
+		}
+	}()
+
+	var gerr error
+
+	for i := 0; i < count; i++ {
+		node := nodes[i%len(nodes)]
+
+		c, err := pool.GetContext(ctx, p, node.Address, dOpts...)
+		if err == context.DeadlineExceeded || err == context.Canceled {
+			return contextError(err, "prewarming connections: %v", err)
+		} else if err != nil {
+			gerr = newConnectionError(err)
+			continue
+		}
+
+		conns = append(conns, c)
+	}
+
+	return gerr
+}
@@ -1,8 +1,13 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"path"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,24 +29,23 @@ type rpcClient struct {
 	seq  uint64
 	once atomic.Value
 	opts Options
+
+	// guards the pools which are replaced on Init
+	pmu  sync.RWMutex
 	pool pool.Pool
+	// pools for services with their own pool size
+	pools map[string]pool.Pool
 }
 
 func newRpcClient(opt ...Option) Client {
 	opts := NewOptions(opt...)
 
-	p := pool.NewPool(
-		pool.Size(opts.PoolSize),
-		pool.TTL(opts.PoolTTL),
-		pool.Transport(opts.Transport),
-	)
-
 	rc := &rpcClient{
 		opts: opts,
-		pool: p,
 		seq:  0,
 	}
 	rc.once.Store(false)
+	rc.newPools()
 
 	c := Client(rc)
 
@@ -53,6 +57,75 @@ func newRpcClient(opt ...Option) Client {
 	return c
 }
 
+// newPools creates the default pool and a pool per service with its own size.
+// It's called with the pool lock held or before the client is shared.
+func (r *rpcClient) newPools() {
+	r.pool = pool.NewPool(
+		pool.Size(r.opts.PoolSize),
+		pool.TTL(r.opts.PoolTTL),
+		pool.Transport(r.opts.Transport),
+		pool.Blocking(r.opts.PoolBlocking),
+	)
+
+	r.pools = make(map[string]pool.Pool, len(r.opts.PoolSizes))
+	for service, size := range r.opts.PoolSizes {
+		r.pools[service] = pool.NewPool(
+			pool.Size(size),
+			pool.TTL(r.opts.PoolTTL),
+			pool.Transport(r.opts.Transport),
+			pool.Blocking(r.opts.PoolBlocking),
+		)
+	}
+}
+
+// getPool returns the connection pool for a service.
+func (r *rpcClient) getPool(service string) pool.Pool {
+	r.pmu.RLock()
+	defer r.pmu.RUnlock()
+
+	if p, ok := r.pools[service]; ok {
+		return p
+	}
+	return r.pool
+}
+
+// contentType returns the content type to call with. One set for the call
+// wins, then one set on the request, then one carried by the context if
+// there's a codec for it.
+func (r *rpcClient) contentType(ctx context.Context, req Request, opts CallOptions) string {
+	if len(opts.ContentType) > 0 {
+		return opts.ContentType
+	}
+
+	if rr, ok := req.(*rpcRequest); ok && len(rr.opts.ContentType) > 0 {
+		return rr.opts.ContentType
+	}
+
+	if ct, ok := ContentTypeFromContext(ctx); ok {
+		if _, err := r.newCodec(ct); err == nil {
+			return ct
+		}
+	}
+
+	return req.ContentType()
+}
+
+// accept returns the codec for the response content type carried by the
+// context, or nil if it's the call content type or there's no codec for it.
+func (r *rpcClient) accept(ctx context.Context, ct string) (string, codec.NewCodec) {
+	accept, ok := AcceptFromContext(ctx)
+	if !ok || accept == ct {
+		return ct, nil
+	}
+
+	cf, err := r.newCodec(accept)
+	if err != nil {
+		return ct, nil
+	}
+
+	return accept, cf
+}
+
 func (r *rpcClient) newCodec(contentType string) (codec.NewCodec, error) {
 	if c, ok := r.opts.Codecs[contentType]; ok {
 		return c, nil
@@ -82,23 +155,35 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 		}
 	}
 
+	setAttemptHeaders(ctx, msg.Header)
+
 	// set timeout in nanoseconds
 	msg.Header["Timeout"] = fmt.Sprintf("%d", opts.RequestTimeout)
+	ct := r.contentType(ctx, req, opts)
+
 	// set the content type for the request
-	msg.Header["Content-Type"] = req.ContentType()
+	msg.Header["Content-Type"] = ct
 	// set the accept header
-	msg.Header["Accept"] = req.ContentType()
+	msg.Header["Accept"] = ct
+	// tell the server not to reply
+	if opts.OneWay {
+		msg.Header["Micro-One-Way"] = "true"
+	}
 
 	// setup old protocol
 	cf := setupProtocol(msg, node)
 
+	// the codec for a response content type other than the request's
+	var af codec.NewCodec
+
 	// no codec specified
 	if cf == nil {
 		var err error
-		cf, err = r.newCodec(req.ContentType())
+		cf, err = r.newCodec(ct)
 		if err != nil {
 			return errors.InternalServerError("go.micro.client", err.Error())
 		}
+		msg.Header["Accept"], af = r.accept(ctx, ct)
 	}
 
 	dOpts := []transport.DialOption{
@@ -109,13 +194,17 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 		dOpts = append(dOpts, transport.WithTimeout(opts.DialTimeout))
 	}
 
-	c, err := r.pool.Get(address, dOpts...)
-	if err != nil {
-		return errors.InternalServerError("go.micro.client", "connection error: %v", err)
+	p := r.getPool(req.Service())
+
+	c, err := pool.GetContext(ctx, p, address, dOpts...)
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return contextError(err, "waiting for a connection: %v", err)
+	} else if err != nil {
+		return newConnectionError(err)
 	}
 
 	seq := atomic.AddUint64(&r.seq, 1) - 1
-	codec := newRpcCodec(msg, c, cf, "")
+	codec := newRpcCodec(msg, c, cf, af, "")
 
 	rsp := &rpcResponse{
 		socket: c,
@@ -129,7 +218,7 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 		response: rsp,
 		codec:    codec,
 		closed:   make(chan bool),
-		release:  func(err error) { r.pool.Release(c, err) },
+		release:  func(err error) { p.Release(c, err) },
 		sendEOS:  false,
 	}
 	// close the stream on exiting this function
@@ -151,12 +240,25 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 			return
 		}
 
+		// no response is coming
+		if opts.OneWay {
+			ch <- nil
+			return
+		}
+
 		// recv request
 		if err := stream.Recv(resp); err != nil {
 			ch <- err
 			return
 		}
 
+		if opts.ValidateResponse {
+			if err := validateResponse(req, resp); err != nil {
+				ch <- err
+				return
+			}
+		}
+
 		// success
 		ch <- nil
 	}()
@@ -165,9 +267,17 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 
 	select {
 	case err := <-ch:
+		if opts.Trailer != nil {
+			*opts.Trailer = trailer(rsp.header)
+		}
 		return err
 	case <-ctx.Done():
-		grr = errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
+		grr = contextError(ctx.Err(), "%v", ctx.Err())
+	}
+
+	// no response was received
+	if opts.Trailer != nil {
+		*opts.Trailer = metadata.Metadata{}
 	}
 
 	// set the stream error
@@ -182,6 +292,17 @@ func (r *rpcClient) call(ctx context.Context, node *registry.Node, req Request,
 	return nil
 }
 
+// trailer returns the trailing metadata carried by the response header.
+func trailer(header map[string]string) metadata.Metadata {
+	md := make(metadata.Metadata)
+	for k, v := range header {
+		if strings.HasPrefix(k, trailerPrefix) {
+			md[strings.TrimPrefix(k, trailerPrefix)] = v
+		}
+	}
+	return md
+}
+
 func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request, opts CallOptions) (Stream, error) {
 	address := node.Address
 
@@ -196,25 +317,33 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 		}
 	}
 
+	setAttemptHeaders(ctx, msg.Header)
+
 	// set timeout in nanoseconds
 	if opts.StreamTimeout > time.Duration(0) {
 		msg.Header["Timeout"] = fmt.Sprintf("%d", opts.StreamTimeout)
 	}
+	ct := r.contentType(ctx, req, opts)
+
 	// set the content type for the request
-	msg.Header["Content-Type"] = req.ContentType()
+	msg.Header["Content-Type"] = ct
 	// set the accept header
-	msg.Header["Accept"] = req.ContentType()
+	msg.Header["Accept"] = ct
 
 	// set old codecs
 	cf := setupProtocol(msg, node)
 
+	// the codec for a response content type other than the request's
+	var af codec.NewCodec
+
 	// no codec specified
 	if cf == nil {
 		var err error
-		cf, err = r.newCodec(req.ContentType())
+		cf, err = r.newCodec(ct)
 		if err != nil {
 			return nil, errors.InternalServerError("go.micro.client", err.Error())
 		}
+		msg.Header["Accept"], af = r.accept(ctx, ct)
 	}
 
 	dOpts := []transport.DialOption{
@@ -227,7 +356,7 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 
 	c, err := r.opts.Transport.Dial(address, dOpts...)
 	if err != nil {
-		return nil, errors.InternalServerError("go.micro.client", "connection error: %v", err)
+		return nil, newConnectionError(err)
 	}
 
 	// increment the sequence number
@@ -235,7 +364,7 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 	id := fmt.Sprintf("%v", seq)
 
 	// create codec with stream id
-	codec := newRpcCodec(msg, c, cf, id)
+	codec := newRpcCodec(msg, c, cf, af, id)
 
 	rsp := &rpcResponse{
 		socket: c,
@@ -259,6 +388,7 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 		sendEOS: true,
 		// release func
 		release: func(err error) { c.Close() },
+		trailer: opts.Trailer,
 	}
 
 	// wait for error response
@@ -275,7 +405,7 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 	case err := <-ch:
 		grr = err
 	case <-ctx.Done():
-		grr = errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
+		grr = contextError(ctx.Err(), "%v", ctx.Err())
 	}
 
 	if grr != nil {
@@ -289,6 +419,11 @@ func (r *rpcClient) stream(ctx context.Context, node *registry.Node, req Request
 		return nil, grr
 	}
 
+	// buffer the rest of the messages
+	if opts.StreamSendBuffer > 0 {
+		stream.buffer(opts.StreamSendBuffer, opts.StreamSendTimeout)
+	}
+
 	return stream, nil
 }
 
@@ -296,21 +431,36 @@ func (r *rpcClient) Init(opts ...Option) error {
 	size := r.opts.PoolSize
 	ttl := r.opts.PoolTTL
 	tr := r.opts.Transport
+	blocking := r.opts.PoolBlocking
+
+	sizes := make(map[string]int, len(r.opts.PoolSizes))
+	for k, v := range r.opts.PoolSizes {
+		sizes[k] = v
+	}
 
 	for _, o := range opts {
 		o(&r.opts)
 	}
 
+	changed := len(sizes) != len(r.opts.PoolSizes)
+	for k, v := range r.opts.PoolSizes {
+		if sizes[k] != v {
+			changed = true
+		}
+	}
+
 	// update pool configuration if the options changed
-	if size != r.opts.PoolSize || ttl != r.opts.PoolTTL || tr != r.opts.Transport {
-		// close existing pool
-		r.pool.Close()
-		// create new pool
-		r.pool = pool.NewPool(
-			pool.Size(r.opts.PoolSize),
-			pool.TTL(r.opts.PoolTTL),
-			pool.Transport(r.opts.Transport),
-		)
+	if changed || size != r.opts.PoolSize || ttl != r.opts.PoolTTL || tr != r.opts.Transport || blocking != r.opts.PoolBlocking {
+		// swap in new pools then close the existing ones
+		r.pmu.Lock()
+		old, oldPools := r.pool, r.pools
+		r.newPools()
+		r.pmu.Unlock()
+
+		old.Close()
+		for _, p := range oldPools {
+			p.Close()
+		}
 	}
 
 	return nil
@@ -321,7 +471,7 @@ func (r *rpcClient) Options() Options {
 }
 
 // next returns an iterator for the next nodes to call.
-func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, error) {
+func (r *rpcClient) next(ctx context.Context, request Request, opts CallOptions) (selector.Next, error) {
 	// try get the proxy
 	service, address, _ := net.Proxy(request.Service(), opts.Address)
 
@@ -339,15 +489,34 @@ func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, erro
 			}
 		}
 
+		// let the call strategy pick between the addresses
+		if opts.Strategy != nil {
+			return opts.Strategy([]*registry.Service{{Name: service, Nodes: nodes}}), nil
+		}
+
 		// crude return method
 		return func() (*registry.Node, error) {
 			return nodes[time.Now().Unix()%int64(len(nodes))], nil
 		}, nil
 	}
 
+	// pass the call context to the selector, call options may override it
+	sopts := append([]selector.SelectOption{selector.WithContext(ctx)}, opts.SelectOptions...)
+
+	// the call strategy takes precedence over any set by select options
+	if opts.Strategy != nil {
+		sopts = append(sopts, selector.WithStrategy(opts.Strategy))
+	}
+
 	// get next nodes from the selector
-	next, err := r.opts.Selector.Select(service, opts.SelectOptions...)
+	next, err := r.opts.Selector.Select(service, sopts...)
+	if (err == selector.ErrNotFound || err == selector.ErrNoneAvailable) && opts.WaitForNodes > 0 {
+		next, err = r.waitForNodes(ctx, service, sopts, opts.WaitForNodes)
+	}
 	if err != nil {
+		if err == context.DeadlineExceeded || err == context.Canceled {
+			return nil, contextError(err, "waiting for %s node: %v", service, err)
+		}
 		if err == selector.ErrNotFound {
 			return nil, errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
 		}
@@ -357,14 +526,223 @@ func (r *rpcClient) next(request Request, opts CallOptions) (selector.Next, erro
 	return next, nil
 }
 
-func (r *rpcClient) Call(ctx context.Context, request Request, response interface{}, opts ...CallOption) error {
+// waitForNodes retries selecting a node of the service until one is
+// available, the wait expires or the context is done. It's retried on
+// every registry change to the service and every DefaultWaitInterval.
+func (r *rpcClient) waitForNodes(ctx context.Context, service string, sopts []selector.SelectOption, wait time.Duration) (selector.Next, error) {
+	wctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	changed := make(chan bool, 1)
+
+	if reg := r.opts.Selector.Options().Registry; reg != nil {
+		if w, err := reg.Watch(registry.WatchService(service)); err == nil {
+			defer w.Stop()
+
+			go func() {
+				for {
+					if _, err := w.Next(); err != nil {
+						return
+					}
+					select {
+					case changed <- true:
+					default:
+					}
+				}
+			}()
+		}
+	}
+
+	t := time.NewTicker(DefaultWaitInterval)
+	defer t.Stop()
+
+	lastErr := selector.ErrNotFound
+
+	for {
+		select {
+		case <-wctx.Done():
+			// the call context is done rather than the wait expiring
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, lastErr
+		case <-changed:
+		case <-t.C:
+		}
+
+		// skip any stale cached result
+		r.opts.Selector.Reset(service)
+
+		next, err := r.opts.Selector.Select(service, sopts...)
+		if err == selector.ErrNotFound || err == selector.ErrNoneAvailable {
+			lastErr = err
+			continue
+		}
+
+		return next, err
+	}
+}
+
+// endpointTimeout returns the configured timeout for an endpoint. An exact match
+// takes precedence, otherwise the longest matching wildcard pattern is used.
+func (r *rpcClient) endpointTimeout(endpoint string) (time.Duration, bool) {
+	if d, ok := r.opts.EndpointTimeouts[endpoint]; ok {
+		return d, true
+	}
+
+	var match string
+	var timeout time.Duration
+
+	for pattern, d := range r.opts.EndpointTimeouts {
+		if ok, _ := path.Match(pattern, endpoint); !ok {
+			continue
+		}
+		// prefer the most specific pattern, break ties lexically
+		if len(pattern) > len(match) || (len(pattern) == len(match) && pattern < match) {
+			match = pattern
+			timeout = d
+		}
+	}
+
+	return timeout, len(match) > 0
+}
+
+// idempotent reports whether the endpoint may be retried. Every endpoint
+// is when no idempotent endpoints are configured.
+func (r *rpcClient) idempotent(endpoint string) bool {
+	if len(r.opts.Idempotent) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.opts.Idempotent {
+		if pattern == endpoint {
+			return true
+		}
+		if ok, _ := path.Match(pattern, endpoint); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNodeError reports whether the error means the selected node is gone,
+// either because the selector has none left or the dial was refused.
+func isNodeError(err error) bool {
+	switch err {
+	case nil:
+		return false
+	case selector.ErrNotFound, selector.ErrNoneAvailable:
+		return true
+	}
+
+	e := errors.Parse(err.Error())
+	if e == nil || e.Id != "go.micro.client" || e.Code != 500 {
+		return false
+	}
+
+	return strings.HasPrefix(e.Detail, "connection error")
+}
+
+func (r *rpcClient) Call(ctx context.Context, request Request, response interface{}, opts ...CallOption) (err error) {
 	// make a copy of call opts
 	callOpts := r.opts.CallOptions
+
+	// apply the endpoint timeout before the call options so they can override it
+	if d, ok := r.endpointTimeout(request.Endpoint()); ok {
+		callOpts.RequestTimeout = d
+	} else if a := r.opts.AdaptiveTimeout; a != nil {
+		if d, ok := a.Timeout(request.Service(), request.Endpoint()); ok {
+			callOpts.RequestTimeout = d
+		}
+	}
+
 	for _, opt := range opts {
 		opt(&callOpts)
 	}
 
-	next, err := r.next(request, callOpts)
+	r.deposit()
+
+	// every attempt carries the same key
+	ctx = withIdempotencyKey(ctx, callOpts.IdempotencyKey)
+
+	// a call holds one slot across its retries and regions
+	if l := r.opts.Limiter; l != nil {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, callOpts.RequestTimeout)
+			defer cancel()
+		}
+
+		if err := l.Acquire(ctx, request); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		defer func() {
+			l.Release(request, time.Since(start), err)
+		}()
+	}
+
+	// regions don't apply to proxied calls
+	if _, _, proxied := net.Proxy(request.Service(), callOpts.Address); len(callOpts.Regions) > 0 && !proxied {
+		return r.failover(ctx, request, response, callOpts)
+	}
+
+	return r.invoke(ctx, request, response, callOpts)
+}
+
+// failover calls the regions in order until one succeeds, each is given an
+// equal share of what's left of the deadline. Failing over is decided like
+// retries are.
+func (r *rpcClient) failover(ctx context.Context, request Request, response interface{}, callOpts CallOptions) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callOpts.RequestTimeout)
+		defer cancel()
+	}
+
+	deadline, _ := ctx.Deadline()
+	regions := callOpts.Regions
+
+	for i, region := range regions {
+		budget := time.Until(deadline) / time.Duration(len(regions)-i)
+		if budget <= 0 {
+			break
+		}
+
+		// only select the nodes in the region
+		opts := callOpts
+		opts.Regions = nil
+		opts.SelectOptions = append(
+			append([]selector.SelectOption{}, callOpts.SelectOptions...),
+			selector.WithFilter(selector.FilterLabel(RegionKey, region)),
+		)
+
+		rctx, cancel := context.WithTimeout(ctx, budget)
+		err := r.invoke(rctx, request, response, opts)
+		cancel()
+
+		if err == nil || i == len(regions)-1 || !r.idempotent(request.Endpoint()) {
+			return err
+		}
+
+		retry, rerr := callOpts.Retry(ctx, request, i, err)
+		if rerr != nil {
+			return rerr
+		}
+
+		if !retry || !r.withdraw() {
+			return err
+		}
+	}
+
+	return errors.Timeout("go.micro.client", "call timeout: %v", context.DeadlineExceeded)
+}
+
+// invoke makes the call, retrying it as the call options allow.
+func (r *rpcClient) invoke(ctx context.Context, request Request, response interface{}, callOpts CallOptions) error {
+	next, err := r.next(ctx, request, callOpts)
 	if err != nil {
 		return err
 	}
@@ -386,7 +764,7 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 	// should we noop right here?
 	select {
 	case <-ctx.Done():
-		return errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
+		return contextError(ctx.Err(), "%v", ctx.Err())
 	default:
 	}
 
@@ -398,6 +776,57 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 		rcall = callOpts.CallWrappers[i-1](rcall)
 	}
 
+	// a proxied call has no selector state to refresh
+	_, _, proxied := net.Proxy(request.Service(), callOpts.Address)
+
+	// the selector may hand out stale nodes, allow a single forced
+	// refresh per call when a node can't be found or reached
+	var (
+		mtx       sync.Mutex
+		refreshed bool
+	)
+
+	getNext := func() selector.Next {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return next
+	}
+
+	refresh := func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if proxied || refreshed {
+			return false
+		}
+		refreshed = true
+
+		r.opts.Selector.Reset(request.Service())
+
+		n, err := r.next(ctx, request, callOpts)
+		if err != nil {
+			return false
+		}
+		next = n
+
+		return true
+	}
+
+	// attempts set a trailer of their own, the caller's is set as the
+	// call returns so an attempt still running can't set it later
+	var (
+		tmtx sync.Mutex
+		tr   = metadata.Metadata{}
+	)
+
+	if md := callOpts.Trailer; md != nil {
+		defer func() {
+			tmtx.Lock()
+			*md = tr
+			tmtx.Unlock()
+		}()
+	}
+
 	// return errors.New("go.micro.client", "request timeout", 408)
 	call := func(i int) error {
 		// call backoff first. Someone may want an initial start delay
@@ -411,27 +840,73 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 			time.Sleep(t)
 		}
 
-		// select next node
-		node, err := next()
 		service := request.Service()
-		if err != nil {
-			if err == selector.ErrNotFound {
-				return errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
+
+		for {
+			// select next node
+			node, err := getNext()()
+			if err != nil {
+				if isNodeError(err) && refresh() {
+					continue
+				}
+				if err == selector.ErrNotFound {
+					return errors.InternalServerError("go.micro.client", "service %s: %s", service, err.Error())
+				}
+				return errors.InternalServerError("go.micro.client", "error getting next %s node: %s", service, err.Error())
 			}
-			return errors.InternalServerError("go.micro.client", "error getting next %s node: %s", service, err.Error())
-		}
 
-		// make the call
-		err = rcall(ctx, node, request, response, callOpts)
-		r.opts.Selector.Mark(service, node, err)
-		return err
+			opts := callOpts
+
+			var md metadata.Metadata
+			if opts.Trailer != nil {
+				opts.Trailer = &md
+			}
+
+			// make the call
+			start := time.Now()
+			err = rcall(withAttempt(ctx, i), node, request, response, opts)
+			r.opts.Selector.Mark(service, node, err)
+
+			if opts.Trailer != nil {
+				tmtx.Lock()
+				tr = md
+				tmtx.Unlock()
+			}
+
+			d := time.Since(start)
+
+			r.observe(CallEvent{
+				Service:  service,
+				Endpoint: request.Endpoint(),
+				Node:     node,
+				Attempt:  i + 1,
+				Duration: d,
+				Error:    err,
+			})
+
+			// only the successful attempt itself, not retries or backoff
+			if a := r.opts.AdaptiveTimeout; a != nil && err == nil {
+				a.observe(service, request.Endpoint(), d)
+			}
+
+			if isNodeError(err) && refresh() {
+				continue
+			}
+
+			return err
+		}
 	}
 
 	// get the retries
 	retries := callOpts.Retries
 
 	// disable retries when using a proxy
-	if _, _, ok := net.Proxy(request.Service(), callOpts.Address); ok {
+	if proxied {
+		retries = 0
+	}
+
+	// only retry endpoints safe to repeat
+	if !r.idempotent(request.Endpoint()) {
 		retries = 0
 	}
 
@@ -445,7 +920,7 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 
 		select {
 		case <-ctx.Done():
-			return errors.Timeout("go.micro.client", fmt.Sprintf("call timeout: %v", ctx.Err()))
+			return contextError(ctx.Err(), "call timeout: %v", ctx.Err())
 		case err := <-ch:
 			// if the call succeeded lets bail early
 			if err == nil {
@@ -461,6 +936,11 @@ func (r *rpcClient) Call(ctx context.Context, request Request, response interfac
 				return err
 			}
 
+			// stop once the budget runs out
+			if i < retries && !r.withdraw() {
+				return err
+			}
+
 			gerr = err
 		}
 	}
@@ -475,7 +955,7 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 		opt(&callOpts)
 	}
 
-	next, err := r.next(request, callOpts)
+	next, err := r.next(ctx, request, callOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -483,10 +963,15 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 	// should we noop right here?
 	select {
 	case <-ctx.Done():
-		return nil, errors.Timeout("go.micro.client", fmt.Sprintf("%v", ctx.Err()))
+		return nil, contextError(ctx.Err(), "%v", ctx.Err())
 	default:
 	}
 
+	r.deposit()
+
+	// every attempt carries the same key
+	ctx = withIdempotencyKey(ctx, callOpts.IdempotencyKey)
+
 	call := func(i int) (Stream, error) {
 		// call backoff first. Someone may want an initial start delay
 		t, err := callOpts.Backoff(ctx, request, i)
@@ -508,7 +993,7 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 			return nil, errors.InternalServerError("go.micro.client", "error getting next %s node: %s", service, err.Error())
 		}
 
-		stream, err := r.stream(ctx, node, request, callOpts)
+		stream, err := r.stream(withAttempt(ctx, i), node, request, callOpts)
 		r.opts.Selector.Mark(service, node, err)
 		return stream, err
 	}
@@ -526,6 +1011,11 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 		retries = 0
 	}
 
+	// only retry endpoints safe to repeat
+	if !r.idempotent(request.Endpoint()) {
+		retries = 0
+	}
+
 	ch := make(chan response, retries+1)
 	var grr error
 
@@ -537,7 +1027,7 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 
 		select {
 		case <-ctx.Done():
-			return nil, errors.Timeout("go.micro.client", fmt.Sprintf("call timeout: %v", ctx.Err()))
+			return nil, contextError(ctx.Err(), "call timeout: %v", ctx.Err())
 		case rsp := <-ch:
 			// if the call succeeded lets bail early
 			if rsp.err == nil {
@@ -553,6 +1043,11 @@ func (r *rpcClient) Stream(ctx context.Context, request Request, opts ...CallOpt
 				return nil, rsp.err
 			}
 
+			// stop once the budget runs out
+			if i < retries && !r.withdraw() {
+				return nil, rsp.err
+			}
+
 			grr = rsp.err
 		}
 	}
@@ -573,6 +1068,10 @@ func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOpt
 		md = make(map[string]string)
 	}
 
+	// messages aren't attempts of a call
+	delete(md, AttemptHeader)
+	delete(md, IdempotencyKeyHeader)
+
 	id := uuid.New().String()
 	md["Content-Type"] = msg.ContentType()
 	md["Micro-Topic"] = msg.Topic()
@@ -605,8 +1104,9 @@ func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOpt
 			Target: topic,
 			Type:   codec.Event,
 			Header: map[string]string{
-				"Micro-Id":    id,
-				"Micro-Topic": msg.Topic(),
+				"Content-Type": msg.ContentType(),
+				"Micro-Id":     id,
+				"Micro-Topic":  msg.Topic(),
 			},
 		}, msg.Payload()); err != nil {
 			return errors.InternalServerError("go.micro.client", err.Error())
@@ -616,6 +1116,22 @@ func (r *rpcClient) Publish(ctx context.Context, msg Message, opts ...PublishOpt
 		body = b.Bytes()
 	}
 
+	// compress large bodies
+	if t := r.opts.PublishCompressThreshold; t > 0 && len(body) > t {
+		var b bytes.Buffer
+
+		w := gzip.NewWriter(&b)
+		if _, err := w.Write(body); err != nil {
+			return errors.InternalServerError("go.micro.client", err.Error())
+		}
+		if err := w.Close(); err != nil {
+			return errors.InternalServerError("go.micro.client", err.Error())
+		}
+
+		body = b.Bytes()
+		md["Content-Encoding"] = "gzip"
+	}
+
 	if !r.once.Load().(bool) {
 		if err = r.opts.Broker.Connect(); err != nil {
 			return errors.InternalServerError("go.micro.client", err.Error())
@@ -2,12 +2,20 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	errs "errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"go-micro.dev/v4/errors"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/selector"
+	"go-micro.dev/v4/transport"
+	"go-micro.dev/v4/util/pool"
 )
 
 func newTestRegistry() registry.Registry {
@@ -157,3 +165,1875 @@ func TestCallWrapper(t *testing.T) {
 		t.Fatal("wrapper not called")
 	}
 }
+
+func TestPoolSizeFor(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(s transport.Socket) {})
+
+	c := NewClient(
+		Transport(tr),
+		PoolSize(1),
+		PoolSizeFor("foo", 5),
+	)
+
+	rc := c.(*rpcClient)
+
+	// burst connections to the service, release them, then count
+	// how many of the next burst come from the pool
+	reused := func(service string) int {
+		p := rc.getPool(service)
+		ids := make(map[string]bool)
+
+		burst := func() []pool.Conn {
+			conns := make([]pool.Conn, 0, 5)
+			for i := 0; i < 5; i++ {
+				conn, err := p.Get(l.Addr())
+				if err != nil {
+					t.Fatal(err)
+				}
+				conns = append(conns, conn)
+			}
+			return conns
+		}
+
+		for _, conn := range burst() {
+			ids[conn.Id()] = true
+			p.Release(conn, nil)
+		}
+
+		var count int
+
+		for _, conn := range burst() {
+			if ids[conn.Id()] {
+				count++
+			}
+			p.Release(conn, nil)
+		}
+
+		return count
+	}
+
+	if n := reused("foo"); n != 5 {
+		t.Fatalf("Expected 5 pooled connections for foo got %d", n)
+	}
+
+	if n := reused("bar"); n != 1 {
+		t.Fatalf("Expected 1 pooled connection for bar got %d", n)
+	}
+
+	// the service pools are recreated when the sizes change
+	if err := c.Init(PoolSizeFor("bar", 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := reused("bar"); n != 3 {
+		t.Fatalf("Expected 3 pooled connections for bar got %d", n)
+	}
+}
+
+// staleRegistry never sends watch events so the selector cache is only
+// refreshed by explicit lookups.
+type staleRegistry struct {
+	registry.Registry
+}
+
+type staleWatcher struct {
+	exit chan bool
+}
+
+func (w *staleWatcher) Next() (*registry.Result, error) {
+	<-w.exit
+	return nil, registry.ErrWatcherStopped
+}
+
+func (w *staleWatcher) Stop() {
+	close(w.exit)
+}
+
+func (r *staleRegistry) Watch(...registry.WatchOption) (registry.Watcher, error) {
+	return &staleWatcher{exit: make(chan bool)}, nil
+}
+
+func TestPoolSizeForConcurrentInit(t *testing.T) {
+	c := NewClient(PoolSizeFor("foo", 2))
+	rc := c.(*rpcClient)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if err := c.Init(PoolSizeFor("foo", 2+i%2)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if p := rc.getPool("foo"); p == nil {
+				t.Error("Expected a pool for foo")
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestCallRefreshStaleSelector(t *testing.T) {
+	service := "test.service"
+	endpoint := "Test.Endpoint"
+	stale := "10.1.10.1:8080"
+	fresh := "10.1.10.2:8080"
+
+	newService := func(address string) *registry.Service {
+		return &registry.Service{
+			Name:    service,
+			Version: "1.0.0",
+			Nodes: []*registry.Node{
+				{Id: address, Address: address},
+			},
+		}
+	}
+
+	r := &staleRegistry{registry.NewMemoryRegistry()}
+	if err := r.Register(newService(stale)); err != nil {
+		t.Fatal(err)
+	}
+
+	var addrs []string
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			addrs = append(addrs, node.Address)
+			if node.Address == stale {
+				return errors.InternalServerError("go.micro.client", "connection error: dial tcp %s: connection refused", node.Address)
+			}
+			return nil
+		}
+	}
+
+	s := selector.NewSelector(selector.Registry(r))
+	c := NewClient(
+		Registry(r),
+		Selector(s),
+		WrapCall(wrap),
+		Retries(0),
+	)
+
+	// warm the selector cache with the stale node
+	if _, err := s.Select(service); err != nil {
+		t.Fatal(err)
+	}
+
+	// the service moves without the cache being told
+	if err := r.Deregister(newService(stale)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(newService(fresh)); err != nil {
+		t.Fatal(err)
+	}
+
+	req := c.NewRequest(service, endpoint, nil)
+	if err := c.Call(context.Background(), req, nil); err != nil {
+		t.Fatalf("expected call to succeed after refresh, got %v", err)
+	}
+
+	if len(addrs) != 2 || addrs[0] != stale || addrs[1] != fresh {
+		t.Fatalf("expected calls to %s then %s, got %v", stale, fresh, addrs)
+	}
+
+	// the refresh is only forced once per call
+	addrs = nil
+	if err := r.Deregister(newService(fresh)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(newService(stale)); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset(service)
+
+	if err := c.Call(context.Background(), req, nil); err == nil {
+		t.Fatal("expected call to a stale node to fail")
+	}
+
+	if len(addrs) != 2 {
+		t.Fatalf("expected a single forced refresh, got calls %v", addrs)
+	}
+}
+
+func TestCallWithStrategy(t *testing.T) {
+	var addrs []string
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			addrs = append(addrs, node.Address)
+			return nil
+		}
+	}
+
+	var calls int
+
+	// always pick the last node seen
+	last := func(services []*registry.Service) selector.Next {
+		calls++
+		var node *registry.Node
+		for _, s := range services {
+			for _, n := range s.Nodes {
+				node = n
+			}
+		}
+		return func() (*registry.Node, error) {
+			return node, nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		Selector(selector.NewSelector(selector.Registry(r))),
+		WrapCall(wrap),
+	)
+
+	req := c.NewRequest("foo", "Test.Endpoint", nil)
+
+	if err := c.Call(context.Background(), req, nil, WithStrategy(last)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected strategy to be used once got %d", calls)
+	}
+
+	// the override only applies to the call it was passed to
+	if err := c.Call(context.Background(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected strategy not to be used without the option got %d", calls)
+	}
+
+	// with an address the strategy picks between the given addresses
+	addrs = nil
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.0.0.1:8080", "10.0.0.2:8080"), WithStrategy(last)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(addrs) != 1 || addrs[0] != "10.0.0.2:8080" {
+		t.Fatalf("Expected strategy to pick 10.0.0.2:8080 got %v after %d calls", addrs, calls)
+	}
+}
+
+func TestCallWithoutNodes(t *testing.T) {
+	seen := make(map[string]int)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			seen[node.Id]++
+			return nil
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		Selector(selector.NewSelector(selector.Registry(r))),
+		WrapCall(wrap),
+	)
+
+	req := c.NewRequest("foo", "Test.Endpoint", nil)
+
+	for i := 0; i < 100; i++ {
+		if err := c.Call(context.Background(), req, nil, WithoutNodes("foo-1.0.0-123", "foo-1.0.3-345")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if seen["foo-1.0.0-123"] > 0 || seen["foo-1.0.3-345"] > 0 {
+		t.Fatalf("Expected the excluded nodes not to be chosen, got %v", seen)
+	}
+	if seen["foo-1.0.0-321"]+seen["foo-1.0.1-321"] != 100 {
+		t.Fatalf("Expected the other nodes to be chosen, got %v", seen)
+	}
+
+	// with every node excluded there's none available
+	var ids []string
+	for _, s := range testData["foo"] {
+		for _, n := range s.Nodes {
+			ids = append(ids, n.Id)
+		}
+	}
+
+	err := c.Call(context.Background(), req, nil, WithoutNodes(ids...))
+	if err == nil || !strings.Contains(err.Error(), selector.ErrNoneAvailable.Error()) {
+		t.Fatalf("Expected %v, got %v", selector.ErrNoneAvailable, err)
+	}
+}
+
+func TestEgressLimit(t *testing.T) {
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			return nil
+		}
+	}
+
+	c := NewClient(
+		WrapCall(wrap),
+		Wrap(EgressLimit("foo", 100, 2)),
+		Wrap(EgressLimit("bar", Every(time.Millisecond*10), 2)),
+		Wrap(EgressLimit("baz", Inf, 0)),
+	)
+
+	call := func(ctx context.Context, service string) error {
+		req := c.NewRequest(service, "Test.Endpoint", nil)
+		return c.Call(ctx, req, nil, WithAddress("10.0.0.1:8080"))
+	}
+
+	// the burst is allowed straight away
+	for _, service := range []string{"foo", "foo", "bar", "bar"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		err := call(ctx, service)
+		cancel()
+		if err != nil {
+			t.Fatalf("Expected the burst to %s to be allowed, got %v", service, err)
+		}
+	}
+
+	// calls which can't wait long enough fail straight away
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := call(ctx, "foo"); !errs.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected %v, got %v", ErrRateLimited, err)
+	}
+
+	// others wait their turn, at 100 per second
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := call(context.Background(), "foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < time.Millisecond*40 {
+		t.Fatalf("Expected 5 calls to take at least 40ms, took %v", d)
+	}
+
+	// other services and infinite limits aren't limited
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		if err := call(context.Background(), "baz"); err != nil {
+			t.Fatal(err)
+		}
+		if err := call(context.Background(), "qux"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > time.Millisecond*40 {
+		t.Fatalf("Expected calls to baz not to be limited, took %v", d)
+	}
+}
+
+func TestCallCodecError(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// respond with a body which isn't valid json
+	go l.Accept(func(s transport.Socket) {
+		var msg transport.Message
+		if err := s.Recv(&msg); err != nil {
+			return
+		}
+		s.Send(&transport.Message{
+			Header: map[string]string{
+				"Micro-Id":     msg.Header["Micro-Id"],
+				"Content-Type": "application/json",
+			},
+			Body: []byte("not json"),
+		})
+	})
+
+	c := NewClient(Transport(tr), Retries(0))
+
+	testCases := []struct {
+		name     string
+		request  interface{}
+		response bool
+	}{
+		// channels can't be marshaled as json
+		{"request", map[string]interface{}{"ch": make(chan int)}, false},
+		{"response", map[string]string{"foo": "bar"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := c.NewRequest("test.service", "Test.Method", tc.request, WithContentType("application/json"))
+
+			var rsp map[string]string
+			err := c.Call(context.Background(), req, &rsp, WithAddress(l.Addr()))
+
+			var cerr *ErrCodec
+			if !errs.As(err, &cerr) {
+				t.Fatalf("Expected a codec error, got %v", err)
+			}
+			if cerr.ContentType != "application/json" {
+				t.Fatalf("Expected content type application/json, got %s", cerr.ContentType)
+			}
+			if cerr.Response != tc.response {
+				t.Fatalf("Expected response %v, got %v", tc.response, cerr.Response)
+			}
+			if cerr.Err == nil {
+				t.Fatal("Expected the underlying codec error")
+			}
+		})
+	}
+}
+
+type validatedResponse struct {
+	Value string `json:"value"`
+}
+
+func (r *validatedResponse) Validate() error {
+	if len(r.Value) == 0 {
+		return fmt.Errorf("missing value")
+	}
+	return nil
+}
+
+func TestCallResponseValidation(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// respond with the value of the request, empty is invalid
+	go l.Accept(func(s transport.Socket) {
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+			s.Send(&transport.Message{
+				Header: map[string]string{
+					"Micro-Id":     msg.Header["Micro-Id"],
+					"Content-Type": "application/json",
+				},
+				Body: msg.Body,
+			})
+		}
+	})
+
+	c := NewClient(Transport(tr), Retries(0))
+
+	testCases := []struct {
+		name     string
+		value    string
+		validate bool
+		invalid  bool
+	}{
+		{"valid", "foo", true, false},
+		{"invalid", "", true, true},
+		{"unvalidated", "", false, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := c.NewRequest("test.service", "Test.Method", map[string]string{"value": tc.value}, WithContentType("application/json"))
+
+			opts := []CallOption{WithAddress(l.Addr())}
+			if tc.validate {
+				opts = append(opts, WithResponseValidation())
+			}
+
+			var rsp validatedResponse
+			err := c.Call(context.Background(), req, &rsp, opts...)
+
+			if tc.invalid {
+				if !errs.Is(err, ErrInvalidResponse) {
+					t.Fatalf("Expected an invalid response error, got %v", err)
+				}
+				if !strings.Contains(err.Error(), "missing value") {
+					t.Fatalf("Expected the validation error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rsp.Value != tc.value {
+				t.Fatalf("Expected value %q, got %q", tc.value, rsp.Value)
+			}
+		})
+	}
+}
+
+func TestCallRetryIdempotent(t *testing.T) {
+	var mtx sync.Mutex
+	called := make(map[string]int)
+
+	// every call fails with a retryable error
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			mtx.Lock()
+			called[req.Endpoint()]++
+			mtx.Unlock()
+			return errors.InternalServerError("test.error", "retry request")
+		}
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		Retries(2),
+		Backoff(func(ctx context.Context, req Request, attempts int) (time.Duration, error) {
+			return 0, nil
+		}),
+		WithIdempotent("Test.Get", "Test.List*"),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	testCases := []struct {
+		endpoint string
+		calls    int
+	}{
+		{"Test.Get", 3},
+		{"Test.ListAll", 3},
+		{"Test.Create", 1},
+	}
+
+	for _, tc := range testCases {
+		req := c.NewRequest("test.service", tc.endpoint, nil)
+		if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1")); err == nil {
+			t.Fatalf("Expected %s to fail", tc.endpoint)
+		}
+
+		mtx.Lock()
+		n := called[tc.endpoint]
+		mtx.Unlock()
+
+		if n != tc.calls {
+			t.Fatalf("Expected %s to be called %d times, got %d", tc.endpoint, tc.calls, n)
+		}
+	}
+}
+
+type testLimiter struct {
+	sync.Mutex
+	limit    int
+	inflight int
+	acquired int
+	released []error
+}
+
+func (l *testLimiter) Acquire(ctx context.Context, req Request) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.inflight >= l.limit {
+		return errors.New("test.limiter", "limit exceeded", 429)
+	}
+	l.inflight++
+	l.acquired++
+
+	return nil
+}
+
+func (l *testLimiter) Release(req Request, latency time.Duration, err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.inflight--
+	l.released = append(l.released, err)
+}
+
+func TestCallLimiter(t *testing.T) {
+	callErr := errors.BadRequest("test.error", "bad request")
+
+	block := make(chan bool)
+	started := make(chan bool)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			switch req.Endpoint() {
+			case "Test.Block":
+				close(started)
+				<-block
+			case "Test.Fail":
+				return callErr
+			}
+			return nil
+		}
+	}
+
+	l := &testLimiter{limit: 1}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		WithLimiter(l),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	call := func(endpoint string) error {
+		req := c.NewRequest("test.service", endpoint, nil)
+		return c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"))
+	}
+
+	if err := call("Test.Ok"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("Test.Fail"); !errors.Equal(err, callErr) {
+		t.Fatalf("Expected %v, got %v", callErr, err)
+	}
+
+	// hold the only slot then check further calls are rejected
+	done := make(chan error, 1)
+	go func() {
+		done <- call("Test.Block")
+	}()
+	<-started
+
+	err := call("Test.Ok")
+	if merr := errors.FromError(err); merr.Code != 429 {
+		t.Fatalf("Expected the call to be rejected, got %v", err)
+	}
+
+	close(block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.acquired != 3 {
+		t.Fatalf("Expected 3 calls to be acquired, got %d", l.acquired)
+	}
+	if l.inflight != 0 {
+		t.Fatalf("Expected every call to be released, %d inflight", l.inflight)
+	}
+	if len(l.released) != 3 || l.released[0] != nil || !errors.Equal(l.released[1], callErr) || l.released[2] != nil {
+		t.Fatalf("Expected the call outcomes to be reported, got %v", l.released)
+	}
+}
+
+func TestCallWaitForNodes(t *testing.T) {
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			return nil
+		}
+	}
+
+	r := registry.NewMemoryRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		WaitForNodes(5*time.Second),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("test.service", "Test.Call", nil)
+
+	// the node is registered shortly after the call begins
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		r.Register(&registry.Service{
+			Name:    "test.service",
+			Version: "1.0.0",
+			Nodes:   []*registry.Node{{Id: "test-1", Address: "10.0.0.1:8080"}},
+		})
+	}()
+
+	start := time.Now()
+	if err := c.Call(context.Background(), req, nil); err != nil {
+		t.Fatalf("Expected the call to succeed once a node appeared, got %v", err)
+	}
+	if d := time.Since(start); d >= DefaultWaitInterval {
+		t.Fatalf("Expected the registry change to end the wait, took %v", d)
+	}
+
+	// the call deadline cuts the wait short
+	req = c.NewRequest("missing.service", "Test.Call", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	err := c.Call(ctx, req, nil)
+	if merr := errors.FromError(err); merr.Code != 408 {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the wait to end at the call deadline, took %v", d)
+	}
+
+	// as does the wait timeout
+	start = time.Now()
+	if err := c.Call(context.Background(), req, nil, WithWaitForNodes(100*time.Millisecond)); err == nil {
+		t.Fatal("Expected the call to fail with no nodes")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the wait to end at the wait timeout, took %v", d)
+	}
+}
+
+type countingRegistry struct {
+	registry.Registry
+
+	mtx   sync.Mutex
+	calls int
+}
+
+func (c *countingRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	c.mtx.Lock()
+	c.calls++
+	c.mtx.Unlock()
+	return c.Registry.GetService(name, opts...)
+}
+
+func TestNodes(t *testing.T) {
+	r := &countingRegistry{Registry: newTestRegistry()}
+	c := NewClient(Selector(selector.NewSelector(selector.Registry(r))))
+
+	var expect []string
+	for _, svc := range testData["foo"] {
+		for _, node := range svc.Nodes {
+			expect = append(expect, node.Id)
+		}
+	}
+	sort.Strings(expect)
+
+	for i := 0; i < 2; i++ {
+		nodes, err := Nodes(c, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, node := range nodes {
+			got = append(got, node.Id)
+		}
+		sort.Strings(got)
+
+		if fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("Expected nodes %v, got %v", expect, got)
+		}
+	}
+
+	// the second lookup is served from the cache
+	r.mtx.Lock()
+	calls := r.calls
+	r.mtx.Unlock()
+
+	if calls != 1 {
+		t.Fatalf("Expected the registry to be queried once, got %d", calls)
+	}
+
+	if _, err := Nodes(c, "missing"); err != selector.ErrNotFound {
+		t.Fatalf("Expected %v, got %v", selector.ErrNotFound, err)
+	}
+}
+
+func TestCallContentTypeContext(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+
+	// record the content type and fail the call
+	go l.Accept(func(s transport.Socket) {
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+			received <- msg.Header["Content-Type"]
+			s.Send(&transport.Message{
+				Header: map[string]string{
+					"Micro-Id":     msg.Header["Micro-Id"],
+					"Content-Type": msg.Header["Content-Type"],
+					"Micro-Error":  "done",
+				},
+			})
+		}
+	})
+
+	c := NewClient(Transport(tr), Retries(0))
+
+	testCases := []struct {
+		name   string
+		ctx    string
+		opts   []RequestOption
+		expect string
+	}{
+		{"default", "", nil, DefaultContentType},
+		{"context", "application/x-protobuf", nil, "application/x-protobuf"},
+		{"no codec", "text/html", nil, DefaultContentType},
+		{"request", "application/x-protobuf", []RequestOption{WithContentType("application/json")}, "application/json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if len(tc.ctx) > 0 {
+				ctx = NewContentTypeContext(ctx, tc.ctx)
+			}
+
+			// a nil body can be written by any codec
+			req := c.NewRequest("test.service", "Test.Method", nil, tc.opts...)
+			c.Call(ctx, req, nil, WithAddress(l.Addr()))
+
+			select {
+			case ct := <-received:
+				if ct != tc.expect {
+					t.Fatalf("Expected content type %s, got %s", tc.expect, ct)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Request not received")
+			}
+		})
+	}
+}
+
+func TestStreamSendBuffer(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the consumer reads a message each time drain is signalled
+	drain := make(chan bool)
+	received := make(chan string, 10)
+
+	go l.Accept(func(s transport.Socket) {
+		var msg transport.Message
+		// the first message is received straight away
+		if err := s.Recv(&msg); err != nil {
+			return
+		}
+		for range drain {
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+			received <- string(msg.Body)
+		}
+		// end of stream
+		s.Recv(&msg)
+	})
+
+	c := NewClient(Transport(tr), Retries(0))
+
+	req := c.NewRequest("test.service", "Test.Stream", "first", StreamingRequest())
+	stream, err := c.Stream(context.Background(), req, WithAddress(l.Addr()), WithStreamSendBuffer(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one message is held by the sender and two are buffered
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(fmt.Sprintf("%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- stream.Send("3")
+	}()
+
+	select {
+	case err := <-sent:
+		t.Fatalf("Expected send to block on the full buffer, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// consuming a message makes space for the blocked send
+	drain <- true
+
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected send to proceed once the consumer drained")
+	}
+
+	for i := 1; i < 4; i++ {
+		drain <- true
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case body := <-received:
+			if expect := fmt.Sprintf("\"%d\"\n", i); body != expect {
+				t.Fatalf("Expected message %q, got %q", expect, body)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Message %d not received", i)
+		}
+	}
+
+	close(drain)
+	stream.Close()
+
+	t.Run("timeout", func(t *testing.T) {
+		l, err := tr.Listen(":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		// never consume past the first message
+		go l.Accept(func(s transport.Socket) {
+			var msg transport.Message
+			s.Recv(&msg)
+		})
+
+		req := c.NewRequest("test.service", "Test.Stream", "first", StreamingRequest())
+		stream, err := c.Stream(context.Background(), req,
+			WithAddress(l.Addr()),
+			WithStreamSendBuffer(1),
+			WithStreamSendTimeout(50*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := stream.Send("msg"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = stream.Send("msg")
+		if merr := errors.FromError(err); merr.Code != 408 {
+			t.Fatalf("Expected a timeout error, got %v", err)
+		}
+	})
+}
+
+func TestCallRegionFailover(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	r.Register(&registry.Service{
+		Name:    "test.service",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "eu-1", Address: "10.0.0.1:8080", Metadata: map[string]string{RegionKey: "eu"}},
+			{Id: "us-1", Address: "10.0.0.2:8080", Metadata: map[string]string{RegionKey: "us"}},
+		},
+	})
+
+	type attempt struct {
+		region string
+		budget time.Duration
+	}
+
+	testCases := []struct {
+		name    string
+		eu      func(ctx context.Context) error
+		err     int32
+		regions []string
+	}{
+		{"local succeeds", func(ctx context.Context) error {
+			return nil
+		}, 0, []string{"eu"}},
+		{"local fails", func(ctx context.Context) error {
+			return errors.InternalServerError("test.service", "failed")
+		}, 0, []string{"eu", "us"}},
+		{"local times out", func(ctx context.Context) error {
+			<-ctx.Done()
+			return errors.Timeout("test.service", "timed out")
+		}, 0, []string{"eu", "us"}},
+		// errors which wouldn't be retried don't fail over
+		{"bad request", func(ctx context.Context) error {
+			return errors.BadRequest("test.service", "bad request")
+		}, 400, []string{"eu"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mtx      sync.Mutex
+				attempts []attempt
+			)
+
+			wrap := func(cf CallFunc) CallFunc {
+				return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+					d, _ := ctx.Deadline()
+					region := node.Metadata[RegionKey]
+
+					mtx.Lock()
+					attempts = append(attempts, attempt{region, time.Until(d)})
+					mtx.Unlock()
+
+					if region == "eu" {
+						return tc.eu(ctx)
+					}
+					return nil
+				}
+			}
+
+			l := &testLimiter{limit: 1}
+
+			c := NewClient(
+				Registry(r),
+				Selector(selector.NewSelector(selector.Registry(r))),
+				WrapCall(wrap),
+				Retries(0),
+				Regions("eu", "us"),
+				WithLimiter(l),
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := c.Call(ctx, c.NewRequest("test.service", "Test.Call", nil), nil)
+			if (err == nil) != (tc.err == 0) || (err != nil && errors.FromError(err).Code != tc.err) {
+				t.Fatalf("Expected error code %d got %v", tc.err, err)
+			}
+			if d := time.Since(start); d >= 200*time.Millisecond {
+				t.Fatalf("Expected the call to finish within its deadline, took %v", d)
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			if len(attempts) != len(tc.regions) {
+				t.Fatalf("Expected attempts in %v got %v", tc.regions, attempts)
+			}
+
+			for i, a := range attempts {
+				if a.region != tc.regions[i] {
+					t.Fatalf("Expected attempt %d in %s got %s", i, tc.regions[i], a.region)
+				}
+			}
+
+			// the local region gets half the deadline, leaving the rest for the remote one
+			if budget := attempts[0].budget; budget > 100*time.Millisecond {
+				t.Fatalf("Expected the local region to get half the deadline, got %v", budget)
+			}
+			if len(attempts) > 1 && attempts[1].budget <= 0 {
+				t.Fatalf("Expected the remote region to have time left, got %v", attempts[1].budget)
+			}
+
+			// a call holds a single slot however many regions it tries
+			l.Lock()
+			defer l.Unlock()
+
+			if l.acquired != 1 || l.inflight != 0 {
+				t.Fatalf("Expected the call to be acquired once and released, got %d acquired %d inflight", l.acquired, l.inflight)
+			}
+		})
+	}
+}
+
+func TestCallPoolBlockingDeadline(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(s transport.Socket) {})
+
+	c := NewClient(
+		Transport(tr),
+		PoolSize(1),
+		PoolBlocking(true),
+	)
+
+	// saturate the pool by holding its only connection
+	p := c.(*rpcClient).getPool("test.service")
+	conn, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := c.NewRequest("test.service", "Test.Call", nil)
+
+	start := time.Now()
+	err = c.Call(context.Background(), req, nil,
+		WithAddress(l.Addr()),
+		WithRequestTimeout(time.Millisecond*50),
+		WithRetries(0),
+	)
+	if merr := errors.FromError(err); err == nil || merr.Code != 408 {
+		t.Fatalf("Expected a timeout waiting for a connection, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the call to give up at its deadline, took %v", d)
+	}
+
+	// a released connection is handed to the next waiter
+	got := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		c, err := pool.GetContext(ctx, p, l.Addr())
+		if err == nil {
+			p.Release(c, nil)
+		}
+		got <- err
+	}()
+
+	time.Sleep(time.Millisecond * 20)
+	p.Release(conn, nil)
+
+	if err := <-got; err != nil {
+		t.Fatalf("Expected the waiter to get the released connection, got %v", err)
+	}
+}
+
+func TestCallObserver(t *testing.T) {
+	callErr := errors.InternalServerError("test.error", "retry request")
+
+	var called int
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			called++
+			if called < 3 {
+				return callErr
+			}
+			return nil
+		}
+	}
+
+	var (
+		mtx    sync.Mutex
+		events []CallEvent
+	)
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		Retries(2),
+		WithCallObserver(func(e CallEvent) {
+			mtx.Lock()
+			events = append(events, e)
+			mtx.Unlock()
+		}),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if len(events) != 3 {
+		t.Fatalf("Expected an event per attempt, got %d", len(events))
+	}
+
+	for i, e := range events {
+		if e.Service != "test.service" || e.Endpoint != "Test.Endpoint" {
+			t.Fatalf("Unexpected service and endpoint %s %s", e.Service, e.Endpoint)
+		}
+		if e.Node == nil || e.Node.Address != "10.1.10.1" {
+			t.Fatalf("Expected the attempt node, got %+v", e.Node)
+		}
+		if e.Attempt != i+1 {
+			t.Fatalf("Expected attempt %d, got %d", i+1, e.Attempt)
+		}
+		if e.Duration < 0 {
+			t.Fatalf("Expected the attempt to be timed, got %v", e.Duration)
+		}
+		if i < 2 && !errors.Equal(e.Error, callErr) {
+			t.Fatalf("Expected attempt %d to fail with %v, got %v", e.Attempt, callErr, e.Error)
+		}
+		if i == 2 && e.Error != nil {
+			t.Fatalf("Expected the last attempt to succeed, got %v", e.Error)
+		}
+	}
+}
+
+func TestCallContextErrors(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// never respond
+	go l.Accept(func(s transport.Socket) {
+		var msg transport.Message
+		s.Recv(&msg)
+	})
+
+	c := NewClient(Transport(tr), Retries(0))
+
+	call := func(ctx context.Context, address string) error {
+		req := c.NewRequest("test.service", "Test.Call", nil)
+		return c.Call(ctx, req, nil, WithAddress(address))
+	}
+
+	// the deadline passes
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	timeoutErr := call(ctx, l.Addr())
+	if !errs.Is(timeoutErr, ErrTimeout) {
+		t.Fatalf("Expected a timeout, got %v", timeoutErr)
+	}
+
+	// the caller gives up
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	cancelErr := call(ctx, l.Addr())
+	if !errs.Is(cancelErr, ErrCanceled) {
+		t.Fatalf("Expected the call to be canceled, got %v", cancelErr)
+	}
+
+	if errs.Is(timeoutErr, ErrCanceled) || errs.Is(cancelErr, ErrTimeout) {
+		t.Fatalf("Expected the errors to differ, got %v and %v", timeoutErr, cancelErr)
+	}
+
+	// nothing is listening
+	transportErr := call(context.Background(), "unknown:8080")
+	if !errs.Is(transportErr, ErrTransport) {
+		t.Fatalf("Expected a transport error, got %v", transportErr)
+	}
+	if errs.Is(transportErr, ErrTimeout) || errs.Is(transportErr, ErrCanceled) {
+		t.Fatalf("Expected only a transport error, got %v", transportErr)
+	}
+	if merr := errors.FromError(transportErr); merr.Id != "go.micro.client" || merr.Code != 500 {
+		t.Fatalf("Expected the connection error to keep its id, got %v", transportErr)
+	}
+}
+
+func TestCallRetryBudget(t *testing.T) {
+	var attempts int
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			attempts++
+			return errors.InternalServerError("test.error", "retry request")
+		}
+	}
+
+	noBackoff := func(ctx context.Context, req Request, attempts int) (time.Duration, error) {
+		return 0, nil
+	}
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		Retries(3),
+		Retry(RetryAlways),
+		Backoff(noBackoff),
+		WithRetryBudget(0.5, 4),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+
+	var perCall []int
+	for i := 0; i < 10; i++ {
+		attempts = 0
+		if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1")); err == nil {
+			t.Fatal("Expected the call to fail")
+		}
+		perCall = append(perCall, attempts)
+	}
+
+	// the first call spends the burst, then retries are
+	// limited to one every other call by the ratio
+	expect := []int{4, 2, 2, 1, 2, 1, 2, 1, 2, 1}
+	for i := range expect {
+		if perCall[i] != expect[i] {
+			t.Fatalf("Expected attempts %v, got %v", expect, perCall)
+		}
+	}
+
+	// without a budget every call is retried
+	attempts = 0
+	c = NewClient(
+		Registry(r),
+		WrapCall(wrap),
+		Retries(3),
+		Retry(RetryAlways),
+		Backoff(noBackoff),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	for i := 0; i < 10; i++ {
+		_ = c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"))
+	}
+
+	if attempts != 40 {
+		t.Fatalf("Expected 40 attempts without a budget, got %d", attempts)
+	}
+}
+
+func TestPrewarm(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+	r := registry.NewMemoryRegistry()
+
+	var (
+		mtx      sync.Mutex
+		accepted = make(map[string]int)
+	)
+
+	var nodes []*registry.Node
+	for i := 0; i < 2; i++ {
+		l, err := tr.Listen(":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		addr := l.Addr()
+		go l.Accept(func(s transport.Socket) {
+			mtx.Lock()
+			accepted[addr]++
+			mtx.Unlock()
+		})
+
+		nodes = append(nodes, &registry.Node{Id: fmt.Sprintf("test-%d", i), Address: addr})
+	}
+
+	if err := r.Register(&registry.Service{Name: "test.service", Nodes: nodes}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(
+		Registry(r),
+		Transport(tr),
+		Selector(selector.NewSelector(selector.Registry(r))),
+		PoolSize(4),
+	)
+
+	if err := c.(Prewarmer).Prewarm(context.Background(), "test.service", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() map[string]int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		counts := make(map[string]int, len(accepted))
+		for k, v := range accepted {
+			counts[k] = v
+		}
+		return counts
+	}
+
+	// connections are accepted asynchronously
+	deadline := time.Now().Add(time.Second)
+	for {
+		counts := count()
+		if counts[nodes[0].Address]+counts[nodes[1].Address] == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 4 connections, got %v", count())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	for _, n := range nodes {
+		if v := count()[n.Address]; v != 2 {
+			t.Fatalf("Expected 2 connections to %s, got %d", n.Address, v)
+		}
+	}
+
+	// the pooled connections are used without dialing
+	p := c.(*rpcClient).getPool("test.service")
+	for _, n := range nodes {
+		for i := 0; i < 2; i++ {
+			if _, err := p.Get(n.Address); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	time.Sleep(time.Millisecond * 20)
+
+	for _, n := range nodes {
+		if v := count()[n.Address]; v != 2 {
+			t.Fatalf("Expected the pooled connections to %s to be used, got %d dialed", n.Address, v)
+		}
+	}
+}
+
+func TestCallFallback(t *testing.T) {
+	callErr := errors.InternalServerError("test.error", "unavailable")
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			return callErr
+		}
+	}
+
+	type value struct {
+		Value string `json:"value"`
+	}
+
+	testCases := []struct {
+		name     string
+		fallback interface{}
+		err      error
+		expect   string
+	}{
+		{"value", value{Value: "cached"}, nil, "cached"},
+		{"pointer", &value{Value: "cached"}, nil, "cached"},
+		{"converted", map[string]string{"value": "converted"}, nil, "converted"},
+		{"none", nil, nil, "untouched"},
+		{"error", nil, errs.New("no fallback"), ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var got error
+
+			r := newTestRegistry()
+			c := NewClient(
+				Registry(r),
+				WrapCall(wrap),
+				Retries(0),
+				WithFallback(func(ctx context.Context, req Request, err error) (interface{}, error) {
+					got = err
+					return tc.fallback, tc.err
+				}),
+			)
+			c.Options().Selector.Init(selector.Registry(r))
+
+			req := c.NewRequest("test.service", "Test.Endpoint", nil)
+			rsp := value{Value: "untouched"}
+
+			err := c.Call(context.Background(), req, &rsp, WithAddress("10.1.10.1"))
+			if !errors.Equal(got, callErr) {
+				t.Fatalf("Expected the fallback to get the call error, got %v", got)
+			}
+
+			if tc.err != nil {
+				if err != tc.err {
+					t.Fatalf("Expected the fallback error %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected the fallback to replace the error, got %v", err)
+			}
+			if rsp.Value != tc.expect {
+				t.Fatalf("Expected response %s, got %s", tc.expect, rsp.Value)
+			}
+		})
+	}
+}
+
+func TestCallBreaker(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		fail  = true
+		calls int
+	)
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			calls++
+			if fail {
+				return errors.InternalServerError("test.error", "failing")
+			}
+			return nil
+		}
+	}
+
+	b := NewBreaker(3, time.Millisecond*50)
+
+	r := newTestRegistry()
+	c := NewClient(
+		Registry(r),
+		WithBreaker(b),
+		WrapCall(wrap),
+		Retries(0),
+	)
+	c.Options().Selector.Init(selector.Registry(r))
+
+	call := func() error {
+		req := c.NewRequest("test.service", "Test.Endpoint", nil)
+		return c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"))
+	}
+
+	state := func() BreakerStatus {
+		s, _ := b.State("test.service", "10.1.10.1")
+		return s
+	}
+
+	// read the states while the calls are made
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				for _, s := range b.States() {
+					if s.State == BreakerOpen && s.Failures < 3 {
+						t.Errorf("Expected an open circuit to have 3 failures, got %+v", s)
+					}
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		if err := call(); err == nil {
+			t.Fatal("Expected the call to fail")
+		}
+	}
+
+	close(done)
+
+	if s := state(); s.State != BreakerOpen || s.Failures != 3 || s.Opened.IsZero() {
+		t.Fatalf("Expected the circuit to open, got %+v", s)
+	}
+
+	states := b.States()
+	if len(states) != 1 || states[0].Service != "test.service" || states[0].Address != "10.1.10.1" {
+		t.Fatalf("Expected the node's state, got %+v", states)
+	}
+
+	// calls are rejected without reaching the node
+	if err := call(); !errs.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected %v, got %v", ErrCircuitOpen, err)
+	}
+	mtx.Lock()
+	if calls != 3 {
+		t.Fatalf("Expected the node not to be called, got %d calls", calls)
+	}
+	mtx.Unlock()
+
+	// a failed trial call opens it again
+	time.Sleep(time.Millisecond * 60)
+	if err := call(); errs.Is(err, ErrCircuitOpen) || err == nil {
+		t.Fatalf("Expected the trial call to reach the node, got %v", err)
+	}
+	if s := state(); s.State != BreakerOpen || s.Failures != 4 {
+		t.Fatalf("Expected the circuit to open again, got %+v", s)
+	}
+
+	// a successful trial closes it
+	time.Sleep(time.Millisecond * 60)
+	mtx.Lock()
+	fail = false
+	mtx.Unlock()
+
+	if err := call(); err != nil {
+		t.Fatal(err)
+	}
+	if s := state(); s.State != BreakerClosed || s.Failures != 0 || s.TotalFailures != 4 {
+		t.Fatalf("Expected the circuit to close, got %+v", s)
+	}
+	if s := state(); s.State.String() != "closed" {
+		t.Fatalf("Expected closed, got %s", s.State)
+	}
+}
+
+func TestCallRequestCodec(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// a legacy service which only speaks json
+	go l.Accept(func(s transport.Socket) {
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+
+			ct := msg.Header["Content-Type"]
+			if ct != "application/json" {
+				s.Send(&transport.Message{
+					Header: map[string]string{
+						"Micro-Id":     msg.Header["Micro-Id"],
+						"Content-Type": ct,
+						"Micro-Error":  errors.BadRequest("test.service", "unsupported content type %s", ct).Error(),
+					},
+				})
+				continue
+			}
+
+			var req map[string]string
+			if err := json.Unmarshal(msg.Body, &req); err != nil {
+				return
+			}
+
+			b, _ := json.Marshal(map[string]string{"echo": req["value"]})
+
+			s.Send(&transport.Message{
+				Header: map[string]string{
+					"Micro-Id":     msg.Header["Micro-Id"],
+					"Content-Type": "application/json",
+				},
+				Body: b,
+			})
+		}
+	})
+
+	c := NewClient(Transport(tr), ContentType("application/protobuf"), Retries(0))
+
+	req := c.NewRequest("test.service", "Test.Method", map[string]string{"value": "foo"})
+	if req.ContentType() != "application/protobuf" {
+		t.Fatalf("Expected the client default application/protobuf, got %s", req.ContentType())
+	}
+
+	var rsp map[string]string
+	if err := c.Call(context.Background(), req, &rsp, WithAddress(l.Addr()), WithRequestCodec("application/json")); err != nil {
+		t.Fatal(err)
+	}
+	if rsp["echo"] != "foo" {
+		t.Fatalf("Expected the response decoded as json, got %v", rsp)
+	}
+
+	// an unknown codec fails before calling
+	err = c.Call(context.Background(), req, &rsp, WithAddress(l.Addr()), WithRequestCodec("application/unknown"))
+	if verr, ok := err.(*errors.Error); !ok || verr.Code != 500 {
+		t.Fatalf("Expected an unsupported content type error, got %v", err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var (
+		mtx      sync.Mutex
+		accepted int
+	)
+
+	go l.Accept(func(s transport.Socket) {
+		mtx.Lock()
+		accepted++
+		mtx.Unlock()
+
+		var msg transport.Message
+		if err := s.Recv(&msg); err != nil {
+			return
+		}
+
+		var req map[string]string
+		if err := json.Unmarshal(msg.Body, &req); err != nil {
+			return
+		}
+
+		b, _ := json.Marshal(map[string]string{"endpoint": msg.Header["Micro-Endpoint"], "echo": req["value"]})
+
+		s.Send(&transport.Message{
+			Header: map[string]string{
+				"Micro-Id":     msg.Header["Micro-Id"],
+				"Content-Type": "application/json",
+			},
+			Body: b,
+		})
+	})
+
+	// the registry doesn't know the node
+	c := NewClient(Transport(tr), Registry(registry.NewMemoryRegistry()))
+
+	req := c.NewRequest("test.service", "Test.Method", map[string]string{"value": "foo"})
+
+	if err := c.Call(context.Background(), req, nil, WithRetries(0)); err == nil {
+		t.Fatal("Expected the call through the selector to fail")
+	}
+
+	for i := 0; i < 2; i++ {
+		var rsp map[string]string
+		if err := c.(Doer).Do(context.Background(), l.Addr(), req, &rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp["echo"] != "foo" || rsp["endpoint"] != "Test.Method" {
+			t.Fatalf("Expected the response from the node, got %v", rsp)
+		}
+	}
+
+	// connections aren't pooled
+	mtx.Lock()
+	defer mtx.Unlock()
+	if accepted != 2 {
+		t.Fatalf("Expected a connection per call, got %d", accepted)
+	}
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	a := NewAdaptiveTimeout(0.9, 2, time.Millisecond*10, time.Millisecond*150)
+
+	// not enough samples yet
+	for i := 1; i < DefaultAdaptiveMinSamples; i++ {
+		a.observe("foo", "Foo.Bar", time.Millisecond)
+	}
+	if _, ok := a.Timeout("foo", "Foo.Bar"); ok {
+		t.Fatal("Expected no timeout before the minimum samples")
+	}
+
+	a = NewAdaptiveTimeout(0.9, 2, time.Millisecond*10, time.Millisecond*500)
+
+	// 1ms to 100ms, p90 is 90ms
+	for i := 1; i <= 100; i++ {
+		a.observe("foo", "Foo.Bar", time.Duration(i)*time.Millisecond)
+	}
+
+	testCases := []struct {
+		name     string
+		latency  time.Duration
+		count    int
+		expected time.Duration
+	}{
+		{"percentile", 0, 0, time.Millisecond * 180},
+		// the window rolls over to the recent latencies
+		{"rolling", time.Millisecond * 20, DefaultAdaptiveWindow, time.Millisecond * 40},
+		// bounded by min and max
+		{"min", time.Millisecond, DefaultAdaptiveWindow, time.Millisecond * 10},
+		{"max", time.Second, DefaultAdaptiveWindow, time.Millisecond * 500},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < tc.count; i++ {
+			a.observe("foo", "Foo.Bar", tc.latency)
+		}
+
+		d, ok := a.Timeout("foo", "Foo.Bar")
+		if !ok || d != tc.expected {
+			t.Fatalf("%s: expected a timeout of %v, got %v", tc.name, tc.expected, d)
+		}
+	}
+
+	// endpoints are tracked separately
+	if _, ok := a.Timeout("foo", "Foo.Baz"); ok {
+		t.Fatal("Expected no timeout for another endpoint")
+	}
+	if _, ok := a.Timeout("bar", "Foo.Bar"); ok {
+		t.Fatal("Expected no timeout for another service")
+	}
+}
+
+func TestCallAdaptiveTimeout(t *testing.T) {
+	var timeouts []time.Duration
+
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			timeouts = append(timeouts, opts.RequestTimeout)
+			return nil
+		}
+	}
+
+	c := NewClient(WrapCall(wrap), WithAdaptiveTimeout(0.99, 3, time.Millisecond*50, time.Second))
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+
+	for i := 0; i <= DefaultAdaptiveMinSamples; i++ {
+		if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, d := range timeouts[:DefaultAdaptiveMinSamples] {
+		if d > DefaultRequestTimeout || d < DefaultRequestTimeout-time.Second {
+			t.Fatalf("Expected call %d to use the default timeout, got %v", i, d)
+		}
+	}
+
+	// the calls took far less than the minimum
+	if d := timeouts[DefaultAdaptiveMinSamples]; d > time.Millisecond*50 || d < time.Millisecond*40 {
+		t.Fatalf("Expected the adapted timeout of 50ms, got %v", d)
+	}
+
+	// an explicit timeout wins
+	if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"), WithRequestTimeout(time.Second*2)); err != nil {
+		t.Fatal(err)
+	}
+	if d := timeouts[len(timeouts)-1]; d < time.Second {
+		t.Fatalf("Expected the call timeout, got %v", d)
+	}
+}
+
+func TestCallAdaptiveTimeoutRetries(t *testing.T) {
+	var (
+		attempts int
+		timeouts []time.Duration
+	)
+
+	// the first attempt of the sampled calls fails and is retried after a backoff
+	wrap := func(cf CallFunc) CallFunc {
+		return func(ctx context.Context, node *registry.Node, req Request, rsp interface{}, opts CallOptions) error {
+			timeouts = append(timeouts, opts.RequestTimeout)
+			attempts++
+			if attempts <= DefaultAdaptiveMinSamples*2 && attempts%2 == 1 {
+				return errors.InternalServerError("test", "failed")
+			}
+			return nil
+		}
+	}
+
+	backoff := func(ctx context.Context, req Request, attempts int) (time.Duration, error) {
+		if attempts == 0 {
+			return 0, nil
+		}
+		return time.Millisecond * 20, nil
+	}
+
+	c := NewClient(WrapCall(wrap), WithAdaptiveTimeout(0.99, 1, time.Millisecond*10, time.Second))
+	req := c.NewRequest("test.service", "Test.Endpoint", nil)
+
+	for i := 0; i <= DefaultAdaptiveMinSamples; i++ {
+		if err := c.Call(context.Background(), req, nil, WithAddress("10.1.10.1"), WithBackoff(backoff), WithRetries(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the backoff between attempts isn't counted
+	if d := timeouts[len(timeouts)-1]; d > time.Millisecond*15 {
+		t.Fatalf("Expected the timeout of the attempts alone, got %v", d)
+	}
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	errs "errors"
+	"fmt"
 
 	"go-micro.dev/v4/codec"
 	raw "go-micro.dev/v4/codec/bytes"
@@ -18,6 +19,14 @@ import (
 
 const (
 	lastStreamResponseError = "EOS"
+	// frameErrorHeader marks an error for a single stream frame
+	frameErrorHeader = "Micro-Frame-Error"
+	// trailerPrefix marks the response headers carrying trailing metadata
+	trailerPrefix = "Micro-Trailer-"
+	// streamHeaderKey marks the frame carrying the stream header
+	streamHeaderKey = "Micro-Stream-Header"
+	// headerPrefix marks the response headers carrying the stream header
+	headerPrefix = "Micro-Header-"
 )
 
 // serverError represents an error that has been returned from
@@ -28,6 +37,29 @@ func (e serverError) Error() string {
 	return string(e)
 }
 
+// ErrCodec is returned when a request can't be marshaled or a response
+// can't be unmarshaled, distinguishing serialization failures from
+// transport or server errors.
+type ErrCodec struct {
+	// ContentType of the codec
+	ContentType string
+	// Response is true if the response failed to unmarshal
+	Response bool
+	// Err is the underlying codec error
+	Err error
+}
+
+func (e *ErrCodec) Error() string {
+	if e.Response {
+		return fmt.Sprintf("failed to unmarshal %s response: %v", e.ContentType, e.Err)
+	}
+	return fmt.Sprintf("failed to marshal %s request: %v", e.ContentType, e.Err)
+}
+
+func (e *ErrCodec) Unwrap() error {
+	return e.Err
+}
+
 // errShutdown holds the specific error for closing/closed connections.
 var (
 	errShutdown = errs.New("connection is shut down")
@@ -36,6 +68,9 @@ var (
 type rpcCodec struct {
 	client transport.Client
 	codec  codec.Codec
+	// rcodec reads responses encoded with
+	// the accepted content type if it's set
+	rcodec codec.Codec
 
 	req *transport.Message
 	buf *readWriteCloser
@@ -57,6 +92,7 @@ var (
 		"application/grpc+json":    grpc.NewCodec,
 		"application/grpc+proto":   grpc.NewCodec,
 		"application/protobuf":     proto.NewCodec,
+		"application/x-protobuf":   proto.NewCodec,
 		"application/json":         json.NewCodec,
 		"application/json-rpc":     jsonrpc.NewCodec,
 		"application/proto-rpc":    protorpc.NewCodec,
@@ -153,7 +189,9 @@ func setupProtocol(msg *transport.Message, node *registry.Node) codec.NewCodec {
 	return defaultCodecs[msg.Header["Content-Type"]]
 }
 
-func newRpcCodec(req *transport.Message, client transport.Client, c codec.NewCodec, stream string) codec.Codec {
+// newRpcCodec returns a codec writing requests with c. Responses are read
+// with rc if it's set, for those encoded with the accepted content type.
+func newRpcCodec(req *transport.Message, client transport.Client, c, rc codec.NewCodec, stream string) codec.Codec {
 	rwc := &readWriteCloser{
 		wbuf: bytes.NewBuffer(nil),
 		rbuf: bytes.NewBuffer(nil),
@@ -165,9 +203,20 @@ func newRpcCodec(req *transport.Message, client transport.Client, c codec.NewCod
 		req:    req,
 		stream: stream,
 	}
+	if rc != nil {
+		r.rcodec = rc(rwc)
+	}
 	return r
 }
 
+// reader returns the codec and content type responses are read with.
+func (c *rpcCodec) reader() (codec.Codec, string) {
+	if c.rcodec != nil {
+		return c.rcodec, c.req.Header["Accept"]
+	}
+	return c.codec, c.req.Header["Content-Type"]
+}
+
 func (c *rpcCodec) Write(m *codec.Message, body interface{}) error {
 	c.buf.wbuf.Reset()
 
@@ -192,7 +241,7 @@ func (c *rpcCodec) Write(m *codec.Message, body interface{}) error {
 		} else {
 			// write to codec
 			if err := c.codec.Write(m, body); err != nil {
-				return errors.InternalServerError("go.micro.client.codec", err.Error())
+				return &ErrCodec{ContentType: c.req.Header["Content-Type"], Err: err}
 			}
 			// set body
 			m.Body = c.buf.wbuf.Bytes()
@@ -228,14 +277,15 @@ func (c *rpcCodec) ReadHeader(m *codec.Message, r codec.MessageType) error {
 	m.Header = tm.Header
 
 	// read header
-	err := c.codec.ReadHeader(m, r)
+	rc, ct := c.reader()
+	err := rc.ReadHeader(m, r)
 
 	// get headers
 	getHeaders(m)
 
 	// return header error
 	if err != nil {
-		return errors.InternalServerError("go.micro.client.codec", err.Error())
+		return &ErrCodec{ContentType: ct, Response: true, Err: err}
 	}
 
 	return nil
@@ -249,8 +299,9 @@ func (c *rpcCodec) ReadBody(b interface{}) error {
 		return nil
 	}
 
-	if err := c.codec.ReadBody(b); err != nil {
-		return errors.InternalServerError("go.micro.client.codec", err.Error())
+	rc, ct := c.reader()
+	if err := rc.ReadBody(b); err != nil {
+		return &ErrCodec{ContentType: ct, Response: true, Err: err}
 	}
 	return nil
 }
@@ -258,6 +309,9 @@ func (c *rpcCodec) ReadBody(b interface{}) error {
 func (c *rpcCodec) Close() error {
 	c.buf.Close()
 	c.codec.Close()
+	if c.rcodec != nil {
+		c.rcodec.Close()
+	}
 	if err := c.client.Close(); err != nil {
 		return errors.InternalServerError("go.micro.client.transport", err.Error())
 	}
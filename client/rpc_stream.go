@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"go-micro.dev/v4/codec"
+	merrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
 )
 
 // Implements the streamer interface.
@@ -25,6 +29,30 @@ type rpcStream struct {
 
 	// release releases the connection back to the pool
 	release func(err error)
+
+	// send buffers the messages written by the sender when set
+	send        chan interface{}
+	sendTimeout time.Duration
+	// sendErr is the error the sender failed with
+	sendErr error
+	// sent is closed once the sender has written the buffer
+	sent chan bool
+
+	// header sent by the server, ready is closed once it's known
+	header      metadata.Metadata
+	headerReady chan bool
+	// reading is set while a frame is being read
+	reading bool
+	// pending is a frame read by Header ahead of Recv
+	pending *pendingFrame
+	// trailer is set to the trailing metadata the stream ended with
+	trailer *metadata.Metadata
+}
+
+// pendingFrame is the header of a frame, its body is still to be read.
+type pendingFrame struct {
+	msg codec.Message
+	err error
 }
 
 func (r *rpcStream) isClosed() bool {
@@ -49,6 +77,10 @@ func (r *rpcStream) Response() Response {
 }
 
 func (r *rpcStream) Send(msg interface{}) error {
+	if r.send != nil {
+		return r.enqueue(msg)
+	}
+
 	r.Lock()
 	defer r.Unlock()
 
@@ -57,6 +89,15 @@ func (r *rpcStream) Send(msg interface{}) error {
 		return errShutdown
 	}
 
+	if err := r.write(msg); err != nil {
+		r.err = err
+		return err
+	}
+
+	return nil
+}
+
+func (r *rpcStream) write(msg interface{}) error {
 	req := codec.Message{
 		Id:       r.id,
 		Target:   r.request.Service(),
@@ -65,12 +106,99 @@ func (r *rpcStream) Send(msg interface{}) error {
 		Type:     codec.Request,
 	}
 
-	if err := r.codec.Write(&req, msg); err != nil {
-		r.err = err
+	return r.codec.Write(&req, msg)
+}
+
+// buffer makes Send add messages to a buffer of the size, written by a
+// sender goroutine. Once full Send blocks for up to the timeout.
+func (r *rpcStream) buffer(size int, timeout time.Duration) {
+	r.send = make(chan interface{}, size)
+	r.sendTimeout = timeout
+	r.sent = make(chan bool)
+
+	go r.sender()
+}
+
+func (r *rpcStream) enqueue(msg interface{}) error {
+	r.Lock()
+	if r.isClosed() {
+		r.err = errShutdown
+		r.Unlock()
+		return errShutdown
+	}
+	if r.sendErr != nil {
+		err := r.sendErr
+		r.Unlock()
 		return err
 	}
+	r.Unlock()
 
-	return nil
+	// try without waiting first
+	select {
+	case r.send <- msg:
+		return nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if r.sendTimeout > 0 {
+		t := time.NewTimer(r.sendTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case r.send <- msg:
+		return nil
+	case <-r.closed:
+		return errShutdown
+	case <-r.context.Done():
+		return contextError(r.context.Err(), "%v", r.context.Err())
+	case <-timeout:
+		return merrors.Timeout("go.micro.client", "stream send buffer full after %v", r.sendTimeout)
+	}
+}
+
+// sender writes the buffered messages until the stream is closed.
+func (r *rpcStream) sender() {
+	defer close(r.sent)
+
+	for {
+		select {
+		case msg := <-r.send:
+			r.writeBuffered(msg)
+		case <-r.closed:
+			// flush what's left
+			for {
+				select {
+				case msg := <-r.send:
+					r.writeBuffered(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *rpcStream) writeBuffered(msg interface{}) {
+	r.RLock()
+	failed := r.sendErr != nil
+	r.RUnlock()
+
+	// drop the rest once a write failed
+	if failed {
+		return
+	}
+
+	// the lock isn't held while writing as the sender is
+	// the only writer, Send must not block on a slow write
+	if err := r.write(msg); err != nil {
+		r.Lock()
+		r.err = err
+		r.sendErr = err
+		r.Unlock()
+	}
 }
 
 func (r *rpcStream) Recv(msg interface{}) error {
@@ -85,8 +213,11 @@ func (r *rpcStream) Recv(msg interface{}) error {
 	var resp codec.Message
 
 	r.Unlock()
-	err := r.codec.ReadHeader(&resp, codec.Response)
+	err := r.readHeader(&resp)
 	r.Lock()
+	if rsp, ok := r.response.(*rpcResponse); ok && resp.Header != nil {
+		rsp.header = resp.Header
+	}
 	if err != nil {
 		if err == io.EOF && !r.isClosed() {
 			r.err = io.ErrUnexpectedEOF
@@ -100,6 +231,17 @@ func (r *rpcStream) Recv(msg interface{}) error {
 	}
 
 	switch {
+	case len(resp.Header[frameErrorHeader]) > 0:
+		// the server couldn't decode a frame we sent but the
+		// stream is still open, so the error isn't kept
+		r.Unlock()
+		if err := r.codec.ReadBody(nil); err != nil {
+			r.Lock()
+			r.err = err
+			r.Unlock()
+			return err
+		}
+		return serverError(resp.Error)
 	case len(resp.Error) > 0:
 		// We've got an error response. Give this to the request;
 		// any subsequent requests will get the ReadResponseBody
@@ -109,6 +251,10 @@ func (r *rpcStream) Recv(msg interface{}) error {
 		} else {
 			r.err = io.EOF
 		}
+		// the stream ended
+		if r.trailer != nil {
+			*r.trailer = trailer(resp.Header)
+		}
 		r.Unlock()
 		err = r.codec.ReadBody(nil)
 		r.Lock()
@@ -128,6 +274,128 @@ func (r *rpcStream) Recv(msg interface{}) error {
 	return r.err
 }
 
+// readHeader reads the header of the next response frame, taking
+// in the stream header and the frame Header read ahead.
+func (r *rpcStream) readHeader(resp *codec.Message) error {
+	r.Lock()
+	if p := r.pending; p != nil {
+		r.pending = nil
+		r.Unlock()
+		*resp = p.msg
+		return p.err
+	}
+	r.reading = true
+	r.Unlock()
+
+	defer func() {
+		r.Lock()
+		r.reading = false
+		r.Unlock()
+	}()
+
+	for {
+		err := r.codec.ReadHeader(resp, codec.Response)
+		if err != nil || len(resp.Header[streamHeaderKey]) == 0 {
+			// no header was sent
+			r.setHeader(nil)
+			return err
+		}
+
+		// the header frame has no body
+		if err := r.codec.ReadBody(nil); err != nil {
+			return err
+		}
+
+		r.setHeader(streamHeader(resp.Header))
+		*resp = codec.Message{}
+	}
+}
+
+// setHeader sets the stream header if it's not already known.
+func (r *rpcStream) setHeader(md metadata.Metadata) {
+	r.Lock()
+	defer r.Unlock()
+
+	ready := r.ready()
+	select {
+	case <-ready:
+	default:
+		r.header = md
+		close(ready)
+	}
+}
+
+// ready returns the channel closed once the header is known, the lock must be held.
+func (r *rpcStream) ready() chan bool {
+	if r.headerReady == nil {
+		r.headerReady = make(chan bool)
+	}
+	return r.headerReady
+}
+
+func (r *rpcStream) Header() (metadata.Metadata, error) {
+	r.Lock()
+	ready := r.ready()
+
+	select {
+	case <-ready:
+		md := r.header
+		r.Unlock()
+		return metadata.Copy(md), nil
+	default:
+	}
+
+	if r.isClosed() {
+		r.Unlock()
+		return nil, errShutdown
+	}
+
+	// wait for the frame being read by Recv
+	if r.reading || r.pending != nil {
+		r.Unlock()
+
+		select {
+		case <-ready:
+		case <-r.closed:
+			return nil, errShutdown
+		case <-r.context.Done():
+			return nil, contextError(r.context.Err(), "%v", r.context.Err())
+		}
+
+		r.RLock()
+		md := r.header
+		r.RUnlock()
+		return metadata.Copy(md), nil
+	}
+	r.Unlock()
+
+	// read ahead, leaving the frame for Recv
+	var resp codec.Message
+	err := r.readHeader(&resp)
+
+	r.Lock()
+	r.pending = &pendingFrame{msg: resp, err: err}
+	md := r.header
+	r.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.Copy(md), nil
+}
+
+// streamHeader returns the metadata in the header of a stream header frame.
+func streamHeader(header map[string]string) metadata.Metadata {
+	md := make(metadata.Metadata)
+	for k, v := range header {
+		if strings.HasPrefix(k, headerPrefix) {
+			md[strings.TrimPrefix(k, headerPrefix)] = v
+		}
+	}
+	return md
+}
+
 func (r *rpcStream) Error() error {
 	r.RLock()
 	defer r.RUnlock()
@@ -149,6 +417,11 @@ func (r *rpcStream) Close() error {
 		close(r.closed)
 		r.Unlock()
 
+		// wait for the buffered messages to be written
+		if r.sent != nil {
+			<-r.sent
+		}
+
 		// send the end of stream message
 		if r.sendEOS {
 			// no need to check for error
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultAdaptiveWindow is the number of recent latencies
+	// an adaptive timeout is computed from per endpoint.
+	DefaultAdaptiveWindow = 128
+	// DefaultAdaptiveMinSamples is how many latencies of an endpoint
+	// must be observed before its timeout adapts.
+	DefaultAdaptiveMinSamples = 10
+)
+
+// AdaptiveTimeout computes the request timeout of an endpoint from the
+// latencies of its recent successful attempts, excluding retries and backoff.
+// The timeout is the percentile of the latencies times the multiplier, kept
+// between min and max.
+type AdaptiveTimeout struct {
+	percentile float64
+	multiplier float64
+	min, max   time.Duration
+
+	sync.RWMutex
+	latencies map[string]*latencyWindow
+}
+
+// latencyWindow is a ring of the most recent latencies.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// NewAdaptiveTimeout returns an adaptive timeout of the percentile, e.g 0.99,
+// of observed latencies times the multiplier, bounded by min and max. A max
+// of zero leaves it unbounded above.
+func NewAdaptiveTimeout(percentile, multiplier float64, min, max time.Duration) *AdaptiveTimeout {
+	if percentile <= 0 || percentile > 1 {
+		percentile = 1
+	}
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	return &AdaptiveTimeout{
+		percentile: percentile,
+		multiplier: multiplier,
+		min:        min,
+		max:        max,
+		latencies:  make(map[string]*latencyWindow),
+	}
+}
+
+// Timeout returns the timeout of the endpoint of the service, false until
+// enough of its latencies have been observed.
+func (a *AdaptiveTimeout) Timeout(service, endpoint string) (time.Duration, bool) {
+	a.RLock()
+	w, ok := a.latencies[service+" "+endpoint]
+	if !ok || w.len() < DefaultAdaptiveMinSamples {
+		a.RUnlock()
+		return 0, false
+	}
+	samples := append([]time.Duration(nil), w.samples[:w.len()]...)
+	a.RUnlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	// nearest rank
+	rank := int(math.Ceil(a.percentile*float64(len(samples)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	d := time.Duration(float64(samples[rank]) * a.multiplier)
+	if d < a.min {
+		d = a.min
+	}
+	if a.max > 0 && d > a.max {
+		d = a.max
+	}
+
+	return d, true
+}
+
+// observe records the latency of a successful call.
+func (a *AdaptiveTimeout) observe(service, endpoint string, d time.Duration) {
+	key := service + " " + endpoint
+
+	a.Lock()
+	defer a.Unlock()
+
+	w, ok := a.latencies[key]
+	if !ok {
+		w = &latencyWindow{samples: make([]time.Duration, DefaultAdaptiveWindow)}
+		a.latencies[key] = w
+	}
+
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+func (w *latencyWindow) len() int {
+	if w.full {
+		return len(w.samples)
+	}
+	return w.next
+}
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"go-micro.dev/v4/errors"
+)
+
+// Validator is implemented by responses which can check they're well formed.
+type Validator interface {
+	Validate() error
+}
+
+// validateResponse returns an ErrInvalidResponse error if the response fails its validation.
+func validateResponse(req Request, resp interface{}) error {
+	v, ok := resp.(Validator)
+	if !ok {
+		return nil
+	}
+
+	if err := v.Validate(); err != nil {
+		return errors.New("go.micro.client.validate", "invalid response from "+req.Service()+": "+err.Error(), 502)
+	}
+
+	return nil
+}
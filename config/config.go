@@ -28,17 +28,49 @@ type Config interface {
 	Watch(path ...string) (Watcher, error)
 }
 
+// Dumper is implemented by configs which can dump their values with
+// secrets masked, see Dump.
+type Dumper interface {
+	// Dump the config as json with secret values masked
+	Dump() string
+}
+
+// Snapshotter is implemented by configs which can take a consistent
+// view of their values, see Snapshot.
+type Snapshotter interface {
+	// Snapshot returns an immutable view of the current config
+	Snapshot() View
+}
+
+// View is an immutable view of the config at a point in time. It's
+// unaffected by later updates so reads of several values are consistent.
+type View interface {
+	Bytes() []byte
+	Get(path ...string) reader.Value
+	Map() map[string]interface{}
+	Scan(v interface{}) error
+}
+
 // Watcher is the config watcher.
 type Watcher interface {
 	Next() (reader.Value, error)
 	Stop() error
 }
 
+// StructWatcher keeps a struct scanned from the config up to date.
+type StructWatcher interface {
+	// Stop updating the struct
+	Stop() error
+}
+
 type Options struct {
 	Loader loader.Loader
 	Reader reader.Reader
 	Source []source.Source
 
+	// Secrets are the paths masked when dumping the config
+	Secrets [][]string
+
 	// for alternative data
 	Context context.Context
 
@@ -72,6 +104,18 @@ func Scan(v interface{}) error {
 	return DefaultConfig.Scan(v)
 }
 
+// Dump the config as json with secret values masked. If the config
+// isn't a Dumper the secrets set in its options are masked.
+func Dump() string {
+	return dump(DefaultConfig)
+}
+
+// Snapshot returns an immutable view of the current config. If the
+// config isn't a Snapshotter the view is copied from its Bytes.
+func Snapshot() View {
+	return snapshot(DefaultConfig)
+}
+
 // Force a source changeset sync.
 func Sync() error {
 	return DefaultConfig.Sync()
@@ -92,6 +136,11 @@ func Watch(path ...string) (Watcher, error) {
 	return DefaultConfig.Watch(path...)
 }
 
+// WatchStruct scans the value at the path into v, rescanning it on changes.
+func WatchStruct(path []string, v interface{}, fn func(error)) (StructWatcher, error) {
+	return NewStructWatcher(DefaultConfig, path, v, fn)
+}
+
 // LoadFile is short hand for creating a file source and loading it.
 func LoadFile(path string) error {
 	return Load(file.NewSource(
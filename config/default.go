@@ -2,9 +2,11 @@ package config
 
 import (
 	"bytes"
+	"strings"
 	"sync"
 	"time"
 
+	encoder "go-micro.dev/v4/config/encoder/json"
 	"go-micro.dev/v4/config/loader"
 	"go-micro.dev/v4/config/loader/memory"
 	"go-micro.dev/v4/config/reader"
@@ -21,6 +23,10 @@ type config struct {
 	snap *loader.Snapshot
 	// the current values
 	vals reader.Values
+	// secret paths found on scan targets, scans only
+	// hold the read lock so they're guarded by smtx
+	smtx    sync.Mutex
+	secrets [][]string
 }
 
 type watcher struct {
@@ -94,6 +100,13 @@ func (c *config) run() {
 				return err
 			}
 
+			// parse before taking the lock so the snapshot and
+			// values are swapped together or not at all
+			vals, err := c.opts.Reader.Values(snap.ChangeSet)
+			if err != nil {
+				continue
+			}
+
 			c.Lock()
 
 			if c.snap.Version >= snap.Version {
@@ -103,9 +116,7 @@ func (c *config) run() {
 
 			// save
 			c.snap = snap
-
-			// set values
-			c.vals, _ = c.opts.Reader.Values(snap.ChangeSet)
+			c.vals = vals
 
 			c.Unlock()
 		}
@@ -156,6 +167,16 @@ func (c *config) Map() map[string]interface{} {
 func (c *config) Scan(v interface{}) error {
 	c.RLock()
 	defer c.RUnlock()
+
+	// remember the fields tagged secret for dumps
+	c.smtx.Lock()
+	for _, path := range reader.SecretPaths(v) {
+		if !hasPath(c.secrets, path) {
+			c.secrets = append(c.secrets, path)
+		}
+	}
+	c.smtx.Unlock()
+
 	return c.vals.Scan(v)
 }
 
@@ -170,14 +191,15 @@ func (c *config) Sync() error {
 		return err
 	}
 
-	c.Lock()
-	defer c.Unlock()
-
-	c.snap = snap
 	vals, err := c.opts.Reader.Values(snap.ChangeSet)
 	if err != nil {
 		return err
 	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.snap = snap
 	c.vals = vals
 
 	return nil
@@ -249,14 +271,15 @@ func (c *config) Load(sources ...source.Source) error {
 		return err
 	}
 
-	c.Lock()
-	defer c.Unlock()
-
-	c.snap = snap
 	vals, err := c.opts.Reader.Values(snap.ChangeSet)
 	if err != nil {
 		return err
 	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.snap = snap
 	c.vals = vals
 
 	return nil
@@ -278,6 +301,90 @@ func (c *config) Watch(path ...string) (Watcher, error) {
 	}, nil
 }
 
+// Dump returns the config as json with the secret paths masked.
+func (c *config) Dump() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.vals == nil {
+		return "{}"
+	}
+
+	c.smtx.Lock()
+	secrets := append(append([][]string{}, c.opts.Secrets...), c.secrets...)
+	c.smtx.Unlock()
+
+	b, err := encoder.NewEncoder().Encode(reader.Redact(c.vals.Map(), secrets))
+	if err != nil {
+		return "{}"
+	}
+
+	return string(b)
+}
+
+// Snapshot copies the current values so the view isn't changed by
+// later updates, which replace the values as a whole once merged.
+func (c *config) Snapshot() View {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.vals == nil {
+		return &view{}
+	}
+
+	vals, err := c.opts.Reader.Values(&source.ChangeSet{
+		Data:   c.vals.Bytes(),
+		Format: "json",
+	})
+	if err != nil {
+		return &view{}
+	}
+
+	return &view{vals: vals}
+}
+
+// dump returns the config as json with secrets masked, masking
+// the secrets in its options if it can't dump itself.
+func dump(c Config) string {
+	if d, ok := c.(Dumper); ok {
+		return d.Dump()
+	}
+
+	b, err := encoder.NewEncoder().Encode(reader.Redact(c.Map(), c.Options().Secrets))
+	if err != nil {
+		return "{}"
+	}
+
+	return string(b)
+}
+
+// snapshot returns a view of the config, copying it from
+// the bytes if it can't take a snapshot itself.
+func snapshot(c Config) View {
+	if s, ok := c.(Snapshotter); ok {
+		return s.Snapshot()
+	}
+
+	vals, err := json.NewReader().Values(&source.ChangeSet{
+		Data:   c.Bytes(),
+		Format: "json",
+	})
+	if err != nil {
+		return &view{}
+	}
+
+	return &view{vals: vals}
+}
+
+func hasPath(paths [][]string, path []string) bool {
+	for _, p := range paths {
+		if strings.Join(p, ".") == strings.Join(path, ".") {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *config) String() string {
 	return "config"
 }
@@ -307,3 +414,37 @@ func (w *watcher) Next() (reader.Value, error) {
 func (w *watcher) Stop() error {
 	return w.lw.Stop()
 }
+
+// view is a copy of the values which is only read.
+type view struct {
+	vals reader.Values
+}
+
+func (v *view) Bytes() []byte {
+	if v.vals == nil {
+		return []byte{}
+	}
+	return v.vals.Bytes()
+}
+
+func (v *view) Get(path ...string) reader.Value {
+	if v.vals == nil {
+		return newValue()
+	}
+	return v.vals.Get(path...)
+}
+
+// Map returns a copy of the values so the view can't be changed through it.
+func (v *view) Map() map[string]interface{} {
+	if v.vals == nil {
+		return map[string]interface{}{}
+	}
+	return reader.Redact(v.vals.Map(), nil)
+}
+
+func (v *view) Scan(val interface{}) error {
+	if v.vals == nil {
+		return nil
+	}
+	return v.vals.Scan(val)
+}
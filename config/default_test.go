@@ -164,3 +164,231 @@ func TestConfigWatcherDirtyOverrite(t *testing.T) {
 		equalS(t, conf.Get(k).String(""), v)
 	}
 }
+
+func TestConfigDumpSecrets(t *testing.T) {
+	data := []byte(`{"database": {"user": "micro", "password": "hunter2"}, "api": {"token": "abc123", "url": "http://localhost"}}`)
+
+	conf, err := NewConfig(
+		WithSource(memory.NewSource(memory.WithJSON(data))),
+		WithSecret("database", "password"),
+		WithWatcherDisabled(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the api token is marked on the scan target
+	var scanned struct {
+		API struct {
+			Token string `json:"token" secret:"true"`
+			URL   string `json:"url"`
+		} `json:"api"`
+	}
+	if err := conf.Scan(&scanned); err != nil {
+		t.Fatal(err)
+	}
+	if scanned.API.Token != "abc123" {
+		t.Fatalf("Expected scanned token abc123, got %s", scanned.API.Token)
+	}
+
+	dump := conf.(Dumper).Dump()
+
+	for _, secret := range []string{"hunter2", "abc123"} {
+		if strings.Contains(dump, secret) {
+			t.Fatalf("Expected %s to be masked in %s", secret, dump)
+		}
+	}
+	for _, value := range []string{"micro", "http://localhost"} {
+		if !strings.Contains(dump, value) {
+			t.Fatalf("Expected %s in %s", value, dump)
+		}
+	}
+
+	// real values are still usable
+	if v := conf.Get("database", "password").String(""); v != "hunter2" {
+		t.Fatalf("Expected password hunter2, got %s", v)
+	}
+	if v := conf.Get("api", "token").String(""); v != "abc123" {
+		t.Fatalf("Expected token abc123, got %s", v)
+	}
+	if !strings.Contains(string(conf.Bytes()), "hunter2") {
+		t.Fatal("Expected the raw bytes to be unmasked")
+	}
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"a": 0, "b": 0}`)))
+
+	conf, err := NewConfig(WithSource(src), WithWatcherDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+
+	// update both keys together while snapshots are taken
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			data := []byte(fmt.Sprintf(`{"a": %d, "b": %d}`, i, i))
+			if err := src.Write(&source.ChangeSet{Data: data, Format: "json"}); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := conf.Sync(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		snap := conf.(Snapshotter).Snapshot()
+		a, b := snap.Get("a").Int(-1), snap.Get("b").Int(-2)
+		if a != b {
+			t.Fatalf("Expected a consistent snapshot, got a=%d b=%d", a, b)
+		}
+
+		select {
+		case <-done:
+		default:
+			continue
+		}
+		break
+	}
+
+	snap := conf.(Snapshotter).Snapshot()
+	if v := snap.Get("a").Int(0); v != 100 {
+		t.Fatalf("Expected a=100, got %d", v)
+	}
+
+	// the snapshot isn't changed by later updates
+	conf.Set(200, "a")
+	if v := snap.Get("a").Int(0); v != 100 {
+		t.Fatalf("Expected snapshot a=100 after set, got %d", v)
+	}
+	snap.Map()["a"] = 300
+	if v := snap.Get("a").Int(0); v != 100 {
+		t.Fatalf("Expected snapshot a=100 after map change, got %d", v)
+	}
+	if v := conf.Get("a").Int(0); v != 200 {
+		t.Fatalf("Expected a=200, got %d", v)
+	}
+}
+
+// plainConfig hides the snapshot of the config it wraps.
+type plainConfig struct {
+	Config
+}
+
+func TestConfigSnapshotFallback(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"a": 1}`)))
+
+	conf, err := NewConfig(WithSource(src), WithWatcherDisabled())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap := snapshot(plainConfig{conf})
+	conf.Set(2, "a")
+
+	if v := snap.Get("a").Int(0); v != 1 {
+		t.Fatalf("Expected snapshot a=1, got %d", v)
+	}
+}
+
+func TestConfigDumpFallback(t *testing.T) {
+	data := []byte(`{"database": {"user": "micro", "password": "hunter2"}}`)
+
+	conf, err := NewConfig(
+		WithSource(memory.NewSource(memory.WithJSON(data))),
+		WithSecret("database", "password"),
+		WithWatcherDisabled(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := dump(plainConfig{conf})
+
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("Expected the password to be masked in %s", out)
+	}
+	if !strings.Contains(out, "micro") {
+		t.Fatalf("Expected the user in %s", out)
+	}
+}
+
+func TestConfigWatchStruct(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"server": {"name": "foo", "port": 8080}}`)))
+
+	conf, err := NewConfig(WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	var server struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	changes := make(chan error, 10)
+
+	w, err := NewStructWatcher(conf, []string{"server"}, &server, func(err error) {
+		changes <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// the current value is scanned straight away
+	if server.Name != "foo" || server.Port != 8080 {
+		t.Fatalf("Expected foo:8080, got %+v", server)
+	}
+
+	update := func(data string) error {
+		if err := src.Write(&source.ChangeSet{Data: []byte(data), Format: "json"}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-changes:
+			return err
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a change for %s", data)
+		}
+		return nil
+	}
+
+	// the loader watches the source in the background
+	time.Sleep(time.Millisecond * 100)
+
+	if err := update(`{"server": {"name": "bar", "port": 9090}}`); err != nil {
+		t.Fatal(err)
+	}
+	if server.Name != "bar" || server.Port != 9090 {
+		t.Fatalf("Expected bar:9090, got %+v", server)
+	}
+
+	// a value which fails to decode keeps the last good one
+	if err := update(`{"server": {"name": "baz", "port": "none"}}`); err == nil {
+		t.Fatal("Expected a decode error")
+	}
+	if server.Name != "bar" || server.Port != 9090 {
+		t.Fatalf("Expected bar:9090 to be kept, got %+v", server)
+	}
+
+	if err := update(`{"server": {"name": "baz", "port": 7070}}`); err != nil {
+		t.Fatal(err)
+	}
+	if server.Name != "baz" || server.Port != 7070 {
+		t.Fatalf("Expected baz:7070, got %+v", server)
+	}
+
+	// only pointers can be updated
+	if _, err := NewStructWatcher(conf, []string{"server"}, server, nil); err == nil {
+		t.Fatal("Expected an error watching a struct value")
+	}
+}
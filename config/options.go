@@ -27,6 +27,14 @@ func WithReader(r reader.Reader) Option {
 	}
 }
 
+// WithSecret marks the path as secret so its value is masked when the
+// config is dumped. The value is still returned by Get and Scan.
+func WithSecret(path ...string) Option {
+	return func(o *Options) {
+		o.Secrets = append(o.Secrets, path)
+	}
+}
+
 func WithWatcherDisabled() Option {
 	return func(o *Options) {
 		o.WithWatcherDisabled = true
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"time"
 
-	"github.com/imdario/mergo"
 	"go-micro.dev/v4/config/encoder"
 	"go-micro.dev/v4/config/encoder/json"
 	"go-micro.dev/v4/config/reader"
@@ -38,9 +37,7 @@ func (j *jsonReader) Merge(changes ...*source.ChangeSet) (*source.ChangeSet, err
 		if err := codec.Decode(m.Data, &data); err != nil {
 			return nil, err
 		}
-		if err := mergo.Map(&merged, data, mergo.WithOverride); err != nil {
-			return nil, err
-		}
+		merged = merge(merged, data, j.opts.SliceMerge)
 	}
 
 	b, err := j.json.Encode(merged)
@@ -1,8 +1,10 @@
 package json
 
 import (
+	"strings"
 	"testing"
 
+	"go-micro.dev/v4/config/reader"
 	"go-micro.dev/v4/config/source"
 )
 
@@ -41,3 +43,62 @@ func TestReader(t *testing.T) {
 		}
 	}
 }
+
+func TestReaderSliceMerge(t *testing.T) {
+	defaults := []byte(`{"hosts": ["a", "b"], "nodes": [{"name": "a", "port": 1}, {"name": "b"}], "server": {"name": "foo", "debug": true}}`)
+	file := []byte(`{"hosts": ["c"], "nodes": [{"port": 2}], "server": {"debug": false}}`)
+
+	testData := []struct {
+		strategy reader.SliceMerge
+		hosts    []string
+		nodes    string
+	}{
+		{
+			reader.SliceReplace,
+			[]string{"c"},
+			`[{"port":2}]`,
+		},
+		{
+			reader.SliceAppend,
+			[]string{"a", "b", "c"},
+			`[{"name":"a","port":1},{"name":"b"},{"port":2}]`,
+		},
+		{
+			reader.SliceMergeIndex,
+			[]string{"c", "b"},
+			`[{"name":"a","port":2},{"name":"b"}]`,
+		},
+	}
+
+	for _, d := range testData {
+		r := NewReader(reader.WithSliceMerge(d.strategy))
+
+		c, err := r.Merge(&source.ChangeSet{Data: defaults}, &source.ChangeSet{Data: file})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := r.Values(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hosts := values.Get("hosts").StringSlice(nil)
+		if strings.Join(hosts, ",") != strings.Join(d.hosts, ",") {
+			t.Fatalf("Expected hosts %v got %v", d.hosts, hosts)
+		}
+
+		if nodes := string(values.Get("nodes").Bytes()); nodes != d.nodes {
+			t.Fatalf("Expected nodes %s got %s", d.nodes, nodes)
+		}
+
+		// maps are always deep merged
+		if v := values.Get("server", "name").String(""); v != "foo" {
+			t.Fatalf("Expected server name foo got %s", v)
+		}
+
+		if v := values.Get("server", "debug").Bool(true); v {
+			t.Fatal("Expected server debug to be overridden")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package json
+
+import (
+	"go-micro.dev/v4/config/reader"
+)
+
+// merge merges src into dst. Maps are deep merged, slices are merged
+// using the given strategy and any other value in src overrides dst.
+func merge(dst, src map[string]interface{}, s reader.SliceMerge) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+
+	for k, v := range src {
+		dst[k] = mergeValue(dst[k], v, s)
+	}
+
+	return dst
+}
+
+func mergeValue(dst, src interface{}, s reader.SliceMerge) interface{} {
+	switch sv := src.(type) {
+	case nil:
+		return dst
+	case map[string]interface{}:
+		if dv, ok := dst.(map[string]interface{}); ok {
+			return merge(dv, sv, s)
+		}
+		return merge(nil, sv, s)
+	case []interface{}:
+		dv, ok := dst.([]interface{})
+		if !ok {
+			return sv
+		}
+
+		switch s {
+		case reader.SliceAppend:
+			merged := make([]interface{}, 0, len(dv)+len(sv))
+			merged = append(merged, dv...)
+			return append(merged, sv...)
+		case reader.SliceMergeIndex:
+			merged := make([]interface{}, len(dv))
+			copy(merged, dv)
+			for i, v := range sv {
+				if i < len(merged) {
+					merged[i] = mergeValue(merged[i], v, s)
+					continue
+				}
+				merged = append(merged, v)
+			}
+			return merged
+		}
+
+		return sv
+	}
+
+	return src
+}
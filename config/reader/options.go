@@ -7,8 +7,24 @@ import (
 
 type Options struct {
 	Encoding map[string]encoder.Encoder
+	// SliceMerge is the strategy used to merge slices
+	// across change sets. Maps are always deep merged.
+	SliceMerge SliceMerge
 }
 
+// SliceMerge is a strategy for merging slices when layering change sets.
+type SliceMerge int
+
+const (
+	// SliceReplace replaces the slice with the one from the later change set.
+	SliceReplace SliceMerge = iota
+	// SliceAppend appends the slice from the later change set.
+	SliceAppend
+	// SliceMergeIndex merges the slices element by element. Maps at the
+	// same index are deep merged, any other value is replaced.
+	SliceMergeIndex
+)
+
 type Option func(o *Options)
 
 func NewOptions(opts ...Option) Options {
@@ -31,3 +47,10 @@ func WithEncoder(e encoder.Encoder) Option {
 		o.Encoding[e.String()] = e
 	}
 }
+
+// WithSliceMerge sets the strategy used to merge slices. The default is SliceReplace.
+func WithSliceMerge(s SliceMerge) Option {
+	return func(o *Options) {
+		o.SliceMerge = s
+	}
+}
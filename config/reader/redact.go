@@ -0,0 +1,115 @@
+package reader
+
+import (
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the values of secret paths in redacted output.
+const RedactedValue = "******"
+
+// SecretPaths returns the paths of the fields tagged `secret:"true"` in v,
+// a struct or a pointer to one. Path elements are the json field names.
+func SecretPaths(v interface{}) [][]string {
+	var paths [][]string
+	secretPaths(reflect.TypeOf(v), nil, make(map[reflect.Type]bool), &paths)
+	return paths
+}
+
+// secretPaths walks the fields of t, types already being walked
+// are skipped so self referencing types terminate.
+func secretPaths(t reflect.Type, prefix []string, seen map[reflect.Type]bool, paths *[][]string) {
+	if t == nil {
+		return
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+
+		name := f.Name
+		if tag := f.Tag.Get("json"); len(tag) > 0 {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if len(tag) > 0 {
+				name = tag
+			}
+		}
+
+		path := append(append([]string{}, prefix...), name)
+
+		if f.Tag.Get("secret") == "true" {
+			*paths = append(*paths, path)
+			continue
+		}
+
+		// embedded structs are flattened by encoding/json
+		if f.Anonymous && len(f.Tag.Get("json")) == 0 {
+			secretPaths(f.Type, prefix, seen, paths)
+			continue
+		}
+
+		secretPaths(f.Type, path, seen, paths)
+	}
+}
+
+// Redact returns a copy of the values with every secret path replaced by
+// RedactedValue. Keys are matched case insensitively like encoding/json.
+func Redact(values map[string]interface{}, secrets [][]string) map[string]interface{} {
+	out := copyMap(values)
+
+	for _, path := range secrets {
+		redact(out, path)
+	}
+
+	return out
+}
+
+func redact(m map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+
+	for k, v := range m {
+		if !strings.EqualFold(k, path[0]) {
+			continue
+		}
+
+		if len(path) == 1 {
+			m[k] = RedactedValue
+			continue
+		}
+
+		if next, ok := v.(map[string]interface{}); ok {
+			redact(next, path[1:])
+		}
+	}
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		if next, ok := v.(map[string]interface{}); ok {
+			v = copyMap(next)
+		}
+		out[k] = v
+	}
+
+	return out
+}
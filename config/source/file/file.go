@@ -2,9 +2,11 @@
 package file
 
 import (
+	"bytes"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 
 	"go-micro.dev/v4/config/source"
 )
@@ -20,15 +22,7 @@ var (
 )
 
 func (f *file) Read() (*source.ChangeSet, error) {
-	var fh fs.File
-	var err error
-
-	if f.fs != nil {
-		fh, err = f.fs.Open(f.path)
-	} else {
-		fh, err = os.Open(f.path)
-	}
-
+	fh, err := f.open(f.path)
 	if err != nil {
 		return nil, err
 	}
@@ -42,8 +36,29 @@ func (f *file) Read() (*source.ChangeSet, error) {
 		return nil, err
 	}
 
+	ft := format(f.path, f.opts.Encoder)
+
+	// expand includes in the format of the encoder
+	if ft == f.opts.Encoder.String() && bytes.Contains(b, []byte(IncludeKey)) {
+		p := f.path
+		if f.fs == nil {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+		}
+
+		v, err := f.include(p, b, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if b, err = f.opts.Encoder.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+
 	cs := &source.ChangeSet{
-		Format:    format(f.path, f.opts.Encoder),
+		Format:    ft,
 		Source:    f.String(),
 		Timestamp: info.ModTime(),
 		Data:      b,
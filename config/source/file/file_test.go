@@ -1,9 +1,11 @@
 package file_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -87,3 +89,68 @@ func TestWithFS(t *testing.T) {
 		t.Error("data from file does not match")
 	}
 }
+
+func TestInclude(t *testing.T) {
+	fsMock := fstest.MapFS{
+		"config.json": &fstest.MapFile{Data: []byte(`{
+			"name": "test",
+			"database": {"$include": "shared/database.json", "port": 3307},
+			"servers": [{"$include": "shared/server.json"}]
+		}`)},
+		"shared/database.json": &fstest.MapFile{Data: []byte(`{
+			"$include": ["defaults.json", "credentials.json"],
+			"host": "10.0.0.1",
+			"port": 3306
+		}`)},
+		"shared/defaults.json":    &fstest.MapFile{Data: []byte(`{"host": "localhost", "pool": {"size": 10, "ttl": 60}}`)},
+		"shared/credentials.json": &fstest.MapFile{Data: []byte(`{"user": "admin", "pool": {"size": 20}}`)},
+		"shared/server.json":      &fstest.MapFile{Data: []byte(`{"address": ":8080"}`)},
+	}
+
+	f := file.NewSource(file.WithFS(fsMock), file.WithPath("config.json"))
+	c, err := f.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(c.Data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// inline keys override included ones, later includes earlier ones
+	expect := map[string]interface{}{
+		"name": "test",
+		"database": map[string]interface{}{
+			"host": "10.0.0.1",
+			"port": float64(3307),
+			"user": "admin",
+			"pool": map[string]interface{}{"size": float64(20), "ttl": float64(60)},
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"address": ":8080"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("Expected %v got %v", expect, got)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	fsMock := fstest.MapFS{
+		"config.json": &fstest.MapFile{Data: []byte(`{"a": {"$include": "a.json"}}`)},
+		"a.json":      &fstest.MapFile{Data: []byte(`{"b": {"$include": "sub/b.json"}}`)},
+		"sub/b.json":  &fstest.MapFile{Data: []byte(`{"$include": "../a.json"}`)},
+	}
+
+	f := file.NewSource(file.WithFS(fsMock), file.WithPath("config.json"))
+	_, err := f.Read()
+	if err == nil {
+		t.Fatal("Expected an include cycle error")
+	}
+
+	if expect := "include cycle: a.json -> sub/b.json -> a.json"; err.Error() != expect {
+		t.Fatalf("Expected %q got %q", expect, err.Error())
+	}
+}
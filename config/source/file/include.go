@@ -0,0 +1,157 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeKey is the directive inlining other files at a node, its value is
+// a path or a list of paths relative to the file they're included from e.g
+//
+//	{"database": {"$include": "database.json", "port": 3307}}
+//
+// The included files must be objects in the format of the source encoder.
+// They're merged in order, later files overriding earlier ones, then the keys
+// set inline override those included. Nested objects are merged key by key.
+const IncludeKey = "$include"
+
+func (f *file) open(p string) (fs.File, error) {
+	if f.fs != nil {
+		return f.fs.Open(p)
+	}
+	return os.Open(p)
+}
+
+func (f *file) readFile(p string) ([]byte, error) {
+	fh, err := f.open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return io.ReadAll(fh)
+}
+
+// join resolves the include path relative to the including file.
+func (f *file) join(from, p string) string {
+	if f.fs != nil {
+		return path.Join(path.Dir(from), p)
+	}
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(filepath.Dir(from), p)
+}
+
+// include decodes the file and expands its includes, stack holds the files
+// being included to detect cycles.
+func (f *file) include(p string, b []byte, stack []string) (interface{}, error) {
+	for i, s := range stack {
+		if s == p {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], p), " -> "))
+		}
+	}
+	stack = append(stack, p)
+
+	var v interface{}
+	if err := f.opts.Encoder.Decode(b, &v); err != nil {
+		return nil, fmt.Errorf("%s: %v", p, err)
+	}
+
+	return f.expand(p, v, stack)
+}
+
+func (f *file) expand(p string, v interface{}, stack []string) (interface{}, error) {
+	switch t := v.(type) {
+	case []interface{}:
+		for i, e := range t {
+			ev, err := f.expand(p, e, stack)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = ev
+		}
+		return t, nil
+	case map[string]interface{}:
+	default:
+		return v, nil
+	}
+
+	m := v.(map[string]interface{})
+	inline := make(map[string]interface{}, len(m))
+
+	for k, e := range m {
+		if k == IncludeKey {
+			continue
+		}
+		ev, err := f.expand(p, e, stack)
+		if err != nil {
+			return nil, err
+		}
+		inline[k] = ev
+	}
+
+	inc, ok := m[IncludeKey]
+	if !ok {
+		return inline, nil
+	}
+
+	var paths []string
+	switch t := inc.(type) {
+	case string:
+		paths = []string{t}
+	case []interface{}:
+		for _, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a path or list of paths", p, IncludeKey)
+			}
+			paths = append(paths, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %s must be a path or list of paths", p, IncludeKey)
+	}
+
+	included := make(map[string]interface{})
+
+	for _, ip := range paths {
+		ip = f.join(p, ip)
+
+		b, err := f.readFile(ip)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+
+		iv, err := f.include(ip, b, stack)
+		if err != nil {
+			return nil, err
+		}
+
+		im, ok := iv.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: included file %s is not an object", p, ip)
+		}
+
+		merge(included, im)
+	}
+
+	return merge(included, inline), nil
+}
+
+// merge sets the values of src in dst, merging nested maps.
+func merge(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		dm, dok := dst[k].(map[string]interface{})
+		sm, sok := v.(map[string]interface{})
+		if dok && sok {
+			dst[k] = merge(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
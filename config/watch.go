@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+)
+
+type structWatcher struct {
+	w Watcher
+}
+
+// NewStructWatcher scans the value at the path of the config into v, which
+// must be a pointer, then rescans it each time the value changes and calls fn.
+// The changed value is decoded into a new struct which only replaces v if it
+// decodes, otherwise v keeps the last good value and fn is passed the error.
+// v is written from the watching goroutine, so reads of v should be
+// synchronised by fn.
+func NewStructWatcher(c Config, path []string, v interface{}, fn func(error)) (StructWatcher, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("config: watching a struct requires a non nil pointer")
+	}
+
+	w, err := c.Watch(path...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Get(path...).Scan(v); err != nil {
+		w.Stop()
+		return nil, err
+	}
+
+	sw := &structWatcher{w: w}
+	go sw.run(rv, fn)
+
+	return sw, nil
+}
+
+func (s *structWatcher) run(rv reflect.Value, fn func(error)) {
+	for {
+		val, err := s.w.Next()
+		if err != nil {
+			// stopped
+			return
+		}
+
+		nv := reflect.New(rv.Elem().Type())
+		if err := val.Scan(nv.Interface()); err != nil {
+			if fn != nil {
+				fn(err)
+			}
+			continue
+		}
+
+		rv.Elem().Set(nv.Elem())
+
+		if fn != nil {
+			fn(nil)
+		}
+	}
+}
+
+func (s *structWatcher) Stop() error {
+	return s.w.Stop()
+}
@@ -0,0 +1,31 @@
+// Package capture records recent requests and their responses for debugging
+package capture
+
+import (
+	"time"
+)
+
+// Capture keeps the most recent requests served.
+type Capture interface {
+	// Write a captured request
+	Write(*Record) error
+	// Read the captured requests, oldest first
+	Read(...ReadOption) ([]*Record, error)
+}
+
+// Record is a request and its response.
+type Record struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Service   string            `json:"service"`
+	Endpoint  string            `json:"endpoint"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	// Request and Response are the encoded payloads
+	Request  string        `json:"request"`
+	Response string        `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+var (
+	DefaultCapture = NewCapture()
+)
@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"go-micro.dev/v4/util/ring"
+)
+
+type memCapture struct {
+	opts Options
+
+	// ring buffer of records
+	buffer *ring.Buffer
+}
+
+func (c *memCapture) Write(r *Record) error {
+	rec := *r
+	rec.Request = c.truncate(rec.Request)
+	rec.Response = c.truncate(rec.Response)
+
+	c.buffer.Put(&rec)
+
+	return nil
+}
+
+func (c *memCapture) truncate(s string) string {
+	if n := c.opts.MaxPayload; n > 0 && len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
+
+func (c *memCapture) Read(opts ...ReadOption) ([]*Record, error) {
+	var options ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	entries := c.buffer.Get(c.buffer.Size())
+
+	records := make([]*Record, 0, len(entries))
+
+	for _, entry := range entries {
+		rec := entry.Value.(*Record)
+		// skip if the endpoint is specified and doesn't match
+		if len(options.Endpoint) > 0 && rec.Endpoint != options.Endpoint {
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	if options.Count > 0 && len(records) > options.Count {
+		records = records[len(records)-options.Count:]
+	}
+
+	return records, nil
+}
+
+// NewCapture returns a capture keeping the most recent requests in memory.
+func NewCapture(opts ...Option) Capture {
+	options := DefaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Size <= 0 {
+		options.Size = DefaultSize
+	}
+
+	return &memCapture{
+		opts:   options,
+		buffer: ring.New(options.Size),
+	}
+}
@@ -0,0 +1,63 @@
+package capture
+
+type Options struct {
+	// Size is the number of requests kept
+	Size int
+	// MaxPayload truncates the request and response to a number
+	// of bytes, zero keeps them whole
+	MaxPayload int
+}
+
+type Option func(o *Options)
+
+type ReadOptions struct {
+	// Endpoint only returns the requests to the endpoint
+	Endpoint string
+	// Count returns only the most recent requests
+	Count int
+}
+
+type ReadOption func(o *ReadOptions)
+
+// Size sets the number of requests kept.
+func Size(n int) Option {
+	return func(o *Options) {
+		o.Size = n
+	}
+}
+
+// MaxPayload truncates payloads longer than n bytes.
+func MaxPayload(n int) Option {
+	return func(o *Options) {
+		o.MaxPayload = n
+	}
+}
+
+// ReadEndpoint reads the requests to the endpoint.
+func ReadEndpoint(e string) ReadOption {
+	return func(o *ReadOptions) {
+		o.Endpoint = e
+	}
+}
+
+// ReadCount reads the most recent n requests.
+func ReadCount(n int) ReadOption {
+	return func(o *ReadOptions) {
+		o.Count = n
+	}
+}
+
+const (
+	// DefaultSize of the buffer.
+	DefaultSize = 64
+	// DefaultMaxPayload of a captured request or response.
+	DefaultMaxPayload = 1024
+)
+
+// DefaultOptions returns default options.
+func DefaultOptions() Options {
+	return Options{
+		Size:       DefaultSize,
+		MaxPayload: DefaultMaxPayload,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/debug/capture"
 	"go-micro.dev/v4/debug/log"
 	proto "go-micro.dev/v4/debug/proto"
 	"go-micro.dev/v4/debug/stats"
@@ -16,9 +17,11 @@ import (
 // NewHandler returns an instance of the Debug Handler.
 func NewHandler(c client.Client) *Debug {
 	return &Debug{
-		log:   log.DefaultLog,
-		stats: stats.DefaultStats,
-		trace: trace.DefaultTracer,
+		log:     log.DefaultLog,
+		stats:   stats.DefaultStats,
+		trace:   trace.DefaultTracer,
+		capture: capture.DefaultCapture,
+		server:  server.DefaultServer,
 	}
 }
 
@@ -31,6 +34,30 @@ type Debug struct {
 	stats stats.Stats
 	// the tracer
 	trace trace.Tracer
+	// the requests captured by the server
+	capture capture.Capture
+	// the server whose endpoints are described
+	server server.Server
+}
+
+// CaptureRequest reads the captured requests, optionally only
+// those to an endpoint and the most recent count.
+type CaptureRequest struct {
+	Endpoint string `json:"endpoint"`
+	Count    int    `json:"count"`
+}
+
+type CaptureResponse struct {
+	Records []*capture.Record `json:"records"`
+}
+
+// SchemaRequest describes the endpoints, or only the one named.
+type SchemaRequest struct {
+	Endpoint string `json:"endpoint"`
+}
+
+type SchemaResponse struct {
+	Endpoints []*server.EndpointSchema `json:"endpoints"`
 }
 
 func (d *Debug) Health(ctx context.Context, req *proto.HealthRequest, rsp *proto.HealthResponse) error {
@@ -61,6 +88,38 @@ func (d *Debug) Stats(ctx context.Context, req *proto.StatsRequest, rsp *proto.S
 	return nil
 }
 
+// Capture returns the requests captured by a server using capture.DefaultCapture.
+// It isn't part of the Debug proto so must be called with a json content type.
+func (d *Debug) Capture(ctx context.Context, req *CaptureRequest, rsp *CaptureResponse) error {
+	records, err := d.capture.Read(capture.ReadEndpoint(req.Endpoint), capture.ReadCount(req.Count))
+	if err != nil {
+		return err
+	}
+
+	rsp.Records = records
+
+	return nil
+}
+
+// Schema describes the request and response messages of the endpoints of
+// server.DefaultServer, derived from the handler types. It isn't part of
+// the Debug proto so must be called with a json content type.
+func (d *Debug) Schema(ctx context.Context, req *SchemaRequest, rsp *SchemaResponse) error {
+	schemas, err := server.Schemas(d.server)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range schemas {
+		if len(req.Endpoint) > 0 && s.Endpoint != req.Endpoint {
+			continue
+		}
+		rsp.Endpoints = append(rsp.Endpoints, s)
+	}
+
+	return nil
+}
+
 func (d *Debug) Trace(ctx context.Context, req *proto.TraceRequest, rsp *proto.TraceResponse) error {
 	traces, err := d.trace.Read(trace.ReadTrace(req.Id))
 	if err != nil {
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/debug/capture"
+	"go-micro.dev/v4/server"
+)
+
+func TestDebugCapture(t *testing.T) {
+	c := capture.NewCapture()
+	d := &Debug{capture: c}
+
+	for _, endpoint := range []string{"Foo.Bar", "Foo.Baz", "Foo.Bar"} {
+		c.Write(&capture.Record{Service: "foo", Endpoint: endpoint})
+	}
+
+	rsp := new(CaptureResponse)
+	if err := d.Capture(context.Background(), &CaptureRequest{}, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(rsp.Records))
+	}
+
+	rsp = new(CaptureResponse)
+	if err := d.Capture(context.Background(), &CaptureRequest{Endpoint: "Foo.Bar"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Records) != 2 {
+		t.Fatalf("Expected 2 records for Foo.Bar, got %d", len(rsp.Records))
+	}
+	for _, r := range rsp.Records {
+		if r.Endpoint != "Foo.Bar" {
+			t.Fatalf("Expected only Foo.Bar, got %s", r.Endpoint)
+		}
+	}
+}
+
+type Address struct {
+	Street string `json:"street"`
+}
+
+type CreateRequest struct {
+	Name     string            `json:"name"`
+	Age      int32             `json:"age,omitempty"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Address  *Address          `json:"address"`
+	Data     []byte            `json:"data"`
+	Created  time.Time         `json:"created"`
+	Parent   *CreateRequest    `json:"parent"`
+	Ignored  string            `json:"-"`
+	internal int
+}
+
+type CreateResponse struct {
+	Id    string `json:"id"`
+	Score float64
+}
+
+type Users struct{}
+
+func (u *Users) Create(ctx context.Context, req *CreateRequest, rsp *CreateResponse) error {
+	return nil
+}
+
+func (u *Users) Watch(ctx context.Context, stream server.Stream) error {
+	return nil
+}
+
+func TestDebugSchema(t *testing.T) {
+	srv := server.NewServer()
+	if err := srv.Handle(srv.NewHandler(&Users{})); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Debug{server: srv}
+
+	rsp := new(SchemaResponse)
+	if err := d.Schema(context.Background(), &SchemaRequest{}, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rsp.Endpoints) != 2 || rsp.Endpoints[0].Endpoint != "Users.Create" || rsp.Endpoints[1].Endpoint != "Users.Watch" {
+		t.Fatalf("Expected the Users endpoints, got %+v", rsp.Endpoints)
+	}
+	if watch := rsp.Endpoints[1]; !watch.Stream || watch.Request != nil {
+		t.Fatalf("Expected Users.Watch to be a stream, got %+v", watch)
+	}
+
+	b, err := json.Marshal(rsp.Endpoints[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"endpoint":"Users.Create",` +
+		`"request":{"title":"CreateRequest","type":"object","properties":{` +
+		`"address":{"title":"Address","type":"object","properties":{"street":{"type":"string"}}},` +
+		`"age":{"type":"integer","format":"int32"},` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"data":{"type":"string","format":"byte"},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string"},` +
+		`"parent":{"title":"CreateRequest","type":"object"},` +
+		`"tags":{"type":"array","items":{"type":"string"}}}},` +
+		`"response":{"title":"CreateResponse","type":"object","properties":{` +
+		`"Score":{"type":"number","format":"double"},` +
+		`"id":{"type":"string"}}}}`
+
+	if string(b) != expected {
+		t.Fatalf("Expected schema\n%s\ngot\n%s", expected, b)
+	}
+
+	// a single endpoint
+	rsp = new(SchemaResponse)
+	if err := d.Schema(context.Background(), &SchemaRequest{Endpoint: "Users.Watch"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Endpoints) != 1 || rsp.Endpoints[0].Endpoint != "Users.Watch" {
+		t.Fatalf("Expected only Users.Watch, got %+v", rsp.Endpoints)
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type meter struct {
+	sync.RWMutex
+	metrics map[string]*Metric
+}
+
+// key identifies a metric by its name and sorted tags.
+func key(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("," + k + "=" + tags[k])
+	}
+	return b.String()
+}
+
+// get returns the metric creating it if needed, the lock must be held.
+func (m *meter) get(name string, tags map[string]string) *Metric {
+	k := key(name, tags)
+
+	metric, ok := m.metrics[k]
+	if !ok {
+		t := make(map[string]string, len(tags))
+		for k, v := range tags {
+			t[k] = v
+		}
+		metric = &Metric{Name: name, Tags: t}
+		m.metrics[k] = metric
+	}
+
+	return metric
+}
+
+func (m *meter) Count(name string, delta int64, tags map[string]string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.get(name, tags).Count += delta
+}
+
+func (m *meter) Time(name string, d time.Duration, tags map[string]string) {
+	m.Lock()
+	defer m.Unlock()
+
+	metric := m.get(name, tags)
+	metric.Count++
+	metric.Total += d
+	if d > metric.Max {
+		metric.Max = d
+	}
+}
+
+func (m *meter) Read() ([]*Metric, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	keys := make([]string, 0, len(m.metrics))
+	for k := range m.metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]*Metric, 0, len(keys))
+	for _, k := range keys {
+		metric := *m.metrics[k]
+		metrics = append(metrics, &metric)
+	}
+
+	return metrics, nil
+}
+
+// NewMeter returns a meter keeping the metrics in memory.
+func NewMeter() Meter {
+	return &meter{
+		metrics: make(map[string]*Metric),
+	}
+}
@@ -0,0 +1,34 @@
+// Package metrics provides a meter to record counters and timings
+package metrics
+
+import (
+	"time"
+)
+
+// Meter records metrics, each identified by its name and tags.
+type Meter interface {
+	// Count adds the delta to a counter
+	Count(name string, delta int64, tags map[string]string)
+	// Time records a duration e.g the time a handler took
+	Time(name string, d time.Duration, tags map[string]string)
+	// Read a snapshot of the metrics
+	Read() ([]*Metric, error)
+}
+
+// A Metric is a counter or a summary of the durations recorded.
+type Metric struct {
+	// Name of the metric
+	Name string
+	// Tags e.g the topic
+	Tags map[string]string
+	// Count is the counter value or the number of durations
+	Count int64
+	// Total of the durations recorded
+	Total time.Duration
+	// Max duration recorded
+	Max time.Duration
+}
+
+var (
+	DefaultMeter = NewMeter()
+)
@@ -16,6 +16,16 @@ func (e *Error) Error() string {
 	return string(b)
 }
 
+// Is reports whether the target is an *Error with the same id and code,
+// letting errors.Is match an error against a sentinel e.g client.ErrTimeout.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || e == nil || t == nil {
+		return false
+	}
+	return e.Id == t.Id && e.Code == t.Code
+}
+
 // New generates a custom error.
 func New(id, detail string, code int32) error {
 	return &Error{
@@ -97,6 +107,17 @@ func Timeout(id, format string, a ...interface{}) error {
 	}
 }
 
+// Canceled generates a 499 error, the code used for
+// requests the client gave up on before a response.
+func Canceled(id, format string, a ...interface{}) error {
+	return &Error{
+		Id:     id,
+		Code:   499,
+		Detail: fmt.Sprintf(format, a...),
+		Status: "Client Closed Request",
+	}
+}
+
 // Conflict generates a 409 error.
 func Conflict(id, format string, a ...interface{}) error {
 	return &Error{
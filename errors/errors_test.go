@@ -2,6 +2,7 @@ package errors
 
 import (
 	er "errors"
+	"fmt"
 	"net/http"
 	"testing"
 )
@@ -98,6 +99,27 @@ func TestAs(t *testing.T) {
 	}
 }
 
+func TestIs(t *testing.T) {
+	sentinel := Timeout("go.micro.test", "timeout")
+
+	testData := []struct {
+		err   error
+		match bool
+	}{
+		{Timeout("go.micro.test", "call timeout after %v", "1s"), true},
+		{fmt.Errorf("wrapped: %w", Timeout("go.micro.test", "timeout")), true},
+		{Timeout("go.micro.other", "timeout"), false},
+		{Canceled("go.micro.test", "canceled"), false},
+		{er.New("timeout"), false},
+	}
+
+	for _, d := range testData {
+		if got := er.Is(d.err, sentinel); got != d.match {
+			t.Fatalf("Expected %v to match %v: %v", d.err, d.match, got)
+		}
+	}
+}
+
 func TestAppend(t *testing.T) {
 	mError := NewMultiError()
 	testData := []*Error{
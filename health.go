@@ -0,0 +1,139 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "go-micro.dev/v4/logger"
+)
+
+// HealthShutdownTimeout is how long the health server waits
+// for in flight requests when the service stops.
+var HealthShutdownTimeout = time.Second * 5
+
+// startHealth starts the http server serving the health and metrics
+// endpoints if a health address is set.
+func (s *service) startHealth() error {
+	if len(s.opts.HealthAddress) == 0 {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", s.opts.HealthAddress)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	srv := &http.Server{Handler: mux}
+
+	s.Lock()
+	s.health = srv
+	s.healthAddr = l.Addr().String()
+	s.Unlock()
+
+	s.opts.Logger.Logf(log.InfoLevel, "Health listening on %s", l.Addr().String())
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			s.opts.Logger.Logf(log.ErrorLevel, "Health server error: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// stopHealth shuts down the health server if it's running.
+func (s *service) stopHealth() error {
+	s.Lock()
+	srv := s.health
+	s.health = nil
+	s.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HealthShutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(ctx)
+}
+
+// handleHealth reports the process is alive.
+func (s *service) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReady reports whether the service is ready to serve requests.
+func (s *service) handleReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if !s.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "not ready")
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
+
+// handleMetrics writes the server's meter in the prometheus text format.
+// Timers are written as their count, total and max in seconds.
+func (s *service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	meter := s.opts.Server.Options().Meter
+	if meter == nil {
+		http.Error(w, "no meter", http.StatusNotFound)
+		return
+	}
+
+	metrics, err := meter.Read()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	for _, m := range metrics {
+		tags := formatTags(m.Tags)
+
+		if m.Total == 0 && m.Max == 0 {
+			fmt.Fprintf(w, "%s%s %d\n", m.Name, tags, m.Count)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s_count%s %d\n", m.Name, tags, m.Count)
+		fmt.Fprintf(w, "%s_seconds_total%s %g\n", m.Name, tags, m.Total.Seconds())
+		fmt.Fprintf(w, "%s_seconds_max%s %g\n", m.Name, tags, m.Max.Seconds())
+	}
+}
+
+// formatTags returns the tags as sorted prometheus labels.
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	labels := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labels = append(labels, fmt.Sprintf("%s=%q", k, tags[k]))
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"sync"
+)
+
+// AsyncLogger buffers log entries and writes them to its logger on a
+// background goroutine so a slow sink doesn't block the caller. Entries
+// are dropped when the buffer is full. Fatal entries are written straight
+// away as the process is expected to exit. The file reported by the
+// logger is the background goroutine rather than the caller.
+type AsyncLogger struct {
+	logger Logger
+	queue  *asyncQueue
+}
+
+// asyncQueue is shared by an async logger and those derived from it with Fields.
+type asyncQueue struct {
+	entries chan asyncEntry
+	onDrop  func()
+	done    chan bool
+
+	sync.RWMutex
+	closed bool
+}
+
+type asyncEntry struct {
+	logger Logger
+	level  Level
+	format string
+	v      []interface{}
+	// whether the entry was logged with Logf
+	formatted bool
+}
+
+// Async returns a logger writing to l on a background goroutine, buffering up
+// to bufferSize entries. When the buffer is full entries are dropped and
+// onDrop, if not nil, is called. Close flushes the buffered entries.
+func Async(l Logger, bufferSize int, onDrop func()) *AsyncLogger {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+
+	q := &asyncQueue{
+		entries: make(chan asyncEntry, bufferSize),
+		onDrop:  onDrop,
+		done:    make(chan bool),
+	}
+
+	go q.run()
+
+	return &AsyncLogger{
+		logger: l,
+		queue:  q,
+	}
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+
+	for e := range q.entries {
+		e.write()
+	}
+}
+
+func (e asyncEntry) write() {
+	if e.formatted {
+		e.logger.Logf(e.level, e.format, e.v...)
+		return
+	}
+	e.logger.Log(e.level, e.v...)
+}
+
+// push queues the entry without blocking, writing it directly once closed.
+func (q *asyncQueue) push(e asyncEntry) {
+	q.RLock()
+
+	if q.closed || e.level == FatalLevel {
+		q.RUnlock()
+		e.write()
+		return
+	}
+
+	select {
+	case q.entries <- e:
+		q.RUnlock()
+	default:
+		q.RUnlock()
+		if q.onDrop != nil {
+			q.onDrop()
+		}
+	}
+}
+
+func (a *AsyncLogger) Init(opts ...Option) error {
+	return a.logger.Init(opts...)
+}
+
+func (a *AsyncLogger) Options() Options {
+	return a.logger.Options()
+}
+
+// Fields returns an async logger with the fields which shares the buffer.
+func (a *AsyncLogger) Fields(fields map[string]interface{}) Logger {
+	return &AsyncLogger{
+		logger: a.logger.Fields(fields),
+		queue:  a.queue,
+	}
+}
+
+func (a *AsyncLogger) Log(level Level, v ...interface{}) {
+	a.queue.push(asyncEntry{logger: a.logger, level: level, v: v})
+}
+
+func (a *AsyncLogger) Logf(level Level, format string, v ...interface{}) {
+	a.queue.push(asyncEntry{logger: a.logger, level: level, format: format, v: v, formatted: true})
+}
+
+func (a *AsyncLogger) String() string {
+	return "async"
+}
+
+// Close writes the buffered entries and stops the background goroutine.
+// Entries logged after it are written directly.
+func (a *AsyncLogger) Close() error {
+	a.queue.Lock()
+	if !a.queue.closed {
+		a.queue.closed = true
+		close(a.queue.entries)
+	}
+	a.queue.Unlock()
+
+	<-a.queue.done
+
+	return nil
+}
@@ -56,6 +56,7 @@ func (l *defaultLogger) Fields(fields map[string]interface{}) Logger {
 		Fields:          nfields,
 		Out:             l.opts.Out,
 		CallerSkipCount: l.opts.CallerSkipCount,
+		Format:          l.opts.Format,
 		Context:         l.opts.Context,
 	}}
 }
@@ -129,6 +130,11 @@ func (l *defaultLogger) Log(level Level, v ...interface{}) {
 
 	dlog.DefaultLog.Write(rec)
 
+	if l.opts.Format == JSONFormat {
+		l.writeJSON(rec.Timestamp, fmt.Sprint(rec.Message), fields)
+		return
+	}
+
 	t := rec.Timestamp.Format("2006-01-02 15:04:05")
 	fmt.Printf("%s %s %v\n", t, metadata, rec.Message)
 }
@@ -170,6 +176,11 @@ func (l *defaultLogger) Logf(level Level, format string, v ...interface{}) {
 
 	dlog.DefaultLog.Write(rec)
 
+	if l.opts.Format == JSONFormat {
+		l.writeJSON(rec.Timestamp, fmt.Sprint(rec.Message), fields)
+		return
+	}
+
 	t := rec.Timestamp.Format("2006-01-02 15:04:05")
 	fmt.Printf("%s %s %v\n", t, metadata, rec.Message)
 }
@@ -190,7 +201,7 @@ func NewLogger(opts ...Option) Logger {
 		Level:           InfoLevel,
 		Fields:          make(map[string]interface{}),
 		Out:             os.Stderr,
-		CallerSkipCount: 2,
+		CallerSkipCount: defaultCallerSkipCount,
 		Context:         context.Background(),
 	}
 
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// TextFormat writes log entries as plain text lines.
+	TextFormat = "text"
+	// JSONFormat writes log entries as JSON objects, one per line.
+	JSONFormat = "json"
+)
+
+type jsonRecord struct {
+	Level     string                 `json:"level"`
+	Timestamp time.Time              `json:"timestamp"`
+	Message   string                 `json:"message"`
+	File      string                 `json:"file,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// writeJSON writes the entry to the output. The level and file are taken
+// from the fields, the rest are nested under "fields".
+func (l *defaultLogger) writeJSON(t time.Time, msg string, fields map[string]interface{}) {
+	rec := jsonRecord{
+		Timestamp: t,
+		Message:   msg,
+	}
+
+	rec.Level, _ = fields["level"].(string)
+	rec.File, _ = fields["file"].(string)
+	delete(fields, "level")
+	delete(fields, "file")
+
+	if len(fields) > 0 {
+		rec.Fields = make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			rec.Fields[k] = jsonValue(v)
+		}
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		b, _ = json.Marshal(jsonRecord{
+			Level:     rec.Level,
+			Timestamp: t,
+			Message:   fmt.Sprintf("failed to encode log entry: %v", err),
+		})
+	}
+
+	l.opts.Out.Write(append(b, '\n'))
+}
+
+// jsonValue returns a value which encodes to something readable. Errors
+// would otherwise encode as {} and some values can't be encoded at all.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case error:
+		return t.Error()
+	case fmt.Stringer:
+		if _, ok := v.(json.Marshaler); ok {
+			return v
+		}
+		return t.String()
+	}
+
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return v
+}
@@ -1,8 +1,17 @@
 package logger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestLogger(t *testing.T) {
@@ -32,3 +41,217 @@ func TestExtract(t *testing.T) {
 	Info("info message without request ID")
 	Extract(ctx).Info("info message with request ID")
 }
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewLogger(WithLevel(TraceLevel), WithFormat(JSONFormat), WithOutput(&buf), WithCallerSkipCount(1))
+
+	l.Fields(map[string]interface{}{
+		"string": "quote \" newline \n tab \t <html>",
+		"int":    42,
+		"float":  1.5,
+		"bool":   true,
+		"error":  errors.New("failed"),
+		"nested": map[string]interface{}{"key": []int{1, 2}},
+		"func":   func() {},
+	}).Log(InfoLevel, "first \"message\"")
+	l.Logf(DebugLevel, "second %d", 2)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines got %d: %s", len(lines), buf.String())
+	}
+
+	var rec struct {
+		Level     string                 `json:"level"`
+		Timestamp time.Time              `json:"timestamp"`
+		Message   string                 `json:"message"`
+		File      string                 `json:"file"`
+		Fields    map[string]interface{} `json:"fields"`
+	}
+
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("Expected valid json got %v: %s", err, lines[0])
+	}
+
+	if rec.Level != "info" || rec.Message != `first "message"` || rec.Timestamp.IsZero() || len(rec.File) == 0 {
+		t.Fatalf("Unexpected record %+v", rec)
+	}
+
+	expect := map[string]interface{}{
+		"string": "quote \" newline \n tab \t <html>",
+		"int":    float64(42),
+		"float":  1.5,
+		"bool":   true,
+		"error":  "failed",
+	}
+	for k, v := range expect {
+		if rec.Fields[k] != v {
+			t.Fatalf("Expected field %s to be %v got %v", k, v, rec.Fields[k])
+		}
+	}
+
+	nested, ok := rec.Fields["nested"].(map[string]interface{})
+	if !ok || len(nested["key"].([]interface{})) != 2 {
+		t.Fatalf("Expected nested field to be an object got %v", rec.Fields["nested"])
+	}
+
+	if _, ok := rec.Fields["func"].(string); !ok {
+		t.Fatalf("Expected unencodable field to be a string got %v", rec.Fields["func"])
+	}
+
+	rec.Fields = nil
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatalf("Expected valid json got %v: %s", err, lines[1])
+	}
+	if rec.Level != "debug" || rec.Message != "second 2" || rec.Fields != nil {
+		t.Fatalf("Unexpected record %+v", rec)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	var buf bytes.Buffer
+
+	file := func() string {
+		var rec struct {
+			File string `json:"file"`
+		}
+		line := strings.TrimSpace(buf.String())
+		buf.Reset()
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("Expected valid json got %v: %s", err, line)
+		}
+		return rec.File
+	}
+
+	defer func(l Logger) {
+		DefaultLogger = l
+	}(DefaultLogger)
+
+	// the package level helpers report their caller by default
+	DefaultLogger = NewLogger(WithFormat(JSONFormat), WithOutput(&buf))
+
+	_, _, line, _ := runtime.Caller(0)
+	Log(InfoLevel, "log")
+	if f, expect := file(), fmt.Sprintf("logger/logger_test.go:%d", line+1); f != expect {
+		t.Fatalf("Expected caller %s, got %s", expect, f)
+	}
+
+	_, _, line, _ = runtime.Caller(0)
+	Logf(InfoLevel, "logf %d", 1)
+	if f, expect := file(), fmt.Sprintf("logger/logger_test.go:%d", line+1); f != expect {
+		t.Fatalf("Expected caller %s, got %s", expect, f)
+	}
+
+	_, _, line, _ = runtime.Caller(0)
+	Info("info")
+	if f, expect := file(), fmt.Sprintf("logger/logger_test.go:%d", line+1); f != expect {
+		t.Fatalf("Expected caller %s, got %s", expect, f)
+	}
+
+	// a wrapper around the helpers is skipped to report its caller
+	wrap := func(msg string) {
+		Infof("wrapped %s", msg)
+	}
+
+	DefaultLogger = NewLogger(WithFormat(JSONFormat), WithOutput(&buf), WithCaller(1))
+
+	_, _, line, _ = runtime.Caller(0)
+	wrap("call")
+	if f, expect := file(), fmt.Sprintf("logger/logger_test.go:%d", line+1); f != expect {
+		t.Fatalf("Expected caller %s, got %s", expect, f)
+	}
+
+	// fields keep the caller annotation
+	DefaultLogger = DefaultLogger.Fields(map[string]interface{}{"key": "val"})
+
+	_, _, line, _ = runtime.Caller(0)
+	wrap("fields")
+	if f, expect := file(), fmt.Sprintf("logger/logger_test.go:%d", line+1); f != expect {
+		t.Fatalf("Expected caller %s, got %s", expect, f)
+	}
+}
+
+// slowLogger blocks writes until released.
+type slowLogger struct {
+	Logger
+	release chan bool
+	calls   int32
+
+	sync.Mutex
+	msgs []string
+}
+
+func (l *slowLogger) Fields(fields map[string]interface{}) Logger {
+	return l
+}
+
+func (l *slowLogger) Log(level Level, v ...interface{}) {
+	l.Logf(level, "%s", fmt.Sprint(v...))
+}
+
+func (l *slowLogger) Logf(level Level, format string, v ...interface{}) {
+	atomic.AddInt32(&l.calls, 1)
+	<-l.release
+	l.Lock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, v...))
+	l.Unlock()
+}
+
+func TestAsync(t *testing.T) {
+	sink := &slowLogger{Logger: NewLogger(), release: make(chan bool)}
+
+	var dropped int32
+	l := Async(sink, 2, func() {
+		atomic.AddInt32(&dropped, 1)
+	})
+
+	// wait for the first entry to block the sink
+	l.Logf(InfoLevel, "msg %d", 0)
+	for atomic.LoadInt32(&sink.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan bool)
+	go func() {
+		for i := 1; i < 10; i++ {
+			l.Fields(map[string]interface{}{"key": "val"}).Logf(InfoLevel, "msg %d", i)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected logging not to block on a slow sink")
+	}
+
+	// two are buffered, the rest dropped
+	if n := atomic.LoadInt32(&dropped); n != 7 {
+		t.Fatalf("Expected 7 dropped entries, got %d", n)
+	}
+
+	close(sink.release)
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Lock()
+	msgs := strings.Join(sink.msgs, ",")
+	sink.Unlock()
+
+	if msgs != "msg 0,msg 1,msg 2" {
+		t.Fatalf("Expected the buffer to be flushed on close, got %s", msgs)
+	}
+
+	// written directly once closed
+	l.Log(InfoLevel, "closed")
+
+	sink.Lock()
+	defer sink.Unlock()
+	if last := sink.msgs[len(sink.msgs)-1]; last != "closed" {
+		t.Fatalf("Expected entries after close to be written, got %s", last)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"io"
 )
 
+// defaultCallerSkipCount skips the logger and a package level helper.
+const defaultCallerSkipCount = 2
+
 type Option func(*Options)
 
 type Options struct {
@@ -16,6 +19,8 @@ type Options struct {
 	Out io.Writer
 	// Caller skip frame count for file:line info
 	CallerSkipCount int
+	// Format of the log output, either `text` (the default) or `json`
+	Format string
 	// Alternative options
 	Context context.Context
 }
@@ -48,6 +53,23 @@ func WithCallerSkipCount(c int) Option {
 	}
 }
 
+// WithCaller skips wrapper frames for the file:line entries are annotated
+// with. By default it's the caller of the package level helpers such as
+// Info and Logf, skip is the number of wrappers around those.
+func WithCaller(skip int) Option {
+	return func(args *Options) {
+		args.CallerSkipCount = defaultCallerSkipCount + skip
+	}
+}
+
+// WithFormat sets the output format for the logger. JSON output is
+// written to the output writer, one object per line.
+func WithFormat(format string) Option {
+	return func(args *Options) {
+		args.Format = format
+	}
+}
+
 func SetOption(k, v interface{}) Option {
 	return func(o *Options) {
 		if o.Context == nil {
@@ -30,6 +30,12 @@ type Service interface {
 	String() string
 }
 
+// Readiness is implemented by services which report whether they're
+// ready to serve, i.e started with all dependency checks passing.
+type Readiness interface {
+	Ready() bool
+}
+
 // Event is used to publish messages to a topic.
 type Event interface {
 	// Publish publishes a message to the event topic
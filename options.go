@@ -2,6 +2,7 @@ package micro
 
 import (
 	"context"
+	"net"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -43,6 +44,19 @@ type Options struct {
 	AfterStart  []func() error
 	AfterStop   []func() error
 
+	// BeforeStopCtx and AfterStopCtx run after the BeforeStop and
+	// AfterStop funcs, their context carries the Shutdown reason
+	BeforeStopCtx []func(context.Context) error
+	AfterStopCtx  []func(context.Context) error
+
+	// DependencyChecks run after the server starts, the service
+	// isn't ready until they all pass
+	DependencyChecks []func() error
+
+	// HealthAddress is where the /healthz, /readyz and
+	// /metrics endpoints are served, none if empty
+	HealthAddress string
+
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -259,6 +273,14 @@ func RegisterInterval(t time.Duration) Option {
 	}
 }
 
+// RegisterJitter randomly varies the re-register interval by up to the fraction
+// of it, e.g 0.1 for 10%, so services sharing a TTL don't register together.
+func RegisterJitter(j float64) Option {
+	return func(o *Options) {
+		o.Server.Init(server.RegisterJitter(j))
+	}
+}
+
 // WrapClient is a convenience method for wrapping a Client with
 // some middleware component. A list of wrappers can be provided.
 // Wrappers are applied in reverse order so the last is executed first.
@@ -313,6 +335,27 @@ func AddListenOption(option server.Option) Option {
 	}
 }
 
+// Listener sets the server to serve on an existing listener rather than
+// listening on its configured address. The listener's address is the one
+// advertised in the registry unless it's a wildcard e.g :0, then the
+// address is detected as usual. Pass Advertise after it to override.
+func Listener(l net.Listener) Option {
+	return func(o *Options) {
+		opts := []server.Option{
+			server.Address(l.Addr().String()),
+			server.ListenOption(transport.NetListener(l)),
+		}
+
+		if host, _, err := net.SplitHostPort(l.Addr().String()); err == nil {
+			if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+				opts = append(opts, server.Advertise(l.Addr().String()))
+			}
+		}
+
+		o.Server.Init(opts...)
+	}
+}
+
 // Before and Afters
 
 // BeforeStart run funcs before service starts.
@@ -336,6 +379,17 @@ func AfterStart(fn func() error) Option {
 	}
 }
 
+// DependencyCheck verifies a dependency is reachable during startup. It's
+// run after the server starts and retried every DependencyCheckInterval
+// until it passes. If it hasn't passed within the timeout startup fails.
+func DependencyCheck(fn func(context.Context) error, timeout time.Duration) Option {
+	return func(o *Options) {
+		o.DependencyChecks = append(o.DependencyChecks, func() error {
+			return checkDependency(fn, timeout)
+		})
+	}
+}
+
 // AfterStop run funcs after service stops.
 func AfterStop(fn func() error) Option {
 	return func(o *Options) {
@@ -343,9 +397,34 @@ func AfterStop(fn func() error) Option {
 	}
 }
 
+// BeforeStopCtx run funcs before service stops, the context
+// carries the Shutdown which can be read with ShutdownFromContext.
+func BeforeStopCtx(fn func(context.Context) error) Option {
+	return func(o *Options) {
+		o.BeforeStopCtx = append(o.BeforeStopCtx, fn)
+	}
+}
+
+// AfterStopCtx run funcs after service stops, the context
+// carries the Shutdown which can be read with ShutdownFromContext.
+func AfterStopCtx(fn func(context.Context) error) Option {
+	return func(o *Options) {
+		o.AfterStopCtx = append(o.AfterStopCtx, fn)
+	}
+}
+
 // Logger sets the logger for the service.
 func Logger(l logger.Logger) Option {
 	return func(o *Options) {
 		o.Logger = l
 	}
 }
+
+// HealthAddress serves the /healthz, /readyz and /metrics endpoints over
+// http on the address while the service runs. /readyz returns 503 until
+// the service is ready and once it starts stopping.
+func HealthAddress(addr string) Option {
+	return func(o *Options) {
+		o.HealthAddress = addr
+	}
+}
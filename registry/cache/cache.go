@@ -2,8 +2,10 @@
 package cache
 
 import (
+	"encoding/json"
 	"math"
 	"math/rand"
+	"os"
 	"sync"
 	"time"
 
@@ -22,9 +24,20 @@ type Cache interface {
 	Stop()
 }
 
+// Expirer is implemented by caches which can expire the entry for a
+// service so the next lookup goes to the registry.
+type Expirer interface {
+	Expire(service string)
+}
+
 type Options struct {
 	// TTL is the cache TTL
 	TTL time.Duration
+	// Snapshot is the file the cache is persisted to on Stop and loaded
+	// from by New. Loaded services are stale, they're returned straight
+	// away while the first lookup of each refreshes it from the registry
+	// in the background. A failed refresh keeps the stale services.
+	Snapshot string
 
 	Logger log.Logger
 }
@@ -40,6 +53,10 @@ type cache struct {
 	cache   map[string][]*registry.Service
 	ttls    map[string]time.Time
 	watched map[string]bool
+	// services loaded from the snapshot, until refreshed
+	stale map[string]bool
+	// stale services being refreshed
+	refreshing map[string]bool
 
 	// used to stop the cache
 	exit chan bool
@@ -135,6 +152,20 @@ func (c *cache) get(service string) ([]*registry.Service, error) {
 		return cp, nil
 	}
 
+	// serve stale services while refreshing them
+	if c.stale[service] && len(cp) > 0 {
+		c.RUnlock()
+
+		c.Lock()
+		if !c.refreshing[service] {
+			c.refreshing[service] = true
+			go c.refresh(service)
+		}
+		c.Unlock()
+
+		return cp, nil
+	}
+
 	// get does the actual request for a service and cache it
 	get := func(service string, cached []*registry.Service) ([]*registry.Service, error) {
 		// ask the registry
@@ -193,6 +224,44 @@ func (c *cache) get(service string) ([]*registry.Service, error) {
 	return get(service, cp)
 }
 
+// refresh looks up a stale service in the registry, keeping the
+// stale services if it fails.
+func (c *cache) refresh(service string) {
+	val, err, _ := c.sg.Do(service, func() (interface{}, error) {
+		return c.Registry.GetService(service)
+	})
+	services, _ := val.([]*registry.Service)
+
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.refreshing, service)
+
+	switch {
+	case err == registry.ErrNotFound:
+		delete(c.stale, service)
+		delete(c.cache, service)
+		delete(c.ttls, service)
+		return
+	case err != nil:
+		c.status = err
+		c.opts.Logger.Logf(log.DebugLevel, "rcache: failed to refresh %s: %v", service, err)
+		return
+	}
+
+	c.status = nil
+	delete(c.stale, service)
+	c.set(service, util.Copy(services))
+
+	// watch the service now it's live
+	if !c.watched[service] {
+		c.watched[service] = true
+		if !c.watchedRunning[service] {
+			go c.run(service)
+		}
+	}
+}
+
 func (c *cache) set(service string, services []*registry.Service) {
 	c.cache[service] = services
 	c.ttls[service] = time.Now().Add(c.opts.TTL)
@@ -446,6 +515,14 @@ func (c *cache) GetService(service string, opts ...registry.GetOption) ([]*regis
 	return services, nil
 }
 
+// Expire drops the ttl for a service. The cached nodes are kept as a
+// fallback in case the registry lookup fails.
+func (c *cache) Expire(service string) {
+	c.Lock()
+	delete(c.ttls, service)
+	c.Unlock()
+}
+
 func (c *cache) Stop() {
 	c.Lock()
 	defer c.Unlock()
@@ -456,6 +533,56 @@ func (c *cache) Stop() {
 	default:
 		close(c.exit)
 	}
+
+	if len(c.opts.Snapshot) > 0 {
+		if err := c.save(); err != nil {
+			c.opts.Logger.Logf(log.ErrorLevel, "rcache: failed to save snapshot %s: %v", c.opts.Snapshot, err)
+		}
+	}
+}
+
+// save writes the cached services to the snapshot file, the lock must be held.
+func (c *cache) save() error {
+	var services []*registry.Service
+	for _, s := range c.cache {
+		services = append(services, s...)
+	}
+
+	b, err := json.MarshalIndent(services, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write then rename so a crash doesn't leave a partial snapshot
+	tmp := c.opts.Snapshot + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.opts.Snapshot)
+}
+
+// load reads the stale services from the snapshot file, a missing file is ignored.
+func (c *cache) load() error {
+	b, err := os.ReadFile(c.opts.Snapshot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var services []*registry.Service
+	if err := json.Unmarshal(b, &services); err != nil {
+		return err
+	}
+
+	for _, s := range services {
+		c.cache[s.Name] = append(c.cache[s.Name], s)
+		c.stale[s.Name] = true
+	}
+
+	return nil
 }
 
 func (c *cache) String() string {
@@ -474,13 +601,23 @@ func New(r registry.Registry, opts ...Option) Cache {
 		o(&options)
 	}
 
-	return &cache{
+	c := &cache{
 		Registry:       r,
 		opts:           options,
 		watched:        make(map[string]bool),
 		watchedRunning: make(map[string]bool),
 		cache:          make(map[string][]*registry.Service),
 		ttls:           make(map[string]time.Time),
+		stale:          make(map[string]bool),
+		refreshing:     make(map[string]bool),
 		exit:           make(chan bool),
 	}
+
+	if len(options.Snapshot) > 0 {
+		if err := c.load(); err != nil {
+			options.Logger.Logf(log.ErrorLevel, "rcache: failed to load snapshot %s: %v", options.Snapshot, err)
+		}
+	}
+
+	return c
 }
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+// blockingRegistry holds lookups until released, counting them.
+type blockingRegistry struct {
+	registry.Registry
+
+	sync.Mutex
+	lookups int
+	release chan bool
+}
+
+func (r *blockingRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	r.Lock()
+	r.lookups++
+	r.Unlock()
+
+	<-r.release
+
+	return r.Registry.GetService(name, opts...)
+}
+
+func (r *blockingRegistry) calls() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.lookups
+}
+
+func testService(node string) *registry.Service {
+	return &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: node, Address: node + ":8080"}},
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	r := registry.NewMemoryRegistry()
+	if err := r.Register(testService("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(r, WithSnapshot(path))
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+	c.Stop()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the snapshot to be saved: %v", err)
+	}
+
+	// the service moved while the process was down
+	r = registry.NewMemoryRegistry()
+	if err := r.Register(testService("new")); err != nil {
+		t.Fatal(err)
+	}
+	br := &blockingRegistry{Registry: r, release: make(chan bool)}
+
+	c = New(br, WithSnapshot(path))
+	defer c.Stop()
+
+	// the stale service is returned before the registry answers
+	services, err := c.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 1 || services[0].Nodes[0].Id != "old" {
+		t.Fatalf("Expected the snapshot's node, got %+v", services)
+	}
+
+	close(br.release)
+
+	// until refreshed in the background
+	deadline := time.Now().Add(time.Second)
+	for {
+		services, err = c.GetService("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if services[0].Nodes[0].Id == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the refreshed node, got %+v", services[0].Nodes)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// then served from the cache
+	n := br.calls()
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if br.calls() != n {
+		t.Fatal("Expected the refreshed service to be cached")
+	}
+}
+
+func TestCacheSnapshotNotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	r := registry.NewMemoryRegistry()
+	if err := r.Register(testService("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(r, WithSnapshot(path))
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatal(err)
+	}
+	c.Stop()
+
+	// the service is gone from the new registry
+	c = New(registry.NewMemoryRegistry(), WithSnapshot(path))
+	defer c.Stop()
+
+	if _, err := c.GetService("foo"); err != nil {
+		t.Fatalf("Expected the stale service, got %v", err)
+	}
+
+	// once refreshed it's dropped
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := c.GetService("foo")
+		if err == registry.ErrNotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the service to be dropped, got %v", err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// a missing snapshot starts empty
+	c = New(registry.NewMemoryRegistry(), WithSnapshot(filepath.Join(t.TempDir(), "missing.json")))
+	defer c.Stop()
+	if _, err := c.GetService("foo"); err != registry.ErrNotFound {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+}
@@ -13,6 +13,15 @@ func WithTTL(t time.Duration) Option {
 	}
 }
 
+// WithSnapshot persists the cached services to the file when the cache is
+// stopped and loads them when it's created, so a restarted process can find
+// services before the registry has been asked. See Options.Snapshot.
+func WithSnapshot(path string) Option {
+	return func(o *Options) {
+		o.Snapshot = path
+	}
+}
+
 // WithLogger sets the underline logger.
 func WithLogger(l logger.Logger) Option {
 	return func(o *Options) {
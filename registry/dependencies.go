@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependenciesKey is the service metadata key listing the names of the
+// services a service depends on, separated by commas e.g "auth,users".
+const DependenciesKey = "dependencies"
+
+// ErrDependencyCycle is returned when services depend on each other.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// Dependencies returns the names of the services s depends on.
+func Dependencies(s *Service) []string {
+	var deps []string
+
+	for _, d := range strings.Split(s.Metadata[DependenciesKey], ",") {
+		if d = strings.TrimSpace(d); len(d) > 0 {
+			deps = append(deps, d)
+		}
+	}
+
+	return deps
+}
+
+// TopoSort returns the services in the default registry ordered so each
+// service comes after the services it depends on.
+func TopoSort() ([]*Service, error) {
+	services, err := listServices(DefaultRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	return SortServices(services)
+}
+
+// listServices returns every version of every service in the registry with
+// its nodes and endpoints, the list may only contain names.
+func listServices(r Registry) ([]*Service, error) {
+	list, err := r.ListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var services []*Service
+
+	for _, s := range list {
+		if seen[s.Name] {
+			continue
+		}
+		seen[s.Name] = true
+
+		svcs, err := r.GetService(s.Name)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		services = append(services, svcs...)
+	}
+
+	return services, nil
+}
+
+// SortServices orders services so each service comes after the services it
+// depends on. Versions of a service are kept together and their dependencies
+// combined. Dependencies on services not in the list are ignored.
+func SortServices(services []*Service) ([]*Service, error) {
+	versions := make(map[string][]*Service)
+	deps := make(map[string]map[string]bool)
+
+	for _, s := range services {
+		versions[s.Name] = append(versions[s.Name], s)
+		if deps[s.Name] == nil {
+			deps[s.Name] = make(map[string]bool)
+		}
+	}
+
+	// number of unsorted dependencies for each service
+	pending := make(map[string]int)
+	// services waiting on each dependency
+	dependents := make(map[string][]string)
+
+	for _, s := range services {
+		for _, d := range Dependencies(s) {
+			if _, ok := versions[d]; !ok || deps[s.Name][d] {
+				continue
+			}
+			deps[s.Name][d] = true
+			pending[s.Name]++
+			dependents[d] = append(dependents[d], s.Name)
+		}
+	}
+
+	var ready []string
+	for name := range versions {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+
+	sorted := make([]*Service, 0, len(services))
+
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, versions[name]...)
+		delete(versions, name)
+
+		var next []string
+		for _, d := range dependents[name] {
+			if pending[d]--; pending[d] == 0 {
+				next = append(next, d)
+			}
+		}
+		sort.Strings(next)
+		ready = append(ready, next...)
+	}
+
+	// anything left over is part of or depends on a cycle
+	if len(versions) > 0 {
+		var names []string
+		for name := range versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w between %s", ErrDependencyCycle, strings.Join(names, ", "))
+	}
+
+	return sorted, nil
+}
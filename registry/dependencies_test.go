@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func newDependentService(name string, deps string) *Service {
+	return &Service{
+		Name:     name,
+		Version:  "1.0.0",
+		Metadata: map[string]string{DependenciesKey: deps},
+		Nodes: []*Node{
+			{Id: name + "-1", Address: "localhost:9999"},
+		},
+	}
+}
+
+func TestTopoSort(t *testing.T) {
+	r := NewMemoryRegistry()
+
+	// api -> users, auth; users -> auth, store; auth -> store
+	for _, s := range []*Service{
+		newDependentService("api", "users, auth"),
+		newDependentService("users", "auth,store"),
+		newDependentService("auth", "store,missing"),
+		newDependentService("store", ""),
+	} {
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dr := DefaultRegistry
+	DefaultRegistry = r
+	defer func() {
+		DefaultRegistry = dr
+	}()
+
+	services, err := TopoSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{"store", "auth", "users", "api"}
+	if len(services) != len(expect) {
+		t.Fatalf("Expected %d services got %d", len(expect), len(services))
+	}
+	for i, s := range services {
+		if s.Name != expect[i] {
+			t.Fatalf("Expected %s at position %d got %s", expect[i], i, s.Name)
+		}
+	}
+}
+
+func TestSortServicesCycle(t *testing.T) {
+	_, err := SortServices([]*Service{
+		newDependentService("a", "b"),
+		newDependentService("b", "c"),
+		newDependentService("c", "a"),
+		newDependentService("d", ""),
+		newDependentService("e", "a"),
+	})
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("Expected dependency cycle error got %v", err)
+	}
+	if err.Error() != "dependency cycle between a, b, c, e" {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if _, err := SortServices([]*Service{newDependentService("a", "a")}); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("Expected self dependency to be a cycle got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package registry
+
+// DrainingKey is the node metadata key marking a node as draining. A draining
+// node is still listed but selectors don't choose it for new requests, so
+// it can finish those in flight before it's deregistered.
+const DrainingKey = "draining"
+
+// IsDraining reports whether the node is draining.
+func IsDraining(n *Node) bool {
+	return n != nil && n.Metadata[DrainingKey] == "true"
+}
+
+// Drain marks the node of the service as draining, or no longer draining,
+// by updating its metadata in the registry. The node isn't modified.
+func Drain(r Registry, service string, node *Node, draining bool) error {
+	u, ok := r.(NodeUpdater)
+	if !ok {
+		return ErrUpdateNotSupported
+	}
+
+	md := make(map[string]string, len(node.Metadata)+1)
+	for k, v := range node.Metadata {
+		md[k] = v
+	}
+
+	if draining {
+		md[DrainingKey] = "true"
+	} else {
+		delete(md, DrainingKey)
+	}
+
+	return u.UpdateNode(service, &Node{Id: node.Id, Address: node.Address, Metadata: md})
+}
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Export serializes every service in the registry, including its versions,
+// nodes and endpoints, as json. The output is sorted by name and version
+// with nodes sorted by id.
+func Export(r Registry) ([]byte, error) {
+	services, err := listServices(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sortServices(services)
+
+	return json.MarshalIndent(services, "", "  ")
+}
+
+func sortServices(services []*Service) {
+	for _, s := range services {
+		sort.Slice(s.Nodes, func(i, j int) bool {
+			return s.Nodes[i].Id < s.Nodes[j].Id
+		})
+	}
+
+	sort.SliceStable(services, func(i, j int) bool {
+		if services[i].Name != services[j].Name {
+			return services[i].Name < services[j].Name
+		}
+		return services[i].Version < services[j].Version
+	})
+}
+
+// Import registers the services serialized by Export with the registry.
+func Import(r Registry, data []byte) error {
+	var services []*Service
+	if err := json.Unmarshal(data, &services); err != nil {
+		return err
+	}
+
+	for _, s := range services {
+		if err := r.Register(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
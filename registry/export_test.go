@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	r := NewMemoryRegistry(Services(testData))
+
+	// a service with metadata and endpoints
+	if err := r.Register(&Service{
+		Name:     "baz",
+		Version:  "2.0.0",
+		Metadata: map[string]string{"owner": "team"},
+		Endpoints: []*Endpoint{
+			{
+				Name:     "Baz.Call",
+				Request:  &Value{Name: "Request", Type: "Request", Values: []*Value{{Name: "name", Type: "string"}}},
+				Response: &Value{Name: "Response", Type: "Response"},
+				Metadata: map[string]string{"stream": "false"},
+			},
+		},
+		Nodes: []*Node{
+			{Id: "baz-2", Address: "localhost:7777", Metadata: map[string]string{"region": "eu"}},
+			{Id: "baz-1", Address: "localhost:7778"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Export(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the output is deterministic
+	again, err := Export(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Fatal("Expected repeated exports to be identical")
+	}
+
+	imported := NewMemoryRegistry()
+	if err := Import(imported, data); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(r Registry) []*Service {
+		services, err := r.ListServices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sortServices(services)
+		return services
+	}
+
+	expected, got := list(r), list(imported)
+	if len(got) != 6 {
+		t.Fatalf("Expected 6 services, got %d", len(got))
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("Expected imported services to match\nexpected: %+v\ngot: %+v", expected, got)
+	}
+
+	if err := Import(NewMemoryRegistry(), []byte("not json")); err == nil {
+		t.Fatal("Expected an error importing invalid data")
+	}
+}
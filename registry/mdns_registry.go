@@ -163,6 +163,13 @@ func (m *mdnsRegistry) Register(service *Service, opts ...RegisterOption) error
 	m.Lock()
 	defer m.Unlock()
 
+	var options RegisterOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	service = tagService(service, options.Tags)
+
 	logger := m.opts.Logger
 	entries, ok := m.services[service.Name]
 	// first entry, create wildcard used for list queries
@@ -34,6 +34,8 @@ type memRegistry struct {
 	sync.RWMutex
 	records  map[string]map[string]*record
 	watchers map[string]*memWatcher
+	// sequence number of the last change
+	seq uint64
 }
 
 func NewMemoryRegistry(opts ...Option) Registry {
@@ -79,10 +81,19 @@ func (m *memRegistry) ttlPrune() {
 	}
 }
 
-func (m *memRegistry) sendEvent(r *Result) {
+// sendEvent sends the result of change seq to the watchers. Watchers created
+// after the change already reflect it in their initial state so are skipped,
+// as are those watching another service.
+func (m *memRegistry) sendEvent(seq uint64, r *Result) {
 	m.RLock()
 	watchers := make([]*memWatcher, 0, len(m.watchers))
 	for _, w := range m.watchers {
+		if w.seq >= seq {
+			continue
+		}
+		if len(w.wo.Service) > 0 && w.wo.Service != r.Service.Name {
+			continue
+		}
 		watchers = append(watchers, w)
 	}
 	m.RUnlock()
@@ -97,6 +108,13 @@ func (m *memRegistry) sendEvent(r *Result) {
 			select {
 			case w.res <- r:
 			case <-time.After(sendEventTime):
+				// the watcher would miss this change, stop it so the
+				// consumer re-watches and gets the current state
+				m.options.Logger.Logf(log.WarnLevel, "Registry watcher %s is not keeping up, stopping it", w.id)
+				w.Stop()
+				m.Lock()
+				delete(m.watchers, w.id)
+				m.Unlock()
 			}
 		}
 	}
@@ -143,6 +161,7 @@ func (m *memRegistry) Register(s *Service, opts ...RegisterOption) error {
 		o(&options)
 	}
 
+	s = tagService(s, options.Tags)
 	r := serviceToRecord(s, options.TTL)
 
 	if _, ok := m.records[s.Name]; !ok {
@@ -152,7 +171,8 @@ func (m *memRegistry) Register(s *Service, opts ...RegisterOption) error {
 	if _, ok := m.records[s.Name][s.Version]; !ok {
 		m.records[s.Name][s.Version] = r
 		logger.Logf(log.DebugLevel, "Registry added new service: %s, version: %s", s.Name, s.Version)
-		go m.sendEvent(&Result{Action: "update", Service: s})
+		m.seq++
+		go m.sendEvent(m.seq, &Result{Action: "update", Service: s})
 		return nil
 	}
 
@@ -178,7 +198,8 @@ func (m *memRegistry) Register(s *Service, opts ...RegisterOption) error {
 
 	if addedNodes {
 		logger.Logf(log.DebugLevel, "Registry added new node to service: %s, version: %s", s.Name, s.Version)
-		go m.sendEvent(&Result{Action: "update", Service: s})
+		m.seq++
+		go m.sendEvent(m.seq, &Result{Action: "update", Service: s})
 		return nil
 	}
 
@@ -213,12 +234,57 @@ func (m *memRegistry) Deregister(s *Service, opts ...DeregisterOption) error {
 			delete(m.records, s.Name)
 			logger.Logf(log.DebugLevel, "Registry removed service: %s", s.Name)
 		}
-		go m.sendEvent(&Result{Action: "delete", Service: s})
+		m.seq++
+		go m.sendEvent(m.seq, &Result{Action: "delete", Service: s})
 	}
 
 	return nil
 }
 
+// UpdateNode replaces the metadata of the node, sending an update
+// event for each version of the service it's registered with.
+func (m *memRegistry) UpdateNode(service string, n *Node) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var updated []*Service
+
+	for _, record := range m.records[service] {
+		rn, ok := record.Nodes[n.Id]
+		if !ok {
+			continue
+		}
+
+		metadata := make(map[string]string, len(n.Metadata))
+		for k, v := range n.Metadata {
+			metadata[k] = v
+		}
+
+		// replace rather than change the node, it may be the registered one
+		rn.Node = &Node{
+			Id:       rn.Id,
+			Address:  rn.Address,
+			Metadata: metadata,
+		}
+
+		updated = append(updated, recordToService(record))
+	}
+
+	if len(updated) == 0 {
+		return ErrNodeNotFound
+	}
+
+	for _, s := range updated {
+		m.options.Logger.Logf(log.DebugLevel, "Registry updated node %s of service: %s, version: %s", n.Id, s.Name, s.Version)
+		m.seq++
+		go m.sendEvent(m.seq, &Result{Action: "update", Service: s})
+	}
+
+	return nil
+}
+
+// GetService returns the versions of the service sorted by version, with
+// their nodes sorted by id, so the output is the same on every call.
 func (m *memRegistry) GetService(name string, opts ...GetOption) ([]*Service, error) {
 	m.RLock()
 	defer m.RUnlock()
@@ -235,9 +301,12 @@ func (m *memRegistry) GetService(name string, opts ...GetOption) ([]*Service, er
 		i++
 	}
 
+	sortServices(services)
+
 	return services, nil
 }
 
+// ListServices returns the services sorted by name and version.
 func (m *memRegistry) ListServices(opts ...ListOption) ([]*Service, error) {
 	m.RLock()
 	defer m.RUnlock()
@@ -249,6 +318,8 @@ func (m *memRegistry) ListServices(opts ...ListOption) ([]*Service, error) {
 		}
 	}
 
+	sortServices(services)
+
 	return services, nil
 }
 
@@ -266,6 +337,18 @@ func (m *memRegistry) Watch(opts ...WatchOption) (Watcher, error) {
 	}
 
 	m.Lock()
+	// snapshot the current state atomically with adding the watcher
+	if wo.InitialState {
+		for name, records := range m.records {
+			if len(wo.Service) > 0 && wo.Service != name {
+				continue
+			}
+			for _, record := range records {
+				w.initial = append(w.initial, &Result{Action: "create", Service: recordToService(record)})
+			}
+		}
+	}
+	w.seq = m.seq
 	m.watchers[w.id] = w
 	m.Unlock()
 
@@ -242,3 +242,288 @@ func TestMemoryRegistryTTLConcurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestMemoryRegistryWatchInitialState(t *testing.T) {
+	m := NewMemoryRegistry()
+
+	for _, name := range []string{"foo", "bar"} {
+		if err := m.Register(&Service{Name: name, Version: "latest", Nodes: []*Node{{Id: name + "-1"}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// register a service immediately before watching, its event may still be in flight
+	if err := m.Register(&Service{Name: "baz", Version: "latest", Nodes: []*Node{{Id: "baz-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := m.Watch(WatchInitialState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// a live change after the watch started
+	go m.Register(&Service{Name: "cat", Version: "latest", Nodes: []*Node{{Id: "cat-1"}}})
+
+	seen := make(map[string]bool)
+
+	for i := 0; i < 3; i++ {
+		r, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Action != "create" {
+			t.Fatalf("Expected create event got %s for %s", r.Action, r.Service.Name)
+		}
+		seen[r.Service.Name] = true
+	}
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if !seen[name] {
+			t.Fatalf("Expected initial state to contain %s", name)
+		}
+	}
+
+	// the next event is the live change, not a duplicate of the initial state
+	r, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Action != "update" || r.Service.Name != "cat" {
+		t.Fatalf("Expected update event for cat got %s for %s", r.Action, r.Service.Name)
+	}
+
+	// the initial state respects the service filter
+	fw, err := m.Watch(WatchInitialState(), WatchService("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Stop()
+
+	r, err = fw.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Service.Name != "foo" {
+		t.Fatalf("Expected initial state for foo got %s", r.Service.Name)
+	}
+}
+
+func TestMemoryRegistryWatchService(t *testing.T) {
+	m := NewMemoryRegistry()
+
+	w, err := m.Watch(WatchService("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	all, err := m.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer all.Stop()
+
+	bar := &Service{Name: "bar", Version: "latest", Nodes: []*Node{{Id: "bar-1"}}}
+	foo := &Service{Name: "foo", Version: "latest", Nodes: []*Node{{Id: "foo-1"}}}
+
+	// another service changes
+	if err := m.Register(bar); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Deregister(bar); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, action := range []string{"update", "delete"} {
+		r, err := all.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Service.Name != "bar" {
+			t.Fatalf("Expected a %s event for bar, got %s for %s", action, r.Action, r.Service.Name)
+		}
+	}
+
+	// the events for bar were never sent to the foo watcher
+	select {
+	case r := <-w.(*memWatcher).res:
+		t.Fatalf("Unexpected %s event for %s", r.Action, r.Service.Name)
+	case <-time.After(sendEventTime * 2):
+	}
+
+	if err := m.Register(foo); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Action != "update" || r.Service.Name != "foo" {
+		t.Fatalf("Expected an update event for foo, got %s for %s", r.Action, r.Service.Name)
+	}
+}
+
+func TestMemoryRegistryUpdateNode(t *testing.T) {
+	m := NewMemoryRegistry()
+
+	node := &Node{Id: "foo-1", Address: "localhost:9999", Metadata: map[string]string{"zone": "a"}}
+	if err := m.Register(&Service{Name: "foo", Version: "latest", Nodes: []*Node{node}}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := m.Watch(WatchService("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	u := m.(NodeUpdater)
+	if err := u.UpdateNode("foo", &Node{Id: "foo-1", Metadata: map[string]string{"draining": "true"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Action != "update" || r.Service.Name != "foo" || len(r.Service.Nodes) != 1 {
+		t.Fatalf("Expected an update event for foo, got %s for %+v", r.Action, r.Service)
+	}
+	if v := r.Service.Nodes[0].Metadata["draining"]; v != "true" {
+		t.Fatalf("Expected the event to carry the metadata, got %v", r.Service.Nodes[0].Metadata)
+	}
+
+	services, err := m.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := services[0].Nodes[0]
+	if n.Address != "localhost:9999" {
+		t.Fatalf("Expected the address to be kept, got %s", n.Address)
+	}
+	if n.Metadata["draining"] != "true" || len(n.Metadata) != 1 {
+		t.Fatalf("Expected the metadata to be replaced, got %v", n.Metadata)
+	}
+
+	// the registered node isn't changed
+	if node.Metadata["zone"] != "a" || len(node.Metadata) != 1 {
+		t.Fatalf("Expected the registered node to be untouched, got %v", node.Metadata)
+	}
+
+	if err := u.UpdateNode("foo", &Node{Id: "foo-2"}); err != ErrNodeNotFound {
+		t.Fatalf("Expected %v for an unknown node, got %v", ErrNodeNotFound, err)
+	}
+	if err := u.UpdateNode("bar", &Node{Id: "foo-1"}); err != ErrNodeNotFound {
+		t.Fatalf("Expected %v for an unknown service, got %v", ErrNodeNotFound, err)
+	}
+}
+
+func TestMemoryRegistryOrdering(t *testing.T) {
+	m := NewMemoryRegistry()
+
+	for _, name := range []string{"foo", "bar", "baz", "cat", "dog"} {
+		for _, version := range []string{"2.0.0", "1.0.0"} {
+			var nodes []*Node
+			for i := 5; i > 0; i-- {
+				nodes = append(nodes, &Node{Id: fmt.Sprintf("%s-%s-%d", name, version, i)})
+			}
+			if err := m.Register(&Service{Name: name, Version: version, Nodes: nodes}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	describe := func(services []*Service) string {
+		var s string
+		for _, svc := range services {
+			s += svc.Name + "@" + svc.Version + ":"
+			for _, n := range svc.Nodes {
+				s += n.Id + ","
+			}
+			s += ";"
+		}
+		return s
+	}
+
+	list, err := m.ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := describe(list)
+	if list[0].Name != "bar" || list[0].Version != "1.0.0" || list[1].Version != "2.0.0" || list[len(list)-1].Name != "foo" {
+		t.Fatalf("Expected services sorted by name and version, got %s", first)
+	}
+	if list[0].Nodes[0].Id != "bar-1.0.0-1" {
+		t.Fatalf("Expected nodes sorted by id, got %s", first)
+	}
+
+	get, err := m.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstGet := describe(get)
+
+	for i := 0; i < 20; i++ {
+		list, _ := m.ListServices()
+		if got := describe(list); got != first {
+			t.Fatalf("Expected the same order on every list, got %s then %s", first, got)
+		}
+		get, _ := m.GetService("foo")
+		if got := describe(get); got != firstGet {
+			t.Fatalf("Expected the same order on every get, got %s then %s", firstGet, got)
+		}
+	}
+}
+
+func BenchmarkMemoryRegistryListServices(b *testing.B) {
+	m := NewMemoryRegistry()
+
+	for i := 0; i < 1000; i++ {
+		nodes := make([]*Node, 0, 10)
+		for j := 0; j < 10; j++ {
+			nodes = append(nodes, &Node{Id: fmt.Sprintf("node-%d-%d", i, j)})
+		}
+		m.Register(&Service{Name: fmt.Sprintf("service-%d", i), Version: "latest", Nodes: nodes})
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := m.ListServices(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMemoryRegistryWatchSlow(t *testing.T) {
+	m := NewMemoryRegistry()
+
+	w, err := m.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// nothing reads the change so the watcher is stopped
+	if err := m.Register(&Service{Name: "foo", Version: "latest", Nodes: []*Node{{Id: "foo-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(sendEventTime * 5)
+
+	if _, err := w.Next(); err == nil {
+		t.Fatal("Expected the slow watcher to be stopped")
+	}
+
+	m.(*memRegistry).RLock()
+	n := len(m.(*memRegistry).watchers)
+	m.(*memRegistry).RUnlock()
+	if n != 0 {
+		t.Fatalf("Expected the slow watcher to be removed, got %d watchers", n)
+	}
+}
@@ -9,19 +9,31 @@ type memWatcher struct {
 	wo   WatchOptions
 	res  chan *Result
 	exit chan bool
+	// the last change reflected in the initial state
+	seq uint64
+	// the initial state sent before any changes
+	initial []*Result
 }
 
 func (m *memWatcher) Next() (*Result, error) {
-	for {
+	if len(m.initial) > 0 {
 		select {
-		case r := <-m.res:
-			if len(m.wo.Service) > 0 && m.wo.Service != r.Service.Name {
-				continue
-			}
-			return r, nil
 		case <-m.exit:
 			return nil, errors.New("watcher stopped")
+		default:
 		}
+
+		r := m.initial[0]
+		m.initial = m.initial[1:]
+		return r, nil
+	}
+
+	// only events for the watched service are sent
+	select {
+	case r := <-m.res:
+		return r, nil
+	case <-m.exit:
+		return nil, errors.New("watcher stopped")
 	}
 }
 
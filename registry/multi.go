@@ -0,0 +1,247 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MultiError is returned by the multi registry when any of its
+// registries fail, holding the error of each one which failed.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	errs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err.Error())
+	}
+	return strings.Join(errs, "; ")
+}
+
+type multiRegistry struct {
+	opts       Options
+	registries []Registry
+}
+
+// NewMulti returns a registry fanning out to every given registry, useful
+// when migrating between registries.
+//
+// Register and Deregister are applied to every registry, if any fail a
+// *MultiError is returned after the rest have been tried. Lookups merge the
+// services of all registries, nodes are deduplicated by id with the first
+// registry taking precedence. A lookup only fails if every registry fails,
+// in which case ErrNotFound is returned if any registry reported it.
+func NewMulti(registries ...Registry) Registry {
+	return &multiRegistry{
+		opts:       *NewOptions(),
+		registries: registries,
+	}
+}
+
+// each calls fn for every registry, collecting the errors.
+func (m *multiRegistry) each(fn func(r Registry) error) error {
+	var merr MultiError
+
+	for _, r := range m.registries {
+		if err := fn(r); err != nil {
+			merr.Errors = append(merr.Errors, fmt.Errorf("%s: %w", r.String(), err))
+		}
+	}
+
+	if len(merr.Errors) > 0 {
+		return &merr
+	}
+
+	return nil
+}
+
+func (m *multiRegistry) Init(opts ...Option) error {
+	for _, o := range opts {
+		o(&m.opts)
+	}
+
+	return m.each(func(r Registry) error {
+		return r.Init(opts...)
+	})
+}
+
+func (m *multiRegistry) Options() Options {
+	return m.opts
+}
+
+func (m *multiRegistry) Register(s *Service, opts ...RegisterOption) error {
+	return m.each(func(r Registry) error {
+		return r.Register(s, opts...)
+	})
+}
+
+func (m *multiRegistry) Deregister(s *Service, opts ...DeregisterOption) error {
+	return m.each(func(r Registry) error {
+		return r.Deregister(s, opts...)
+	})
+}
+
+// lookup merges the results of fn from every registry.
+func (m *multiRegistry) lookup(fn func(r Registry) ([]*Service, error)) ([]*Service, error) {
+	var (
+		services  []*Service
+		errs      []error
+		notFound  bool
+		succeeded bool
+	)
+
+	for _, r := range m.registries {
+		s, err := fn(r)
+		if err == ErrNotFound {
+			notFound = true
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.String(), err))
+			continue
+		}
+		services = mergeServices(services, s)
+		succeeded = true
+	}
+
+	switch {
+	case succeeded:
+		return services, nil
+	case notFound:
+		return nil, ErrNotFound
+	case len(errs) > 0:
+		return nil, &MultiError{Errors: errs}
+	default:
+		return nil, nil
+	}
+}
+
+func (m *multiRegistry) GetService(name string, opts ...GetOption) ([]*Service, error) {
+	return m.lookup(func(r Registry) ([]*Service, error) {
+		return r.GetService(name, opts...)
+	})
+}
+
+func (m *multiRegistry) ListServices(opts ...ListOption) ([]*Service, error) {
+	return m.lookup(func(r Registry) ([]*Service, error) {
+		return r.ListServices(opts...)
+	})
+}
+
+// Watch merges the results of a watcher on every registry. If any
+// watcher fails its error is returned by Next and the rest are stopped.
+func (m *multiRegistry) Watch(opts ...WatchOption) (Watcher, error) {
+	w := &multiWatcher{
+		next: make(chan *Result),
+		exit: make(chan bool),
+	}
+
+	for _, r := range m.registries {
+		rw, err := r.Watch(opts...)
+		if err != nil {
+			w.Stop()
+			return nil, err
+		}
+		w.watchers = append(w.watchers, rw)
+	}
+
+	for _, rw := range w.watchers {
+		go w.run(rw)
+	}
+
+	return w, nil
+}
+
+func (m *multiRegistry) String() string {
+	names := make([]string, 0, len(m.registries))
+	for _, r := range m.registries {
+		names = append(names, r.String())
+	}
+	return "multi(" + strings.Join(names, ",") + ")"
+}
+
+// mergeServices adds the services to the list, merging the
+// nodes of those with the same name and version.
+func mergeServices(list, services []*Service) []*Service {
+	for _, s := range services {
+		var found *Service
+		for _, l := range list {
+			if l.Name == s.Name && l.Version == s.Version {
+				found = l
+				break
+			}
+		}
+
+		if found == nil {
+			svc := *s
+			svc.Nodes = append([]*Node{}, s.Nodes...)
+			list = append(list, &svc)
+			continue
+		}
+
+	nodes:
+		for _, n := range s.Nodes {
+			for _, fn := range found.Nodes {
+				if fn.Id == n.Id {
+					continue nodes
+				}
+			}
+			found.Nodes = append(found.Nodes, n)
+		}
+	}
+
+	return list
+}
+
+type multiWatcher struct {
+	watchers []Watcher
+	next     chan *Result
+
+	once sync.Once
+	exit chan bool
+	err  error
+}
+
+func (w *multiWatcher) run(rw Watcher) {
+	for {
+		r, err := rw.Next()
+		if err != nil {
+			w.once.Do(func() {
+				w.err = err
+				w.stop()
+			})
+			return
+		}
+
+		select {
+		case w.next <- r:
+		case <-w.exit:
+			return
+		}
+	}
+}
+
+func (w *multiWatcher) Next() (*Result, error) {
+	select {
+	case r := <-w.next:
+		return r, nil
+	case <-w.exit:
+		return nil, w.err
+	}
+}
+
+func (w *multiWatcher) Stop() {
+	w.once.Do(func() {
+		w.err = ErrWatcherStopped
+		w.stop()
+	})
+}
+
+func (w *multiWatcher) stop() {
+	close(w.exit)
+	for _, rw := range w.watchers {
+		rw.Stop()
+	}
+}
@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingRegistry struct {
+	Registry
+	err error
+}
+
+func (f *failingRegistry) Register(s *Service, opts ...RegisterOption) error {
+	return f.err
+}
+
+func (f *failingRegistry) GetService(name string, opts ...GetOption) ([]*Service, error) {
+	return nil, f.err
+}
+
+func TestMultiRegister(t *testing.T) {
+	r1 := NewMemoryRegistry()
+	r2 := NewMemoryRegistry()
+	m := NewMulti(r1, r2)
+
+	svc := &Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes:   []*Node{{Id: "foo-1", Address: "localhost:9999"}},
+	}
+
+	if err := m.Register(svc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []Registry{r1, r2} {
+		if _, err := r.GetService("foo"); err != nil {
+			t.Fatalf("Expected the service in every registry, got %v", err)
+		}
+	}
+
+	if err := m.Deregister(svc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []Registry{r1, r2} {
+		if _, err := r.GetService("foo"); err != ErrNotFound {
+			t.Fatalf("Expected the service to be deregistered, got %v", err)
+		}
+	}
+
+	if _, err := m.GetService("foo"); err != ErrNotFound {
+		t.Fatalf("Expected %v, got %v", ErrNotFound, err)
+	}
+}
+
+func TestMultiLookup(t *testing.T) {
+	r1 := NewMemoryRegistry()
+	r2 := NewMemoryRegistry()
+	m := NewMulti(r1, r2)
+
+	// the old registry has a node the new one doesn't and vice versa
+	for _, reg := range []struct {
+		r     Registry
+		nodes []string
+	}{
+		{r1, []string{"foo-1", "foo-2"}},
+		{r2, []string{"foo-2", "foo-3"}},
+	} {
+		for _, id := range reg.nodes {
+			if err := reg.r.Register(&Service{
+				Name:    "foo",
+				Version: "1.0.0",
+				Nodes:   []*Node{{Id: id, Address: id + ":9999"}},
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := r2.Register(&Service{Name: "bar", Version: "1.0.0", Nodes: []*Node{{Id: "bar-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := m.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("Expected 1 version, got %d", len(services))
+	}
+	if len(services[0].Nodes) != 3 {
+		t.Fatalf("Expected the 3 distinct nodes, got %d", len(services[0].Nodes))
+	}
+
+	services, err = m.ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("Expected 2 services, got %d", len(services))
+	}
+
+	// the registries are left untouched
+	services, err = r1.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services[0].Nodes) != 2 {
+		t.Fatalf("Expected 2 nodes in the first registry, got %d", len(services[0].Nodes))
+	}
+}
+
+func TestMultiErrors(t *testing.T) {
+	failErr := errors.New("unavailable")
+
+	r1 := NewMemoryRegistry()
+	r2 := &failingRegistry{Registry: NewMemoryRegistry(), err: failErr}
+	m := NewMulti(r1, r2)
+
+	svc := &Service{Name: "foo", Version: "1.0.0", Nodes: []*Node{{Id: "foo-1"}}}
+
+	// the healthy registry is still written to
+	err := m.Register(svc)
+	merr, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("Expected a MultiError, got %v", err)
+	}
+	if len(merr.Errors) != 1 || !errors.Is(merr.Errors[0], failErr) {
+		t.Fatalf("Expected the failing registry's error, got %v", merr.Errors)
+	}
+	if _, err := r1.GetService("foo"); err != nil {
+		t.Fatalf("Expected the service to be registered, got %v", err)
+	}
+
+	// lookups succeed while any registry answers
+	if _, err := m.GetService("foo"); err != nil {
+		t.Fatalf("Expected the lookup to succeed, got %v", err)
+	}
+
+	// unless none do
+	m = NewMulti(r2, &failingRegistry{Registry: NewMemoryRegistry(), err: failErr})
+	if _, err := m.GetService("foo"); err == nil {
+		t.Fatal("Expected the lookup to fail")
+	} else if merr, ok := err.(*MultiError); !ok || len(merr.Errors) != 2 {
+		t.Fatalf("Expected both errors, got %v", err)
+	}
+}
+
+func TestMultiWatch(t *testing.T) {
+	r1 := NewMemoryRegistry()
+	r2 := NewMemoryRegistry()
+	m := NewMulti(r1, r2)
+
+	w, err := m.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r1.Register(&Service{Name: "foo", Version: "1.0.0", Nodes: []*Node{{Id: "foo-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.Register(&Service{Name: "bar", Version: "1.0.0", Nodes: []*Node{{Id: "bar-1"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		res, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[res.Service.Name] = true
+	}
+
+	if !seen["foo"] || !seen["bar"] {
+		t.Fatalf("Expected events from both registries, got %v", seen)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+
+	w.Stop()
+
+	select {
+	case err := <-done:
+		if err != ErrWatcherStopped {
+			t.Fatalf("Expected %v, got %v", ErrWatcherStopped, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Next to return once stopped")
+	}
+}
@@ -21,6 +21,8 @@ type Options struct {
 
 type RegisterOptions struct {
 	TTL time.Duration
+	// Tags to add to the nodes' metadata
+	Tags map[string]string
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -30,6 +32,9 @@ type WatchOptions struct {
 	// Specify a service to watch
 	// If blank, the watch is for all services
 	Service string
+	// InitialState emits a create event for every current
+	// service before streaming live changes
+	InitialState bool
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -106,6 +111,15 @@ func WatchService(name string) WatchOption {
 	}
 }
 
+// WatchInitialState delivers a create event for every service currently in the
+// registry before any live changes. This closes the race between listing
+// the services and starting a watch. Not all registries support it.
+func WatchInitialState() WatchOption {
+	return func(o *WatchOptions) {
+		o.InitialState = true
+	}
+}
+
 func WatchContext(ctx context.Context) WatchOption {
 	return func(o *WatchOptions) {
 		o.Context = ctx
@@ -12,6 +12,10 @@ var (
 	ErrNotFound = errors.New("service not found")
 	// Watcher stopped error when watcher is stopped.
 	ErrWatcherStopped = errors.New("watcher stopped")
+	// Node not found error when UpdateNode is called.
+	ErrNodeNotFound = errors.New("node not found")
+	// Not supported error when the registry can't update nodes.
+	ErrUpdateNotSupported = errors.New("registry does not support node updates")
 )
 
 // The registry provides an interface for service discovery
@@ -28,6 +32,14 @@ type Registry interface {
 	String() string
 }
 
+// NodeUpdater is implemented by registries which can update a
+// registered node in place, without registering the whole service.
+type NodeUpdater interface {
+	// UpdateNode replaces the metadata of the node with the same id in
+	// every version of the service, returning ErrNodeNotFound if there's none.
+	UpdateNode(service string, node *Node) error
+}
+
 type Service struct {
 	Name      string            `json:"name"`
 	Version   string            `json:"version"`
@@ -77,6 +89,15 @@ func Deregister(s *Service) error {
 	return DefaultRegistry.Deregister(s)
 }
 
+// UpdateNode updates the metadata of a registered node e.g to mark it draining.
+func UpdateNode(service string, node *Node) error {
+	u, ok := DefaultRegistry.(NodeUpdater)
+	if !ok {
+		return ErrUpdateNotSupported
+	}
+	return u.UpdateNode(service, node)
+}
+
 // Retrieve a service. A slice is returned since we separate Name/Version.
 func GetService(name string) ([]*Service, error) {
 	return DefaultRegistry.GetService(name)
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"strings"
+)
+
+// TagPrefix namespaces the tags in the node metadata e.g tag.gpu=true.
+const TagPrefix = "tag."
+
+// WithTags tags the nodes being registered, merging the
+// tags into their metadata under the TagPrefix.
+func WithTags(tags map[string]string) RegisterOption {
+	return func(o *RegisterOptions) {
+		if o.Tags == nil {
+			o.Tags = make(map[string]string, len(tags))
+		}
+		for k, v := range tags {
+			o.Tags[k] = v
+		}
+	}
+}
+
+// Tags returns the tags of the node.
+func Tags(n *Node) map[string]string {
+	tags := make(map[string]string)
+	for k, v := range n.Metadata {
+		if strings.HasPrefix(k, TagPrefix) {
+			tags[strings.TrimPrefix(k, TagPrefix)] = v
+		}
+	}
+	return tags
+}
+
+// HasTags reports whether the node has every one of the tags.
+func HasTags(n *Node, tags map[string]string) bool {
+	for k, v := range tags {
+		if val, ok := n.Metadata[TagPrefix+k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+// tagService returns a copy of the service with the tags added to its nodes.
+func tagService(s *Service, tags map[string]string) *Service {
+	if len(tags) == 0 {
+		return s
+	}
+
+	service := *s
+	service.Nodes = make([]*Node, 0, len(s.Nodes))
+
+	for _, n := range s.Nodes {
+		md := make(map[string]string, len(n.Metadata)+len(tags))
+		for k, v := range n.Metadata {
+			md[k] = v
+		}
+		for k, v := range tags {
+			md[TagPrefix+k] = v
+		}
+
+		node := *n
+		node.Metadata = md
+		service.Nodes = append(service.Nodes, &node)
+	}
+
+	return &service
+}
@@ -1,23 +1,43 @@
 package selector
 
 import (
+	"context"
 	"time"
 
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/registry/cache"
 )
 
+type snapshotKey struct{}
+
+// CacheSnapshot persists the selector's registry cache to the file when the
+// selector is closed and loads it when the cache is created, so a restarted
+// client can select nodes before the registry has been asked. Loaded services
+// are stale until refreshed, see cache.WithSnapshot.
+func CacheSnapshot(path string) Option {
+	return func(o *Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, snapshotKey{}, path)
+	}
+}
+
 type registrySelector struct {
 	so Options
 	rc cache.Cache
+	hc *healthChecker
 }
 
 func (c *registrySelector) newCache() cache.Cache {
-	opts := make([]cache.Option, 0, 1)
+	opts := make([]cache.Option, 0, 2)
 	if c.so.Context != nil {
 		if t, ok := c.so.Context.Value("selector_ttl").(time.Duration); ok {
 			opts = append(opts, cache.WithTTL(t))
 		}
+		if p, ok := c.so.Context.Value(snapshotKey{}).(string); ok && len(p) > 0 {
+			opts = append(opts, cache.WithSnapshot(p))
+		}
 	}
 	return cache.New(c.so.Registry, opts...)
 }
@@ -30,6 +50,11 @@ func (c *registrySelector) Init(opts ...Option) error {
 	c.rc.Stop()
 	c.rc = c.newCache()
 
+	if c.hc != nil {
+		c.hc.stop()
+	}
+	c.hc = newHealthChecker(c.so.Context)
+
 	return nil
 }
 
@@ -57,6 +82,15 @@ func (c *registrySelector) Select(service string, opts ...SelectOption) (Next, e
 		return nil, err
 	}
 
+	// drop the nodes failing health probes
+	if c.hc != nil {
+		c.hc.track(service, services)
+		services = c.hc.filter(services)
+	}
+
+	// draining nodes don't take new requests
+	services = filterDraining(services)
+
 	// apply the filters
 	for _, filter := range sopts.Filters {
 		services = filter(services)
@@ -70,16 +104,44 @@ func (c *registrySelector) Select(service string, opts ...SelectOption) (Next, e
 	return sopts.Strategy(services), nil
 }
 
+// Nodes returns the cached nodes of the service, the registry
+// is only queried if the service isn't cached or has expired.
+func (c *registrySelector) Nodes(service string) ([]*registry.Node, error) {
+	services, err := c.rc.GetService(service)
+	if err != nil {
+		if err == registry.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var nodes []*registry.Node
+	for _, s := range services {
+		nodes = append(nodes, s.Nodes...)
+	}
+
+	return nodes, nil
+}
+
 func (c *registrySelector) Mark(service string, node *registry.Node, err error) {
 }
 
+// Reset expires the cached nodes for the service so the next
+// select is served from the registry.
 func (c *registrySelector) Reset(service string) {
+	if e, ok := c.rc.(cache.Expirer); ok {
+		e.Expire(service)
+	}
 }
 
 // Close stops the watcher and destroys the cache.
 func (c *registrySelector) Close() error {
 	c.rc.Stop()
 
+	if c.hc != nil {
+		c.hc.stop()
+	}
+
 	return nil
 }
 
@@ -104,6 +166,7 @@ func NewSelector(opts ...Option) Selector {
 		so: sopts,
 	}
 	s.rc = s.newCache()
+	s.hc = newHealthChecker(sopts.Context)
 
 	return s
 }
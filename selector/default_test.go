@@ -2,6 +2,7 @@ package selector
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"go-micro.dev/v4/registry"
@@ -30,3 +31,112 @@ func TestRegistrySelector(t *testing.T) {
 		t.Logf("Selector Counts %v", counts)
 	}
 }
+
+func TestRegistrySelectorDraining(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	svc := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "foo-1", Address: "localhost:9999", Metadata: map[string]string{"region": "a"}},
+			{Id: "foo-2", Address: "localhost:6666"},
+		},
+	}
+	if err := r.Register(svc); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSelector(Registry(r))
+
+	if err := registry.Drain(r, "foo", svc.Nodes[0], true); err != nil {
+		t.Fatal(err)
+	}
+
+	// the node is still listed, marked draining
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found *registry.Node
+	for _, n := range services[0].Nodes {
+		if n.Id == "foo-1" {
+			found = n
+		}
+	}
+	if !registry.IsDraining(found) || found.Metadata["region"] != "a" {
+		t.Fatalf("Expected foo-1 to be listed and draining, got %+v", found)
+	}
+
+	nodes, err := s.(interface {
+		Nodes(string) ([]*registry.Node, error)
+	}).Nodes("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("Expected the selector to list both nodes, got %d", len(nodes))
+	}
+
+	next, err := s.Select("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Id != "foo-2" {
+			t.Fatalf("Expected the draining node not to be selected, got %s", node.Id)
+		}
+	}
+
+	// none are available once every node drains
+	if err := registry.Drain(r, "foo", svc.Nodes[1], true); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset("foo")
+	if _, err := s.Select("foo"); err != ErrNoneAvailable {
+		t.Fatalf("Expected %v, got %v", ErrNoneAvailable, err)
+	}
+
+	// undraining makes it selectable again
+	if err := registry.Drain(r, "foo", svc.Nodes[0], false); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset("foo")
+	next, err = s.Select("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node, err := next(); err != nil || node.Id != "foo-1" {
+		t.Fatalf("Expected foo-1 to be selected, got %v %v", node, err)
+	}
+}
+
+func TestRegistrySelectorCacheSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	r := registry.NewMemoryRegistry(registry.Services(testData))
+
+	s := NewSelector(Registry(r), CacheSnapshot(path))
+	if _, err := s.Select("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// restarted against a registry which hasn't got the service
+	s = NewSelector(Registry(registry.NewMemoryRegistry()), CacheSnapshot(path))
+	defer s.Close()
+
+	next, err := s.Select("foo")
+	if err != nil {
+		t.Fatalf("Expected the snapshot's service, got %v", err)
+	}
+	if _, err := next(); err != nil {
+		t.Fatal(err)
+	}
+}
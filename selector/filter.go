@@ -71,3 +71,90 @@ func FilterVersion(version string) Filter {
 		return services
 	}
 }
+
+// TagFilter is a tag based Select Filter which will only return
+// the nodes with every one of the tags, see registry.WithTags.
+func TagFilter(tags map[string]string) Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		var services []*registry.Service
+
+		for _, service := range old {
+			var nodes []*registry.Node
+
+			for _, node := range service.Nodes {
+				if registry.HasTags(node, tags) {
+					nodes = append(nodes, node)
+				}
+			}
+
+			// only add service if there's some nodes
+			if len(nodes) > 0 {
+				serv := *service
+				serv.Nodes = nodes
+				services = append(services, &serv)
+			}
+		}
+
+		return services
+	}
+}
+
+// ExcludeNodes is a Select Filter which drops the nodes with any of
+// the ids, and the services left without nodes.
+func ExcludeNodes(ids ...string) Filter {
+	exclude := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		exclude[id] = true
+	}
+
+	return func(old []*registry.Service) []*registry.Service {
+		var services []*registry.Service
+
+		for _, service := range old {
+			var nodes []*registry.Node
+
+			for _, node := range service.Nodes {
+				if !exclude[node.Id] {
+					nodes = append(nodes, node)
+				}
+			}
+
+			// only add service if there's some nodes
+			if len(nodes) > 0 {
+				serv := *service
+				serv.Nodes = nodes
+				services = append(services, &serv)
+			}
+		}
+
+		return services
+	}
+}
+
+// filterDraining drops the draining nodes, and the services left without nodes.
+func filterDraining(old []*registry.Service) []*registry.Service {
+	var services []*registry.Service
+
+	for _, service := range old {
+		nodes := make([]*registry.Node, 0, len(service.Nodes))
+		for _, node := range service.Nodes {
+			if !registry.IsDraining(node) {
+				nodes = append(nodes, node)
+			}
+		}
+
+		// nothing draining, avoid the copy
+		if len(nodes) == len(service.Nodes) {
+			services = append(services, service)
+			continue
+		}
+
+		if len(nodes) > 0 {
+			serv := *service
+			serv.Nodes = nodes
+			services = append(services, &serv)
+		}
+	}
+
+	return services
+}
@@ -1,6 +1,8 @@
 package selector
 
 import (
+	"reflect"
+	"sort"
 	"testing"
 
 	"go-micro.dev/v4/registry"
@@ -237,3 +239,80 @@ func TestFilterVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestTagFilter(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+
+	register := func(id string, tags map[string]string) {
+		err := r.Register(&registry.Service{
+			Name:    "test",
+			Version: "1.0.0",
+			Nodes:   []*registry.Node{{Id: id, Address: id + ":8080"}},
+		}, registry.WithTags(tags))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	register("gpu-premium", map[string]string{"gpu": "true", "tier": "premium"})
+	register("gpu-basic", map[string]string{"gpu": "true", "tier": "basic"})
+	register("cpu-premium", map[string]string{"gpu": "false", "tier": "premium"})
+	register("untagged", nil)
+
+	s := NewSelector(Registry(r))
+	defer s.Close()
+
+	testData := []struct {
+		tags  map[string]string
+		nodes []string
+	}{
+		{map[string]string{"gpu": "true"}, []string{"gpu-basic", "gpu-premium"}},
+		{map[string]string{"tier": "premium"}, []string{"cpu-premium", "gpu-premium"}},
+		{map[string]string{"gpu": "true", "tier": "premium"}, []string{"gpu-premium"}},
+		{map[string]string{"gpu": "true", "tier": "gold"}, nil},
+		{map[string]string{"zone": "a"}, nil},
+	}
+
+	for _, data := range testData {
+		next, err := s.Select("test", WithFilter(TagFilter(data.tags)))
+		if len(data.nodes) == 0 {
+			if err != ErrNoneAvailable {
+				t.Fatalf("Expected no nodes for %v, got %v", data.tags, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen := make(map[string]bool)
+		for i := 0; i < 20; i++ {
+			node, err := next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen[node.Id] = true
+		}
+
+		var ids []string
+		for id := range seen {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		if !reflect.DeepEqual(ids, data.nodes) {
+			t.Fatalf("Expected nodes %v for %v, got %v", data.nodes, data.tags, ids)
+		}
+	}
+
+	// the tags are node metadata
+	services, err := r.GetService("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range services[0].Nodes {
+		if node.Id == "gpu-premium" && !reflect.DeepEqual(registry.Tags(node), map[string]string{"gpu": "true", "tier": "premium"}) {
+			t.Fatalf("Unexpected tags %v", registry.Tags(node))
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package selector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+// HealthURLKey is the node metadata key holding the url probed by HTTPHealthProbe.
+const HealthURLKey = "health_url"
+
+// HealthProbe checks a node of the service, returning an error if it is unhealthy.
+type HealthProbe func(service string, node *registry.Node) error
+
+type healthKey struct{}
+
+type healthOptions struct {
+	probe    HealthProbe
+	interval time.Duration
+}
+
+// HealthCheck enables probing of the nodes returned by the registry at the
+// given interval. Nodes whose last probe failed are excluded from selection
+// until a later probe succeeds, even if they're still registered.
+func HealthCheck(probe HealthProbe, interval time.Duration) Option {
+	return func(o *Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, healthKey{}, healthOptions{probe, interval})
+	}
+}
+
+// HTTPHealthProbe returns a probe issuing a GET against the url stored in the
+// node metadata under HealthURLKey. Any non 2xx response marks the node as
+// unhealthy. Nodes without a health url are always considered healthy.
+func HTTPHealthProbe(timeout time.Duration) HealthProbe {
+	client := &http.Client{Timeout: timeout}
+
+	return func(service string, node *registry.Node) error {
+		url := node.Metadata[HealthURLKey]
+		if len(url) == 0 {
+			return nil
+		}
+
+		rsp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		rsp.Body.Close()
+
+		if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+			return fmt.Errorf("health check %s returned %s", url, rsp.Status)
+		}
+
+		return nil
+	}
+}
+
+// ServiceHealth returns the fraction of the service's nodes the default
+// selector considers healthy, from 0 to 1. It's 0 if the service has no
+// nodes or the selector can't report health.
+func ServiceHealth(name string) float64 {
+	h, ok := DefaultSelector.(HealthReporter)
+	if !ok {
+		return 0
+	}
+	return h.ServiceHealth(name)
+}
+
+// ServiceHealth returns the fraction of the service's nodes which are healthy.
+// A node is healthy unless it's draining or failed its last health probe, so
+// without a health check every node which isn't draining is healthy. Nodes
+// are probed from the first call, so they're healthy until then.
+func (c *registrySelector) ServiceHealth(service string) float64 {
+	services, err := c.rc.GetService(service)
+	if err != nil {
+		return 0
+	}
+
+	if c.hc != nil {
+		c.hc.track(service, services)
+	}
+
+	var total, healthy int
+
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			total++
+			if !registry.IsDraining(n) && (c.hc == nil || c.hc.healthy(n)) {
+				healthy++
+			}
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(healthy) / float64(total)
+}
+
+type healthChecker struct {
+	probe    HealthProbe
+	interval time.Duration
+
+	sync.RWMutex
+	// nodes last seen for each service
+	nodes map[string][]*registry.Node
+	// ids of nodes which failed their last probe
+	unhealthy map[string]bool
+
+	exit chan bool
+	once sync.Once
+}
+
+func newHealthChecker(ctx context.Context) *healthChecker {
+	if ctx == nil {
+		return nil
+	}
+
+	hopts, ok := ctx.Value(healthKey{}).(healthOptions)
+	if !ok || hopts.probe == nil || hopts.interval <= 0 {
+		return nil
+	}
+
+	h := &healthChecker{
+		probe:     hopts.probe,
+		interval:  hopts.interval,
+		nodes:     make(map[string][]*registry.Node),
+		unhealthy: make(map[string]bool),
+		exit:      make(chan bool),
+	}
+	go h.run()
+
+	return h
+}
+
+// track records the current nodes of a service so they're probed.
+func (h *healthChecker) track(service string, services []*registry.Service) {
+	var nodes []*registry.Node
+	for _, s := range services {
+		nodes = append(nodes, s.Nodes...)
+	}
+
+	h.Lock()
+	h.nodes[service] = nodes
+	h.Unlock()
+}
+
+// filter removes the unhealthy nodes from the services.
+func (h *healthChecker) filter(services []*registry.Service) []*registry.Service {
+	h.RLock()
+	defer h.RUnlock()
+
+	if len(h.unhealthy) == 0 {
+		return services
+	}
+
+	filtered := make([]*registry.Service, 0, len(services))
+
+	for _, s := range services {
+		nodes := make([]*registry.Node, 0, len(s.Nodes))
+		for _, n := range s.Nodes {
+			if !h.unhealthy[n.Id] {
+				nodes = append(nodes, n)
+			}
+		}
+
+		if len(nodes) == 0 {
+			continue
+		}
+
+		svc := *s
+		svc.Nodes = nodes
+		filtered = append(filtered, &svc)
+	}
+
+	return filtered
+}
+
+// healthy reports whether the node passed its last probe, or wasn't probed.
+func (h *healthChecker) healthy(n *registry.Node) bool {
+	h.RLock()
+	defer h.RUnlock()
+	return !h.unhealthy[n.Id]
+}
+
+// check probes every tracked node and replaces the unhealthy set.
+func (h *healthChecker) check() {
+	h.RLock()
+	nodes := make(map[string][]*registry.Node, len(h.nodes))
+	for service, n := range h.nodes {
+		nodes[service] = n
+	}
+	h.RUnlock()
+
+	var (
+		mtx       sync.Mutex
+		wg        sync.WaitGroup
+		unhealthy = make(map[string]bool)
+	)
+
+	for service, n := range nodes {
+		for _, node := range n {
+			wg.Add(1)
+
+			go func(service string, node *registry.Node) {
+				defer wg.Done()
+
+				if err := h.probe(service, node); err != nil {
+					mtx.Lock()
+					unhealthy[node.Id] = true
+					mtx.Unlock()
+				}
+			}(service, node)
+		}
+	}
+
+	wg.Wait()
+
+	h.Lock()
+	h.unhealthy = unhealthy
+	h.Unlock()
+}
+
+func (h *healthChecker) run() {
+	t := time.NewTicker(h.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-h.exit:
+			return
+		case <-t.C:
+			h.check()
+		}
+	}
+}
+
+func (h *healthChecker) stop() {
+	h.once.Do(func() {
+		close(h.exit)
+	})
+}
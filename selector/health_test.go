@@ -0,0 +1,189 @@
+package selector
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+func TestHealthCheckExcludesUnhealthy(t *testing.T) {
+	r := registry.NewMemoryRegistry(registry.Services(testData))
+
+	probe := func(service string, node *registry.Node) error {
+		if node.Id == "foo-1.0.0-123" {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}
+
+	s := NewSelector(Registry(r), HealthCheck(probe, 10*time.Millisecond))
+	defer s.Close()
+
+	// the first select tracks the nodes, all are healthy until probed
+	if _, err := s.Select("foo"); err != nil {
+		t.Fatalf("Unexpected select error: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	next, err := s.Select("foo")
+	if err != nil {
+		t.Fatalf("Unexpected select error: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatalf("Unexpected node error: %v", err)
+		}
+		seen[node.Id] = true
+	}
+
+	if seen["foo-1.0.0-123"] {
+		t.Fatal("Expected unhealthy node to be excluded from selection")
+	}
+	var healthy int
+	for _, svc := range testData["foo"] {
+		healthy += len(svc.Nodes)
+	}
+	if len(seen) != healthy-1 {
+		t.Fatalf("Expected the %d healthy nodes to be selected, got %v", healthy-1, seen)
+	}
+
+	// the node is still registered
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registered bool
+	for _, svc := range services {
+		for _, node := range svc.Nodes {
+			if node.Id == "foo-1.0.0-123" {
+				registered = true
+			}
+		}
+	}
+	if !registered {
+		t.Fatal("Expected unhealthy node to remain in the registry")
+	}
+}
+
+func TestHealthCheckNoneAvailable(t *testing.T) {
+	r := registry.NewMemoryRegistry(registry.Services(testData))
+
+	probe := func(service string, node *registry.Node) error {
+		return errors.New("unhealthy")
+	}
+
+	s := NewSelector(Registry(r), HealthCheck(probe, 10*time.Millisecond))
+	defer s.Close()
+
+	if _, err := s.Select("foo"); err != nil {
+		t.Fatalf("Unexpected select error: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := s.Select("foo"); err != ErrNoneAvailable {
+		t.Fatalf("Expected %v, got %v", ErrNoneAvailable, err)
+	}
+}
+
+func TestServiceHealth(t *testing.T) {
+	svc := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "foo-1", Address: "localhost:9991"},
+			{Id: "foo-2", Address: "localhost:9992"},
+			{Id: "foo-3", Address: "localhost:9993"},
+			{Id: "foo-4", Address: "localhost:9994"},
+		},
+	}
+
+	probe := func(service string, node *registry.Node) error {
+		if node.Id == "foo-2" {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name string
+		opts []Option
+		// before and after the nodes are probed
+		before float64
+		after  float64
+	}{
+		// without a probe only the draining node is unhealthy
+		{"no probe", nil, 0.75, 0.75},
+		{"probe", []Option{HealthCheck(probe, 10*time.Millisecond)}, 0.75, 0.5},
+	}
+
+	for _, tc := range testCases {
+		r := registry.NewMemoryRegistry()
+		if err := r.Register(svc); err != nil {
+			t.Fatal(err)
+		}
+		if err := registry.Drain(r, "foo", svc.Nodes[0], true); err != nil {
+			t.Fatal(err)
+		}
+
+		s := NewSelector(append([]Option{Registry(r)}, tc.opts...)...)
+		h := s.(HealthReporter)
+
+		if ratio := h.ServiceHealth("foo"); ratio != tc.before {
+			t.Fatalf("%s: expected health %v, got %v", tc.name, tc.before, ratio)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		if ratio := h.ServiceHealth("foo"); ratio != tc.after {
+			t.Fatalf("%s: expected health %v once probed, got %v", tc.name, tc.after, ratio)
+		}
+
+		if ratio := h.ServiceHealth("bar"); ratio != 0 {
+			t.Fatalf("%s: expected no health for an unknown service, got %v", tc.name, ratio)
+		}
+
+		s.Close()
+	}
+}
+
+func TestHTTPHealthProbe(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	probe := HTTPHealthProbe(time.Second)
+
+	testCases := []struct {
+		node    *registry.Node
+		healthy bool
+	}{
+		{&registry.Node{Id: "1", Metadata: map[string]string{HealthURLKey: healthy.URL}}, true},
+		{&registry.Node{Id: "2", Metadata: map[string]string{HealthURLKey: unhealthy.URL}}, false},
+		{&registry.Node{Id: "3"}, true},
+	}
+
+	for _, tc := range testCases {
+		err := probe("foo", tc.node)
+		if tc.healthy && err != nil {
+			t.Fatalf("Expected node %s to be healthy, got %v", tc.node.Id, err)
+		}
+		if !tc.healthy && err == nil {
+			t.Fatalf("Expected node %s to be unhealthy", tc.node.Id)
+		}
+	}
+}
@@ -54,6 +54,14 @@ func WithFilter(fn ...Filter) SelectOption {
 	}
 }
 
+// WithContext sets the context for the select call.
+// It may be used by strategies such as ConsistentHash.
+func WithContext(ctx context.Context) SelectOption {
+	return func(o *SelectOptions) {
+		o.Context = ctx
+	}
+}
+
 // Strategy sets the selector strategy.
 func WithStrategy(fn Strategy) SelectOption {
 	return func(o *SelectOptions) {
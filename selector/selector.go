@@ -25,6 +25,21 @@ type Selector interface {
 	String() string
 }
 
+// NodeLister is implemented by selectors which can list the
+// nodes they currently select from.
+type NodeLister interface {
+	// Nodes returns the nodes of every version of the service
+	Nodes(service string) ([]*registry.Node, error)
+}
+
+// HealthReporter is implemented by selectors which can report
+// the health of a service.
+type HealthReporter interface {
+	// ServiceHealth returns the fraction of the service's nodes
+	// which are healthy
+	ServiceHealth(service string) float64
+}
+
 // Next is a function that returns the next node
 // based on the selector's strategy.
 type Next func() (*registry.Node, error)
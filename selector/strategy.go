@@ -1,13 +1,21 @@
 package selector
 
 import (
+	"context"
+	"hash/crc32"
 	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"go-micro.dev/v4/registry"
 )
 
+// number of virtual nodes per node on the hash ring.
+var hashReplicas = 100
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -54,3 +62,173 @@ func RoundRobin(services []*registry.Service) Next {
 		return node, nil
 	}
 }
+
+// Canary is a strategy which sends canaryPercent (0-100) of selections to nodes
+// not running the stable version and the rest to the stable nodes. A node's
+// version is read from its "version" metadata, falling back to the service
+// version. If only one of the stable or canary versions is present all
+// selections go to the nodes available.
+func Canary(stableVersion string, canaryPercent float64) Strategy {
+	return func(services []*registry.Service) Next {
+		var stable, canary []*registry.Node
+
+		for _, service := range services {
+			for _, node := range service.Nodes {
+				version := node.Metadata["version"]
+				if len(version) == 0 {
+					version = service.Version
+				}
+
+				if version == stableVersion {
+					stable = append(stable, node)
+				} else {
+					canary = append(canary, node)
+				}
+			}
+		}
+
+		return func() (*registry.Node, error) {
+			nodes := stable
+
+			switch {
+			case len(stable) == 0:
+				nodes = canary
+			case len(canary) == 0:
+			case rand.Float64()*100 < canaryPercent:
+				nodes = canary
+			}
+
+			if len(nodes) == 0 {
+				return nil, ErrNoneAvailable
+			}
+
+			return nodes[rand.Int()%len(nodes)], nil
+		}
+	}
+}
+
+// ConsistentHash is a sticky routing strategy which maps the key returned by keyFn
+// to the same node while the set of nodes is stable. When a node is added or removed
+// only the keys on that node are remapped. The key is derived from the select context
+// which the client sets to the call context. An empty key falls back to Random.
+// The hash ring is cached so the returned option should be reused across calls.
+func ConsistentHash(keyFn func(context.Context) string) SelectOption {
+	ch := &consistentHash{keyFn: keyFn}
+
+	return func(o *SelectOptions) {
+		o.Strategy = func(services []*registry.Service) Next {
+			ctx := o.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			return ch.next(ctx, services)
+		}
+	}
+}
+
+type consistentHash struct {
+	keyFn func(context.Context) string
+
+	sync.Mutex
+	// the node set the ring was built for
+	nodeSet string
+	ring    *hashRing
+}
+
+type hashRing struct {
+	hashes []uint32
+	nodes  map[uint32]*registry.Node
+}
+
+func newHashRing(nodes []*registry.Node) *hashRing {
+	r := &hashRing{
+		hashes: make([]uint32, 0, len(nodes)*hashReplicas),
+		nodes:  make(map[uint32]*registry.Node, len(nodes)*hashReplicas),
+	}
+
+	for _, node := range nodes {
+		for i := 0; i < hashReplicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(node.Id + "#" + strconv.Itoa(i)))
+			if _, ok := r.nodes[h]; ok {
+				continue
+			}
+			r.nodes[h] = node
+			r.hashes = append(r.hashes, h)
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+
+	return r
+}
+
+// search returns the position on the ring for the key.
+func (r *hashRing) search(key string) int {
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return i
+}
+
+func (c *consistentHash) getRing(nodes []*registry.Node) *hashRing {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.Id+"@"+node.Address)
+	}
+	sort.Strings(ids)
+	nodeSet := strings.Join(ids, ",")
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.ring == nil || c.nodeSet != nodeSet {
+		c.ring = newHashRing(nodes)
+		c.nodeSet = nodeSet
+	}
+
+	return c.ring
+}
+
+func (c *consistentHash) next(ctx context.Context, services []*registry.Service) Next {
+	nodes := make([]*registry.Node, 0, len(services))
+
+	for _, service := range services {
+		nodes = append(nodes, service.Nodes...)
+	}
+
+	key := c.keyFn(ctx)
+	if len(key) == 0 || len(nodes) == 0 {
+		return Random(services)
+	}
+
+	ring := c.getRing(nodes)
+	pos := ring.search(key)
+
+	var mtx sync.Mutex
+	seen := make(map[string]bool)
+
+	// walk the ring so subsequent calls e.g retries return the next distinct node
+	return func() (*registry.Node, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		for j := 0; j < 2; j++ {
+			for i := 0; i < len(ring.hashes); i++ {
+				node := ring.nodes[ring.hashes[(pos+i)%len(ring.hashes)]]
+				if seen[node.Id] {
+					continue
+				}
+				seen[node.Id] = true
+				pos = (pos + i) % len(ring.hashes)
+				return node, nil
+			}
+
+			// start over once every node has been returned
+			seen = make(map[string]bool)
+		}
+
+		return nil, ErrNoneAvailable
+	}
+}
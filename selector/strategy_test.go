@@ -1,6 +1,8 @@
 package selector
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"testing"
 
@@ -56,3 +58,170 @@ func TestStrategies(t *testing.T) {
 		}
 	}
 }
+
+type testKey struct{}
+
+func TestConsistentHash(t *testing.T) {
+	newServices := func(n int) []*registry.Service {
+		nodes := make([]*registry.Node, 0, n)
+		for i := 0; i < n; i++ {
+			nodes = append(nodes, &registry.Node{
+				Id:      fmt.Sprintf("test-%d", i),
+				Address: fmt.Sprintf("10.0.0.%d:1000", i),
+			})
+		}
+		return []*registry.Service{{Name: "test", Version: "latest", Nodes: nodes}}
+	}
+
+	keyFn := func(ctx context.Context) string {
+		k, _ := ctx.Value(testKey{}).(string)
+		return k
+	}
+
+	sticky := ConsistentHash(keyFn)
+
+	route := func(services []*registry.Service, key string) string {
+		opts := SelectOptions{}
+		for _, o := range []SelectOption{sticky, WithContext(context.WithValue(context.Background(), testKey{}, key))} {
+			o(&opts)
+		}
+		node, err := opts.Strategy(services)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node.Id
+	}
+
+	keys := 1000
+	services := newServices(5)
+	mapping := make(map[string]string, keys)
+
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("session-%d", i)
+		mapping[key] = route(services, key)
+	}
+
+	// the mapping is stable while the node set is
+	for i := 0; i < 5; i++ {
+		for key, id := range mapping {
+			if got := route(services, key); got != id {
+				t.Fatalf("Expected %s to map to %s got %s", key, id, got)
+			}
+		}
+	}
+
+	// adding a node only moves keys onto the new node
+	added := newServices(6)
+	var moved int
+
+	for key, id := range mapping {
+		got := route(added, key)
+		if got == id {
+			continue
+		}
+		if got != "test-5" {
+			t.Fatalf("Expected %s to stay on %s or move to test-5 got %s", key, id, got)
+		}
+		moved++
+	}
+
+	// expect roughly 1/6 of keys to move, allow for variance
+	if moved == 0 || moved > keys/3 {
+		t.Fatalf("Expected a bounded number of keys to move got %d of %d", moved, keys)
+	}
+
+	// removing a node only moves the keys on that node
+	removed := newServices(4)
+
+	for key, id := range mapping {
+		got := route(removed, key)
+		if id != "test-4" && got != id {
+			t.Fatalf("Expected %s to stay on %s got %s", key, id, got)
+		}
+		if got == "test-4" {
+			t.Fatalf("Expected %s to move off removed node", key)
+		}
+	}
+
+	// retries walk to the next distinct node
+	opts := SelectOptions{}
+	sticky(&opts)
+	WithContext(context.WithValue(context.Background(), testKey{}, "session-1"))(&opts)
+	next := opts.Strategy(services)
+	seen := make(map[string]bool)
+
+	for i := 0; i < 5; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[node.Id] {
+			t.Fatalf("Expected distinct node got %s twice", node.Id)
+		}
+		seen[node.Id] = true
+	}
+}
+
+func TestCanary(t *testing.T) {
+	services := []*registry.Service{
+		{
+			Name:    "test",
+			Version: "1.0.0",
+			Nodes: []*registry.Node{
+				{Id: "stable-1", Address: "10.0.0.1:1001"},
+				{Id: "stable-2", Address: "10.0.0.2:1002"},
+				// version metadata overrides the service version
+				{Id: "canary-2", Address: "10.0.0.4:1004", Metadata: map[string]string{"version": "1.1.0"}},
+			},
+		},
+		{
+			Name:    "test",
+			Version: "1.1.0",
+			Nodes: []*registry.Node{
+				{Id: "canary-1", Address: "10.0.0.3:1003"},
+			},
+		},
+	}
+
+	const calls = 20000
+
+	for _, percent := range []float64{0, 10, 50} {
+		next := Canary("1.0.0", percent)(services)
+
+		var canary int
+		for i := 0; i < calls; i++ {
+			node, err := next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node.Id == "canary-1" || node.Id == "canary-2" {
+				canary++
+			}
+		}
+
+		// allow 2% either side of the split
+		got := float64(canary) / calls * 100
+		if got < percent-2 || got > percent+2 {
+			t.Fatalf("Expected %.0f%% canary traffic, got %.2f%%", percent, got)
+		}
+	}
+
+	// only one version present, all traffic goes to it
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		next := Canary(version, 10)([]*registry.Service{services[1]})
+
+		for i := 0; i < 100; i++ {
+			node, err := next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node.Id != "canary-1" {
+				t.Fatalf("Expected the only node to be selected, got %s", node.Id)
+			}
+		}
+	}
+
+	if _, err := Canary("1.0.0", 10)(nil)(); err != ErrNoneAvailable {
+		t.Fatalf("Expected %v, got %v", ErrNoneAvailable, err)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-micro.dev/v4/metadata"
+)
+
+// AccountingTenantHeader is the metadata key the tenant
+// of a request is read from by AccountingWrapper.
+var AccountingTenantHeader = "Micro-Tenant"
+
+// AccountingRecord is the approximate resources used by a request.
+type AccountingRecord struct {
+	Service  string
+	Endpoint string
+	// Tenant is the value of AccountingTenantHeader, if set
+	Tenant string
+	// BytesIn is the size of the encoded request body, it's zero
+	// for other Request implementations than the server's
+	BytesIn int
+	// BytesOut is the size of the encoded responses written, it's zero
+	// for requests which weren't received by the server
+	BytesOut int
+	// WallTime is from the request being received until the handler
+	// returned, including decoding and any queueing for a limit
+	WallTime time.Duration
+	// HandlerDuration is the time spent in the handler
+	HandlerDuration time.Duration
+	Error           error
+}
+
+type accountingKey struct{}
+
+// accounting is carried by the context of a request received by the
+// server, it's served once the responses to the request are written.
+type accounting struct {
+	received time.Time
+	codec    *rpcCodec
+
+	sync.Mutex
+	fns []func(bytesOut int)
+}
+
+// onServed calls fn with the bytes written once the request is served.
+func (a *accounting) onServed(fn func(bytesOut int)) {
+	a.Lock()
+	a.fns = append(a.fns, fn)
+	a.Unlock()
+}
+
+func (a *accounting) served() {
+	a.Lock()
+	fns := a.fns
+	a.fns = nil
+	a.Unlock()
+
+	var n int
+	if a.codec != nil {
+		n = int(atomic.LoadInt64(&a.codec.written))
+	}
+
+	for _, fn := range fns {
+		fn(n)
+	}
+}
+
+// AccountingWrapper is a handler wrapper calling fn with the resources used
+// by every request, e.g to attribute costs to tenants. For requests received
+// by the server fn is called once the responses have been written so the
+// bytes the codec wrote are counted, for streams too.
+func AccountingWrapper(fn func(context.Context, AccountingRecord)) HandlerWrapper {
+	return func(h HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			start := time.Now()
+
+			acct, _ := ctx.Value(accountingKey{}).(*accounting)
+
+			received := start
+			if acct != nil {
+				received = acct.received
+			}
+
+			err := h(ctx, req, rsp)
+
+			end := time.Now()
+
+			rec := AccountingRecord{
+				Service:         req.Service(),
+				Endpoint:        req.Endpoint(),
+				WallTime:        end.Sub(received),
+				HandlerDuration: end.Sub(start),
+				Error:           err,
+			}
+
+			rec.Tenant, _ = metadata.Get(ctx, AccountingTenantHeader)
+
+			// the router keeps the encoded body
+			if r, ok := req.(*rpcRequest); ok {
+				rec.BytesIn = len(r.body)
+			}
+
+			if acct == nil {
+				fn(ctx, rec)
+				return err
+			}
+
+			acct.onServed(func(bytesOut int) {
+				rec.BytesOut = bytesOut
+				fn(ctx, rec)
+			})
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+)
+
+type SleepHandler struct{}
+
+func (h *SleepHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	time.Sleep(time.Millisecond)
+	rsp.Value = req.Value
+	return nil
+}
+
+func TestAccountingWrapper(t *testing.T) {
+	records := make(chan AccountingRecord, 1)
+
+	srv, cl := newTestServer(WrapHandler(AccountingWrapper(func(ctx context.Context, rec AccountingRecord) {
+		records <- rec
+	})))
+
+	if err := srv.Handle(srv.NewHandler(&SleepHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Micro-Tenant": "acme"})
+
+	req := cl.NewRequest("test.service", "SleepHandler.Call", &TestValue{Value: "foo"})
+	var rsp TestValue
+	if err := cl.Call(ctx, req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	var rec AccountingRecord
+	select {
+	case rec = <-records:
+	case <-time.After(time.Second):
+		t.Fatal("Expected an accounting record")
+	}
+
+	if rec.Service != "test.service" || rec.Endpoint != "SleepHandler.Call" {
+		t.Fatalf("Unexpected service and endpoint %s %s", rec.Service, rec.Endpoint)
+	}
+	if rec.Tenant != "acme" {
+		t.Fatalf("Expected tenant acme, got %q", rec.Tenant)
+	}
+	// the response echoes the request so it's encoded to the same size
+	if rec.BytesIn == 0 || rec.BytesOut != rec.BytesIn {
+		t.Fatalf("Expected bytes in and out to be counted, got %d and %d", rec.BytesIn, rec.BytesOut)
+	}
+	if rec.HandlerDuration < time.Millisecond {
+		t.Fatalf("Expected the handler to be timed, got %v", rec.HandlerDuration)
+	}
+	if rec.WallTime < rec.HandlerDuration {
+		t.Fatalf("Expected the wall time %v to include the handler %v", rec.WallTime, rec.HandlerDuration)
+	}
+	if rec.Error != nil {
+		t.Fatalf("Expected no error, got %v", rec.Error)
+	}
+}
+
+func TestAccountingWrapperStream(t *testing.T) {
+	records := make(chan AccountingRecord, 1)
+
+	srv, cl := newTestServer(WrapHandler(AccountingWrapper(func(ctx context.Context, rec AccountingRecord) {
+		records <- rec
+	})))
+
+	if err := srv.Handle(srv.NewHandler(&StreamHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	req := cl.NewRequest("test.service", "StreamHandler.Echo", &TestValue{}, client.WithContentType("application/json"))
+	stream, err := cl.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written int
+	for _, value := range []string{"foo", "bar"} {
+		if err := stream.Send(&TestValue{Value: value}); err != nil {
+			t.Fatal(err)
+		}
+		var rsp TestValue
+		if err := stream.Recv(&rsp); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := json.Marshal(&rsp)
+		written += len(b)
+	}
+	stream.Close()
+
+	select {
+	case rec := <-records:
+		// every frame written is counted
+		if rec.BytesOut < written {
+			t.Fatalf("Expected at least %d bytes out, got %d", written, rec.BytesOut)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an accounting record")
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"errors"
+)
+
+// AckMode controls how messages delivered to a subscriber are acknowledged.
+type AckMode int
+
+const (
+	// AckModeAuto acks the message when the handler returns a nil error.
+	AckModeAuto AckMode = iota
+	// AckModeManual leaves acknowledgement to the handler, which gets an
+	// Acker from its context via AckerFromContext.
+	AckModeManual
+)
+
+// ErrNackNotSupported is returned by Nack when the broker can't redeliver.
+var ErrNackNotSupported = errors.New("broker does not support nack")
+
+// Acker acknowledges the message being handled by a subscriber.
+type Acker interface {
+	// Ack marks the message as processed
+	Ack() error
+	// Nack asks the broker to redeliver the message
+	Nack() error
+}
+
+type ackerKey struct{}
+
+// AckerFromContext returns the Acker for the message being handled.
+func AckerFromContext(ctx context.Context) (Acker, bool) {
+	a, ok := ctx.Value(ackerKey{}).(Acker)
+	return a, ok
+}
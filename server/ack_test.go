@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+)
+
+func TestServerSubscriberManualAck(t *testing.T) {
+	srv, cl := newTestServer()
+
+	var mtx sync.Mutex
+	deliveries := make(map[string]int)
+	acked := make(chan string, 10)
+
+	// nack the first delivery of each message as if a downstream commit failed
+	fn := func(ctx context.Context, msg *TestValue) error {
+		acker, ok := AckerFromContext(ctx)
+		if !ok {
+			t.Error("Expected an acker in the context")
+			return nil
+		}
+
+		mtx.Lock()
+		deliveries[msg.Value]++
+		n := deliveries[msg.Value]
+		mtx.Unlock()
+
+		if n == 1 {
+			return acker.Nack()
+		}
+
+		acked <- msg.Value
+		return acker.Ack()
+	}
+
+	sub := srv.NewSubscriber("test.topic", fn, SubscriberAckMode(AckModeManual))
+	if sub.Options().AutoAck {
+		t.Fatal("Expected auto ack to be disabled in manual mode")
+	}
+
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	for _, v := range []string{"one", "two"} {
+		if err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: v})); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case got := <-acked:
+			if got != v {
+				t.Fatalf("Expected %s to be acked got %s", v, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s to be redelivered after nack", v)
+		}
+	}
+
+	// acked messages are not redelivered
+	time.Sleep(time.Millisecond * 50)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	for v, n := range deliveries {
+		if n != 2 {
+			t.Fatalf("Expected %s to be delivered twice got %d", v, n)
+		}
+	}
+}
+
+// ackBroker records the messages acked by subscribers.
+type ackBroker struct {
+	broker.Broker
+
+	sync.Mutex
+	acked []string
+}
+
+type ackEvent struct {
+	broker.Event
+	b *ackBroker
+}
+
+func (e *ackEvent) Ack() error {
+	e.b.Lock()
+	e.b.acked = append(e.b.acked, e.Message().Header["Type"])
+	e.b.Unlock()
+	return e.Event.Ack()
+}
+
+func (b *ackBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Broker.Subscribe(topic, func(e broker.Event) error {
+		return h(&ackEvent{e, b})
+	}, opts...)
+}
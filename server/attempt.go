@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+)
+
+const (
+	// AttemptHeader carries the attempt number of a call, 1 for the
+	// first attempt and incremented on each retry.
+	AttemptHeader = client.AttemptHeader
+	// IdempotencyKeyHeader carries a key which is the same for every
+	// attempt of a call.
+	IdempotencyKeyHeader = client.IdempotencyKeyHeader
+)
+
+// AttemptFromContext returns the attempt number of the request being
+// served, greater than 1 if it's a retry.
+func AttemptFromContext(ctx context.Context) (int, bool) {
+	v, ok := metadata.Get(ctx, AttemptHeader)
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the request
+// being served, shared by every attempt of the call e.g to detect a
+// retry of a request which was already applied.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := metadata.Get(ctx, IdempotencyKeyHeader)
+	return key, ok && len(key) > 0
+}
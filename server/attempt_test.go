@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+type AttemptHandler struct {
+	sync.Mutex
+	attempts []int
+	keys     []string
+}
+
+func (h *AttemptHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	attempt, _ := AttemptFromContext(ctx)
+	key, _ := IdempotencyKeyFromContext(ctx)
+
+	h.Lock()
+	h.attempts = append(h.attempts, attempt)
+	h.keys = append(h.keys, key)
+	h.Unlock()
+
+	// fail until the last retry
+	if attempt < 3 {
+		return errors.InternalServerError("test", "attempt %d failed", attempt)
+	}
+
+	rsp.Value = req.Value
+	return nil
+}
+
+func TestServerAttempt(t *testing.T) {
+	srv, cl := newTestServer()
+
+	h := &AttemptHandler{}
+	if err := srv.Handle(srv.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	var rsp TestValue
+	req := cl.NewRequest("test.service", "AttemptHandler.Call", &TestValue{Value: "foo"})
+	if err := cl.Call(context.Background(), req, &rsp, client.WithRetries(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %v", h.attempts)
+	}
+	for i, attempt := range h.attempts {
+		if attempt != i+1 {
+			t.Fatalf("Expected attempt %d, got %d", i+1, attempt)
+		}
+	}
+	if len(h.keys[0]) == 0 {
+		t.Fatal("Expected an idempotency key")
+	}
+	for _, key := range h.keys {
+		if key != h.keys[0] {
+			t.Fatalf("Expected the same idempotency key on every attempt, got %v", h.keys)
+		}
+	}
+
+	// a key set by the caller is kept
+	h.attempts, h.keys = nil, nil
+	h.Unlock()
+	err := cl.Call(context.Background(), req, &rsp, client.WithRetries(2), client.WithIdempotencyKey("order-1"))
+	h.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.keys) != 3 || h.keys[0] != "order-1" || h.keys[2] != "order-1" {
+		t.Fatalf("Expected the caller's idempotency key, got %v", h.keys)
+	}
+
+	// the headers of the request being served aren't forwarded
+	h.attempts, h.keys = nil, nil
+	h.Unlock()
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{
+		client.IdempotencyKeyHeader: "order-1",
+		client.AttemptHeader:        "7",
+	})
+	err = cl.Call(ctx, req, &rsp, client.WithRetries(2))
+	h.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.keys) != 3 || len(h.keys[0]) == 0 || h.keys[0] == "order-1" || h.keys[2] != h.keys[0] {
+		t.Fatalf("Expected a new idempotency key, got %v", h.keys)
+	}
+	if h.attempts[0] != 1 {
+		t.Fatalf("Expected the attempts to start at 1, got %v", h.attempts)
+	}
+}
+
+func TestServerStreamAttempt(t *testing.T) {
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(&StreamAttemptHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	req := cl.NewRequest("test.service", "StreamAttemptHandler.Stream", &TestValue{}, client.WithContentType("application/json"))
+	stream, err := cl.Stream(context.Background(), req, client.WithIdempotencyKey("order-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var rsp TestValue
+	if err := stream.Recv(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Value != "1 order-1" {
+		t.Fatalf("Expected the attempt and key of the stream, got %q", rsp.Value)
+	}
+}
+
+type StreamAttemptHandler struct{}
+
+func (h *StreamAttemptHandler) Stream(ctx context.Context, stream Stream) error {
+	attempt, _ := AttemptFromContext(ctx)
+	key, _ := IdempotencyKeyFromContext(ctx)
+	return stream.Send(&TestValue{Value: fmt.Sprintf("%d %s", attempt, key)})
+}
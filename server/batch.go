@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/debug/metrics"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/metadata"
+)
+
+// batcher accumulates the events for a batch subscriber
+// and delivers them to its handlers together.
+type batcher struct {
+	s        *rpcServer
+	sub      *subscriber
+	logger   log.Logger
+	meter    metrics.Meter
+	tags     map[string]string
+	wrappers []SubscriberWrapper
+	reporter PanicReporter
+
+	sync.Mutex
+	events []broker.Event
+	// generation of the pending batch, guards the wait timer
+	gen   int
+	timer *time.Timer
+}
+
+// batchSubscriber flushes the pending batch when unsubscribed.
+type batchSubscriber struct {
+	broker.Subscriber
+	b *batcher
+}
+
+func newBatcher(s *rpcServer, sub *subscriber, opts Options) *batcher {
+	return &batcher{
+		s:        s,
+		sub:      sub,
+		logger:   opts.Logger,
+		meter:    opts.Meter,
+		tags:     map[string]string{"topic": sub.topic},
+		wrappers: opts.SubWrappers,
+		reporter: opts.PanicReporter,
+	}
+}
+
+func (b *batcher) Handle(e broker.Event) error {
+	b.count(SubscriberReceived, 1)
+
+	skip := b.sub.opts.Filter != nil && !b.sub.opts.Filter(e.Message().Header)
+	if !skip {
+		_, skip = b.sub.duplicate(e.Message().Header)
+	}
+
+	// ack filtered and duplicate messages straight away
+	if skip {
+		if err := e.Ack(); err != nil {
+			return err
+		}
+		b.count(SubscriberProcessed, 1)
+		b.count(SubscriberAcked, 1)
+		return nil
+	}
+
+	b.Lock()
+
+	b.events = append(b.events, e)
+
+	// start waiting on the first message of a batch
+	if len(b.events) == 1 && b.sub.opts.BatchWait > 0 {
+		gen := b.gen
+		b.timer = time.AfterFunc(b.sub.opts.BatchWait, func() {
+			b.expire(gen)
+		})
+	}
+
+	if len(b.events) < b.sub.opts.BatchSize {
+		b.Unlock()
+		return nil
+	}
+
+	events := b.take()
+	b.Unlock()
+
+	b.process(events)
+
+	return nil
+}
+
+// take returns the pending batch and resets it, the lock must be held.
+func (b *batcher) take() []broker.Event {
+	events := b.events
+	b.events = nil
+	b.gen++
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	return events
+}
+
+func (b *batcher) expire(gen int) {
+	b.Lock()
+	if gen != b.gen || len(b.events) == 0 {
+		b.Unlock()
+		return
+	}
+	events := b.take()
+	b.Unlock()
+
+	b.process(events)
+}
+
+// flush delivers any pending messages.
+func (b *batcher) flush() {
+	b.Lock()
+	events := b.take()
+	b.Unlock()
+
+	if len(events) > 0 {
+		b.process(events)
+	}
+}
+
+// process delivers the events to the handlers then acks them
+// all if every handler succeeded, otherwise nacks them all.
+func (b *batcher) process(events []broker.Event) {
+	logger := b.logger
+
+	start := time.Now()
+	err := b.deliver(events)
+	if b.meter != nil {
+		b.meter.Time(SubscriberDuration, time.Since(start), b.tags)
+	}
+
+	if err != nil {
+		logger.Logf(log.ErrorLevel, "Batch of %d messages on topic %s failed: %v", len(events), b.sub.topic, err)
+		b.count(SubscriberFailed, int64(len(events)))
+	} else {
+		b.count(SubscriberProcessed, int64(len(events)))
+	}
+
+	for _, e := range events {
+		if err == nil {
+			b.sub.remember(b.sub.dedupKey(e.Message().Header))
+
+			if aerr := e.Ack(); aerr != nil {
+				logger.Logf(log.ErrorLevel, "Failed to ack message on topic %s: %v", b.sub.topic, aerr)
+				continue
+			}
+			b.count(SubscriberAcked, 1)
+			continue
+		}
+
+		if n, ok := e.(broker.Nacker); ok {
+			if nerr := n.Nack(); nerr != nil {
+				logger.Logf(log.ErrorLevel, "Failed to nack message on topic %s: %v", b.sub.topic, nerr)
+			}
+		}
+	}
+}
+
+func (b *batcher) count(name string, delta int64) {
+	if b.meter != nil {
+		b.meter.Count(name, delta, b.tags)
+	}
+}
+
+func (b *batcher) deliver(events []broker.Event) (err error) {
+	defer func() {
+		// recover any panics
+		if r := recover(); r != nil {
+			err = recoverPanic(context.Background(), b.reporter, b.logger, r)
+		}
+	}()
+
+	msgs := make([]*rpcMessage, 0, len(events))
+	for _, e := range events {
+		msg, err := newMessage(e.Message(), b.s.opts.Codecs, b.s.maxBodySize())
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	for _, handler := range b.sub.handlers {
+		elemType := handler.reqType.Elem()
+		batch := reflect.MakeSlice(handler.reqType, 0, len(msgs))
+
+		for _, msg := range msgs {
+			var req reflect.Value
+
+			// check whether the element is a pointer
+			if elemType.Kind() == reflect.Ptr {
+				req = reflect.New(elemType.Elem())
+			} else {
+				req = reflect.New(elemType)
+			}
+
+			cc := msg.Codec()
+
+			// read the header. mostly a noop but the codec
+			// may need the content type to decode the body
+			if err := cc.ReadHeader(&codec.Message{Header: msg.Header()}, codec.Event); err != nil {
+				return err
+			}
+
+			if err := cc.ReadBody(req.Interface()); err != nil {
+				return err
+			}
+
+			if elemType.Kind() != reflect.Ptr {
+				req = req.Elem()
+			}
+
+			batch = reflect.Append(batch, req)
+		}
+
+		fn := func(ctx context.Context, msg Message) error {
+			var vals []reflect.Value
+			if b.sub.typ.Kind() != reflect.Func {
+				vals = append(vals, b.sub.rcvr)
+			}
+			if handler.ctxType != nil {
+				vals = append(vals, reflect.ValueOf(ctx))
+			}
+
+			vals = append(vals, reflect.ValueOf(msg.Payload()))
+
+			returnValues := handler.method.Call(vals)
+			if rerr := returnValues[0].Interface(); rerr != nil {
+				return rerr.(error)
+			}
+			return nil
+		}
+
+		// wrap with subscriber wrappers
+		for i := len(b.wrappers); i > 0; i-- {
+			fn = b.wrappers[i-1](fn)
+		}
+
+		// the batch message carries the first message's header
+		rpcMsg := &rpcMessage{
+			topic:       b.sub.topic,
+			contentType: msgs[0].contentType,
+			payload:     batch.Interface(),
+			codec:       msgs[0].codec,
+			header:      msgs[0].header,
+		}
+
+		hdr := make(map[string]string, len(rpcMsg.header))
+		for k, v := range rpcMsg.header {
+			hdr[k] = v
+		}
+
+		if err := fn(metadata.NewContext(context.Background(), hdr), rpcMsg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (bs *batchSubscriber) Unsubscribe() error {
+	err := bs.Subscriber.Unsubscribe()
+	bs.b.flush()
+	return err
+}
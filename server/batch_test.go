@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+func TestServerSubscriberBatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		size    int
+		wait    time.Duration
+		publish int
+		batches []int
+	}{
+		{"size", 3, time.Minute, 6, []int{3, 3}},
+		{"time", 10, time.Millisecond * 50, 4, []int{4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, cl := newTestServer()
+
+			batches := make(chan []*TestValue, len(tc.batches))
+
+			fn := func(ctx context.Context, msgs []*TestValue) error {
+				batches <- msgs
+				return nil
+			}
+
+			if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn, SubscriberBatch(tc.size, tc.wait))); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			for i := 0; i < tc.publish; i++ {
+				msg := cl.NewMessage("test.topic", &TestValue{Value: strconv.Itoa(i)})
+				if err := cl.Publish(context.Background(), msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var n int
+			for _, size := range tc.batches {
+				select {
+				case msgs := <-batches:
+					if len(msgs) != size {
+						t.Fatalf("Expected batch of %d messages, got %d", size, len(msgs))
+					}
+					for _, msg := range msgs {
+						if msg.Value != strconv.Itoa(n) {
+							t.Fatalf("Expected message %d, got %s", n, msg.Value)
+						}
+						n++
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("Expected a batch of %d messages", size)
+				}
+			}
+
+			select {
+			case msgs := <-batches:
+				t.Fatalf("Unexpected batch of %d messages", len(msgs))
+			case <-time.After(time.Millisecond * 100):
+			}
+		})
+	}
+}
+
+func TestServerSubscriberBatchContext(t *testing.T) {
+	srv, cl := newTestServer()
+
+	topics := make(chan string, 1)
+
+	fn := func(ctx context.Context, msgs []*TestValue) error {
+		md, _ := metadata.FromContext(ctx)
+		topics <- md["Micro-Topic"]
+		return nil
+	}
+
+	// per message options don't apply to batches
+	for _, opt := range []SubscriberOption{SubscriberPrefetch(1), SubscriberTimeout(time.Second)} {
+		if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn, SubscriberBatch(1, time.Minute), opt)); err == nil {
+			t.Fatal("Expected the batch subscriber to be rejected")
+		}
+	}
+
+	if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn, SubscriberBatch(1, time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	if err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: "foo"})); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case topic := <-topics:
+		if topic != "test.topic" {
+			t.Fatalf("Expected the message header in the context, got topic %q", topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a batch")
+	}
+}
+
+func TestServerSubscriberBatchNack(t *testing.T) {
+	srv, cl := newTestServer()
+
+	var mtx sync.Mutex
+	var calls int
+	batches := make(chan []*TestValue, 4)
+
+	// fail the first batch, its messages are all redelivered
+	fn := func(ctx context.Context, msgs []*TestValue) error {
+		mtx.Lock()
+		calls++
+		n := calls
+		mtx.Unlock()
+
+		if n == 1 {
+			return errors.InternalServerError("test", "commit failed")
+		}
+
+		batches <- msgs
+		return nil
+	}
+
+	if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn, SubscriberBatch(2, time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	for _, v := range []string{"one", "two"} {
+		if err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: v})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case msgs := <-batches:
+		got := map[string]bool{}
+		for _, msg := range msgs {
+			got[msg.Value] = true
+		}
+		if len(got) != 2 || !got["one"] || !got["two"] {
+			t.Fatalf("Expected the failed batch to be redelivered, got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the failed batch to be redelivered")
+	}
+}
+
+func TestServerSubscriberBatchSignature(t *testing.T) {
+	srv, _ := newTestServer()
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		return nil
+	}
+
+	if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn, SubscriberBatch(2, time.Second))); err == nil {
+		t.Fatal("Expected an error subscribing a batch handler without a slice argument")
+	}
+}
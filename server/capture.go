@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-micro.dev/v4/debug/capture"
+	"go-micro.dev/v4/metadata"
+)
+
+// captureWrapper writes every request with its response to the capture.
+// Payloads are encoded as json so they're readable whatever the codec.
+func captureWrapper(c capture.Capture) HandlerWrapper {
+	return func(fn HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			// encode the request before the handler can modify it
+			request := capturePayload(req.Body())
+
+			start := time.Now()
+			err := fn(ctx, req, rsp)
+
+			rec := &capture.Record{
+				Timestamp: start,
+				Service:   req.Service(),
+				Endpoint:  req.Endpoint(),
+				Request:   request,
+				Duration:  time.Since(start),
+			}
+
+			if md, ok := metadata.FromContext(ctx); ok {
+				rec.Metadata = md
+			}
+
+			if err != nil {
+				rec.Error = err.Error()
+			} else {
+				rec.Response = capturePayload(rsp)
+			}
+
+			c.Write(rec)
+
+			return err
+		}
+	}
+}
+
+func capturePayload(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "<unable to marshal payload: " + err.Error() + ">"
+	}
+	return string(b)
+}
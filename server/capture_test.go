@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-micro.dev/v4/debug/capture"
+	"go-micro.dev/v4/metadata"
+)
+
+func TestServerCapture(t *testing.T) {
+	c := capture.NewCapture(capture.Size(3), capture.MaxPayload(20))
+
+	srv, cl := newTestServer(Capture(c))
+
+	if err := srv.Handle(srv.NewHandler(&EchoHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Handle(srv.NewHandler(&DeprecatedHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	call := func(endpoint, value string) {
+		ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Tenant": "acme"})
+		req := cl.NewRequest("test.service", endpoint, &TestValue{Value: value})
+		var rsp TestValue
+		if err := cl.Call(ctx, req, &rsp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		call("EchoHandler.Call", fmt.Sprintf("call-%d", i))
+	}
+	call("DeprecatedHandler.New", "a very long value which is truncated")
+
+	records, err := c.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the most recent are kept, oldest first
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[0].Request != `{"value":"call-2"}` || records[1].Response != `{"value":"call-3"}` {
+		t.Fatalf("Expected the most recent calls, got %+v and %+v", records[0], records[1])
+	}
+	if records[0].Endpoint != "EchoHandler.Call" || records[0].Service != "test.service" {
+		t.Fatalf("Unexpected service and endpoint %s %s", records[0].Service, records[0].Endpoint)
+	}
+	if records[0].Metadata["Tenant"] != "acme" {
+		t.Fatalf("Expected the metadata to be captured, got %v", records[0].Metadata)
+	}
+
+	last := records[2]
+	if last.Request != `{"value":"a very lon...` {
+		t.Fatalf("Expected the payload to be truncated, got %s", last.Request)
+	}
+
+	records, err = c.Read(capture.ReadEndpoint("EchoHandler.Call"), capture.ReadCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Request != `{"value":"call-3"}` {
+		t.Fatalf("Expected the last echo call, got %+v", records)
+	}
+}
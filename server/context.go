@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 type serverKey struct{}
@@ -26,3 +27,21 @@ func FromContext(ctx context.Context) (Server, bool) {
 func NewContext(ctx context.Context, s Server) context.Context {
 	return context.WithValue(ctx, serverKey{}, s)
 }
+
+// DeadlineRemaining returns how long is left until the deadline of the request,
+// set from the timeout of the call, so a handler can give up on expensive work
+// it won't finish in time. It's zero once the deadline has passed, ok is false
+// if there's no deadline.
+func DeadlineRemaining(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	remaining := time.Until(d)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return remaining, true
+}
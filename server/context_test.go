@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+)
+
+type DeadlineHandler struct{}
+
+func (h *DeadlineHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	remaining, ok := DeadlineRemaining(ctx)
+	if !ok {
+		return errors.BadRequest("test", "no deadline")
+	}
+	rsp.Value = remaining.String()
+	return nil
+}
+
+func TestServerDeadlineRemaining(t *testing.T) {
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(&DeadlineHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	timeout := time.Millisecond * 500
+
+	var rsp TestValue
+	req := cl.NewRequest("test.service", "DeadlineHandler.Call", &TestValue{})
+	if err := cl.Call(context.Background(), req, &rsp, client.WithRequestTimeout(timeout)); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := time.ParseDuration(rsp.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining > timeout || remaining < timeout-time.Millisecond*100 {
+		t.Fatalf("Expected close to %v remaining, got %v", timeout, remaining)
+	}
+
+	// without a deadline there's nothing remaining
+	if _, ok := DeadlineRemaining(context.Background()); ok {
+		t.Fatal("Expected no deadline")
+	}
+
+	// once passed there's no time left
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if remaining, ok := DeadlineRemaining(ctx); !ok || remaining != 0 {
+		t.Fatalf("Expected no time remaining, got %v", remaining)
+	}
+}
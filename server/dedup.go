@@ -0,0 +1,113 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultDedupSize is the most message keys a subscriber
+// remembers for deduplication, the oldest are evicted first.
+var DefaultDedupSize = 10000
+
+// dedupSet remembers the keys seen within the window.
+type dedupSet struct {
+	window time.Duration
+	size   int
+
+	sync.Mutex
+	keys map[string]*list.Element
+	// entries oldest first
+	order *list.List
+}
+
+type dedupEntry struct {
+	key  string
+	seen time.Time
+}
+
+func newDedupSet(window time.Duration, size int) *dedupSet {
+	return &dedupSet{
+		window: window,
+		size:   size,
+		keys:   make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// seen returns whether the key was added within the window.
+func (d *dedupSet) seen(key string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	d.expire(time.Now())
+
+	_, ok := d.keys[key]
+	return ok
+}
+
+// add records the key, returning false if it was seen within the window.
+func (d *dedupSet) add(key string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	now := time.Now()
+
+	d.expire(now)
+
+	if _, ok := d.keys[key]; ok {
+		return false
+	}
+
+	d.keys[key] = d.order.PushBack(&dedupEntry{key: key, seen: now})
+
+	if d.size > 0 && d.order.Len() > d.size {
+		d.evict(d.order.Front())
+	}
+
+	return true
+}
+
+// expire evicts the keys added before the window.
+func (d *dedupSet) expire(now time.Time) {
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		if now.Sub(e.Value.(*dedupEntry).seen) < d.window {
+			break
+		}
+		d.evict(e)
+	}
+}
+
+func (d *dedupSet) evict(e *list.Element) {
+	d.order.Remove(e)
+	delete(d.keys, e.Value.(*dedupEntry).key)
+}
+
+// dedupKey returns the dedup key of the message, it's
+// empty if the subscriber doesn't deduplicate messages.
+func (s *subscriber) dedupKey(header map[string]string) string {
+	if s.dedup == nil {
+		return ""
+	}
+	return s.opts.Dedup(header)
+}
+
+// duplicate returns the dedup key of the message and whether
+// it's a duplicate of one handled within the window.
+func (s *subscriber) duplicate(header map[string]string) (string, bool) {
+	key := s.dedupKey(header)
+	if len(key) == 0 {
+		return "", false
+	}
+
+	return key, s.dedup.seen(key)
+}
+
+// remember records the key of a message which was handled, so its
+// duplicates are skipped. Keys of failed messages aren't recorded so
+// their redeliveries aren't dropped.
+func (s *subscriber) remember(key string) {
+	if s.dedup != nil && len(key) > 0 {
+		s.dedup.add(key)
+	}
+}
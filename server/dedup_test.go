@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+func TestServerSubscriberDedup(t *testing.T) {
+	ab := &ackBroker{Broker: broker.NewMemoryBroker()}
+
+	srv, cl := newTestServer(Broker(ab))
+	if err := cl.Init(client.Broker(ab)); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 10)
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		received <- msg.Value
+		if msg.Value == "fail" {
+			return errors.InternalServerError("test", "failed")
+		}
+		acker, _ := AckerFromContext(ctx)
+		return acker.Ack()
+	}
+
+	key := func(header map[string]string) string {
+		return header["Type"]
+	}
+
+	window := time.Millisecond * 100
+
+	sub := srv.NewSubscriber("test.topic", fn, SubscriberAckMode(AckModeManual), SubscriberDedup(key, window))
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	publish := func(key, value string) {
+		ctx := metadata.NewContext(context.Background(), map[string]string{"Type": key})
+		// the memory broker returns the error of the failed message
+		if err := cl.Publish(ctx, cl.NewMessage("test.topic", &TestValue{Value: value})); err != nil && value != "fail" {
+			t.Fatal(err)
+		}
+	}
+
+	expect := func(want string) {
+		var got []string
+		for len(got) < len(strings.Split(want, ",")) {
+			select {
+			case v := <-received:
+				got = append(got, v)
+			case <-time.After(time.Second):
+				t.Fatalf("Expected messages %s, got %v", want, got)
+			}
+		}
+		if strings.Join(got, ",") != want {
+			t.Fatalf("Expected messages %s, got %v", want, got)
+		}
+	}
+
+	publish("a", "0")
+	// a duplicate within the window
+	publish("a", "1")
+	publish("b", "2")
+	// messages without a key aren't deduplicated
+	publish("", "3")
+	publish("", "4")
+	// a failed message can be redelivered
+	publish("c", "fail")
+	publish("c", "5")
+
+	expect("0,2,3,4,fail,5")
+
+	// outside the window
+	time.Sleep(window + time.Millisecond*20)
+	publish("a", "6")
+
+	expect("6")
+
+	select {
+	case v := <-received:
+		t.Fatalf("Unexpected message %s reached the handler", v)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	ab.Lock()
+	defer ab.Unlock()
+
+	// all but the failed message are acked, duplicates included
+	if strings.Join(ab.acked, ",") != "a,a,b,,,c,a" {
+		t.Fatalf("Expected the duplicate to be acked, got %v", ab.acked)
+	}
+}
+
+func TestDedupSetSize(t *testing.T) {
+	d := newDedupSet(time.Minute, 2)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if !d.add(key) {
+			t.Fatalf("Expected %s to be new", key)
+		}
+	}
+
+	// the oldest key was evicted to stay within the size
+	if !d.add("a") {
+		t.Fatal("Expected a to have been evicted")
+	}
+	if d.add("c") {
+		t.Fatal("Expected c to be a duplicate")
+	}
+	if len(d.keys) != 2 || d.order.Len() != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(d.keys))
+	}
+}
+
+func TestServerSubscriberDedupInFlight(t *testing.T) {
+	srv, cl := newTestServer()
+
+	started := make(chan bool)
+	release := make(chan bool)
+	received := make(chan string, 10)
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		received <- msg.Value
+		if msg.Value == "first" {
+			close(started)
+			<-release
+			return errors.InternalServerError("test", "failed")
+		}
+		return nil
+	}
+
+	key := func(header map[string]string) string {
+		return header["Type"]
+	}
+
+	sub := srv.NewSubscriber("test.topic", fn, SubscriberDedup(key, time.Minute))
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	publish := func(value string) error {
+		ctx := metadata.NewContext(context.Background(), map[string]string{"Type": "a"})
+		return cl.Publish(ctx, cl.NewMessage("test.topic", &TestValue{Value: value}))
+	}
+
+	go publish("first")
+	<-started
+
+	// the first is still being handled so the duplicate isn't skipped
+	done := make(chan error, 1)
+	go func() {
+		done <- publish("second")
+	}()
+
+	select {
+	case v := <-received:
+		if v != "first" {
+			t.Fatalf("Expected first, got %s", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the first message")
+	}
+
+	select {
+	case v := <-received:
+		if v != "second" {
+			t.Fatalf("Expected second, got %s", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the duplicate of a message in flight to be delivered")
+	}
+
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the second succeeded so later duplicates are skipped
+	if err := publish("third"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case v := <-received:
+		t.Fatalf("Unexpected message %s reached the handler", v)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-micro.dev/v4/registry"
+)
+
+const (
+	// DeprecationHeader is set on the responses of deprecated endpoints
+	DeprecationHeader = "Deprecation"
+	// SunsetHeader is the http date the endpoint will be removed
+	SunsetHeader = "Sunset"
+	// WarningHeader carries the deprecation message
+	WarningHeader = "Warning"
+)
+
+// Deprecation describes a deprecated endpoint.
+type Deprecation struct {
+	// Sunset is when the endpoint will be removed, it may be zero
+	Sunset time.Time
+	// Message tells callers what to use instead
+	Message string
+}
+
+// header returns the headers added to the responses of the endpoint.
+func (d Deprecation) header() map[string]string {
+	hdr := map[string]string{
+		DeprecationHeader: "true",
+	}
+
+	if !d.Sunset.IsZero() {
+		hdr[SunsetHeader] = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	if len(d.Message) > 0 {
+		hdr[WarningHeader] = "299 - " + strconv.Quote(d.Message)
+	}
+
+	return hdr
+}
+
+// String describes the deprecation for the logs and endpoint metadata.
+func (d Deprecation) String() string {
+	s := "deprecated"
+	if !d.Sunset.IsZero() {
+		s += fmt.Sprintf(", sunset %s", d.Sunset.UTC().Format(time.RFC3339))
+	}
+	if len(d.Message) > 0 {
+		s += ": " + d.Message
+	}
+	return s
+}
+
+// deprecateEndpoints returns the endpoints with the deprecated
+// ones copied and marked in their metadata.
+func deprecateEndpoints(endpoints []*registry.Endpoint, deprecated map[string]Deprecation) []*registry.Endpoint {
+	if len(deprecated) == 0 {
+		return endpoints
+	}
+
+	list := make([]*registry.Endpoint, 0, len(endpoints))
+
+	for _, e := range endpoints {
+		d, ok := deprecated[e.Name]
+		if !ok {
+			list = append(list, e)
+			continue
+		}
+
+		ep := *e
+		ep.Metadata = make(map[string]string, len(e.Metadata)+2)
+		for k, v := range e.Metadata {
+			ep.Metadata[k] = v
+		}
+		ep.Metadata["deprecated"] = "true"
+		if !d.Sunset.IsZero() {
+			ep.Metadata["sunset"] = d.Sunset.UTC().Format(time.RFC3339)
+		}
+		if len(d.Message) > 0 {
+			ep.Metadata["deprecation"] = d.Message
+		}
+
+		list = append(list, &ep)
+	}
+
+	return list
+}
+
+// caller names the caller of a request for the logs.
+func caller(hdr map[string]string, remote string) string {
+	if from := getHeader("Micro-From-Service", hdr); len(from) > 0 {
+		return from
+	}
+	return remote
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/transport"
+)
+
+type DeprecatedHandler struct{}
+
+func (h *DeprecatedHandler) Old(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	rsp.Value = req.Value
+	return nil
+}
+
+func (h *DeprecatedHandler) New(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	rsp.Value = req.Value
+	return nil
+}
+
+func TestServerDeprecateEndpoint(t *testing.T) {
+	sunset := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf := new(syncBuffer)
+
+	srv, _ := newTestServer(
+		DeprecateEndpoint("DeprecatedHandler.Old", sunset, "use DeprecatedHandler.New"),
+		WithLogger(log.NewLogger(log.WithFormat(log.JSONFormat), log.WithOutput(buf))),
+	)
+
+	if err := srv.Handle(srv.NewHandler(&DeprecatedHandler{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	call := func(endpoint string) transport.Message {
+		c, err := srv.Options().Transport.Dial(srv.Options().Address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		if err := c.Send(&transport.Message{
+			Header: map[string]string{
+				"Micro-Id":           "1",
+				"Micro-Service":      "test.service",
+				"Micro-Endpoint":     endpoint,
+				"Micro-From-Service": "test.caller",
+				"Content-Type":       "application/json",
+			},
+			Body: []byte(`{"value":"hello"}`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		var rsp transport.Message
+		if err := c.Recv(&rsp); err != nil {
+			t.Fatal(err)
+		}
+		return rsp
+	}
+
+	// the deprecated endpoint still succeeds
+	rsp := call("DeprecatedHandler.Old")
+	if e := rsp.Header["Micro-Error"]; len(e) > 0 {
+		t.Fatalf("Unexpected error %s", e)
+	}
+	if body := string(rsp.Body); !strings.Contains(body, `"value":"hello"`) {
+		t.Fatalf("Unexpected response %s", body)
+	}
+
+	expect := map[string]string{
+		DeprecationHeader: "true",
+		SunsetHeader:      "Wed, 02 Jan 2030 03:04:05 GMT",
+		WarningHeader:     `299 - "use DeprecatedHandler.New"`,
+	}
+	for k, v := range expect {
+		if got := rsp.Header[k]; got != v {
+			t.Fatalf("Expected header %s to be %q got %q", k, v, got)
+		}
+	}
+
+	if !strings.Contains(buf.String(), "Deprecated endpoint DeprecatedHandler.Old called by test.caller") {
+		t.Fatalf("Expected the call to be logged, got %s", buf.String())
+	}
+
+	rsp = call("DeprecatedHandler.New")
+	for k := range expect {
+		if v, ok := rsp.Header[k]; ok {
+			t.Fatalf("Unexpected header %s: %s", k, v)
+		}
+	}
+
+	// the endpoint is marked in the registry
+	services, err := srv.Options().Registry.GetService("test.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range services[0].Endpoints {
+		if deprecated := e.Metadata["deprecated"] == "true"; deprecated != (e.Name == "DeprecatedHandler.Old") {
+			t.Fatalf("Unexpected deprecation of %s: %v", e.Name, e.Metadata)
+		}
+	}
+}
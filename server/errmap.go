@@ -0,0 +1,27 @@
+package server
+
+import (
+	"go-micro.dev/v4/errors"
+	log "go-micro.dev/v4/logger"
+)
+
+// mapError returns the error to send to the client for the error
+// serving the endpoint, logging the original if it was mapped.
+func (s *rpcServer) mapError(endpoint string, err error) error {
+	s.RLock()
+	fn := s.opts.ErrorMapper
+	logger := s.opts.Logger
+	s.RUnlock()
+
+	if fn == nil {
+		return err
+	}
+
+	logger.Logf(log.ErrorLevel, "rpc: %s failed: %v", endpoint, err)
+
+	if merr := fn(err); merr != nil {
+		return merr
+	}
+
+	return errors.InternalServerError("go.micro.server", "internal server error")
+}
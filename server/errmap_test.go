@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	log "go-micro.dev/v4/logger"
+)
+
+type FailingHandler struct{}
+
+func (h *FailingHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	if req.Value == "missing" {
+		return errors.NotFound("test", "%s not found", req.Value)
+	}
+	return fmt.Errorf("connecting to db.internal:5432 as admin: connection refused")
+}
+
+func TestServerErrorMapper(t *testing.T) {
+	buf := new(syncBuffer)
+
+	// client errors are passed through, the rest are hidden
+	mapper := func(err error) error {
+		if e := errors.FromError(err); e.Code > 0 && e.Code < 500 {
+			return e
+		}
+		return nil
+	}
+
+	srv, cl := newTestServer(
+		ErrorMapper(mapper),
+		WithLogger(log.NewLogger(log.WithFormat(log.JSONFormat), log.WithOutput(buf))),
+	)
+
+	if err := srv.Handle(srv.NewHandler(&FailingHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	testCases := []struct {
+		value  string
+		code   int32
+		detail string
+	}{
+		{"missing", 404, "missing not found"},
+		{"foo", 500, "internal server error"},
+	}
+
+	for _, tc := range testCases {
+		var rsp TestValue
+		req := cl.NewRequest("test.service", "FailingHandler.Call", &TestValue{Value: tc.value})
+		err := cl.Call(context.Background(), req, &rsp, client.WithRetries(0))
+		if err == nil {
+			t.Fatalf("Expected an error for %s", tc.value)
+		}
+
+		e := errors.FromError(err)
+		if e.Code != tc.code || e.Detail != tc.detail {
+			t.Fatalf("Expected %d %q for %s, got %v", tc.code, tc.detail, tc.value, err)
+		}
+		if strings.Contains(err.Error(), "db.internal") {
+			t.Fatalf("Expected the internal detail to be hidden, got %v", err)
+		}
+	}
+
+	// the original error is logged
+	if !strings.Contains(buf.String(), "connecting to db.internal:5432 as admin") {
+		t.Fatalf("Expected the original error to be logged, got %s", buf.String())
+	}
+}
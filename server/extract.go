@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+
+	"go-micro.dev/v4/auth"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+// HeaderExtractor parses the value of a request header into a typed value
+// stored in the returned context. Returning an error rejects the request.
+type HeaderExtractor func(ctx context.Context, value string) (context.Context, error)
+
+// extractHeaders runs the extractor of every header present in the request.
+func (s *rpcServer) extractHeaders(ctx context.Context) (context.Context, error) {
+	s.RLock()
+	extractors := s.opts.HeaderExtractors
+	s.RUnlock()
+
+	for key, fn := range extractors {
+		val, ok := metadata.Get(ctx, key)
+		if !ok {
+			continue
+		}
+
+		var err error
+		if ctx, err = fn(ctx, val); err != nil {
+			return nil, errors.BadRequest("go.micro.server", "invalid %s header: %v", key, err)
+		}
+	}
+
+	return ctx, nil
+}
+
+// extractAccount sets the account resolved from the request headers in the context.
+func (s *rpcServer) extractAccount(ctx context.Context) (context.Context, error) {
+	s.RLock()
+	fn := s.opts.AccountExtractor
+	s.RUnlock()
+
+	if fn == nil {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromContext(ctx)
+
+	acc, err := fn(ctx, md)
+	if err != nil {
+		return nil, errors.Unauthorized("go.micro.server", "invalid account: %v", err)
+	}
+	if acc == nil {
+		return ctx, nil
+	}
+
+	return auth.ContextWithAccount(ctx, acc), nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+type tenantKey struct{}
+
+type TenantHandler struct{}
+
+func (h *TenantHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	tenant, ok := ctx.Value(tenantKey{}).(int)
+	if !ok {
+		return errors.BadRequest("test", "no tenant in context")
+	}
+	rsp.Value = strconv.Itoa(tenant)
+	return nil
+}
+
+func TestServerExtractHeader(t *testing.T) {
+	srv, cl := newTestServer(ExtractHeader("Tenant-Id", func(ctx context.Context, value string) (context.Context, error) {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		return context.WithValue(ctx, tenantKey{}, id), nil
+	}))
+
+	if err := srv.Handle(srv.NewHandler(&TenantHandler{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	call := func(tenant string) (string, error) {
+		ctx := context.Background()
+		if len(tenant) > 0 {
+			ctx = metadata.NewContext(ctx, metadata.Metadata{"Tenant-Id": tenant})
+		}
+		req := cl.NewRequest("test.service", "TenantHandler.Call", &TestValue{})
+		var rsp TestValue
+		err := cl.Call(ctx, req, &rsp)
+		return rsp.Value, err
+	}
+
+	val, err := call("42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "42" {
+		t.Fatalf("Expected tenant 42, got %s", val)
+	}
+
+	// invalid values reject the request
+	if _, err := call("abc"); errors.FromError(err).Code != 400 {
+		t.Fatalf("Expected a bad request, got %v", err)
+	}
+
+	// the extractor only runs when the header is present
+	if _, err := call(""); errors.FromError(err).Detail != "no tenant in context" {
+		t.Fatalf("Expected the handler to find no tenant, got %v", err)
+	}
+}
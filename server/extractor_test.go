@@ -2,9 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
+	"go-micro.dev/v4/auth"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
 	"go-micro.dev/v4/registry"
 )
 
@@ -62,3 +69,111 @@ func TestExtractEndpoint(t *testing.T) {
 		t.Errorf("Expected testResponse type got %s", endpoints[0].Response.Type)
 	}
 }
+
+type AccountHandler struct{}
+
+func (h *AccountHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	acc, ok := auth.AccountFromContext(ctx)
+	if !ok {
+		rsp.Value = "anonymous"
+		return nil
+	}
+	rsp.Value = acc.ID + ":" + acc.Type
+	return nil
+}
+
+func TestServerAccountExtractor(t *testing.T) {
+	// a jwt like token carrying the account in its payload
+	jwt := func(ctx context.Context, header map[string]string) (*auth.Account, error) {
+		token := strings.TrimPrefix(header["Authorization"], auth.BearerScheme)
+		if len(token) == 0 {
+			return nil, nil
+		}
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed token")
+		}
+		b, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		var acc auth.Account
+		if err := json.Unmarshal(b, &acc); err != nil {
+			return nil, err
+		}
+		return &acc, nil
+	}
+
+	// an opaque token looked up in a store
+	tokens := map[string]*auth.Account{
+		"opaque-1": {ID: "alice", Type: "user"},
+	}
+	opaque := func(ctx context.Context, header map[string]string) (*auth.Account, error) {
+		token := strings.TrimPrefix(header["Authorization"], auth.BearerScheme)
+		if len(token) == 0 {
+			return nil, nil
+		}
+		acc, ok := tokens[token]
+		if !ok {
+			return nil, auth.ErrInvalidToken
+		}
+		return acc, nil
+	}
+
+	payload, _ := json.Marshal(&auth.Account{ID: "bob", Type: "service"})
+	signed := "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+
+	testCases := []struct {
+		name      string
+		extractor func(context.Context, map[string]string) (*auth.Account, error)
+		token     string
+		account   string
+	}{
+		{"jwt", jwt, signed, "bob:service"},
+		{"opaque", opaque, "opaque-1", "alice:user"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			srv, cl := newTestServer(AccountExtractor(tc.extractor))
+
+			if err := srv.Handle(srv.NewHandler(&AccountHandler{})); err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			call := func(token string) (string, error) {
+				ctx := context.Background()
+				if len(token) > 0 {
+					ctx = metadata.NewContext(ctx, metadata.Metadata{"Authorization": auth.BearerScheme + token})
+				}
+				req := cl.NewRequest("test.service", "AccountHandler.Call", &TestValue{})
+				var rsp TestValue
+				err := cl.Call(ctx, req, &rsp)
+				return rsp.Value, err
+			}
+
+			val, err := call(tc.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if val != tc.account {
+				t.Fatalf("Expected account %s, got %s", tc.account, val)
+			}
+
+			// no token leaves the request anonymous
+			if val, err := call(""); err != nil || val != "anonymous" {
+				t.Fatalf("Expected an anonymous request, got %s %v", val, err)
+			}
+
+			// invalid tokens are rejected
+			if _, err := call("invalid"); errors.FromError(err).Code != 401 {
+				t.Fatalf("Expected an unauthorized error, got %v", err)
+			}
+		})
+	}
+}
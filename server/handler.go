@@ -1,12 +1,17 @@
 package server
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type HandlerOption func(*HandlerOptions)
 
 type HandlerOptions struct {
 	Internal bool
 	Metadata map[string]map[string]string
+	// Wrappers applied to individual methods keyed by method name
+	Wrappers map[string][]HandlerWrapper
 }
 
 type SubscriberOption func(*SubscriberOptions)
@@ -18,6 +23,22 @@ type SubscriberOptions struct {
 	Queue    string
 	Internal bool
 	Context  context.Context
+
+	// BatchSize enables batch delivery, up to BatchSize messages
+	// are passed to the handler at once
+	BatchSize int
+	// BatchWait is how long to wait for a batch to fill
+	BatchWait time.Duration
+	// Filter skips messages whose header it returns false for
+	Filter func(header map[string]string) bool
+	// Dedup returns the key of a message, those with a key
+	// seen within the DedupWindow are skipped
+	Dedup       func(header map[string]string) string
+	DedupWindow time.Duration
+	// Prefetch limits the unacked messages held at once, zero is unlimited
+	Prefetch int
+	// Timeout cancels the handler of a message taking longer, zero is none
+	Timeout time.Duration
 }
 
 // EndpointMetadata is a Handler option that allows metadata to be added to
@@ -28,6 +49,19 @@ func EndpointMetadata(name string, md map[string]string) HandlerOption {
 	}
 }
 
+// MethodWrapper wraps a single method of the handler e.g "Create". Method
+// wrappers run inside the server wrappers set with WrapHandler, so a request
+// passes through the server wrappers first then the method wrappers in the
+// order they were added.
+func MethodWrapper(method string, w ...HandlerWrapper) HandlerOption {
+	return func(o *HandlerOptions) {
+		if o.Wrappers == nil {
+			o.Wrappers = make(map[string][]HandlerWrapper)
+		}
+		o.Wrappers[method] = append(o.Wrappers[method], w...)
+	}
+}
+
 // Internal Handler options specifies that a handler is not advertised
 // to the discovery system. In the future this may also limit request
 // to the internal network or authorized user.
@@ -65,6 +99,82 @@ func DisableAutoAck() SubscriberOption {
 	}
 }
 
+// SubscriberAckMode sets how messages are acknowledged. In manual mode
+// auto ack is disabled and the handler acks or nacks the message using
+// the Acker from AckerFromContext e.g after committing its side effects.
+func SubscriberAckMode(mode AckMode) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.AutoAck = mode == AckModeAuto
+	}
+}
+
+// SubscriberBatch delivers messages to the subscriber in batches of up to size
+// messages, or whatever has arrived maxWait after the first message of a batch.
+// The handler takes a slice of the message type e.g
+//
+//	func(ctx context.Context, events []*proto.Event) error
+//
+// A batch is acked or nacked as a whole. When the handler returns nil every
+// message is acked, otherwise every message is nacked so the broker may
+// redeliver it. The ack mode of the subscriber is ignored, so the context
+// carries the first message's header as metadata but no Acker. It can't be
+// combined with SubscriberPrefetch or SubscriberTimeout, which work on
+// single messages.
+func SubscriberBatch(size int, maxWait time.Duration) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.BatchSize = size
+		o.BatchWait = maxWait
+	}
+}
+
+// SubscriberFilter only delivers messages whose header the filter returns
+// true for. Other messages are acked without invoking the handler so
+// they're not redelivered.
+func SubscriberFilter(fn func(header map[string]string) bool) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.Filter = fn
+	}
+}
+
+// SubscriberDedup skips messages whose key was seen within the window, e.g
+// duplicates redelivered by an at least once broker. The key is returned by
+// keyFn from the message header, messages with an empty key are always
+// delivered. Duplicates are acked without invoking the handler. A key is
+// only remembered once its message was handled, so the redelivery of one
+// which failed isn't skipped and a duplicate arriving while the first is
+// still being handled is delivered too. Up to DefaultDedupSize keys are
+// remembered.
+func SubscriberDedup(keyFn func(header map[string]string) string, window time.Duration) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.Dedup = keyFn
+		o.DedupWindow = window
+	}
+}
+
+// SubscriberPrefetch limits the subscriber to n unacked messages at once,
+// delivery is paused until an ack or nack frees a slot. With auto ack a
+// message holds its slot until the handler returns, in manual ack mode
+// until it's acked, nacked or the handler fails. A message the handler
+// returned without settling frees its slot after DefaultPrefetchAckTimeout.
+// It can't be used by batch subscribers, which are bounded by their batch size.
+func SubscriberPrefetch(n int) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.Prefetch = n
+	}
+}
+
+// SubscriberTimeout cancels the context of a handler still running after d
+// and stops waiting on it. The message is failed with ErrSubscriberTimeout,
+// in manual ack mode it's nacked so the broker redelivers it. Acks and
+// nacks by the handler after the timeout are ignored.
+// Its prefetch slot is freed even if the handler ignores the cancellation.
+// It can't be used by batch subscribers.
+func SubscriberTimeout(d time.Duration) SubscriberOption {
+	return func(o *SubscriberOptions) {
+		o.Timeout = d
+	}
+}
+
 // Shared queue name distributed messages across subscribers.
 func SubscriberQueue(n string) SubscriberOption {
 	return func(o *SubscriberOptions) {
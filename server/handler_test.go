@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+)
+
+type ResourceHandler struct{}
+
+func (h *ResourceHandler) Create(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	rsp.Value = req.Value
+	return nil
+}
+
+func (h *ResourceHandler) Health(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	rsp.Value = "ok"
+	return nil
+}
+
+func TestServerMethodWrapper(t *testing.T) {
+	var mtx sync.Mutex
+	var order []string
+
+	wrapper := func(id string) HandlerWrapper {
+		return func(fn HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, req Request, rsp interface{}) error {
+				mtx.Lock()
+				order = append(order, id)
+				mtx.Unlock()
+				return fn(ctx, req, rsp)
+			}
+		}
+	}
+
+	srv, cl := newTestServer(WrapHandler(wrapper("global")))
+
+	hdlr := srv.NewHandler(&ResourceHandler{},
+		MethodWrapper("Create", wrapper("auth"), wrapper("validate")),
+	)
+	if err := srv.Handle(hdlr); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	testCases := []struct {
+		endpoint string
+		order    string
+	}{
+		{"ResourceHandler.Create", "global,auth,validate"},
+		{"ResourceHandler.Health", "global"},
+	}
+
+	for _, tc := range testCases {
+		mtx.Lock()
+		order = nil
+		mtx.Unlock()
+
+		req := cl.NewRequest("test.service", tc.endpoint, &TestValue{Value: "foo"})
+		var rsp TestValue
+		if err := cl.Call(context.Background(), req, &rsp); err != nil {
+			t.Fatal(err)
+		}
+
+		mtx.Lock()
+		got := strings.Join(order, ",")
+		mtx.Unlock()
+
+		if got != tc.order {
+			t.Fatalf("Expected %s to run wrappers %s got %s", tc.endpoint, tc.order, got)
+		}
+	}
+
+	// wrappers for methods the handler doesn't have are rejected
+	bad := srv.NewHandler(&EchoHandler{}, MethodWrapper("Missing", wrapper("auth")))
+	if err := srv.Handle(bad); err == nil {
+		t.Fatal("Expected an error wrapping an unknown method")
+	}
+}
+
+func TestServerSubscriberFilter(t *testing.T) {
+	ab := &ackBroker{Broker: broker.NewMemoryBroker()}
+
+	srv, cl := newTestServer(Broker(ab))
+	if err := cl.Init(client.Broker(ab)); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 10)
+
+	// manual ack so filtered messages are only acked by the filter
+	fn := func(ctx context.Context, msg *TestValue) error {
+		received <- msg.Value
+		acker, _ := AckerFromContext(ctx)
+		return acker.Ack()
+	}
+
+	filter := func(header map[string]string) bool {
+		return header["Type"] == "keep"
+	}
+
+	sub := srv.NewSubscriber("test.topic", fn, SubscriberAckMode(AckModeManual), SubscriberFilter(filter))
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	for i, typ := range []string{"keep", "drop", "keep", "drop", ""} {
+		ctx := metadata.NewContext(context.Background(), map[string]string{"Type": typ})
+		msg := cl.NewMessage("test.topic", &TestValue{Value: strconv.Itoa(i)})
+		if err := cl.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case v := <-received:
+			got = append(got, v)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 matching messages, got %v", got)
+		}
+	}
+
+	if strings.Join(got, ",") != "0,2" {
+		t.Fatalf("Expected only the matching messages 0,2 got %v", got)
+	}
+
+	select {
+	case v := <-received:
+		t.Fatalf("Unexpected message %s reached the handler", v)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// every message is acked, filtered or not
+	ab.Lock()
+	defer ab.Unlock()
+
+	if len(ab.acked) != 5 {
+		t.Fatalf("Expected all 5 messages to be acked, got %v", ab.acked)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/metadata"
+)
+
+const (
+	// streamHeaderKey marks the frame carrying the stream header.
+	streamHeaderKey = "Micro-Stream-Header"
+	// headerPrefix marks the response headers carrying the stream header.
+	headerPrefix = "Micro-Header-"
+)
+
+// ErrHeaderSent is returned sending the header of a stream which
+// already sent its header or a message.
+var ErrHeaderSent = errors.New("server: stream header already sent")
+
+// HeaderSender is implemented by streams which can send a header.
+type HeaderSender interface {
+	SendHeader(md metadata.Metadata) error
+}
+
+// SendHeader sends metadata to the client ahead of the stream's first
+// message e.g to complete a handshake, clients read it with
+// client.StreamHeader. It can only be sent once, before any message.
+func SendHeader(s Stream, md metadata.Metadata) error {
+	hs, ok := s.(HeaderSender)
+	if !ok {
+		return errors.New("server: stream does not support sending a header")
+	}
+	return hs.SendHeader(md)
+}
+
+func (r *rpcStream) SendHeader(md metadata.Metadata) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.expired {
+		return r.context.Err()
+	}
+	if r.headerSent {
+		return ErrHeaderSent
+	}
+
+	header := make(map[string]string, len(md)+1)
+	for k, v := range md {
+		header[headerPrefix+k] = v
+	}
+	header[streamHeaderKey] = "true"
+
+	rsp := codec.Message{
+		Target:   r.request.Service(),
+		Method:   r.request.Method(),
+		Endpoint: r.request.Endpoint(),
+		Id:       r.id,
+		Type:     codec.Response,
+		Header:   header,
+	}
+
+	if err := r.codec.Write(&rsp, nil); err != nil {
+		r.err = err
+		return err
+	}
+
+	r.headerSent = true
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+)
+
+func TestServerStreamHeader(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		header   metadata.Metadata
+	}{
+		{"StreamHandler.Handshake", metadata.Metadata{"Version": "2", "Session": "abc"}},
+		// streams without a header have an empty one
+		{"StreamHandler.Echo", metadata.Metadata{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.endpoint, func(t *testing.T) {
+			srv, cl := newTestServer()
+
+			if err := srv.Handle(srv.NewHandler(&StreamHandler{})); err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			req := cl.NewRequest("test.service", tc.endpoint, &TestValue{}, client.WithContentType("application/json"))
+			stream, err := cl.Stream(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+
+			// the server handles the stream once the first message arrives
+			if err := stream.Send(&TestValue{Value: "foo"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// the header frame precedes the first message
+			header, err := client.StreamHeader(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(header, tc.header) {
+				t.Fatalf("Expected header %v, got %v", tc.header, header)
+			}
+
+			// the message read ahead by Header is still received
+			var rsp TestValue
+			if err := stream.Recv(&rsp); err != nil {
+				t.Fatal(err)
+			}
+			if rsp.Value != "foo" {
+				t.Fatalf("Expected foo, got %s", rsp.Value)
+			}
+
+			// the header doesn't change
+			if header, _ := client.StreamHeader(stream); !reflect.DeepEqual(header, tc.header) {
+				t.Fatalf("Expected header %v, got %v", tc.header, header)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"go-micro.dev/v4/errors"
+)
+
+// requestLimiter bounds the number of requests being handled at once.
+type requestLimiter struct {
+	max     int
+	timeout time.Duration
+	sem     chan struct{}
+}
+
+func newRequestLimiter(max int, timeout time.Duration) *requestLimiter {
+	if max <= 0 {
+		return nil
+	}
+
+	return &requestLimiter{
+		max:     max,
+		timeout: timeout,
+		sem:     make(chan struct{}, max),
+	}
+}
+
+// acquire takes a slot, queueing for up to the timeout when
+// they're all taken. Without a timeout it fails straight away.
+func (l *requestLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if l.timeout <= 0 {
+		return errors.New("go.micro.server", "too many concurrent requests", 429)
+	}
+
+	t := time.NewTimer(l.timeout)
+	defer t.Stop()
+
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-t.C:
+		return errors.New("go.micro.server", "too many concurrent requests, queued for "+l.timeout.String(), 429)
+	case <-ctx.Done():
+		return errors.Timeout("go.micro.server", "queued request: %v", ctx.Err())
+	}
+}
+
+func (l *requestLimiter) release() {
+	<-l.sem
+}
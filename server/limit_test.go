@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+)
+
+func TestServerMaxConcurrentRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		// whether the requests beyond the limit succeed once slots free up
+		queued bool
+	}{
+		{"reject", []Option{MaxConcurrentRequests(2)}, false},
+		{"queue", []Option{MaxConcurrentRequests(2), RequestQueueTimeout(5 * time.Second)}, true},
+		{"queue timeout", []Option{MaxConcurrentRequests(2), RequestQueueTimeout(50 * time.Millisecond)}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &BlockingHandler{
+				started: make(chan bool),
+				release: make(chan bool),
+			}
+
+			srv, cl := newTestServer(tc.opts...)
+
+			if err := srv.Handle(srv.NewHandler(h)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			requests := 4
+			errCh := make(chan error, requests)
+
+			for i := 0; i < requests; i++ {
+				go func() {
+					req := cl.NewRequest("test.service", "BlockingHandler.Call", &TestValue{Value: "foo"})
+					var rsp TestValue
+					errCh <- cl.Call(context.Background(), req, &rsp, client.WithRetries(0))
+				}()
+			}
+
+			// only the limit reach the handler
+			<-h.started
+			<-h.started
+
+			select {
+			case <-h.started:
+				t.Fatal("Expected no more than 2 requests to be handled at once")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			if !tc.queued {
+				// the rest fail without reaching the handler
+				for i := 0; i < requests-2; i++ {
+					err := <-errCh
+					if merr := errors.FromError(err); merr.Code != 429 {
+						t.Fatalf("Expected the request to be rejected, got %v", err)
+					}
+				}
+
+				h.release <- true
+				h.release <- true
+
+				for i := 0; i < 2; i++ {
+					if err := <-errCh; err != nil {
+						t.Fatal(err)
+					}
+				}
+				return
+			}
+
+			// queued requests are handled as slots free up
+			for i := 0; i < requests; i++ {
+				if i >= 2 {
+					<-h.started
+				}
+				h.release <- true
+			}
+
+			for i := 0; i < requests; i++ {
+				if err := <-errCh; err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
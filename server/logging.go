@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+
+	log "go-micro.dev/v4/logger"
+)
+
+var (
+	// DefaultPayloadLogSize is the max number of bytes of a payload logged by LogPayloads.
+	DefaultPayloadLogSize = 1024
+)
+
+// LogPayloads is a handler wrapper which logs the request and response payloads
+// for a sample of calls. A sampleRate of 0.1 logs roughly 10% of calls. Payloads
+// are marshaled to json and truncated to DefaultPayloadLogSize. Streams are not logged.
+func LogPayloads(sampleRate float64, logger log.Logger) HandlerWrapper {
+	logger = log.LoggerOrDefault(logger)
+
+	return func(fn HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			if req.Stream() || sampleRate <= 0 || rand.Float64() >= sampleRate {
+				return fn(ctx, req, rsp)
+			}
+
+			// marshal the request before the handler can modify it
+			request := truncatePayload(req.Body())
+
+			err := fn(ctx, req, rsp)
+
+			fields := map[string]interface{}{
+				"service":  req.Service(),
+				"endpoint": req.Endpoint(),
+				"request":  request,
+				"response": truncatePayload(rsp),
+			}
+
+			if err != nil {
+				fields["error"] = err.Error()
+			}
+
+			logger.Fields(fields).Log(log.InfoLevel, "Request payload")
+
+			return err
+		}
+	}
+}
+
+// truncatePayload marshals the payload to json and truncates it.
+func truncatePayload(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "<unable to marshal payload: " + err.Error() + ">"
+	}
+
+	if size := DefaultPayloadLogSize; size > 0 && len(b) > size {
+		return string(b[:size]) + "..."
+	}
+
+	return string(b)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	log "go-micro.dev/v4/logger"
+)
+
+// testLogger records the fields of each log entry.
+type testLogger struct {
+	sync.Mutex
+	fields  map[string]interface{}
+	entries *[]map[string]interface{}
+}
+
+func (l *testLogger) Init(...log.Option) error { return nil }
+
+func (l *testLogger) Options() log.Options { return log.Options{} }
+
+func (l *testLogger) Fields(fields map[string]interface{}) log.Logger {
+	return &testLogger{fields: fields, entries: l.entries}
+}
+
+func (l *testLogger) Log(level log.Level, v ...interface{}) {
+	l.Lock()
+	*l.entries = append(*l.entries, l.fields)
+	l.Unlock()
+}
+
+func (l *testLogger) Logf(level log.Level, format string, v ...interface{}) {
+	l.Log(level, v...)
+}
+
+func (l *testLogger) String() string { return "test" }
+
+func TestLogPayloads(t *testing.T) {
+	var entries []map[string]interface{}
+	logger := &testLogger{entries: &entries}
+
+	handler := func(ctx context.Context, req Request, rsp interface{}) error {
+		rsp.(*TestValue).Value = "bar"
+		return nil
+	}
+
+	fn := LogPayloads(0.25, logger)(handler)
+
+	calls := 2000
+
+	for i := 0; i < calls; i++ {
+		req := &rpcRequest{
+			service:  "test.service",
+			endpoint: "Test.Call",
+			rawBody:  &TestValue{Value: "foo"},
+		}
+
+		rsp := new(TestValue)
+		if err := fn(context.Background(), req, rsp); err != nil {
+			t.Fatal(err)
+		}
+
+		// the call is unaffected whether logged or not
+		if rsp.Value != "bar" {
+			t.Fatalf("Expected response bar got %s", rsp.Value)
+		}
+	}
+
+	// allow for variance around the expected 500
+	if n := len(entries); n < 350 || n > 650 {
+		t.Fatalf("Expected roughly %d entries got %d", calls/4, n)
+	}
+
+	e := entries[0]
+	if e["request"] != `{"value":"foo"}` || e["response"] != `{"value":"bar"}` {
+		t.Fatalf("Unexpected payloads %v %v", e["request"], e["response"])
+	}
+
+	// large payloads are truncated
+	entries = nil
+	fn = LogPayloads(1, logger)(handler)
+
+	req := &rpcRequest{rawBody: &TestValue{Value: strings.Repeat("x", DefaultPayloadLogSize*2)}}
+	if err := fn(context.Background(), req, new(TestValue)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(entries[0]["request"].(string)); n != DefaultPayloadLogSize+3 {
+		t.Fatalf("Expected truncated payload of %d got %d", DefaultPayloadLogSize+3, n)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/debug/metrics"
+)
+
+// The metrics recorded for subscribers, tagged by topic.
+const (
+	// SubscriberReceived counts the messages received
+	SubscriberReceived = "subscriber_messages_received"
+	// SubscriberProcessed counts the messages handled without error
+	SubscriberProcessed = "subscriber_messages_processed"
+	// SubscriberFailed counts the messages the handlers failed
+	SubscriberFailed = "subscriber_messages_failed"
+	// SubscriberAcked counts the messages acked
+	SubscriberAcked = "subscriber_messages_acked"
+	// SubscriberDuration times the handlers
+	SubscriberDuration = "subscriber_handler_duration"
+)
+
+// meterSubscriber records the metrics of the messages delivered to the handler.
+// If autoAck is set it acks the message once the handler succeeds in place of
+// the broker, so only acks which succeed are counted.
+func meterSubscriber(m metrics.Meter, topic string, autoAck bool, h broker.Handler) broker.Handler {
+	if m == nil {
+		return h
+	}
+
+	tags := map[string]string{"topic": topic}
+
+	return func(e broker.Event) error {
+		m.Count(SubscriberReceived, 1, tags)
+
+		// count the acks which succeed
+		if !autoAck {
+			e = newSettleEvent(e, func(acked bool, err error) {
+				if acked && err == nil {
+					m.Count(SubscriberAcked, 1, tags)
+				}
+			})
+		}
+
+		start := time.Now()
+		err := h(e)
+		m.Time(SubscriberDuration, time.Since(start), tags)
+
+		if err != nil {
+			m.Count(SubscriberFailed, 1, tags)
+			return err
+		}
+
+		m.Count(SubscriberProcessed, 1, tags)
+
+		if autoAck {
+			if err := e.Ack(); err != nil {
+				return err
+			}
+			m.Count(SubscriberAcked, 1, tags)
+		}
+
+		return nil
+	}
+}
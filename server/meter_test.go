@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/debug/metrics"
+	"go-micro.dev/v4/errors"
+)
+
+func TestServerSubscriberMetrics(t *testing.T) {
+	value := func(m metrics.Meter, name string) int64 {
+		ms, err := m.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, metric := range ms {
+			if metric.Name == name && metric.Tags["topic"] == "test.topic" {
+				return metric.Count
+			}
+		}
+		return 0
+	}
+
+	testCases := []struct {
+		name   string
+		mode   AckMode
+		expect map[string]int64
+	}{
+		{"auto ack", AckModeAuto, map[string]int64{
+			SubscriberReceived:  3,
+			SubscriberProcessed: 2,
+			SubscriberFailed:    1,
+			SubscriberAcked:     2,
+			SubscriberDuration:  3,
+		}},
+		// the failed message is left unacked
+		{"manual ack", AckModeManual, map[string]int64{
+			SubscriberReceived:  3,
+			SubscriberProcessed: 2,
+			SubscriberFailed:    1,
+			SubscriberAcked:     2,
+			SubscriberDuration:  3,
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := metrics.NewMeter()
+			srv, cl := newTestServer(Meter(m))
+
+			fn := func(ctx context.Context, msg *TestValue) error {
+				if msg.Value == "fail" {
+					return errors.InternalServerError("test", "failed")
+				}
+				if acker, ok := AckerFromContext(ctx); ok && tc.mode == AckModeManual {
+					return acker.Ack()
+				}
+				return nil
+			}
+
+			if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn, SubscriberAckMode(tc.mode))); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			// the memory broker returns the handler error
+			for _, v := range []string{"one", "fail", "two"} {
+				err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: v}))
+				if (err != nil) != (v == "fail") {
+					t.Fatalf("Unexpected publish error for %s: %v", v, err)
+				}
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for value(m, SubscriberDuration) < 3 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond * 10)
+			}
+
+			for name, expect := range tc.expect {
+				if got := value(m, name); got != expect {
+					t.Errorf("Expected %s to be %d got %d", name, expect, got)
+				}
+			}
+		})
+	}
+}
+
+type failingAckEvent struct {
+	countingEvent
+}
+
+func (e *failingAckEvent) Ack() error {
+	e.countingEvent.Ack()
+	return errors.InternalServerError("test", "ack failed")
+}
+
+func TestMeterSubscriberAckFailed(t *testing.T) {
+	m := metrics.NewMeter()
+
+	h := meterSubscriber(m, "test.topic", true, func(e broker.Event) error {
+		return nil
+	})
+
+	e := &failingAckEvent{}
+	if err := h(e); err == nil {
+		t.Fatal("Expected the ack error")
+	}
+	if e.acks != 1 {
+		t.Fatalf("Expected the meter to ack once, got %d", e.acks)
+	}
+
+	ms, err := m.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range ms {
+		if metric.Name == SubscriberAcked {
+			t.Fatalf("Expected the failed ack not to be counted, got %d", metric.Count)
+		}
+	}
+}
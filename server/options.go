@@ -6,8 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"go-micro.dev/v4/auth"
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/debug/capture"
+	"go-micro.dev/v4/debug/metrics"
 	"go-micro.dev/v4/debug/trace"
 	"go-micro.dev/v4/logger"
 	"go-micro.dev/v4/registry"
@@ -44,6 +47,7 @@ type Options struct {
 	Broker        broker.Broker
 	Registry      registry.Registry
 	Tracer        trace.Tracer
+	Meter         metrics.Meter
 	Transport     transport.Transport
 	Metadata      map[string]string
 	Name          string
@@ -56,16 +60,67 @@ type Options struct {
 	ListenOptions []transport.ListenOption
 	Logger        logger.Logger
 
+	// PreferAddresses choose the address registered when bound to a
+	// wildcard address, each is an interface name or a CIDR block
+	PreferAddresses []string
+
 	// RegisterCheck runs a check function before registering the service
 	RegisterCheck func(context.Context) error
+	// RequestID generates an id for requests received without one
+	RequestID func() string
 	// The register expiry time
 	RegisterTTL time.Duration
 	// The interval on which to register
 	RegisterInterval time.Duration
+	// RegisterJitter randomly varies the register interval by up to the
+	// fraction either way e.g 0.1 for 10%, spreading out re-registrations
+	RegisterJitter float64
 
 	// The router for requests
 	Router Router
 
+	// AllowEndpoints restricts the callable endpoints to those matching
+	// a pattern, DenyEndpoints disables those matching. Deny wins.
+	AllowEndpoints []string
+	DenyEndpoints  []string
+
+	// DeprecatedEndpoints are still served, with responses
+	// warning callers they're deprecated
+	DeprecatedEndpoints map[string]Deprecation
+
+	// ListMethods includes the handler's methods in the
+	// error returned when calling a method it doesn't have
+	ListMethods bool
+
+	// StreamFrameErrors keeps a stream open when a received
+	// frame fails to decode, returning an error for just that frame
+	StreamFrameErrors bool
+
+	// Capture records recent requests and responses for debugging
+	Capture capture.Capture
+
+	// PanicReporter is passed the panics recovered from handlers
+	PanicReporter PanicReporter
+
+	// MaxConcurrentRequests bounds the number of requests handled
+	// at once, zero is unlimited
+	MaxConcurrentRequests int
+	// RequestQueueTimeout is how long a request waits for a free slot
+	// once the limit is reached, zero rejects it immediately
+	RequestQueueTimeout time.Duration
+
+	// HeaderExtractors convert request headers into context values,
+	// keyed by header
+	HeaderExtractors map[string]HeaderExtractor
+
+	// AccountExtractor resolves the account making a request from
+	// its headers, it's set in the context passed to the handler
+	AccountExtractor func(ctx context.Context, header map[string]string) (*auth.Account, error)
+
+	// ErrorMapper maps the errors returned by handlers to those
+	// sent to the client
+	ErrorMapper func(err error) error
+
 	// TLSConfig specifies tls.Config for secure serving
 	TLSConfig *tls.Config
 
@@ -103,6 +158,10 @@ func newOptions(opt ...Option) Options {
 		opts.RegisterCheck = DefaultRegisterCheck
 	}
 
+	if opts.RequestID == nil {
+		opts.RequestID = DefaultRequestID
+	}
+
 	if len(opts.Address) == 0 {
 		opts.Address = DefaultAddress
 	}
@@ -157,6 +216,60 @@ func Advertise(a string) Option {
 	}
 }
 
+// DeprecateEndpoint marks the endpoint e.g Greeter.Hello deprecated. It's
+// still served but the responses carry Deprecation, Sunset and Warning
+// headers, calls to it are logged and it's marked in the registry. The
+// sunset may be zero if there's no date for its removal yet.
+func DeprecateEndpoint(name string, sunset time.Time, message string) Option {
+	return func(o *Options) {
+		if o.DeprecatedEndpoints == nil {
+			o.DeprecatedEndpoints = make(map[string]Deprecation)
+		}
+		o.DeprecatedEndpoints[name] = Deprecation{
+			Sunset:  sunset,
+			Message: message,
+		}
+	}
+}
+
+// ListMethods includes the available methods of a handler in the not found
+// error returned for calls to a method it doesn't have. It's off by default
+// as it reveals the methods to any caller.
+func ListMethods(b bool) Option {
+	return func(o *Options) {
+		o.ListMethods = b
+	}
+}
+
+// StreamFrameErrors isolates decode errors to the stream frame they occur
+// in. A frame which fails to decode is skipped and an error returned to the
+// client for it, the stream stays open for the frames which follow. By
+// default a bad frame fails the Recv call of the handler.
+func StreamFrameErrors(b bool) Option {
+	return func(o *Options) {
+		o.StreamFrameErrors = b
+	}
+}
+
+// Capture records the requests served with their responses and metadata,
+// e.g capture.DefaultCapture which is read by the Debug.Capture endpoint.
+// Streams aren't captured. Metadata is kept as is, including credentials,
+// so it's meant for debugging rather than left on.
+func Capture(c capture.Capture) Option {
+	return func(o *Options) {
+		o.Capture = c
+	}
+}
+
+// PreferAddress sets the interfaces e.g eth0 or CIDR blocks e.g 10.0.0.0/8
+// to take the registered address from, in order, when the server is bound
+// to a wildcard address like 0.0.0.0 and there's no advertise address.
+func PreferAddress(prefer ...string) Option {
+	return func(o *Options) {
+		o.PreferAddresses = prefer
+	}
+}
+
 // Broker to use for pub/sub.
 func Broker(b broker.Broker) Option {
 	return func(o *Options) {
@@ -187,6 +300,14 @@ func Registry(r registry.Registry) Option {
 	}
 }
 
+// Meter to record the subscriber metrics with, e.g metrics.DefaultMeter.
+// Subscribers aren't metered without one.
+func Meter(m metrics.Meter) Option {
+	return func(o *Options) {
+		o.Meter = m
+	}
+}
+
 // Tracer mechanism for distributed tracking.
 func Tracer(t trace.Tracer) Option {
 	return func(o *Options) {
@@ -215,6 +336,72 @@ func RegisterCheck(fn func(context.Context) error) Option {
 	}
 }
 
+// RequestIDGenerator sets the func used to generate ids for
+// requests which arrive without an X-Request-ID header.
+func RequestIDGenerator(fn func() string) Option {
+	return func(o *Options) {
+		o.RequestID = fn
+	}
+}
+
+// MaxConcurrentRequests limits the number of requests handled at once,
+// streams count until they end. Requests beyond the limit are rejected
+// with a 429 error unless a RequestQueueTimeout is set.
+func MaxConcurrentRequests(n int) Option {
+	return func(o *Options) {
+		o.MaxConcurrentRequests = n
+	}
+}
+
+// RequestQueueTimeout queues requests beyond the MaxConcurrentRequests
+// limit for up to d before rejecting them. The request deadline still applies.
+func RequestQueueTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.RequestQueueTimeout = d
+	}
+}
+
+// ExtractHeader runs fn once per request carrying the header, letting it
+// store the parsed value in the context passed to the handler.
+func ExtractHeader(key string, fn HeaderExtractor) Option {
+	return func(o *Options) {
+		if o.HeaderExtractors == nil {
+			o.HeaderExtractors = make(map[string]HeaderExtractor)
+		}
+		o.HeaderExtractors[key] = fn
+	}
+}
+
+// AccountExtractor resolves the account making each request from its headers
+// before the handler is called, e.g by parsing a JWT or looking up an opaque
+// token. The account is available to handlers from auth.AccountFromContext.
+// A nil account leaves the request anonymous, an error rejects it as
+// unauthorized.
+func AccountExtractor(fn func(ctx context.Context, header map[string]string) (*auth.Account, error)) Option {
+	return func(o *Options) {
+		o.AccountExtractor = fn
+	}
+}
+
+// ErrorMapper maps every error returned while serving a request to the
+// error sent to the client, e.g to hide internal detail or map errors to
+// status codes. The original error is logged. Returning nil sends a
+// generic internal server error.
+func ErrorMapper(fn func(err error) error) Option {
+	return func(o *Options) {
+		o.ErrorMapper = fn
+	}
+}
+
+// WithPanicReporter reports the panics recovered from handlers and
+// subscribers to r, along with their stack and request context. Panics
+// are still recovered and logged if reporting fails.
+func WithPanicReporter(r PanicReporter) Option {
+	return func(o *Options) {
+		o.PanicReporter = r
+	}
+}
+
 // Register the service with a TTL.
 func RegisterTTL(t time.Duration) Option {
 	return func(o *Options) {
@@ -229,6 +416,15 @@ func RegisterInterval(t time.Duration) Option {
 	}
 }
 
+// RegisterJitter varies the register interval by a random amount of up to
+// the fraction of it either way, e.g 0.1 for 10%. The interval is capped
+// below the TTL so the service doesn't expire between registrations.
+func RegisterJitter(j float64) Option {
+	return func(o *Options) {
+		o.RegisterJitter = j
+	}
+}
+
 // TLSConfig specifies a *tls.Config.
 func TLSConfig(t *tls.Config) Option {
 	return func(o *Options) {
@@ -249,6 +445,22 @@ func TLSConfig(t *tls.Config) Option {
 	}
 }
 
+// AllowEndpoints only allows calls to endpoints matching one of the
+// patterns e.g "Greeter.*". Patterns use path.Match syntax.
+func AllowEndpoints(eps ...string) Option {
+	return func(o *Options) {
+		o.AllowEndpoints = eps
+	}
+}
+
+// DenyEndpoints disables calls to endpoints matching one of the
+// patterns e.g "Debug.*". A denied endpoint can't be allowed.
+func DenyEndpoints(eps ...string) Option {
+	return func(o *Options) {
+		o.DenyEndpoints = eps
+	}
+}
+
 // WithRouter sets the request router.
 func WithRouter(r Router) Option {
 	return func(o *Options) {
@@ -280,6 +492,8 @@ func Wait(wg *sync.WaitGroup) Option {
 }
 
 // Adds a handler Wrapper to a list of options passed into the server.
+// Wrappers execute in the order they are added, the first added is
+// the outermost and sees the request first.
 func WrapHandler(w HandlerWrapper) Option {
 	return func(o *Options) {
 		o.HdlrWrappers = append(o.HdlrWrappers, w)
@@ -287,6 +501,8 @@ func WrapHandler(w HandlerWrapper) Option {
 }
 
 // Adds a subscriber Wrapper to a list of options passed into the server.
+// Wrappers execute in the order they are added, the first added is
+// the outermost and sees the message first.
 func WrapSubscriber(w SubscriberWrapper) Option {
 	return func(o *Options) {
 		o.SubWrappers = append(o.SubWrappers, w)
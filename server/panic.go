@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"runtime/debug"
+
+	merrors "go-micro.dev/v4/errors"
+	log "go-micro.dev/v4/logger"
+)
+
+// PanicReporter ships the panics recovered while serving requests
+// and messages, e.g to an error tracking service.
+type PanicReporter interface {
+	// ReportPanic is passed the context of the request, the value
+	// recovered and the stack of the goroutine which panicked
+	ReportPanic(ctx context.Context, value interface{}, stack []byte) error
+}
+
+// PanicReporterFunc is an adapter to use a func as a PanicReporter.
+type PanicReporterFunc func(ctx context.Context, value interface{}, stack []byte) error
+
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, value interface{}, stack []byte) error {
+	return f(ctx, value, stack)
+}
+
+// reportPanic passes a recovered panic to the reporter. Its errors and
+// panics are logged so they don't get in the way of the recovery.
+func reportPanic(ctx context.Context, r PanicReporter, logger log.Logger, value interface{}, stack []byte) {
+	if r == nil {
+		return
+	}
+
+	defer func() {
+		if rerr := recover(); rerr != nil {
+			logger.Logf(log.ErrorLevel, "Panic reporter panicked: %v", rerr)
+		}
+	}()
+
+	if err := r.ReportPanic(ctx, value, stack); err != nil {
+		logger.Logf(log.ErrorLevel, "Failed to report panic: %v", err)
+	}
+}
+
+// recoverPanic logs and reports the value recovered from a panic,
+// returning the error to fail the request or message with. It must
+// be called from the deferred func so the stack is the panicking one.
+func recoverPanic(ctx context.Context, r PanicReporter, logger log.Logger, value interface{}) error {
+	stack := debug.Stack()
+	logger.Logf(log.ErrorLevel, "panic recovered: %v", value)
+	logger.Log(log.ErrorLevel, string(stack))
+	reportPanic(ctx, r, logger, value, stack)
+	return merrors.InternalServerError("go.micro.server", "panic recovered: %v", value)
+}
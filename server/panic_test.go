@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/metadata"
+)
+
+type PanicHandler struct{}
+
+func (h *PanicHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	panic("boom " + req.Value)
+}
+
+type panicReport struct {
+	value interface{}
+	stack string
+	md    metadata.Metadata
+}
+
+func TestServerPanicReporter(t *testing.T) {
+	reports := make(chan panicReport, 10)
+
+	testCases := []struct {
+		name     string
+		reporter PanicReporterFunc
+	}{
+		{"report", func(ctx context.Context, value interface{}, stack []byte) error {
+			md, _ := metadata.FromContext(ctx)
+			reports <- panicReport{value, string(stack), md}
+			return nil
+		}},
+		// failing reporters don't affect the server
+		{"error", func(ctx context.Context, value interface{}, stack []byte) error {
+			return fmt.Errorf("reporter unavailable")
+		}},
+		{"panic", func(ctx context.Context, value interface{}, stack []byte) error {
+			panic("reporter panicked")
+		}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			srv, cl := newTestServer(WithPanicReporter(tc.reporter))
+
+			if err := srv.Handle(srv.NewHandler(&PanicHandler{})); err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.Handle(srv.NewHandler(&EchoHandler{})); err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Tenant": "acme"})
+
+			var rsp TestValue
+			req := cl.NewRequest("test.service", "PanicHandler.Call", &TestValue{Value: "foo"})
+			if err := cl.Call(ctx, req, &rsp, client.WithRetries(0), client.WithRequestTimeout(time.Millisecond*100)); err == nil {
+				t.Fatal("Expected the call to fail")
+			}
+
+			if tc.name == "report" {
+				select {
+				case r := <-reports:
+					if r.value != "boom foo" {
+						t.Fatalf("Expected the panic value, got %v", r.value)
+					}
+					if !strings.Contains(r.stack, "PanicHandler") {
+						t.Fatalf("Expected the stack of the handler, got %s", r.stack)
+					}
+					if r.md["Tenant"] != "acme" {
+						t.Fatalf("Expected the request context, got %v", r.md)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("Expected the panic to be reported")
+				}
+			}
+
+			// the server still serves requests
+			req = cl.NewRequest("test.service", "EchoHandler.Call", &TestValue{Value: "bar"})
+			if err := cl.Call(context.Background(), req, &rsp, client.WithRetries(0)); err != nil {
+				t.Fatal(err)
+			}
+			if rsp.Value != "bar" {
+				t.Fatalf("Expected bar, got %s", rsp.Value)
+			}
+		})
+	}
+}
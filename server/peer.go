@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+
+	"go-micro.dev/v4/transport"
+)
+
+// Peer describes the remote end of the connection a request arrived on.
+type Peer struct {
+	// Addr is the remote address of the connection
+	Addr string
+	// Certificates presented by the peer over tls, leaf first
+	Certificates []*x509.Certificate
+	// TLS is the connection state, nil for plain connections
+	TLS *tls.ConnectionState
+}
+
+type peerKey struct{}
+
+func newPeer(sock transport.Socket) *Peer {
+	p := &Peer{
+		Addr: sock.Remote(),
+	}
+
+	if ts, ok := sock.(transport.TLSSocket); ok {
+		if state := ts.ConnectionState(); state != nil {
+			p.TLS = state
+			p.Certificates = state.PeerCertificates
+		}
+	}
+
+	return p
+}
+
+// PeerFromContext returns the peer of the request being handled.
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(*Peer)
+	return p, ok
+}
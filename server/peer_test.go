@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/transport"
+	mls "go-micro.dev/v4/util/tls"
+)
+
+type PeerHandler struct{}
+
+func (h *PeerHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	peer, ok := PeerFromContext(ctx)
+	if !ok {
+		return errors.InternalServerError("test", "no peer in context")
+	}
+	if len(peer.Addr) == 0 {
+		return errors.InternalServerError("test", "no peer address")
+	}
+	if len(peer.Certificates) == 0 {
+		return errors.Unauthorized("test", "no peer certificate")
+	}
+	rsp.Value = peer.Certificates[0].Subject.CommonName
+	return nil
+}
+
+// testClientCertificate creates a self signed client certificate for the common name.
+func testClientCertificate(t *testing.T, cn string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+func TestServerPeerCertificate(t *testing.T) {
+	serverCert, err := mls.Certificate("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert := testClientCertificate(t, "test-client")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	srv, cl := newTestServer(
+		Address("127.0.0.1:0"),
+		Transport(transport.NewHTTPTransport(transport.TLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		}))),
+	)
+
+	if err := cl.Init(client.Transport(transport.NewHTTPTransport(transport.TLSConfig(&tls.Config{
+		Certificates:       []tls.Certificate{clientCert},
+		InsecureSkipVerify: true,
+	})))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Handle(srv.NewHandler(&PeerHandler{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	req := cl.NewRequest("test.service", "PeerHandler.Call", &TestValue{})
+	var rsp TestValue
+	if err := cl.Call(context.Background(), req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if rsp.Value != "test-client" {
+		t.Fatalf("Expected peer certificate subject test-client, got %s", rsp.Value)
+	}
+}
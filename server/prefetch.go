@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	log "go-micro.dev/v4/logger"
+)
+
+// DefaultPrefetchAckTimeout is how long a message delivered in manual ack
+// mode holds its prefetch slot unsettled after its handler returned.
+var DefaultPrefetchAckTimeout = time.Minute
+
+// prefetchSlot is held by a message until it's freed, once.
+type prefetchSlot struct {
+	release func()
+
+	sync.Mutex
+	freed bool
+	timer *time.Timer
+}
+
+func (s *prefetchSlot) free() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.freed {
+		return
+	}
+	s.freed = true
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	s.release()
+}
+
+// expire calls fn after d unless the slot is freed first.
+func (s *prefetchSlot) expire(d time.Duration, fn func()) {
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.freed {
+		s.timer = time.AfterFunc(d, fn)
+	}
+}
+
+// prefetchSubscriber blocks delivery to the handler while n messages are unacked.
+// With autoAck a message is done when the handler returns, otherwise when it's
+// acked or nacked, or the handler fails. A message the handler returned without
+// settling frees its slot after the ackTimeout so it can't hold it forever.
+func prefetchSubscriber(n int, autoAck bool, ackTimeout time.Duration, logger log.Logger, h broker.Handler) broker.Handler {
+	if n <= 0 {
+		return h
+	}
+
+	slots := make(chan struct{}, n)
+	release := func() { <-slots }
+
+	return func(e broker.Event) error {
+		slots <- struct{}{}
+
+		if autoAck {
+			defer release()
+			return h(e)
+		}
+
+		slot := &prefetchSlot{release: release}
+
+		pe := newSettleEvent(e, func(bool, error) {
+			slot.free()
+		})
+
+		if err := h(pe); err != nil {
+			slot.free()
+			return err
+		}
+
+		if ackTimeout > 0 {
+			slot.expire(ackTimeout, func() {
+				if !pe.isSettled() {
+					logger.Logf(log.WarnLevel, "Message on topic %s not acked within %v, freeing its prefetch slot", e.Topic(), ackTimeout)
+				}
+				slot.free()
+			})
+		}
+
+		return nil
+	}
+}
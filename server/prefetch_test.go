@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+)
+
+func TestServerSubscriberPrefetch(t *testing.T) {
+	prefetch := 2
+
+	for _, mode := range []AckMode{AckModeAuto, AckModeManual} {
+		srv, cl := newTestServer()
+		b := srv.Options().Broker
+		if err := cl.Init(client.Broker(b)); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			mtx      sync.Mutex
+			inflight int
+			peak     int
+			wg       sync.WaitGroup
+		)
+
+		done := func() {
+			mtx.Lock()
+			inflight--
+			mtx.Unlock()
+			wg.Done()
+		}
+
+		fn := func(ctx context.Context, msg *TestValue) error {
+			mtx.Lock()
+			inflight++
+			if inflight > peak {
+				peak = inflight
+			}
+			mtx.Unlock()
+
+			if mode == AckModeAuto {
+				time.Sleep(time.Millisecond * 10)
+				done()
+				return nil
+			}
+
+			// ack after the handler returns
+			acker, _ := AckerFromContext(ctx)
+			go func() {
+				time.Sleep(time.Millisecond * 10)
+				done()
+				acker.Ack()
+			}()
+			return nil
+		}
+
+		sub := srv.NewSubscriber("test.topic", fn, SubscriberAckMode(mode), SubscriberPrefetch(prefetch))
+		if err := srv.Subscribe(sub); err != nil {
+			t.Fatal(err)
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		n := 10
+		wg.Add(n)
+
+		var pwg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			pwg.Add(1)
+			go func(i int) {
+				defer pwg.Done()
+				if err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: fmt.Sprint(i)})); err != nil {
+					t.Error(err)
+				}
+			}(i)
+		}
+		pwg.Wait()
+		wg.Wait()
+
+		srv.Stop()
+
+		if peak > prefetch {
+			t.Fatalf("Expected at most %d messages in flight with %v, got %d", prefetch, mode, peak)
+		}
+		if peak < prefetch {
+			t.Fatalf("Expected %d messages in flight with %v, got %d", prefetch, mode, peak)
+		}
+	}
+}
+
+func TestServerSubscriberPrefetchAckTimeout(t *testing.T) {
+	timeout := DefaultPrefetchAckTimeout
+	DefaultPrefetchAckTimeout = time.Millisecond * 50
+	defer func() { DefaultPrefetchAckTimeout = timeout }()
+
+	srv, cl := newTestServer()
+	b := srv.Options().Broker
+	if err := cl.Init(client.Broker(b)); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan struct{}, 2)
+
+	// never ack or nack
+	fn := func(ctx context.Context, msg *TestValue) error {
+		received <- struct{}{}
+		return nil
+	}
+
+	sub := srv.NewSubscriber("test.topic", fn, SubscriberAckMode(AckModeManual), SubscriberPrefetch(1))
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	errCh := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			errCh <- cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: fmt.Sprint(i)}))
+		}(i)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected message %d to be delivered once the unacked one freed its slot", i)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"go-micro.dev/v4/metadata"
+)
+
+// RequestIDHeader is the header used to carry the request id.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDFromContext returns the id of the request being served.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	return metadata.Get(ctx, RequestIDHeader)
+}
+
+// setRequestID makes sure the request headers carry exactly one request id,
+// preserving the one sent by the caller or generating a new one.
+func setRequestID(hdr map[string]string, gen func() string) string {
+	var id string
+
+	// headers may be canonicalised by the transport so match on any case
+	for k, v := range hdr {
+		if !strings.EqualFold(k, RequestIDHeader) {
+			continue
+		}
+		if len(v) > 0 {
+			id = v
+		}
+		delete(hdr, k)
+	}
+
+	if len(id) == 0 {
+		id = gen()
+	}
+
+	hdr[RequestIDHeader] = id
+
+	return id
+}
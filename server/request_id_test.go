@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-micro.dev/v4/transport"
+)
+
+type RequestIDHandler struct{}
+
+func (h *RequestIDHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	rsp.Value, _ = RequestIDFromContext(ctx)
+	return nil
+}
+
+func TestServerRequestID(t *testing.T) {
+	srv, _ := newTestServer(RequestIDGenerator(func() string {
+		return "generated"
+	}))
+
+	if err := srv.Handle(srv.NewHandler(&RequestIDHandler{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	testData := []struct {
+		header map[string]string
+		expect string
+	}{
+		{map[string]string{}, "generated"},
+		{map[string]string{"X-Request-ID": "abc"}, "abc"},
+		// canonicalised by http based transports
+		{map[string]string{"X-Request-Id": "def"}, "def"},
+	}
+
+	for _, d := range testData {
+		c, err := srv.Options().Transport.Dial(srv.Options().Address)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hdr := map[string]string{
+			"Micro-Id":       "1",
+			"Micro-Service":  "test.service",
+			"Micro-Endpoint": "RequestIDHandler.Call",
+			"Content-Type":   "application/json",
+		}
+		for k, v := range d.header {
+			hdr[k] = v
+		}
+
+		if err := c.Send(&transport.Message{Header: hdr, Body: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+
+		var rsp transport.Message
+		if err := c.Recv(&rsp); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+
+		if id := rsp.Header[RequestIDHeader]; id != d.expect {
+			t.Fatalf("Expected response header %s to be %q got %q", RequestIDHeader, d.expect, id)
+		}
+
+		if body := string(rsp.Body); !strings.Contains(body, `"value":"`+d.expect+`"`) {
+			t.Fatalf("Expected handler to see request id %q got %s", d.expect, body)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"sync"
+	"sync/atomic"
 
 	"github.com/oxtoacart/bpool"
 	"github.com/pkg/errors"
@@ -17,13 +18,25 @@ import (
 )
 
 type rpcCodec struct {
+	// bytes of the bodies written, first for atomic alignment
+	written int64
+
 	socket   transport.Socket
 	codec    codec.Codec
+	newCodec codec.NewCodec
 	protocol string
+	// wcodec encodes responses when the client accepts
+	// another content type than the request's
+	wcodec codec.Codec
 
 	req *transport.Message
 	buf *readWriteCloser
 
+	// header is added to every response
+	header map[string]string
+	// more than one response may be written
+	stream bool
+
 	// check if we're the first
 	sync.RWMutex
 	first chan bool
@@ -45,6 +58,7 @@ var (
 		"application/json":         json.NewCodec,
 		"application/json-rpc":     jsonrpc.NewCodec,
 		"application/protobuf":     proto.NewCodec,
+		"application/x-protobuf":   proto.NewCodec,
 		"application/proto-rpc":    protorpc.NewCodec,
 		"application/octet-stream": raw.NewCodec,
 	}
@@ -164,7 +178,9 @@ func setupProtocol(msg *transport.Message) codec.NewCodec {
 	return nil
 }
 
-func newRpcCodec(req *transport.Message, socket transport.Socket, c codec.NewCodec) codec.Codec {
+// newRpcCodec returns a codec reading requests with c. Responses are written
+// with wc if it's set, for a client accepting another content type.
+func newRpcCodec(req *transport.Message, socket transport.Socket, c, wc codec.NewCodec) codec.Codec {
 	rwc := &readWriteCloser{
 		rbuf: bufferPool.Get(),
 		wbuf: bufferPool.Get(),
@@ -173,10 +189,16 @@ func newRpcCodec(req *transport.Message, socket transport.Socket, c codec.NewCod
 	r := &rpcCodec{
 		buf:      rwc,
 		codec:    c(rwc),
+		newCodec: c,
 		req:      req,
 		socket:   socket,
 		protocol: "mucp",
 		first:    make(chan bool),
+		stream:   len(getHeader("Micro-Stream", req.Header)) > 0,
+	}
+
+	if wc != nil {
+		r.wcodec = wc(rwc)
 	}
 
 	// if grpc pre-load the buffer
@@ -280,6 +302,12 @@ func (c *rpcCodec) ReadBody(b interface{}) error {
 	return c.codec.ReadBody(b)
 }
 
+// reset replaces the codec after a frame failed to decode, codecs
+// such as json keep the error and would fail every later frame.
+func (c *rpcCodec) reset() {
+	c.codec = c.newCodec(c.buf)
+}
+
 func (c *rpcCodec) Write(r *codec.Message, b interface{}) error {
 	c.buf.wbuf.Reset()
 
@@ -310,36 +338,71 @@ func (c *rpcCodec) Write(r *codec.Message, b interface{}) error {
 	} else if len(r.Body) > 0 {
 		body = r.Body
 		// write the body to codec
-	} else if err := c.codec.Write(m, b); err != nil {
+	} else if err := c.writer().Write(m, b); err != nil {
 		c.buf.wbuf.Reset()
 
 		// write an error if it failed
 		m.Error = errors.Wrapf(err, "Unable to encode body").Error()
 		m.Header["Micro-Error"] = m.Error
 		// no body to write
-		if err := c.codec.Write(m, nil); err != nil {
+		if err := c.writer().Write(m, nil); err != nil {
 			return err
 		}
 	} else {
 		// set the body
 		body = c.buf.wbuf.Bytes()
+
+		// stream frames may still be queued on the socket
+		// when the buffer is reused for the next one
+		if c.stream {
+			body = append([]byte(nil), body...)
+		}
 	}
 
 	// Set content type if theres content
 	if len(body) > 0 {
 		m.Header["Content-Type"] = c.req.Header["Content-Type"]
+		if c.wcodec != nil {
+			m.Header["Content-Type"] = c.req.Header["Accept"]
+		}
+	}
+
+	for k, v := range c.header {
+		m.Header[k] = v
+	}
+
+	// echo the request id
+	if id, ok := c.req.Header[RequestIDHeader]; ok {
+		m.Header[RequestIDHeader] = id
 	}
 
 	// send on the socket
-	return c.socket.Send(&transport.Message{
+	if err := c.socket.Send(&transport.Message{
 		Header: m.Header,
 		Body:   body,
-	})
+	}); err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&c.written, int64(len(body)))
+
+	return nil
+}
+
+// writer returns the codec responses are encoded with.
+func (c *rpcCodec) writer() codec.Codec {
+	if c.wcodec != nil {
+		return c.wcodec
+	}
+	return c.codec
 }
 
 func (c *rpcCodec) Close() error {
 	// close the codec
 	c.codec.Close()
+	if c.wcodec != nil {
+		c.wcodec.Close()
+	}
 	// close the socket
 	err := c.socket.Close()
 	// put back the buffers
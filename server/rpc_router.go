@@ -12,13 +12,14 @@ import (
 	"fmt"
 	"io"
 	"reflect"
-	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"unicode"
 	"unicode/utf8"
 
 	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/debug/capture"
 	merrors "go-micro.dev/v4/errors"
 
 	log "go-micro.dev/v4/logger"
@@ -39,6 +40,8 @@ type methodType struct {
 	ReplyType   reflect.Type
 	ContextType reflect.Type
 	stream      bool
+	// wrappers for this method only
+	wrappers []HandlerWrapper
 }
 
 type service struct {
@@ -76,6 +79,14 @@ type router struct {
 	hdlrWrappers []HandlerWrapper
 	// subscriber wrappers
 	subWrappers []SubscriberWrapper
+	// list the methods in method not found errors
+	listMethods bool
+	// isolate decode errors to the stream frame
+	frameErrors bool
+	// records recent requests for debugging
+	capture capture.Capture
+	// reports the panics recovered from handlers
+	panicReporter PanicReporter
 
 	su          sync.RWMutex
 	subscribers map[string][]*subscriber
@@ -189,9 +200,10 @@ func prepareMethod(method reflect.Method, logger log.Logger) *methodType {
 	return &methodType{method: method, ArgType: argType, ReplyType: replyType, ContextType: contextType, stream: stream}
 }
 
-func (router *router) sendResponse(sending sync.Locker, req *request, reply interface{}, cc codec.Writer, last bool) error {
+func (router *router) sendResponse(ctx context.Context, sending sync.Locker, req *request, reply interface{}, cc codec.Writer, last bool) error {
 	msg := new(codec.Message)
 	msg.Type = codec.Response
+	msg.Header = trailerFromContext(ctx).header(nil)
 	resp := router.getResponse()
 	resp.msg = msg
 
@@ -216,6 +228,8 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 		endpoint:    req.msg.Endpoint,
 		body:        req.msg.Body,
 		header:      req.msg.Header,
+		// Read returns the body of a unary request
+		first: !mtype.stream,
 	}
 
 	// only set if not nil
@@ -235,28 +249,37 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 			return nil
 		}
 
-		// wrap the handler
+		// wrap the method then the handler
+		for i := len(mtype.wrappers); i > 0; i-- {
+			fn = mtype.wrappers[i-1](fn)
+		}
 		for i := len(router.hdlrWrappers); i > 0; i-- {
 			fn = router.hdlrWrappers[i-1](fn)
 		}
 
+		// capture the outcome of every wrapper
+		if router.capture != nil {
+			fn = captureWrapper(router.capture)(fn)
+		}
+
 		// execute handler
 		if err := fn(ctx, r, replyv.Interface()); err != nil {
 			return err
 		}
 
 		// send response
-		return router.sendResponse(sending, req, replyv.Interface(), cc, true)
+		return router.sendResponse(ctx, sending, req, replyv.Interface(), cc, true)
 	}
 
 	// declare a local error to see if we errored out already
 	// keep track of the type, to make sure we return
 	// the same one consistently
 	rawStream := &rpcStream{
-		context: ctx,
-		codec:   cc.(codec.Codec),
-		request: r,
-		id:      req.msg.Id,
+		context:     ctx,
+		codec:       cc.(codec.Codec),
+		request:     r,
+		id:          req.msg.Id,
+		frameErrors: router.frameErrors,
 	}
 
 	// Invoke the method, providing a new value for the reply.
@@ -273,7 +296,10 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 		}
 	}
 
-	// wrap the handler
+	// wrap the method then the handler
+	for i := len(mtype.wrappers); i > 0; i-- {
+		fn = mtype.wrappers[i-1](fn)
+	}
 	for i := len(router.hdlrWrappers); i > 0; i-- {
 		fn = router.hdlrWrappers[i-1](fn)
 	}
@@ -282,7 +308,49 @@ func (s *service) call(ctx context.Context, router *router, sending *sync.Mutex,
 	r.stream = true
 
 	// execute handler
-	return fn(ctx, r, rawStream)
+	return router.serveStream(ctx, fn, r, rawStream)
+}
+
+// serveStream runs the stream handler until it returns or the deadline
+// passes. At the deadline the frames already sent are flushed and the
+// stream is ended with a timeout error, later sends and receives fail.
+// The stream's socket is closed once the error is written, unblocking
+// a handler still waiting to receive even if it ignores its context.
+func (router *router) serveStream(ctx context.Context, fn HandlerFunc, req Request, stream *rpcStream) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return fn(ctx, req, stream)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			// recover any panics
+			if r := recover(); r != nil {
+				done <- recoverPanic(ctx, router.panicReporter, router.ops.Logger, r)
+			}
+		}()
+
+		done <- fn(ctx, req, stream)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	// waits for a frame being written
+	stream.expire()
+
+	// the handler may have finished as the deadline passed
+	select {
+	case err := <-done:
+		return err
+	default:
+	}
+
+	return merrors.Timeout("go.micro.server", "stream %s: %v", req.Endpoint(), ctx.Err())
 }
 
 func (m *methodType) prepareContext(ctx context.Context) reflect.Value {
@@ -405,16 +473,33 @@ func (router *router) readHeader(cc codec.Reader) (service *service, mtype *meth
 	service = router.serviceMap[serviceMethod[0]]
 	router.mu.Unlock()
 	if service == nil {
-		err = errors.New("rpc: can't find service " + serviceMethod[0])
+		err = merrors.NotFound("go.micro.server", "rpc: can't find service %s", serviceMethod[0])
 		return
 	}
 	mtype = service.method[serviceMethod[1]]
 	if mtype == nil {
-		err = errors.New("rpc: can't find method " + serviceMethod[1])
+		err = router.methodNotFound(service, serviceMethod[1])
 	}
 	return
 }
 
+// methodNotFound returns the error for a call to a method the service
+// doesn't have, listing those it does have if enabled.
+func (router *router) methodNotFound(s *service, method string) error {
+	if !router.listMethods {
+		return merrors.NotFound("go.micro.server", "rpc: can't find method %s.%s", s.name, method)
+	}
+
+	methods := make([]string, 0, len(s.method))
+	for name := range s.method {
+		methods = append(methods, s.name+"."+name)
+	}
+	sort.Strings(methods)
+
+	return merrors.NotFound("go.micro.server", "rpc: can't find method %s.%s, available methods: %s",
+		s.name, method, strings.Join(methods, ", "))
+}
+
 func (router *router) NewHandler(h interface{}, opts ...HandlerOption) Handler {
 	return newRpcHandler(h, opts...)
 }
@@ -459,6 +544,15 @@ func (router *router) Handle(h Handler) error {
 		return errors.New("rpc Register: type " + s.name + " has no exported methods of suitable type")
 	}
 
+	// Install the method wrappers
+	for name, wrappers := range h.Options().Wrappers {
+		mt, ok := s.method[name]
+		if !ok {
+			return errors.New("rpc.Handle: wrapper for unknown method " + s.name + "." + name)
+		}
+		mt.wrappers = wrappers
+	}
+
 	// save handler
 	router.serviceMap[s.name] = s
 	return nil
@@ -497,6 +591,11 @@ func (router *router) Subscribe(s Subscriber) error {
 		return err
 	}
 
+	// batches are delivered by the server rather than per message
+	if sub.opts.BatchSize > 0 {
+		return nil
+	}
+
 	router.su.Lock()
 	defer router.su.Unlock()
 
@@ -508,13 +607,46 @@ func (router *router) Subscribe(s Subscriber) error {
 	return nil
 }
 
+// decodePayload reads the body of the message into a new value of the type.
+func decodePayload(typ reflect.Type, msg Message) (reflect.Value, error) {
+	var isVal bool
+	var req reflect.Value
+
+	// check whether the handler is a pointer
+	if typ.Kind() == reflect.Ptr {
+		req = reflect.New(typ.Elem())
+	} else {
+		req = reflect.New(typ)
+		isVal = true
+	}
+
+	// if its a value get the element
+	if isVal {
+		req = req.Elem()
+	}
+
+	cc := msg.Codec()
+
+	// read the header. mostly a noop but the codec
+	// may need the content type to decode the body
+	if err := cc.ReadHeader(&codec.Message{Header: msg.Header()}, codec.Event); err != nil {
+		return req, err
+	}
+
+	// make request value a pointer, if it's not already
+	reqVal := req.Interface()
+	if req.CanAddr() {
+		reqVal = req.Addr().Interface()
+	}
+
+	return req, cc.ReadBody(reqVal)
+}
+
 func (router *router) ProcessMessage(ctx context.Context, msg Message) (err error) {
 	defer func() {
 		// recover any panics
 		if r := recover(); r != nil {
-			router.ops.Logger.Logf(log.ErrorLevel, "panic recovered: %v", r)
-			router.ops.Logger.Log(log.ErrorLevel, string(debug.Stack()))
-			err = merrors.InternalServerError("go.micro.server", "panic recovered: %v", r)
+			err = recoverPanic(ctx, router.panicReporter, router.ops.Logger, r)
 		}
 	}()
 
@@ -531,42 +663,35 @@ func (router *router) ProcessMessage(ctx context.Context, msg Message) (err erro
 
 	// we may have multiple subscribers for the topic
 	for _, sub := range subs {
-		// we may have multiple handlers per subscriber
-		for i := 0; i < len(sub.handlers); i++ {
-			// get the handler
-			handler := sub.handlers[i]
+		// skip messages the subscriber filters out or has already
+		// seen, acking them if the handler would have been left to
+		skip := sub.opts.Filter != nil && !sub.opts.Filter(msg.Header())
 
-			var isVal bool
-			var req reflect.Value
-
-			// check whether the handler is a pointer
-			if handler.reqType.Kind() == reflect.Ptr {
-				req = reflect.New(handler.reqType.Elem())
-			} else {
-				req = reflect.New(handler.reqType)
-				isVal = true
-			}
+		var key string
+		if !skip {
+			key, skip = sub.duplicate(msg.Header())
+		}
 
-			// if its a value get the element
-			if isVal {
-				req = req.Elem()
+		if skip {
+			if acker, ok := AckerFromContext(ctx); ok && !sub.opts.AutoAck {
+				if err := acker.Ack(); err != nil {
+					errResults = append(errResults, err.Error())
+				}
 			}
+			continue
+		}
 
-			cc := msg.Codec()
-
-			// read the header. mostly a noop
-			if err = cc.ReadHeader(&codec.Message{}, codec.Event); err != nil {
-				return err
-			}
+		// whether every handler of the subscriber succeeded
+		handled := true
 
-			// make request value a pointer, if it's not already
-			reqVal := req.Interface()
-			if req.CanAddr() {
-				reqVal = req.Addr().Interface()
-			}
+		// we may have multiple handlers per subscriber
+		for i := 0; i < len(sub.handlers); i++ {
+			// get the handler
+			handler := sub.handlers[i]
 
 			// read the body into the handler request value
-			if err = cc.ReadBody(reqVal); err != nil {
+			var req reflect.Value
+			if req, err = decodePayload(handler.reqType, msg); err != nil {
 				return err
 			}
 
@@ -608,9 +733,14 @@ func (router *router) ProcessMessage(ctx context.Context, msg Message) (err erro
 
 			// execute the message handler
 			if err = fn(ctx, rpcMsg); err != nil {
+				handled = false
 				errResults = append(errResults, err.Error())
 			}
 		}
+
+		if handled {
+			sub.remember(key)
+		}
 	}
 
 	// if no errors just return
@@ -1,20 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"net"
+	"path"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/codec"
 	raw "go-micro.dev/v4/codec/bytes"
+	"go-micro.dev/v4/errors"
 
 	log "go-micro.dev/v4/logger"
 	"go-micro.dev/v4/metadata"
@@ -27,6 +32,11 @@ import (
 )
 
 type rpcServer struct {
+	// number of requests being served, accessed atomically
+	inflight int64
+	// unix nano time of the last request, accessed atomically
+	lastRequest int64
+
 	router *router
 	exit   chan chan error
 
@@ -44,6 +54,9 @@ type rpcServer struct {
 	wg *sync.WaitGroup
 
 	rsvc *registry.Service
+
+	// bounds concurrent requests, nil when unlimited
+	limiter *requestLimiter
 }
 
 func newRpcServer(opts ...Option) Server {
@@ -51,6 +64,10 @@ func newRpcServer(opts ...Option) Server {
 	router := newRpcRouter()
 	router.hdlrWrappers = options.HdlrWrappers
 	router.subWrappers = options.SubWrappers
+	router.listMethods = options.ListMethods
+	router.frameErrors = options.StreamFrameErrors
+	router.capture = options.Capture
+	router.panicReporter = options.PanicReporter
 
 	return &rpcServer{
 		opts:        options,
@@ -59,57 +76,38 @@ func newRpcServer(opts ...Option) Server {
 		subscribers: make(map[Subscriber][]broker.Subscriber),
 		exit:        make(chan chan error),
 		wg:          wait(options.Context),
+		limiter:     newRequestLimiter(options.MaxConcurrentRequests, options.RequestQueueTimeout),
 	}
 }
 
 // HandleEvent handles inbound messages to the service directly
 // TODO: handle requests from an event. We won't send a response.
 func (s *rpcServer) HandleEvent(e broker.Event) error {
-	// formatting horrible cruft
-	msg := e.Message()
-
-	if msg.Header == nil {
-		// create empty map in case of headers empty to avoid panic later
-		msg.Header = make(map[string]string)
-	}
-
-	// get codec
-	ct := msg.Header["Content-Type"]
-
-	// default content type
-	if len(ct) == 0 {
-		msg.Header["Content-Type"] = DefaultContentType
-		ct = DefaultContentType
-	}
+	return s.handleEvent(context.Background(), e)
+}
 
-	// get codec
-	cf, err := s.newCodec(ct)
+func (s *rpcServer) handleEvent(ctx context.Context, e broker.Event) error {
+	rpcMsg, err := newMessage(e.Message(), s.opts.Codecs, s.maxBodySize())
 	if err != nil {
 		return err
 	}
 
 	// copy headers
-	hdr := make(map[string]string, len(msg.Header))
-	for k, v := range msg.Header {
+	hdr := make(map[string]string, len(rpcMsg.header))
+	for k, v := range rpcMsg.header {
 		hdr[k] = v
 	}
 
 	// create context
-	ctx := metadata.NewContext(context.Background(), hdr)
+	ctx = metadata.NewContext(ctx, hdr)
+
+	// let subscribers in manual ack mode ack the event
+	ctx = context.WithValue(ctx, ackerKey{}, newSettleEvent(e, nil))
 
 	// TODO: inspect message header
 	// Micro-Service means a request
 	// Micro-Topic means a message
 
-	rpcMsg := &rpcMessage{
-		topic:       msg.Header["Micro-Topic"],
-		contentType: ct,
-		payload:     &raw.Frame{Data: msg.Body},
-		codec:       cf,
-		header:      msg.Header,
-		body:        msg.Body,
-	}
-
 	// existing router
 	r := Router(s.router)
 
@@ -130,6 +128,90 @@ func (s *rpcServer) HandleEvent(e broker.Event) error {
 	return r.ProcessMessage(ctx, rpcMsg)
 }
 
+// newMessage creates the rpc message for a broker message with the codecs,
+// falling back to the DefaultCodecs, decompressing the body up to max bytes.
+func newMessage(msg *broker.Message, codecs map[string]codec.NewCodec, max int64) (*rpcMessage, error) {
+	// formatting horrible cruft
+	if msg.Header == nil {
+		// create empty map in case of headers empty to avoid panic later
+		msg.Header = make(map[string]string)
+	}
+
+	// get codec
+	ct := msg.Header["Content-Type"]
+
+	// default content type
+	if len(ct) == 0 {
+		msg.Header["Content-Type"] = DefaultContentType
+		ct = DefaultContentType
+	}
+
+	// get codec
+	cf, err := newCodec(codecs, ct)
+	if err != nil {
+		return nil, err
+	}
+
+	body := msg.Body
+	header := msg.Header
+
+	// decompress the body, the message is shared
+	// between subscribers so it's left untouched
+	if msg.Header["Content-Encoding"] == "gzip" {
+		body, err = gunzip(msg.Body, max)
+		if err != nil {
+			return nil, err
+		}
+
+		header = make(map[string]string, len(msg.Header))
+		for k, v := range msg.Header {
+			header[k] = v
+		}
+		delete(header, "Content-Encoding")
+	}
+
+	return &rpcMessage{
+		topic:       header["Micro-Topic"],
+		contentType: ct,
+		payload:     &raw.Frame{Data: body},
+		codec:       cf,
+		header:      header,
+		body:        body,
+	}, nil
+}
+
+// maxBodySize is the largest a decompressed message body may be, the max
+// frame size of the transport or the default if it doesn't set one.
+func (s *rpcServer) maxBodySize() int64 {
+	if s.opts.Transport != nil {
+		if max := s.opts.Transport.Options().MaxFrameSize; max > 0 {
+			return max
+		}
+	}
+	return transport.DefaultMaxFrameSize
+}
+
+// gunzip decompresses the body, failing with transport.ErrFrameTooLarge
+// rather than inflating it beyond max bytes.
+func gunzip(b []byte, max int64) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > max {
+		return nil, transport.ErrFrameTooLarge
+	}
+
+	return body, nil
+}
+
 // ServeConn serves a single connection.
 func (s *rpcServer) ServeConn(sock transport.Socket) {
 	logger := s.opts.Logger
@@ -137,6 +219,8 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 	var gerr error
 	// streams are multiplexed on Micro-Stream or Micro-Id header
 	pool := socket.NewPool()
+	// the remote end of the connection
+	peer := newPeer(sock)
 
 	// get global waitgroup
 	s.Lock()
@@ -213,6 +297,9 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 			id = msg.Header["Micro-Id"]
 		}
 
+		// one way requests don't get a response
+		oneWay := getHeader("Micro-One-Way", msg.Header) == "true"
+
 		// check stream id
 		var stream bool
 
@@ -256,6 +343,10 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		psock.SetLocal(sock.Local())
 		psock.SetRemote(sock.Remote())
 
+		// tag the request with an id, it's passed on to downstream
+		// calls through the metadata and echoed in the response
+		setRequestID(msg.Header, s.opts.RequestID)
+
 		// load the socket with the current message
 		psock.Accept(&msg)
 
@@ -279,6 +370,17 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// create new context with the metadata
 		ctx := metadata.NewContext(context.Background(), hdr)
 
+		// let handlers inspect the connection
+		ctx = context.WithValue(ctx, peerKey{}, peer)
+
+		// when the request was received and the bytes written for accounting
+		acct := &accounting{received: time.Now()}
+		ctx = context.WithValue(ctx, accountingKey{}, acct)
+
+		// trailing metadata set by the handler
+		tr := new(trailer)
+		ctx = context.WithValue(ctx, trailerKey{}, tr)
+
 		// set the timeout from the header if we have it
 		if len(to) > 0 {
 			if n, err := strconv.ParseUint(to, 10, 64); err == nil {
@@ -297,6 +399,9 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// setup old protocol
 		cf := setupProtocol(&msg)
 
+		// the codec for a response content type the client accepts
+		var wcf codec.NewCodec
+
 		// no legacy codec needed
 		if cf == nil {
 			var err error
@@ -317,10 +422,16 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 				// now continue
 				continue
 			}
+
+			// an accept we've no codec for gets the request content type
+			if accept := msg.Header["Accept"]; len(accept) > 0 && accept != ct {
+				wcf, _ = s.newCodec(accept)
+			}
 		}
 
 		// create a new rpc codec based on the pseudo socket and codec
-		rcodec := newRpcCodec(&msg, psock, cf)
+		rcodec := newRpcCodec(&msg, psock, cf, wcf)
+		acct.codec = rcodec.(*rpcCodec)
 		// check the protocol as well
 		protocol := rcodec.String()
 
@@ -337,6 +448,12 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 			stream:      stream,
 		}
 
+		// warn callers of deprecated endpoints
+		if d, ok := s.deprecation(request.Endpoint()); ok {
+			rcodec.(*rpcCodec).header = d.header()
+			logger.Logf(log.WarnLevel, "Deprecated endpoint %s called by %s: %s", request.Endpoint(), caller(msg.Header, sock.Remote()), d)
+		}
+
 		// internal response
 		response := &rpcResponse{
 			header: make(map[string]string),
@@ -367,6 +484,10 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// serve the request and process the outbound messages
 		wg.Add(2)
 
+		// track the request as in flight until served
+		atomic.AddInt64(&s.inflight, 1)
+		atomic.StoreInt64(&s.lastRequest, time.Now().UnixNano())
+
 		// process the outbound messages from the socket
 		go func(id string, psock *socket.Socket) {
 			defer func() {
@@ -395,6 +516,11 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 					return
 				}
 
+				// drop replies to one way requests
+				if oneWay {
+					continue
+				}
+
 				// send the message back over the socket
 				if err := sock.Send(m); err != nil {
 					return
@@ -405,6 +531,10 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 		// serve the request in a go routine as this may be a stream
 		go func(id string, psock *socket.Socket) {
 			defer func() {
+				// the responses have been written
+				acct.served()
+				// the request is no longer in flight
+				atomic.AddInt64(&s.inflight, -1)
 				// release the socket
 				pool.Release(psock)
 				// signal we're done
@@ -412,16 +542,40 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 
 				// recover any panics for call handler
 				if r := recover(); r != nil {
+					stack := debug.Stack()
 					logger.Log(log.ErrorLevel, "panic recovered: ", r)
-					logger.Log(log.ErrorLevel, string(debug.Stack()))
+					logger.Log(log.ErrorLevel, string(stack))
+					reportPanic(ctx, s.opts.PanicReporter, logger, r, stack)
 				}
 			}()
 
 			// serve the actual request using the request router
-			if serveRequestError := r.ServeRequest(ctx, request, response); serveRequestError != nil {
+			serveRequestError := s.checkEndpoint(request.Endpoint())
+			if serveRequestError == nil {
+				ctx, serveRequestError = s.extractHeaders(ctx)
+			}
+			if serveRequestError == nil {
+				ctx, serveRequestError = s.extractAccount(ctx)
+			}
+			if serveRequestError == nil {
+				serveRequestError = s.serveLimited(ctx, r, request, response)
+			}
+
+			if serveRequestError != nil {
+				// nobody is waiting for the error
+				if oneWay {
+					logger.Logf(log.DebugLevel, "rpc: one way request to %s failed: %v", request.Endpoint(), serveRequestError)
+					return
+				}
+
+				// map the error sent to the client
+				if serveRequestError != errLastStreamResponse {
+					serveRequestError = s.mapError(request.Endpoint(), serveRequestError)
+				}
+
 				// write an error response
 				writeError := rcodec.Write(&codec.Message{
-					Header: msg.Header,
+					Header: tr.header(msg.Header),
 					Error:  serveRequestError.Error(),
 					Type:   codec.Error,
 				}, nil)
@@ -439,8 +593,93 @@ func (s *rpcServer) ServeConn(sock transport.Socket) {
 	}
 }
 
+// serveLimited serves the request once the limiter has a free slot.
+func (s *rpcServer) serveLimited(ctx context.Context, r Router, req Request, rsp Response) error {
+	s.RLock()
+	l := s.limiter
+	s.RUnlock()
+
+	if l == nil {
+		return r.ServeRequest(ctx, req, rsp)
+	}
+
+	if err := l.acquire(ctx); err != nil {
+		return err
+	}
+	defer l.release()
+
+	return r.ServeRequest(ctx, req, rsp)
+}
+
+// deprecation returns the deprecation of the endpoint, if it's deprecated.
+func (s *rpcServer) deprecation(endpoint string) (Deprecation, bool) {
+	s.RLock()
+	d, ok := s.opts.DeprecatedEndpoints[endpoint]
+	s.RUnlock()
+	return d, ok
+}
+
+// checkEndpoint returns an error if the endpoint has been disabled
+// by the allow or deny lists.
+func (s *rpcServer) checkEndpoint(endpoint string) error {
+	s.RLock()
+	allow, deny := s.opts.AllowEndpoints, s.opts.DenyEndpoints
+	s.RUnlock()
+
+	match := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, endpoint); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if match(deny) || (len(allow) > 0 && !match(allow)) {
+		return errors.Forbidden("go.micro.server", "endpoint %s is disabled", endpoint)
+	}
+
+	return nil
+}
+
+// Handlers returns the registered handlers sorted by name.
+func (s *rpcServer) Handlers() []Handler {
+	s.RLock()
+	defer s.RUnlock()
+
+	handlers := make([]Handler, 0, len(s.handlers))
+	for _, h := range s.handlers {
+		handlers = append(handlers, h)
+	}
+
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Name() < handlers[j].Name()
+	})
+
+	return handlers
+}
+
+// InFlight returns the number of requests currently being served.
+func (s *rpcServer) InFlight() int {
+	return int(atomic.LoadInt64(&s.inflight))
+}
+
+// LastRequestTime returns the time the last request was received.
+// It's the zero time if no request has been received.
+func (s *rpcServer) LastRequestTime() time.Time {
+	t := atomic.LoadInt64(&s.lastRequest)
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
+
 func (s *rpcServer) newCodec(contentType string) (codec.NewCodec, error) {
-	if cf, ok := s.opts.Codecs[contentType]; ok {
+	return newCodec(s.opts.Codecs, contentType)
+}
+
+func newCodec(codecs map[string]codec.NewCodec, contentType string) (codec.NewCodec, error) {
+	if cf, ok := codecs[contentType]; ok {
 		return cf, nil
 	}
 	if cf, ok := DefaultCodecs[contentType]; ok {
@@ -469,9 +708,18 @@ func (s *rpcServer) Init(opts ...Option) error {
 		r.hdlrWrappers = s.opts.HdlrWrappers
 		r.serviceMap = s.router.serviceMap
 		r.subWrappers = s.opts.SubWrappers
+		r.listMethods = s.opts.ListMethods
+		r.frameErrors = s.opts.StreamFrameErrors
+		r.capture = s.opts.Capture
+		r.panicReporter = s.opts.PanicReporter
 		s.router = r
 	}
 
+	// replace the limiter if its settings changed
+	if l := s.limiter; l == nil || l.max != s.opts.MaxConcurrentRequests || l.timeout != s.opts.RequestQueueTimeout {
+		s.limiter = newRequestLimiter(s.opts.MaxConcurrentRequests, s.opts.RequestQueueTimeout)
+	}
+
 	s.rsvc = nil
 
 	return nil
@@ -574,7 +822,7 @@ func (s *rpcServer) Register() error {
 		cacheService = true
 	}
 
-	addr, err := addr.Extract(host)
+	addr, err := addr.ExtractPreferred(host, config.PreferAddresses...)
 	if err != nil {
 		return err
 	}
@@ -628,7 +876,7 @@ func (s *rpcServer) Register() error {
 	endpoints := make([]*registry.Endpoint, 0, len(handlerList)+len(subscriberList))
 
 	for _, n := range handlerList {
-		endpoints = append(endpoints, s.handlers[n].Endpoints()...)
+		endpoints = append(endpoints, deprecateEndpoints(s.handlers[n].Endpoints(), config.DeprecatedEndpoints)...)
 	}
 
 	for _, e := range subscriberList {
@@ -690,11 +938,32 @@ func (s *rpcServer) Register() error {
 			opts = append(opts, broker.SubscribeContext(cx))
 		}
 
-		if !sb.Options().AutoAck {
+		// a meter acks in place of the broker to count the acks which succeed
+		if !sb.Options().AutoAck || config.Meter != nil {
 			opts = append(opts, broker.DisableAutoAck())
 		}
 
-		sub, err := config.Broker.Subscribe(sb.Topic(), s.HandleEvent, opts...)
+		// batches are acked by the batcher once handled
+		if rsb, ok := sb.(*subscriber); ok && rsb.opts.BatchSize > 0 {
+			b := newBatcher(s, rsb, config)
+
+			sub, err := config.Broker.Subscribe(sb.Topic(), b.Handle, append(opts, broker.DisableAutoAck())...)
+			if err != nil {
+				return err
+			}
+			logger.Logf(log.InfoLevel, "Subscribing to topic: %s in batches of %d", sub.Topic(), rsb.opts.BatchSize)
+			s.subscribers[sb] = []broker.Subscriber{&batchSubscriber{sub, b}}
+			continue
+		}
+
+		handler := s.HandleEvent
+		if d := sb.Options().Timeout; d > 0 {
+			handler = timeoutSubscriber(d, sb.Options().AutoAck, logger, s.handleEvent)
+		}
+		handler = meterSubscriber(config.Meter, sb.Topic(), sb.Options().AutoAck, handler)
+		handler = prefetchSubscriber(sb.Options().Prefetch, sb.Options().AutoAck, DefaultPrefetchAckTimeout, logger, handler)
+
+		sub, err := config.Broker.Subscribe(sb.Topic(), handler, opts...)
 		if err != nil {
 			return err
 		}
@@ -736,7 +1005,7 @@ func (s *rpcServer) Deregister() error {
 		host = advt
 	}
 
-	addr, err := addr.Extract(host)
+	addr, err := addr.ExtractPreferred(host, config.PreferAddresses...)
 	if err != nil {
 		return err
 	}
@@ -772,21 +1041,29 @@ func (s *rpcServer) Deregister() error {
 
 	s.registered = false
 
+	// unsubscribe once unlocked as it waits for in flight messages
+	var unsubscribe []broker.Subscriber
+
 	// close the subscriber
 	if s.subscriber != nil {
-		s.subscriber.Unsubscribe()
+		unsubscribe = append(unsubscribe, s.subscriber)
 		s.subscriber = nil
 	}
 
 	for sb, subs := range s.subscribers {
-		for _, sub := range subs {
-			logger.Logf(log.InfoLevel, "Unsubscribing %s from topic: %s", node.Id, sub.Topic())
-			sub.Unsubscribe()
-		}
+		unsubscribe = append(unsubscribe, subs...)
 		s.subscribers[sb] = nil
 	}
 
 	s.Unlock()
+
+	for _, sub := range unsubscribe {
+		logger.Logf(log.InfoLevel, "Unsubscribing %s from topic: %s", node.Id, sub.Topic())
+		if err := sub.Unsubscribe(); err != nil {
+			logger.Logf(log.ErrorLevel, "Unsubscribing %s from topic %s: %v", node.Id, sub.Topic(), err)
+		}
+	}
+
 	return nil
 }
 
@@ -834,6 +1111,23 @@ func (s *rpcServer) Start() error {
 		}
 	}
 
+	// summarise the running server in one structured line
+	s.RLock()
+	var endpoints int
+	for _, h := range s.handlers {
+		endpoints += len(h.Endpoints())
+	}
+	s.RUnlock()
+
+	logger.Fields(map[string]interface{}{
+		"service":   config.Name,
+		"id":        config.Id,
+		"address":   ts.Addr(),
+		"transport": config.Transport.String(),
+		"registry":  config.Registry.String(),
+		"endpoints": endpoints,
+	}).Log(log.InfoLevel, "Server started")
+
 	exit := make(chan bool)
 
 	go func() {
@@ -863,12 +1157,17 @@ func (s *rpcServer) Start() error {
 	}()
 
 	go func() {
-		t := new(time.Ticker)
+		var (
+			t *time.Timer
+			// nil unless re-registering, so never fires
+			tick <-chan time.Time
+		)
 
 		// only process if it exists
 		if s.opts.RegisterInterval > time.Duration(0) {
-			// new ticker
-			t = time.NewTicker(s.opts.RegisterInterval)
+			// new timer, reset with a fresh jitter after each registration
+			t = time.NewTimer(registerInterval(config))
+			tick = t.C
 		}
 
 		// return error chan
@@ -878,7 +1177,8 @@ func (s *rpcServer) Start() error {
 		for {
 			select {
 			// register self on interval
-			case <-t.C:
+			case <-tick:
+				t.Reset(registerInterval(config))
 				s.RLock()
 				registered := s.registered
 				s.RUnlock()
@@ -898,7 +1198,9 @@ func (s *rpcServer) Start() error {
 				}
 			// wait for exit
 			case ch = <-s.exit:
-				t.Stop()
+				if t != nil {
+					t.Stop()
+				}
 				close(exit)
 				break Loop
 			}
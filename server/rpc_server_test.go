@@ -0,0 +1,740 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	"go-micro.dev/v4/transport"
+	"go-micro.dev/v4/util/addr"
+)
+
+type TestValue struct {
+	Value string `json:"value"`
+}
+
+type BlockingHandler struct {
+	started chan bool
+	release chan bool
+}
+
+func (h *BlockingHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	h.started <- true
+	<-h.release
+	rsp.Value = req.Value
+	return nil
+}
+
+// newTestServer starts a server and a client connected to it over
+// an in memory transport and registry.
+func newTestServer(opts ...Option) (Server, client.Client) {
+	r := registry.NewMemoryRegistry()
+	tr := transport.NewMemoryTransport()
+	b := broker.NewMemoryBroker()
+
+	opts = append([]Option{
+		Name("test.service"),
+		Registry(r),
+		Transport(tr),
+		Broker(b),
+	}, opts...)
+
+	srv := NewServer(opts...)
+
+	cl := client.NewClient(
+		client.Registry(r),
+		client.Transport(tr),
+		client.Broker(b),
+		client.Selector(selector.NewSelector(selector.Registry(r))),
+	)
+
+	return srv, cl
+}
+
+func TestServerInFlight(t *testing.T) {
+	h := &BlockingHandler{
+		started: make(chan bool),
+		release: make(chan bool),
+	}
+
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	act, ok := srv.(Activity)
+	if !ok {
+		t.Fatal("Expected server to implement Activity")
+	}
+
+	if n := act.InFlight(); n != 0 {
+		t.Fatalf("Expected 0 in flight requests got %d", n)
+	}
+
+	if !act.LastRequestTime().IsZero() {
+		t.Fatal("Expected zero last request time")
+	}
+
+	requests := 10
+	before := time.Now()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req := cl.NewRequest("test.service", "BlockingHandler.Call", &TestValue{Value: "foo"})
+			var rsp TestValue
+			if err := cl.Call(context.Background(), req, &rsp); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// wait for all the requests to reach the handler
+	for i := 0; i < requests; i++ {
+		<-h.started
+	}
+
+	if n := act.InFlight(); n != requests {
+		t.Fatalf("Expected %d in flight requests got %d", requests, n)
+	}
+
+	if act.LastRequestTime().Before(before) {
+		t.Fatalf("Expected last request time after %v got %v", before, act.LastRequestTime())
+	}
+
+	// release the requests one at a time
+	for i := 0; i < requests; i++ {
+		h.release <- true
+	}
+
+	wg.Wait()
+
+	// the counter is decremented once the response is written
+	deadline := time.Now().Add(time.Second)
+	for act.InFlight() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if n := act.InFlight(); n != 0 {
+		t.Fatalf("Expected 0 in flight requests got %d", n)
+	}
+}
+
+func TestServerPublishRoundTrip(t *testing.T) {
+	srv, cl := newTestServer()
+
+	type result struct {
+		contentType string
+		msg         *TestValue
+	}
+
+	ch := make(chan result, 1)
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		ct, _ := metadata.Get(ctx, "Content-Type")
+		ch <- result{ct, msg}
+		return nil
+	}
+
+	if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	for _, ct := range []string{"application/json", "application/grpc+json"} {
+		msg := cl.NewMessage("test.topic", &TestValue{Value: ct}, client.WithMessageContentType(ct))
+
+		if err := cl.Publish(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case rsp := <-ch:
+			if rsp.contentType != ct {
+				t.Fatalf("Expected content type %s got %s", ct, rsp.contentType)
+			}
+			if rsp.msg.Value != ct {
+				t.Fatalf("Expected value %s got %s", ct, rsp.msg.Value)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for message")
+		}
+	}
+}
+
+type EchoHandler struct{}
+
+func (h *EchoHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	rsp.Value = req.Value
+	return nil
+}
+
+func TestServerWrapperOrder(t *testing.T) {
+	var mtx sync.Mutex
+	var order []string
+
+	wrapper := func(id string) HandlerWrapper {
+		return func(fn HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, req Request, rsp interface{}) error {
+				mtx.Lock()
+				order = append(order, id)
+				mtx.Unlock()
+				return fn(ctx, req, rsp)
+			}
+		}
+	}
+
+	srv, cl := newTestServer(
+		WrapHandler(wrapper("first")),
+		WrapHandler(wrapper("second")),
+	)
+
+	// wrappers added via Init are appended
+	if err := srv.Init(WrapHandler(wrapper("third"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Handle(srv.NewHandler(&EchoHandler{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	req := cl.NewRequest("test.service", "EchoHandler.Call", &TestValue{Value: "foo"})
+	var rsp TestValue
+	if err := cl.Call(context.Background(), req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if got := strings.Join(order, ","); got != "first,second,third" {
+		t.Fatalf("Expected wrappers to execute in order first,second,third got %s", got)
+	}
+}
+
+func TestServerOneWay(t *testing.T) {
+	h := &BlockingHandler{
+		started: make(chan bool, 1),
+		release: make(chan bool),
+	}
+
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Handle(srv.NewHandler(&EchoHandler{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	// the call returns without waiting on the blocked handler
+	req := cl.NewRequest("test.service", "BlockingHandler.Call", &TestValue{Value: "one way"})
+	rsp := &TestValue{}
+	if err := cl.Call(context.Background(), req, rsp, client.OneWay()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Value) > 0 {
+		t.Fatalf("Expected no response got %q", rsp.Value)
+	}
+
+	select {
+	case <-h.started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected one way request to reach the handler")
+	}
+	close(h.release)
+
+	// the server writes nothing back for a one way request, so the next
+	// message on the connection is the reply to the following request
+	c, err := srv.Options().Transport.Dial(srv.Options().Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	send := func(id string, hdr map[string]string) {
+		msg := &transport.Message{
+			Header: map[string]string{
+				"Micro-Id":       id,
+				"Micro-Service":  "test.service",
+				"Micro-Endpoint": "EchoHandler.Call",
+				"Content-Type":   "application/json",
+			},
+			Body: []byte(`{"value":"` + id + `"}`),
+		}
+		for k, v := range hdr {
+			msg.Header[k] = v
+		}
+		if err := c.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("1", map[string]string{"Micro-One-Way": "true"})
+	send("2", nil)
+
+	var m transport.Message
+	if err := c.Recv(&m); err != nil {
+		t.Fatal(err)
+	}
+	if id := m.Header["Micro-Id"]; id != "2" {
+		t.Fatalf("Expected the only response to be for request 2 got %s: %s", id, m.Body)
+	}
+}
+
+func TestServerEndpointFilter(t *testing.T) {
+	srv, cl := newTestServer(
+		AllowEndpoints("EchoHandler.*", "RequestIDHandler.*"),
+		DenyEndpoints("RequestIDHandler.Call"),
+	)
+
+	for _, h := range []interface{}{&EchoHandler{}, &RequestIDHandler{}, &BlockingHandler{}} {
+		if err := srv.Handle(srv.NewHandler(h)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	testData := []struct {
+		endpoint string
+		allowed  bool
+	}{
+		{"EchoHandler.Call", true},
+		// allowed by pattern but deny wins
+		{"RequestIDHandler.Call", false},
+		// not in the allow list
+		{"BlockingHandler.Call", false},
+	}
+
+	for _, d := range testData {
+		req := cl.NewRequest("test.service", d.endpoint, &TestValue{Value: "hello"})
+		rsp := &TestValue{}
+
+		err := cl.Call(context.Background(), req, rsp)
+		if d.allowed {
+			if err != nil || rsp.Value != "hello" {
+				t.Fatalf("Expected %s to be callable got %v", d.endpoint, err)
+			}
+			continue
+		}
+
+		if e := errors.FromError(err); e.Code != 403 {
+			t.Fatalf("Expected %s to be forbidden got %v", d.endpoint, err)
+		}
+	}
+}
+
+type syncBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.String()
+}
+
+func TestServerStartLog(t *testing.T) {
+	for _, level := range []log.Level{log.InfoLevel, log.WarnLevel} {
+		buf := new(syncBuffer)
+		logger := log.NewLogger(log.WithLevel(level), log.WithFormat(log.JSONFormat), log.WithOutput(buf))
+
+		srv, _ := newTestServer(WithLogger(logger))
+
+		if err := srv.Handle(srv.NewHandler(&EchoHandler{})); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := srv.Start(); err != nil {
+			t.Fatal(err)
+		}
+		srv.Stop()
+
+		var entry struct {
+			Message string                 `json:"message"`
+			Fields  map[string]interface{} `json:"fields"`
+		}
+
+		var found bool
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if err := json.Unmarshal([]byte(line), &entry); err == nil && entry.Message == "Server started" {
+				found = true
+				break
+			}
+		}
+
+		if level != log.InfoLevel {
+			if found {
+				t.Fatalf("Expected start log to be suppressed at %s level", level)
+			}
+			continue
+		}
+
+		if !found {
+			t.Fatalf("Expected a start log line got %s", buf.String())
+		}
+
+		expect := map[string]interface{}{
+			"service":   "test.service",
+			"address":   srv.Options().Address,
+			"transport": "memory",
+			"registry":  "memory",
+			"endpoints": float64(1),
+		}
+		for k, v := range expect {
+			if entry.Fields[k] != v {
+				t.Fatalf("Expected %s to be %v got %v", k, v, entry.Fields[k])
+			}
+		}
+	}
+}
+
+func TestServerSubscriberCompressed(t *testing.T) {
+	srv, cl := newTestServer()
+
+	if err := cl.Init(client.PublishCompression(1024)); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *TestValue, 2)
+	encodings := make(chan string, 2)
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		md, _ := metadata.FromContext(ctx)
+		encodings <- md["Content-Encoding"]
+		received <- msg
+		return nil
+	}
+
+	if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	// watch what goes over the broker
+	wire := make(chan *broker.Message, 2)
+	bsub, err := srv.Options().Broker.Subscribe("test.topic", func(e broker.Event) error {
+		wire <- e.Message()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bsub.Unsubscribe()
+
+	large := strings.Repeat("a", 64*1024)
+
+	// mixed compressed and uncompressed delivery
+	for _, v := range []string{large, "small"} {
+		if err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: v})); err != nil {
+			t.Fatal(err)
+		}
+
+		var msg *broker.Message
+		select {
+		case msg = <-wire:
+		case <-time.After(time.Second):
+			t.Fatal("Expected message on the broker")
+		}
+
+		compressed := msg.Header["Content-Encoding"] == "gzip"
+		if compressed != (len(v) > 1024) {
+			t.Fatalf("Expected compressed %v for body of %d bytes", !compressed, len(v))
+		}
+		if compressed && len(msg.Body) >= len(v) {
+			t.Fatalf("Expected compressed body smaller than %d bytes, got %d", len(v), len(msg.Body))
+		}
+
+		select {
+		case got := <-received:
+			if got.Value != v {
+				t.Fatalf("Expected the original value of %d bytes, got %d bytes", len(v), len(got.Value))
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected message to be received")
+		}
+
+		if enc := <-encodings; len(enc) > 0 {
+			t.Fatalf("Expected no content encoding for the subscriber, got %s", enc)
+		}
+	}
+}
+
+func TestGunzipLimit(t *testing.T) {
+	// a small body inflating to a much larger one
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(make([]byte, 1024*1024))
+	w.Close()
+
+	if _, err := gunzip(buf.Bytes(), 1024); err != transport.ErrFrameTooLarge {
+		t.Fatalf("Expected %v, got %v", transport.ErrFrameTooLarge, err)
+	}
+
+	b, err := gunzip(buf.Bytes(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 1024*1024 {
+		t.Fatalf("Expected %d bytes, got %d", 1024*1024, len(b))
+	}
+}
+
+func TestServerNoRegisterInterval(t *testing.T) {
+	srv, _ := newTestServer(RegisterInterval(0))
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// there's no timer to stop
+	if err := srv.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerSubscriberDrain(t *testing.T) {
+	srv, cl := newTestServer()
+
+	started := make(chan bool)
+	var handled int32
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}
+
+	if err := srv.Subscribe(srv.NewSubscriber("test.topic", fn)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	go cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: "foo"}))
+	<-started
+
+	// stopping waits for the message being handled
+	if err := srv.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(&handled); n != 1 {
+		t.Fatal("Expected the in flight message to be handled before the server stopped")
+	}
+}
+
+func TestServerRegisterWildcard(t *testing.T) {
+	testCases := []struct {
+		name   string
+		opts   []Option
+		expect func(ip net.IP) bool
+	}{
+		{"detected", nil, func(ip net.IP) bool {
+			// loopback is only registered if there's nothing else
+			if !ip.IsLoopback() {
+				return true
+			}
+			for _, a := range addr.IPs() {
+				if !net.ParseIP(a).IsLoopback() {
+					return false
+				}
+			}
+			return true
+		}},
+		{"preferred", []Option{PreferAddress("127.0.0.0/8")}, func(ip net.IP) bool {
+			return ip.Equal(net.ParseIP("127.0.0.1"))
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := registry.NewMemoryRegistry()
+
+			srv := NewServer(append([]Option{
+				Name("test.service"),
+				Address("0.0.0.0:10001"),
+				Registry(r),
+				Transport(transport.NewMemoryTransport()),
+				Broker(broker.NewMemoryBroker()),
+			}, tc.opts...)...)
+
+			if err := srv.(*rpcServer).Register(); err != nil {
+				t.Fatal(err)
+			}
+
+			services, err := r.GetService("test.service")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			host, port, err := net.SplitHostPort(services[0].Nodes[0].Address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if port != "10001" {
+				t.Fatalf("Expected port 10001, got %s", port)
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsUnspecified() {
+				t.Fatalf("Expected a concrete address, got %s", host)
+			}
+			if !tc.expect(ip) {
+				t.Fatalf("Unexpected address %s", host)
+			}
+		})
+	}
+}
+
+func TestServerMethodNotFound(t *testing.T) {
+	testData := []struct {
+		list   bool
+		detail string
+	}{
+		{false, "rpc: can't find method DeprecatedHandler.Missing"},
+		{true, "rpc: can't find method DeprecatedHandler.Missing, available methods: DeprecatedHandler.New, DeprecatedHandler.Old"},
+	}
+
+	for _, d := range testData {
+		srv, cl := newTestServer(ListMethods(d.list))
+
+		if err := srv.Handle(srv.NewHandler(&DeprecatedHandler{})); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := srv.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		req := cl.NewRequest("test.service", "DeprecatedHandler.Missing", &TestValue{Value: "hello"})
+		err := cl.Call(context.Background(), req, &TestValue{})
+
+		srv.Stop()
+
+		e := errors.FromError(err)
+		if e.Code != 404 || e.Id != "go.micro.server" {
+			t.Fatalf("Expected a not found error got %v", err)
+		}
+		if e.Detail != d.detail {
+			t.Fatalf("Expected detail %q got %q", d.detail, e.Detail)
+		}
+	}
+
+	// unknown handlers are also not found
+	srv, cl := newTestServer(ListMethods(true))
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	req := cl.NewRequest("test.service", "MissingHandler.Call", &TestValue{Value: "hello"})
+	if e := errors.FromError(cl.Call(context.Background(), req, &TestValue{})); e.Code != 404 {
+		t.Fatalf("Expected a not found error got %v", e)
+	}
+}
+
+func TestRegisterJitter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		interval time.Duration
+		ttl      time.Duration
+		jitter   float64
+		min      time.Duration
+		max      time.Duration
+	}{
+		{"none", time.Second * 30, time.Second * 90, 0, time.Second * 30, time.Second * 30},
+		{"ten percent", time.Second * 30, time.Second * 90, 0.1, time.Second * 27, time.Second * 33},
+		{"capped by ttl", time.Second * 30, time.Second * 36, 0.5, time.Second * 15, time.Second * 33},
+		{"interval at ttl", time.Second * 30, time.Second * 30, 0.2, time.Second * 24, time.Second * 30},
+		{"no ttl", time.Second * 10, 0, 0.2, time.Second * 8, time.Second * 12},
+		{"full jitter", time.Second * 10, time.Second * 30, 5, 1, time.Second * 20},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// several services sharing the options
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < 10; i++ {
+				srv := NewServer(Name(fmt.Sprintf("test.service.%d", i)), RegisterInterval(tc.interval), RegisterTTL(tc.ttl), RegisterJitter(tc.jitter))
+
+				for j := 0; j < 100; j++ {
+					d := registerInterval(srv.Options())
+					if d < tc.min || d > tc.max {
+						t.Fatalf("Expected an interval between %v and %v, got %v", tc.min, tc.max, d)
+					}
+					if tc.ttl > 0 && d > tc.ttl {
+						t.Fatalf("Expected the interval %v not to exceed the ttl %v", d, tc.ttl)
+					}
+					seen[d] = true
+				}
+			}
+
+			if tc.jitter == 0 && len(seen) != 1 {
+				t.Fatalf("Expected a fixed interval, got %d", len(seen))
+			}
+			if tc.jitter > 0 && len(seen) < 100 {
+				t.Fatalf("Expected the interval to vary, got %d distinct values", len(seen))
+			}
+		})
+	}
+}
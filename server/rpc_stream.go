@@ -4,11 +4,26 @@ import (
 	"context"
 	"errors"
 	"io"
+	"reflect"
 	"sync"
 
 	"go-micro.dev/v4/codec"
+	merrors "go-micro.dev/v4/errors"
 )
 
+// frameErrorHeader marks an error response as being for a single
+// stream frame, the stream stays open.
+const frameErrorHeader = "Micro-Frame-Error"
+
+// frameError is a stream frame which failed to decode.
+type frameError struct {
+	err error
+}
+
+func (e *frameError) Error() string {
+	return e.err.Error()
+}
+
 // Implements the Streamer interface.
 type rpcStream struct {
 	sync.RWMutex
@@ -18,6 +33,12 @@ type rpcStream struct {
 	request Request
 	codec   codec.Codec
 	context context.Context
+	// skip frames which fail to decode, reporting them to the client
+	frameErrors bool
+	// the deadline passed, no more frames are sent
+	expired bool
+	// a header or message was sent, the header can't be sent
+	headerSent bool
 }
 
 func (r *rpcStream) Context() context.Context {
@@ -32,6 +53,10 @@ func (r *rpcStream) Send(msg interface{}) error {
 	r.Lock()
 	defer r.Unlock()
 
+	if r.expired {
+		return r.context.Err()
+	}
+
 	resp := codec.Message{
 		Target:   r.request.Service(),
 		Method:   r.request.Method(),
@@ -44,10 +69,67 @@ func (r *rpcStream) Send(msg interface{}) error {
 		r.err = err
 	}
 
+	r.headerSent = true
+
 	return nil
 }
 
 func (r *rpcStream) Recv(msg interface{}) error {
+	for {
+		err := r.recv(msg)
+
+		ferr, ok := err.(*frameError)
+		if !ok {
+			return err
+		}
+
+		if err := r.sendFrameError(ferr); err != nil {
+			return err
+		}
+
+		// don't leave a partially decoded frame behind
+		if v := reflect.ValueOf(msg); v.Kind() == reflect.Ptr && !v.IsNil() {
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		}
+	}
+}
+
+// sendFrameError tells the client its frame couldn't be decoded.
+func (r *rpcStream) sendFrameError(ferr *frameError) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.expired {
+		return r.context.Err()
+	}
+
+	rsp := codec.Message{
+		Target:   r.request.Service(),
+		Method:   r.request.Method(),
+		Endpoint: r.request.Endpoint(),
+		Id:       r.id,
+		Error:    merrors.BadRequest("go.micro.server", "rpc: failed to decode stream frame: %v", ferr).Error(),
+		Type:     codec.Error,
+		Header:   map[string]string{frameErrorHeader: "true"},
+	}
+
+	if err := r.codec.Write(&rsp, nil); err != nil {
+		r.err = err
+		return err
+	}
+
+	return nil
+}
+
+func (r *rpcStream) recv(msg interface{}) error {
+	r.RLock()
+	expired := r.expired
+	r.RUnlock()
+
+	if expired {
+		return r.context.Err()
+	}
+
 	req := new(codec.Message)
 	req.Type = codec.Request
 
@@ -83,6 +165,12 @@ func (r *rpcStream) Recv(msg interface{}) error {
 	err = r.codec.ReadBody(msg)
 	r.Lock()
 	if err != nil {
+		if r.frameErrors {
+			if rc, ok := r.codec.(*rpcCodec); ok {
+				rc.reset()
+			}
+			return &frameError{err}
+		}
 		r.err = err
 		return err
 	}
@@ -90,6 +178,14 @@ func (r *rpcStream) Recv(msg interface{}) error {
 	return nil
 }
 
+// expire stops any more frames being sent once those in
+// flight are written, and any more being received.
+func (r *rpcStream) expire() {
+	r.Lock()
+	r.expired = true
+	r.Unlock()
+}
+
 func (r *rpcStream) Error() error {
 	r.RLock()
 	defer r.RUnlock()
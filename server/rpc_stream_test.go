@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
@@ -10,8 +11,12 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"go-micro.dev/v4/client"
+	raw "go-micro.dev/v4/codec/bytes"
 	"go-micro.dev/v4/codec/json"
 	protoCodec "go-micro.dev/v4/codec/proto"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
 )
 
 // protoStruct implements proto.Message.
@@ -131,3 +136,202 @@ func TestRPCStream_Concurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+type StreamHandler struct{}
+
+func (h *StreamHandler) Echo(ctx context.Context, stream Stream) error {
+	for {
+		var req TestValue
+		if err := stream.Recv(&req); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.Send(&req); err != nil {
+			return err
+		}
+	}
+}
+
+// Partial sends a few frames then stalls past the deadline.
+func (h *StreamHandler) Partial(ctx context.Context, stream Stream) error {
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&TestValue{Value: fmt.Sprintf("frame-%d", i)}); err != nil {
+			return err
+		}
+	}
+
+	time.Sleep(time.Millisecond * 500)
+
+	return stream.Send(&TestValue{Value: "late"})
+}
+
+// Handshake sends a header before echoing.
+func (h *StreamHandler) Handshake(ctx context.Context, stream Stream) error {
+	if err := SendHeader(stream, metadata.Metadata{"Version": "2", "Session": "abc"}); err != nil {
+		return err
+	}
+	if err := SendHeader(stream, metadata.Metadata{"Version": "3"}); err != ErrHeaderSent {
+		return errors.InternalServerError("test", "expected the header to be sent once, got %v", err)
+	}
+	return h.Echo(ctx, stream)
+}
+
+func TestServerStreamFrameErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		isolate  bool
+		survives bool
+	}{
+		{"isolated", true, true},
+		{"default", false, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			srv, cl := newTestServer(StreamFrameErrors(tc.isolate))
+
+			if err := srv.Handle(srv.NewHandler(&StreamHandler{})); err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Stop()
+
+			req := cl.NewRequest("test.service", "StreamHandler.Echo", &TestValue{}, client.WithContentType("application/json"))
+			stream, err := cl.Stream(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+
+			echo := func(value string) {
+				if err := stream.Send(&TestValue{Value: value}); err != nil {
+					t.Fatal(err)
+				}
+				var rsp TestValue
+				if err := stream.Recv(&rsp); err != nil {
+					t.Fatalf("Expected %s to be echoed, got %v", value, err)
+				}
+				if rsp.Value != value {
+					t.Fatalf("Expected %s, got %s", value, rsp.Value)
+				}
+			}
+
+			echo("foo")
+
+			// a frame which isn't valid json
+			if err := stream.Send(&raw.Frame{Data: []byte(`{"value": `)}); err != nil {
+				t.Fatal(err)
+			}
+
+			var rsp TestValue
+			err = stream.Recv(&rsp)
+			if err == nil {
+				t.Fatal("Expected an error for the bad frame")
+			}
+
+			if !tc.survives {
+				return
+			}
+
+			if e := errors.Parse(err.Error()); e.Code != 400 {
+				t.Fatalf("Expected a bad request error, got %v", err)
+			}
+			if err := stream.Error(); err != nil {
+				t.Fatalf("Expected the stream to have no error, got %v", err)
+			}
+
+			echo("bar")
+		})
+	}
+}
+
+func TestServerStreamTimeout(t *testing.T) {
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(&StreamHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	start := time.Now()
+
+	req := cl.NewRequest("test.service", "StreamHandler.Partial", &TestValue{}, client.WithContentType("application/json"))
+	stream, err := cl.Stream(context.Background(), req, client.WithStreamTimeout(time.Millisecond*100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	for i := 0; i < 3; i++ {
+		var rsp TestValue
+		if err := stream.Recv(&rsp); err != nil {
+			t.Fatalf("Expected frame %d, got %v", i, err)
+		}
+		if want := fmt.Sprintf("frame-%d", i); rsp.Value != want {
+			t.Fatalf("Expected %s, got %s", want, rsp.Value)
+		}
+	}
+
+	var rsp TestValue
+	err = stream.Recv(&rsp)
+	if e := errors.Parse(fmt.Sprint(err)); e.Code != 408 {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if d := time.Since(start); d > time.Millisecond*400 {
+		t.Fatalf("Expected the stream to end at the deadline, took %v", d)
+	}
+}
+
+// IgnoreHandler waits for a frame ignoring its context.
+type IgnoreHandler struct {
+	done chan error
+}
+
+func (h *IgnoreHandler) Wait(ctx context.Context, stream Stream) error {
+	var req TestValue
+	err := stream.Recv(&req)
+	h.done <- err
+	return err
+}
+
+func TestServerStreamTimeoutUnblocks(t *testing.T) {
+	srv, cl := newTestServer()
+
+	h := &IgnoreHandler{done: make(chan error, 1)}
+	if err := srv.Handle(srv.NewHandler(h)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	req := cl.NewRequest("test.service", "IgnoreHandler.Wait", &TestValue{}, client.WithContentType("application/json"))
+	stream, err := cl.Stream(context.Background(), req, client.WithStreamTimeout(time.Millisecond*100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var rsp TestValue
+	if err := stream.Recv(&rsp); errors.FromError(err).Code != 408 {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+
+	// the stream is closed under the handler so it doesn't hang on
+	select {
+	case err := <-h.done:
+		if err == nil {
+			t.Fatal("Expected the handler's receive to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler to be unblocked at the deadline")
+	}
+}
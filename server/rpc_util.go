@@ -1,7 +1,9 @@
 package server
 
 import (
+	"math/rand"
 	"sync"
+	"time"
 )
 
 // waitgroup for global management of connections.
@@ -30,3 +32,38 @@ func (w *waitGroup) Wait() {
 	// only wait on local group
 	w.lg.Wait()
 }
+
+// registerInterval returns the register interval varied by the jitter.
+// The interval is never extended by more than half the time left before
+// the TTL, so a late registration still lands before the service expires.
+func registerInterval(opts Options) time.Duration {
+	interval := opts.RegisterInterval
+	if opts.RegisterJitter <= 0 || interval <= 0 {
+		return interval
+	}
+
+	jitter := opts.RegisterJitter
+	if jitter > 1 {
+		jitter = 1
+	}
+
+	// the most the interval can be shortened or extended by
+	lower := time.Duration(float64(interval) * jitter)
+	upper := lower
+
+	if ttl := opts.RegisterTTL; ttl > 0 {
+		if slack := (ttl - interval) / 2; slack < upper {
+			upper = slack
+		}
+		if upper < 0 {
+			upper = 0
+		}
+	}
+
+	// avoid a zero interval
+	if lower >= interval {
+		lower = interval - 1
+	}
+
+	return interval - lower + time.Duration(rand.Int63n(int64(lower+upper)+1))
+}
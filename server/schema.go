@@ -0,0 +1,184 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HandlerLister is implemented by servers which can list their handlers.
+type HandlerLister interface {
+	// Handlers returns the registered handlers sorted by name
+	Handlers() []Handler
+}
+
+// EndpointSchema describes the messages of an endpoint. The messages
+// of streams aren't known so only the endpoint is described.
+type EndpointSchema struct {
+	Endpoint string  `json:"endpoint"`
+	Stream   bool    `json:"stream,omitempty"`
+	Request  *Schema `json:"request,omitempty"`
+	Response *Schema `json:"response,omitempty"`
+}
+
+// Schema is the JSON schema of a message as it's encoded as json.
+type Schema struct {
+	// Title is the name of the Go type
+	Title string `json:"title,omitempty"`
+	// Type is the JSON type, empty if any value is allowed
+	Type string `json:"type,omitempty"`
+	// Format refines the type e.g int64 or date-time
+	Format string `json:"format,omitempty"`
+	// Properties are the fields of an object
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Items are the elements of an array
+	Items *Schema `json:"items,omitempty"`
+	// AdditionalProperties are the values of a map
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schemas describes the endpoints of every handler registered with
+// the server, sorted by endpoint.
+func Schemas(s Server) ([]*EndpointSchema, error) {
+	l, ok := s.(HandlerLister)
+	if !ok {
+		return nil, fmt.Errorf("server %s can't list its handlers", s.String())
+	}
+
+	var schemas []*EndpointSchema
+	for _, h := range l.Handlers() {
+		schemas = append(schemas, HandlerSchema(h)...)
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Endpoint < schemas[j].Endpoint
+	})
+
+	return schemas, nil
+}
+
+// HandlerSchema describes the endpoints of the handler from the types of
+// its methods, sorted by endpoint.
+func HandlerSchema(h Handler) []*EndpointSchema {
+	typ := reflect.TypeOf(h.Handler())
+
+	var schemas []*EndpointSchema
+
+	// methods are sorted by name
+	for m := 0; m < typ.NumMethod(); m++ {
+		method := typ.Method(m)
+		if extractEndpoint(method) == nil {
+			continue
+		}
+
+		es := &EndpointSchema{Endpoint: h.Name() + "." + method.Name}
+
+		mt := method.Type
+		if mt.NumIn() == 4 {
+			es.Request = schemaOf(mt.In(2), nil)
+			es.Response = schemaOf(mt.In(3), nil)
+		} else {
+			es.Stream = true
+		}
+
+		schemas = append(schemas, es)
+	}
+
+	return schemas
+}
+
+// schemaOf returns the schema of the type. Types already being
+// described are seen, recursive types are described as a named object.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		// bytes are encoded as base64
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		return structSchema(t, seen)
+	default:
+		// interfaces, e.g proto oneofs, hold any value
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	s := &Schema{Title: t.Name(), Type: "object"}
+
+	if seen[t] {
+		return s
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	s.Properties = make(map[string]*Schema)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		// the fields of embedded structs are promoted
+		if f.Anonymous && len(f.Tag.Get("json")) == 0 {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range structSchema(ft, seen).Properties {
+					s.Properties[k] = v
+				}
+				continue
+			}
+		}
+
+		// unexported, including the internal proto state
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Name
+		if tag := f.Tag.Get("json"); len(tag) > 0 {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if len(parts[0]) > 0 {
+				name = parts[0]
+			}
+		}
+
+		s.Properties[name] = schemaOf(f.Type, seen)
+	}
+
+	return s
+}
@@ -37,6 +37,15 @@ type Server interface {
 	String() string
 }
 
+// Activity is implemented by servers which track request activity.
+// It can be used for autoscaling or to detect an idle server.
+type Activity interface {
+	// InFlight returns the number of requests being served
+	InFlight() int
+	// LastRequestTime returns the time of the last request
+	LastRequestTime() time.Time
+}
+
 // Router handle serving messages.
 type Router interface {
 	// ProcessMessage processes a message
@@ -144,6 +153,7 @@ var (
 	DefaultServer           Server = newRpcServer()
 	DefaultRouter                  = newRpcRouter()
 	DefaultRegisterCheck           = func(context.Context) error { return nil }
+	DefaultRequestID               = func() string { return uuid.New().String() }
 	DefaultRegisterInterval        = time.Second * 30
 	DefaultRegisterTTL             = time.Second * 90
 
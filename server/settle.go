@@ -0,0 +1,71 @@
+package server
+
+import (
+	"sync"
+
+	"go-micro.dev/v4/broker"
+)
+
+// settleEvent lets an event be settled, acked or nacked, once. Later acks
+// and nacks are ignored. done is called with the outcome of settling it.
+type settleEvent struct {
+	broker.Event
+	done func(acked bool, err error)
+
+	sync.Mutex
+	settled bool
+}
+
+func newSettleEvent(e broker.Event, done func(acked bool, err error)) *settleEvent {
+	return &settleEvent{Event: e, done: done}
+}
+
+func (e *settleEvent) Ack() error {
+	if !e.settle() {
+		return nil
+	}
+
+	err := e.Event.Ack()
+	if e.done != nil {
+		e.done(true, err)
+	}
+
+	return err
+}
+
+func (e *settleEvent) Nack() error {
+	n, ok := e.Event.(broker.Nacker)
+	if !ok {
+		return ErrNackNotSupported
+	}
+	if !e.settle() {
+		return nil
+	}
+
+	err := n.Nack()
+	if e.done != nil {
+		e.done(false, err)
+	}
+
+	return err
+}
+
+// settle marks the event settled, returning false if it already was.
+func (e *settleEvent) settle() bool {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.settled {
+		return false
+	}
+	e.settled = true
+
+	return true
+}
+
+// isSettled returns whether the event was acked or nacked.
+func (e *settleEvent) isSettled() bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.settled
+}
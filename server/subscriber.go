@@ -1,10 +1,15 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 
+	"go-micro.dev/v4/broker"
+	log "go-micro.dev/v4/logger"
+	"go-micro.dev/v4/metadata"
 	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/transport"
 )
 
 const (
@@ -25,6 +30,8 @@ type subscriber struct {
 	handlers   []*handler
 	endpoints  []*registry.Endpoint
 	opts       SubscriberOptions
+	// keys of recent messages when deduplicating
+	dedup *dedupSet
 }
 
 func newSubscriber(topic string, sub interface{}, opts ...SubscriberOption) Subscriber {
@@ -93,7 +100,7 @@ func newSubscriber(topic string, sub interface{}, opts ...SubscriberOption) Subs
 		}
 	}
 
-	return &subscriber{
+	s := &subscriber{
 		rcvr:       reflect.ValueOf(sub),
 		typ:        reflect.TypeOf(sub),
 		topic:      topic,
@@ -102,12 +109,22 @@ func newSubscriber(topic string, sub interface{}, opts ...SubscriberOption) Subs
 		endpoints:  endpoints,
 		opts:       options,
 	}
+
+	if options.Dedup != nil && options.DedupWindow > 0 {
+		s.dedup = newDedupSet(options.DedupWindow, DefaultDedupSize)
+	}
+
+	return s
 }
 
 func validateSubscriber(sub Subscriber) error {
 	typ := reflect.TypeOf(sub.Subscriber())
 	var argType reflect.Type
 
+	if opts := sub.Options(); opts.BatchSize > 0 && (opts.Prefetch > 0 || opts.Timeout > 0) {
+		return fmt.Errorf("batch subscriber on %s can't use prefetch or timeout", sub.Topic())
+	}
+
 	if typ.Kind() == reflect.Func {
 		name := "Func"
 		switch typ.NumIn() {
@@ -119,6 +136,9 @@ func validateSubscriber(sub Subscriber) error {
 		if !isExportedOrBuiltinType(argType) {
 			return fmt.Errorf("subscriber %v argument type not exported: %v", name, argType)
 		}
+		if sub.Options().BatchSize > 0 && argType.Kind() != reflect.Slice {
+			return fmt.Errorf("batch subscriber %v argument is not a slice: %v", name, argType)
+		}
 		if typ.NumOut() != 1 {
 			return fmt.Errorf("subscriber %v has wrong number of outs: %v require signature %s",
 				name, typ.NumOut(), subSig)
@@ -144,6 +164,9 @@ func validateSubscriber(sub Subscriber) error {
 			if !isExportedOrBuiltinType(argType) {
 				return fmt.Errorf("%v argument type not exported: %v", name, argType)
 			}
+			if sub.Options().BatchSize > 0 && argType.Kind() != reflect.Slice {
+				return fmt.Errorf("batch subscriber %v.%v argument is not a slice: %v", name, method.Name, argType)
+			}
 			if method.Type.NumOut() != 1 {
 				return fmt.Errorf(
 					"subscriber %v.%v has wrong number of outs: %v require signature %s",
@@ -173,3 +196,55 @@ func (s *subscriber) Endpoints() []*registry.Endpoint {
 func (s *subscriber) Options() SubscriberOptions {
 	return s.opts
 }
+
+// TypedSubscriber adapts fn, a func(context.Context, *T) error, to a
+// broker.Handler which decodes each message into the argument with the
+// codec for its Content-Type before calling fn, as server subscribers do.
+// The context carries the message header as metadata and an Acker for
+// subscriptions with auto ack disabled. Panics in fn are recovered and
+// returned as errors. It panics if fn isn't a valid subscriber func.
+func TypedSubscriber(fn interface{}) broker.Handler {
+	if v := reflect.ValueOf(fn); v.Kind() != reflect.Func || v.IsNil() {
+		panic(fmt.Sprintf("typed subscriber %T is not a func", fn))
+	}
+
+	sub := newSubscriber("", fn)
+	if err := validateSubscriber(sub); err != nil {
+		panic(err)
+	}
+
+	h := sub.(*subscriber).handlers[0]
+
+	return func(e broker.Event) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(context.Background(), nil, log.DefaultLogger, r)
+			}
+		}()
+
+		// the default codecs are used as there's no server
+		msg, err := newMessage(e.Message(), nil, transport.DefaultMaxFrameSize)
+		if err != nil {
+			return err
+		}
+
+		req, err := decodePayload(h.reqType, msg)
+		if err != nil {
+			return err
+		}
+
+		hdr := make(map[string]string, len(msg.header))
+		for k, v := range msg.header {
+			hdr[k] = v
+		}
+
+		ctx := metadata.NewContext(context.Background(), hdr)
+		ctx = context.WithValue(ctx, ackerKey{}, newSettleEvent(e, nil))
+
+		rsp := h.method.Call([]reflect.Value{reflect.ValueOf(ctx), req})
+		if rerr := rsp[0].Interface(); rerr != nil {
+			return rerr.(error)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+func TestTypedSubscriber(t *testing.T) {
+	srv, cl := newTestServer()
+
+	b := srv.Options().Broker
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	type received struct {
+		value string
+		from  string
+	}
+
+	ch := make(chan received, 1)
+
+	fn := func(ctx context.Context, msg *TestValue) error {
+		md, _ := metadata.FromContext(ctx)
+		from, _ := md.Get("From")
+		ch <- received{msg.Value, from}
+		return nil
+	}
+
+	if _, err := b.Subscribe("test.topic", TypedSubscriber(fn)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.NewContext(context.Background(), map[string]string{"From": "test"})
+	if err := cl.Publish(ctx, cl.NewMessage("test.topic", &TestValue{Value: "hello"})); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-ch:
+		if got.value != "hello" || got.from != "test" {
+			t.Fatalf("Expected the decoded message and its metadata, got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the typed subscriber to be called")
+	}
+
+	// invalid funcs are rejected up front
+	var nilFn func(context.Context, *TestValue) error
+
+	for _, fn := range []interface{}{
+		nil,
+		nilFn,
+		&TestValue{},
+		func(msg *TestValue) error { return nil },
+		func(ctx context.Context, msg *TestValue) {},
+	} {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatalf("Expected %T to be rejected", fn)
+				}
+				if _, ok := r.(runtime.Error); ok {
+					t.Fatalf("Expected %T to be validated, got %v", fn, r)
+				}
+			}()
+			TypedSubscriber(fn)
+		}()
+	}
+}
+
+func TestTypedSubscriberPanic(t *testing.T) {
+	h := TypedSubscriber(func(ctx context.Context, msg *TestValue) error {
+		panic("oops")
+	})
+
+	b := broker.NewMemoryBroker()
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	errs := make(chan error, 1)
+	if _, err := b.Subscribe("test.topic", func(e broker.Event) error {
+		errs <- h(e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(&TestValue{Value: "hello"})
+	msg := &broker.Message{
+		Header: map[string]string{"Content-Type": "application/json"},
+		Body:   body,
+	}
+	if err := b.Publish("test.topic", msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if merr := errors.FromError(err); merr.Code != 500 || !strings.Contains(merr.Detail, "oops") {
+			t.Fatalf("Expected the panic to be returned as an error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the typed subscriber to be called")
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	log "go-micro.dev/v4/logger"
+)
+
+// ErrSubscriberTimeout fails a message whose handler exceeded the subscriber timeout.
+var ErrSubscriberTimeout = errors.New("subscriber timed out")
+
+// timeoutSubscriber runs the handler with a context cancelled after d, giving
+// up on it once cancelled. In manual ack mode the message is nacked unless the
+// handler settled it already, with autoAck the error fails it.
+func timeoutSubscriber(d time.Duration, autoAck bool, logger log.Logger, h func(context.Context, broker.Event) error) broker.Handler {
+	return func(e broker.Event) error {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+
+		// settled once, so a handler still running after its timeout
+		// can't ack or nack the message nacked for it
+		te := newSettleEvent(e, nil)
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- h(ctx, te)
+		}()
+
+		select {
+		case err := <-errc:
+			// a handler failing as it's cancelled timed out too
+			if err == nil || ctx.Err() == nil {
+				return err
+			}
+		case <-ctx.Done():
+		}
+
+		logger.Logf(log.WarnLevel, "Subscriber on topic %s timed out after %v", e.Topic(), d)
+
+		// later acks and nacks by the handler are ignored, the
+		// message is nacked unless the handler already settled it
+		if te.settle() && !autoAck {
+			if n, ok := e.(broker.Nacker); ok {
+				if err := n.Nack(); err != nil {
+					logger.Logf(log.ErrorLevel, "Failed to nack message on topic %s: %v", e.Topic(), err)
+				}
+			}
+		}
+
+		return ErrSubscriberTimeout
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/broker"
+	"go-micro.dev/v4/client"
+	log "go-micro.dev/v4/logger"
+)
+
+func TestServerSubscriberTimeout(t *testing.T) {
+	srv, cl := newTestServer()
+	b := srv.Options().Broker
+	if err := cl.Init(client.Broker(b)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mtx        sync.Mutex
+		deliveries = make(map[string]int)
+	)
+
+	release := make(chan bool)
+	defer close(release)
+
+	cancelled := make(chan error, 1)
+	acked := make(chan string, 10)
+
+	// the first delivery of "hang" ignores its cancellation until released
+	fn := func(ctx context.Context, msg *TestValue) error {
+		mtx.Lock()
+		deliveries[msg.Value]++
+		n := deliveries[msg.Value]
+		mtx.Unlock()
+
+		if msg.Value == "hang" && n == 1 {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			<-release
+			return nil
+		}
+
+		acker, _ := AckerFromContext(ctx)
+		acked <- msg.Value
+		return acker.Ack()
+	}
+
+	sub := srv.NewSubscriber("test.topic", fn,
+		SubscriberAckMode(AckModeManual),
+		SubscriberPrefetch(1),
+		SubscriberTimeout(time.Millisecond*50),
+	)
+	if err := srv.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: "hang"}))
+	if err == nil || !strings.Contains(err.Error(), ErrSubscriberTimeout.Error()) {
+		t.Fatalf("Expected the subscriber to time out, got %v", err)
+	}
+
+	select {
+	case err := <-cancelled:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("Expected the handler's context to exceed its deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler's context to be cancelled")
+	}
+
+	// the slot of the hanging handler is freed for the next message
+	if err := cl.Publish(context.Background(), cl.NewMessage("test.topic", &TestValue{Value: "next"})); err != nil {
+		t.Fatal(err)
+	}
+
+	// the nacked message is redelivered
+	got := make(map[string]bool)
+	for len(got) < 2 {
+		select {
+		case v := <-acked:
+			got[v] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the next and redelivered messages to be acked, got %v", got)
+		}
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if deliveries["hang"] != 2 {
+		t.Fatalf("Expected the timed out message to be delivered twice, got %d", deliveries["hang"])
+	}
+}
+
+// countingEvent counts how the message was settled.
+type countingEvent struct {
+	broker.Event
+
+	sync.Mutex
+	acks, nacks int
+}
+
+func (e *countingEvent) Topic() string {
+	return "test.topic"
+}
+
+func (e *countingEvent) Ack() error {
+	e.Lock()
+	defer e.Unlock()
+	e.acks++
+	return nil
+}
+
+func (e *countingEvent) Nack() error {
+	e.Lock()
+	defer e.Unlock()
+	e.nacks++
+	return nil
+}
+
+func TestTimeoutSubscriberLateAck(t *testing.T) {
+	release := make(chan bool)
+	acked := make(chan error, 1)
+
+	// the handler acks once it's released, after timing out
+	h := func(ctx context.Context, e broker.Event) error {
+		<-ctx.Done()
+		<-release
+		acked <- newSettleEvent(e, nil).Ack()
+		return nil
+	}
+
+	e := new(countingEvent)
+	fn := timeoutSubscriber(time.Millisecond*10, false, log.DefaultLogger, h)
+
+	if err := fn(e); err != ErrSubscriberTimeout {
+		t.Fatalf("Expected %v, got %v", ErrSubscriberTimeout, err)
+	}
+
+	close(release)
+	if err := <-acked; err != nil {
+		t.Fatal(err)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if e.nacks != 1 || e.acks != 0 {
+		t.Fatalf("Expected only the nack, got %d acks and %d nacks", e.acks, e.nacks)
+	}
+}
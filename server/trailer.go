@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go-micro.dev/v4/metadata"
+)
+
+// trailerPrefix marks the response headers carrying trailing metadata.
+const trailerPrefix = "Micro-Trailer-"
+
+type trailerKey struct{}
+
+// trailer holds the trailing metadata set while serving a request.
+type trailer struct {
+	sync.Mutex
+	md metadata.Metadata
+}
+
+// SetTrailer sets metadata sent to the client along with the response, e.g
+// final counts. For streams it's sent when the stream ends. It's merged with
+// any trailers set earlier in the request, clients read it with the
+// client.WithTrailer call option.
+func SetTrailer(ctx context.Context, md metadata.Metadata) error {
+	t, ok := ctx.Value(trailerKey{}).(*trailer)
+	if !ok {
+		return errors.New("server: no request in context to set the trailer of")
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if t.md == nil {
+		t.md = make(metadata.Metadata, len(md))
+	}
+	for k, v := range md {
+		t.md[k] = v
+	}
+
+	return nil
+}
+
+// header returns a copy of the header with the trailers added.
+func (t *trailer) header(header map[string]string) map[string]string {
+	if t == nil {
+		return header
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if len(t.md) == 0 {
+		return header
+	}
+
+	h := make(map[string]string, len(header)+len(t.md))
+	for k, v := range header {
+		h[k] = v
+	}
+	for k, v := range t.md {
+		h[trailerPrefix+k] = v
+	}
+
+	return h
+}
+
+func trailerFromContext(ctx context.Context) *trailer {
+	t, _ := ctx.Value(trailerKey{}).(*trailer)
+	return t
+}
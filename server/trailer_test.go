@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+type TrailerHandler struct{}
+
+func (h *TrailerHandler) Call(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	if err := SetTrailer(ctx, metadata.Metadata{"Count": "1"}); err != nil {
+		return err
+	}
+	rsp.Value = req.Value
+	// later trailers are merged
+	if err := SetTrailer(ctx, metadata.Metadata{"Count": "3", "Status": "done"}); err != nil {
+		return err
+	}
+	if req.Value == "fail" {
+		return errors.BadRequest("test", "failed")
+	}
+	return nil
+}
+
+// Stream echoes then sets its trailer as it ends.
+func (h *TrailerHandler) Stream(ctx context.Context, stream Stream) error {
+	var req TestValue
+	if err := stream.Recv(&req); err != nil {
+		return err
+	}
+	if err := stream.Send(&req); err != nil {
+		return err
+	}
+	return SetTrailer(ctx, metadata.Metadata{"Count": "1"})
+}
+
+// Slow doesn't respond before the call times out.
+func (h *TrailerHandler) Slow(ctx context.Context, req *TestValue, rsp *TestValue) error {
+	if err := SetTrailer(ctx, metadata.Metadata{"Count": "1"}); err != nil {
+		return err
+	}
+	time.Sleep(time.Millisecond * 200)
+	return nil
+}
+
+func TestServerTrailer(t *testing.T) {
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(&TrailerHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	testCases := []struct {
+		value string
+		code  int32
+	}{
+		{"foo", 0},
+		// trailers are returned with errors
+		{"fail", 400},
+	}
+
+	for _, tc := range testCases {
+		var md metadata.Metadata
+		var rsp TestValue
+
+		req := cl.NewRequest("test.service", "TrailerHandler.Call", &TestValue{Value: tc.value})
+		err := cl.Call(context.Background(), req, &rsp, client.WithTrailer(&md), client.WithRetries(0))
+		if (tc.code == 0 && err != nil) || (tc.code > 0 && errors.FromError(err).Code != tc.code) {
+			t.Fatalf("Expected code %d, got %v", tc.code, err)
+		}
+		if tc.code == 0 && rsp.Value != tc.value {
+			t.Fatalf("Expected %s, got %s", tc.value, rsp.Value)
+		}
+		if len(md) != 2 || md["Count"] != "3" || md["Status"] != "done" {
+			t.Fatalf("Expected the trailers for %s, got %v", tc.value, md)
+		}
+	}
+
+	// a call which times out gets no trailer
+	md := metadata.Metadata{"Stale": "true"}
+	req := cl.NewRequest("test.service", "TrailerHandler.Slow", &TestValue{})
+	err := cl.Call(context.Background(), req, &TestValue{}, client.WithTrailer(&md), client.WithRetries(0), client.WithRequestTimeout(time.Millisecond*50))
+	if errors.FromError(err).Code != 408 {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if md == nil || len(md) != 0 {
+		t.Fatalf("Expected an empty trailer, got %v", md)
+	}
+
+	// outside of a request there's nothing to set
+	if err := SetTrailer(context.Background(), metadata.Metadata{"Count": "1"}); err == nil {
+		t.Fatal("Expected an error setting a trailer without a request")
+	}
+}
+
+func TestServerStreamTrailer(t *testing.T) {
+	srv, cl := newTestServer()
+
+	if err := srv.Handle(srv.NewHandler(&TrailerHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	var md metadata.Metadata
+
+	req := cl.NewRequest("test.service", "TrailerHandler.Stream", &TestValue{}, client.WithContentType("application/json"))
+	stream, err := cl.Stream(context.Background(), req, client.WithTrailer(&md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if err := stream.Send(&TestValue{Value: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rsp TestValue
+	if err := stream.Recv(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	if md != nil {
+		t.Fatalf("Expected no trailer before the stream ends, got %v", md)
+	}
+
+	// the trailer is set as the stream ends
+	if err := stream.Recv(&rsp); err != io.EOF {
+		t.Fatalf("Expected the end of the stream, got %v", err)
+	}
+	if len(md) != 1 || md["Count"] != "1" {
+		t.Fatalf("Expected the stream trailer, got %v", md)
+	}
+}
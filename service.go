@@ -1,10 +1,15 @@
 package micro
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	rtime "runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go-micro.dev/v4/client"
 	log "go-micro.dev/v4/logger"
@@ -14,10 +19,20 @@ import (
 	signalutil "go-micro.dev/v4/util/signal"
 )
 
+// DependencyCheckInterval is how often a failing dependency check is retried.
+var DependencyCheckInterval = time.Second
+
 type service struct {
 	opts Options
 
 	once sync.Once
+	// set once started and dependencies are healthy
+	ready int32
+
+	sync.Mutex
+	// serves the health endpoints if a health address is set
+	health     *http.Server
+	healthAddr string
 }
 
 func newService(opts ...Option) Service {
@@ -93,10 +108,28 @@ func (s *service) Start() error {
 		}
 	}
 
+	if err := s.startHealth(); err != nil {
+		return err
+	}
+
 	if err := s.opts.Server.Start(); err != nil {
+		if herr := s.stopHealth(); herr != nil {
+			s.opts.Logger.Log(log.ErrorLevel, herr)
+		}
 		return err
 	}
 
+	for _, fn := range s.opts.DependencyChecks {
+		if err := fn(); err != nil {
+			if serr := s.stop(Shutdown{Reason: ShutdownError, Err: err}); serr != nil {
+				s.opts.Logger.Log(log.ErrorLevel, serr)
+			}
+			return err
+		}
+	}
+
+	atomic.StoreInt32(&s.ready, 1)
+
 	for _, fn := range s.opts.AfterStart {
 		if err := fn(); err != nil {
 			return err
@@ -107,12 +140,28 @@ func (s *service) Start() error {
 }
 
 func (s *service) Stop() error {
+	return s.stop(Shutdown{Reason: ShutdownStop})
+}
+
+// stop runs the stop funcs around stopping the server, the
+// ctx funcs are passed the reason for the shutdown.
+func (s *service) stop(sd Shutdown) error {
 	var err error
 
+	atomic.StoreInt32(&s.ready, 0)
+
+	s.opts.Logger.Logf(log.InfoLevel, "Stopping [service] %s (%s)", s.Name(), sd)
+
+	ctx := newShutdownContext(context.Background(), sd)
+
 	for _, fn := range s.opts.BeforeStop {
 		err = fn()
 	}
 
+	for _, fn := range s.opts.BeforeStopCtx {
+		err = fn(ctx)
+	}
+
 	if err = s.opts.Server.Stop(); err != nil {
 		return err
 	}
@@ -121,9 +170,54 @@ func (s *service) Stop() error {
 		err = fn()
 	}
 
+	for _, fn := range s.opts.AfterStopCtx {
+		err = fn(ctx)
+	}
+
+	if herr := s.stopHealth(); herr != nil {
+		s.opts.Logger.Log(log.ErrorLevel, herr)
+	}
+
 	return err
 }
 
+// wait blocks until a shutdown signal is received
+// or the service context is done.
+func (s *service) wait(ch <-chan os.Signal) Shutdown {
+	select {
+	// wait on kill signal
+	case sig := <-ch:
+		return Shutdown{Reason: ShutdownSignal, Signal: sig}
+	// wait on context cancel
+	case <-s.opts.Context.Done():
+		return Shutdown{Reason: ShutdownContext, Err: s.opts.Context.Err()}
+	}
+}
+
+// Ready reports whether the service has started and its dependency checks passed.
+func (s *service) Ready() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
+// checkDependency retries fn until it passes or the timeout expires.
+func checkDependency(fn func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("dependency check failed: %v", err)
+		case <-time.After(DependencyCheckInterval):
+		}
+	}
+}
+
 func (s *service) Run() (err error) {
 	logger := s.opts.Logger
 
@@ -163,12 +257,5 @@ func (s *service) Run() (err error) {
 		signal.Notify(ch, signalutil.Shutdown()...)
 	}
 
-	select {
-	// wait on kill signal
-	case <-ch:
-	// wait on context cancel
-	case <-s.opts.Context.Done():
-	}
-
-	return s.Stop()
+	return s.stop(s.wait(ch))
 }
@@ -3,12 +3,19 @@ package micro
 import (
 	"context"
 	"errors"
+	"io"
 	"net"
+	"net/http"
+	"os"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"go-micro.dev/v4/client"
 	"go-micro.dev/v4/debug/handler"
+	"go-micro.dev/v4/debug/metrics"
 	proto "go-micro.dev/v4/debug/proto"
 	"go-micro.dev/v4/registry"
 	"go-micro.dev/v4/server"
@@ -83,6 +90,33 @@ func testCustomListenService(ctx context.Context, customListener net.Listener, w
 	return srv
 }
 
+func testListenerService(ctx context.Context, l net.Listener, wg *sync.WaitGroup, name string) Service {
+	// add self
+	wg.Add(1)
+
+	r := registry.NewMemoryRegistry(registry.Services(test.Data))
+
+	// create service
+	srv := NewService(
+		Name(name),
+		Context(ctx),
+		Registry(r),
+		Listener(l),
+		AfterStart(func() error {
+			wg.Done()
+			return nil
+		}),
+		AfterStop(func() error {
+			wg.Done()
+			return nil
+		}),
+	)
+
+	RegisterHandler(srv.Server(), handler.NewHandler(srv.Client()))
+
+	return srv
+}
+
 func testRequest(ctx context.Context, c client.Client, name string) error {
 	// test call debug
 	req := c.NewRequest(
@@ -284,3 +318,431 @@ func BenchmarkService64(b *testing.B) {
 func BenchmarkCustomListenService1(b *testing.B) {
 	benchmarkCustomListenService(b, 1, "test.service.1")
 }
+
+func TestServiceListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waitgroup for server start
+	var wg sync.WaitGroup
+
+	// cancellation context
+	ctx, cancel := context.WithCancel(context.Background())
+
+	service := testListenerService(ctx, l, &wg, "test.service.listener")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- service.Run()
+	}()
+
+	// wait for service to start
+	wg.Wait()
+
+	services, err := service.Options().Registry.GetService("test.service.listener")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 1 {
+		t.Fatalf("Expected a single registered node, got %+v", services)
+	}
+	if addr := services[0].Nodes[0].Address; addr != l.Addr().String() {
+		t.Fatalf("Expected registered address %s, got %s", l.Addr().String(), addr)
+	}
+
+	if err := testRequest(ctx, service.Client(), "test.service.listener"); err != nil {
+		t.Fatal(err)
+	}
+
+	// shutdown the service
+	testShutdown(&wg, cancel)
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceListenerWildcard(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// a server of its own so nothing's advertised already
+	wg.Add(1)
+	service := NewService(
+		Server(server.NewServer()),
+		Name("test.service.wildcard"),
+		Context(ctx),
+		Registry(registry.NewMemoryRegistry()),
+		Listener(l),
+		AfterStart(func() error {
+			wg.Done()
+			return nil
+		}),
+		AfterStop(func() error {
+			wg.Done()
+			return nil
+		}),
+	)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- service.Run()
+	}()
+
+	wg.Wait()
+
+	services, err := service.Options().Registry.GetService("test.service.wildcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 1 {
+		t.Fatalf("Expected a single registered node, got %+v", services)
+	}
+
+	// the wildcard isn't advertised, a real address is detected
+	host, port, err := net.SplitHostPort(services[0].Nodes[0].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		t.Fatalf("Expected a detected address, got %s", host)
+	}
+	if _, lport, _ := net.SplitHostPort(l.Addr().String()); port != lport {
+		t.Fatalf("Expected the listener's port %s, got %s", lport, port)
+	}
+
+	testShutdown(&wg, cancel)
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceDependencyCheck(t *testing.T) {
+	interval := DependencyCheckInterval
+	DependencyCheckInterval = time.Millisecond * 10
+	defer func() {
+		DependencyCheckInterval = interval
+	}()
+
+	var (
+		mtx      sync.Mutex
+		attempts int
+	)
+
+	healthy := make(chan bool)
+
+	check := func(ctx context.Context) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		attempts++
+		select {
+		case <-healthy:
+			return nil
+		default:
+			return errors.New("dependency unavailable")
+		}
+	}
+
+	r := registry.NewMemoryRegistry()
+	srv := newService(
+		Name("test.service"),
+		Registry(r),
+		Transport(transport.NewMemoryTransport()),
+		DependencyCheck(check, time.Second),
+	).(*service)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start()
+	}()
+
+	// still failing so the service isn't ready
+	time.Sleep(time.Millisecond * 50)
+	if srv.Ready() {
+		t.Fatal("Expected service not to be ready while the dependency is failing")
+	}
+
+	close(healthy)
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	if !srv.Ready() {
+		t.Fatal("Expected service to be ready after the dependency check passed")
+	}
+
+	mtx.Lock()
+	if attempts < 2 {
+		t.Fatalf("Expected the check to be retried, got %d attempts", attempts)
+	}
+	mtx.Unlock()
+}
+
+func TestServiceDependencyCheckTimeout(t *testing.T) {
+	interval := DependencyCheckInterval
+	DependencyCheckInterval = time.Millisecond * 10
+	defer func() {
+		DependencyCheckInterval = interval
+	}()
+
+	srv := newService(
+		Name("test.service"),
+		Registry(registry.NewMemoryRegistry()),
+		Transport(transport.NewMemoryTransport()),
+		DependencyCheck(func(ctx context.Context) error {
+			return errors.New("dependency unavailable")
+		}, time.Millisecond*50),
+	).(*service)
+
+	if err := srv.Start(); err == nil {
+		t.Fatal("Expected startup to fail")
+	}
+
+	if srv.Ready() {
+		t.Fatal("Expected service not to be ready")
+	}
+}
+
+func TestServiceShutdownReason(t *testing.T) {
+	interval := DependencyCheckInterval
+	DependencyCheckInterval = time.Millisecond * 10
+	defer func() {
+		DependencyCheckInterval = interval
+	}()
+
+	startErr := errors.New("dependency unavailable")
+
+	testCases := []struct {
+		name   string
+		opts   []Option
+		run    func(t *testing.T, s *service, cancel func())
+		expect Shutdown
+	}{
+		{
+			name: "stop",
+			run: func(t *testing.T, s *service, cancel func()) {
+				if err := s.Start(); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.Stop(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: Shutdown{Reason: ShutdownStop},
+		},
+		{
+			name: "signal",
+			run: func(t *testing.T, s *service, cancel func()) {
+				if err := s.Start(); err != nil {
+					t.Fatal(err)
+				}
+				ch := make(chan os.Signal, 1)
+				ch <- syscall.SIGTERM
+				if err := s.stop(s.wait(ch)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: Shutdown{Reason: ShutdownSignal, Signal: syscall.SIGTERM},
+		},
+		{
+			name: "context",
+			opts: []Option{HandleSignal(false)},
+			run: func(t *testing.T, s *service, cancel func()) {
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- s.Run()
+				}()
+				time.Sleep(time.Millisecond * 50)
+				cancel()
+				if err := <-errCh; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: Shutdown{Reason: ShutdownContext, Err: context.Canceled},
+		},
+		{
+			name: "error",
+			opts: []Option{
+				DependencyCheck(func(ctx context.Context) error {
+					return startErr
+				}, time.Millisecond*20),
+			},
+			run: func(t *testing.T, s *service, cancel func()) {
+				if err := s.Start(); err == nil {
+					t.Fatal("Expected startup to fail")
+				}
+			},
+			expect: Shutdown{Reason: ShutdownError},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var before, after []Shutdown
+
+			opts := append([]Option{
+				Name("test.service"),
+				Context(ctx),
+				Registry(registry.NewMemoryRegistry()),
+				Transport(transport.NewMemoryTransport()),
+				BeforeStopCtx(func(ctx context.Context) error {
+					sd, ok := ShutdownFromContext(ctx)
+					if !ok {
+						t.Error("Expected shutdown in before stop context")
+					}
+					before = append(before, sd)
+					return nil
+				}),
+				AfterStopCtx(func(ctx context.Context) error {
+					sd, ok := ShutdownFromContext(ctx)
+					if !ok {
+						t.Error("Expected shutdown in after stop context")
+					}
+					after = append(after, sd)
+					return nil
+				}),
+			}, tc.opts...)
+
+			tc.run(t, newService(opts...).(*service), cancel)
+
+			for _, got := range [][]Shutdown{before, after} {
+				if len(got) != 1 {
+					t.Fatalf("Expected the hook to run once, ran %d times", len(got))
+				}
+				if got[0].Reason != tc.expect.Reason {
+					t.Fatalf("Expected reason %s, got %s", tc.expect.Reason, got[0].Reason)
+				}
+				if got[0].Signal != tc.expect.Signal {
+					t.Fatalf("Expected signal %v, got %v", tc.expect.Signal, got[0].Signal)
+				}
+				if tc.expect.Err != nil && !errors.Is(got[0].Err, tc.expect.Err) {
+					t.Fatalf("Expected error %v, got %v", tc.expect.Err, got[0].Err)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceHealth(t *testing.T) {
+	interval := DependencyCheckInterval
+	DependencyCheckInterval = time.Millisecond * 10
+	defer func() {
+		DependencyCheckInterval = interval
+	}()
+
+	healthy := make(chan bool)
+
+	meter := metrics.NewMeter()
+	meter.Count("test_requests", 3, map[string]string{"endpoint": "Test.Call"})
+
+	srv := newService(
+		Name("test.service"),
+		Registry(registry.NewMemoryRegistry()),
+		Transport(transport.NewMemoryTransport()),
+		HealthAddress("127.0.0.1:0"),
+		DependencyCheck(func(ctx context.Context) error {
+			select {
+			case <-healthy:
+				return nil
+			default:
+				return errors.New("dependency unavailable")
+			}
+		}, time.Second),
+	).(*service)
+	srv.Server().Init(server.Meter(meter))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start()
+	}()
+
+	var addr string
+	for i := 0; i < 100 && len(addr) == 0; i++ {
+		time.Sleep(time.Millisecond * 5)
+		srv.Lock()
+		addr = srv.healthAddr
+		srv.Unlock()
+	}
+	if len(addr) == 0 {
+		t.Fatal("Expected the health server to be listening")
+	}
+
+	get := func(path string) (int, string, error) {
+		rsp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer rsp.Body.Close()
+		b, err := io.ReadAll(rsp.Body)
+		return rsp.StatusCode, string(b), err
+	}
+
+	expect := func(path string, code int) string {
+		t.Helper()
+		got, body, err := get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != code {
+			t.Fatalf("Expected %s to return %d, got %d", path, code, got)
+		}
+		return body
+	}
+
+	// alive but the dependency is failing
+	expect("/healthz", http.StatusOK)
+	expect("/readyz", http.StatusServiceUnavailable)
+
+	close(healthy)
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	expect("/healthz", http.StatusOK)
+	expect("/readyz", http.StatusOK)
+
+	body := expect("/metrics", http.StatusOK)
+	if want := `test_requests{endpoint="Test.Call"} 3`; !strings.Contains(body, want) {
+		t.Fatalf("Expected metrics to contain %q, got %q", want, body)
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := get("/healthz"); err == nil {
+		t.Fatal("Expected the health server to be stopped")
+	}
+}
+
+func TestServiceHealthDisabled(t *testing.T) {
+	srv := newService(
+		Name("test.service"),
+		Registry(registry.NewMemoryRegistry()),
+		Transport(transport.NewMemoryTransport()),
+	).(*service)
+
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	if srv.health != nil {
+		t.Fatal("Expected no health server without a health address")
+	}
+}
@@ -0,0 +1,68 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// ShutdownReason is why a service stopped.
+type ShutdownReason int
+
+const (
+	// ShutdownStop means Stop was called directly.
+	ShutdownStop ShutdownReason = iota
+	// ShutdownSignal means a shutdown signal was received.
+	ShutdownSignal
+	// ShutdownContext means the service context was done.
+	ShutdownContext
+	// ShutdownError means the service failed to start.
+	ShutdownError
+)
+
+func (r ShutdownReason) String() string {
+	switch r {
+	case ShutdownSignal:
+		return "signal"
+	case ShutdownContext:
+		return "context"
+	case ShutdownError:
+		return "error"
+	default:
+		return "stop"
+	}
+}
+
+// Shutdown describes why a service is stopping. It's passed to
+// the BeforeStopCtx and AfterStopCtx funcs in their context.
+type Shutdown struct {
+	Reason ShutdownReason
+	// Signal received for ShutdownSignal
+	Signal os.Signal
+	// Err is the context error for ShutdownContext
+	// or the startup error for ShutdownError
+	Err error
+}
+
+func (s Shutdown) String() string {
+	switch {
+	case s.Signal != nil:
+		return fmt.Sprintf("%s %s", s.Reason, s.Signal)
+	case s.Err != nil:
+		return fmt.Sprintf("%s: %v", s.Reason, s.Err)
+	default:
+		return s.Reason.String()
+	}
+}
+
+type shutdownKey struct{}
+
+// ShutdownFromContext retrieves the Shutdown from the context of a stop func.
+func ShutdownFromContext(ctx context.Context) (Shutdown, bool) {
+	s, ok := ctx.Value(shutdownKey{}).(Shutdown)
+	return s, ok
+}
+
+func newShutdownContext(ctx context.Context, s Shutdown) context.Context {
+	return context.WithValue(ctx, shutdownKey{}, s)
+}
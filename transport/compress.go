@@ -0,0 +1,128 @@
+package transport
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+const (
+	// compressAcceptHeader lists the compressors a client supports, most preferred first
+	compressAcceptHeader = "Micro-Compression-Accept"
+	// compressHeader names the compressor the body was compressed with
+	compressHeader = "Micro-Compression"
+)
+
+// Compressor compresses the message bodies sent over a connection.
+type Compressor interface {
+	// Name is exchanged to negotiate the compressor e.g gzip
+	Name() string
+	Compress([]byte) ([]byte, error)
+	Decompress([]byte) ([]byte, error)
+}
+
+type gzipCompressor struct{}
+
+type flateCompressor struct{}
+
+// NewGzipCompressor returns a Compressor using gzip.
+func NewGzipCompressor() Compressor {
+	return gzipCompressor{}
+}
+
+// NewFlateCompressor returns a Compressor using deflate.
+func NewFlateCompressor() Compressor {
+	return flateCompressor{}
+}
+
+func (gzipCompressor) Name() string {
+	return "gzip"
+}
+
+func (gzipCompressor) Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (flateCompressor) Name() string {
+	return "deflate"
+}
+
+func (flateCompressor) Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCompressor) Decompress(b []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(b))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// offerCompression returns the value of the accept header for the compressors.
+func offerCompression(cs []Compressor) string {
+	names := make([]string, 0, len(cs))
+	for _, c := range cs {
+		names = append(names, c.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// negotiateCompression returns the first of the local compressors
+// which was offered, or nil if there's no overlap.
+func negotiateCompression(local []Compressor, offer string) Compressor {
+	if len(offer) == 0 {
+		return nil
+	}
+
+	offered := make(map[string]bool)
+	for _, name := range strings.Split(offer, ",") {
+		offered[strings.TrimSpace(name)] = true
+	}
+
+	for _, c := range local {
+		if offered[c.Name()] {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// findCompressor returns the compressor with the name.
+func findCompressor(cs []Compressor, name string) Compressor {
+	for _, c := range cs {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
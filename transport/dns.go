@@ -0,0 +1,139 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Resolver looks up the addresses for a host. It's satisfied by *net.Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+var (
+	// DefaultDNSNegativeTTL is how long failed lookups are cached for,
+	// capped by the cache ttl.
+	DefaultDNSNegativeTTL = time.Second * 5
+	// DefaultDNSLookupTimeout bounds a single lookup.
+	DefaultDNSLookupTimeout = time.Second * 5
+)
+
+type dnsEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+	// index of the next address to hand out
+	next int
+	// a background refresh is running
+	refreshing bool
+}
+
+// dnsCache caches host lookups, rotating through the addresses returned.
+// Expired entries are served while they're refreshed in the background,
+// failed lookups are cached for a shorter negative ttl.
+type dnsCache struct {
+	ttl      time.Duration
+	negative time.Duration
+	resolver Resolver
+
+	sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+func newDNSCache(ttl time.Duration, r Resolver) *dnsCache {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	negative := DefaultDNSNegativeTTL
+	if negative > ttl {
+		negative = ttl
+	}
+
+	return &dnsCache{
+		ttl:      ttl,
+		negative: negative,
+		resolver: r,
+		entries:  make(map[string]*dnsEntry),
+	}
+}
+
+func (d *dnsCache) lookup(host string) (*dnsEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDNSLookupTimeout)
+	defer cancel()
+
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	if err != nil {
+		return &dnsEntry{err: err, expires: time.Now().Add(d.negative)}, err
+	}
+
+	return &dnsEntry{addrs: addrs, expires: time.Now().Add(d.ttl)}, nil
+}
+
+// refresh looks up the host in the background. The stale entry is kept
+// if the lookup fails and isn't refreshed again for the negative ttl, so
+// an outage doesn't cost a lookup per dial.
+func (d *dnsCache) refresh(host string) {
+	e, err := d.lookup(host)
+
+	d.Lock()
+	defer d.Unlock()
+
+	old := d.entries[host]
+	if err != nil && old != nil && len(old.addrs) > 0 {
+		old.refreshing = false
+		old.expires = time.Now().Add(d.negative)
+		return
+	}
+
+	d.entries[host] = e
+}
+
+// Resolve returns the next address for the host.
+func (d *dnsCache) Resolve(host string) (string, error) {
+	d.Lock()
+
+	e, ok := d.entries[host]
+	if !ok || (len(e.addrs) == 0 && time.Now().After(e.expires)) {
+		// nothing usable cached so look it up now
+		d.Unlock()
+
+		ne, err := d.lookup(host)
+		if err == nil {
+			ne.next = 1
+		}
+
+		d.Lock()
+		d.entries[host] = ne
+		d.Unlock()
+
+		if err != nil {
+			return "", err
+		}
+
+		return ne.addrs[0], nil
+	}
+
+	defer d.Unlock()
+
+	// cached failure
+	if len(e.addrs) == 0 {
+		return "", e.err
+	}
+
+	if time.Now().After(e.expires) && !e.refreshing {
+		e.refreshing = true
+		go d.refresh(host)
+	}
+
+	addr := e.addrs[e.next%len(e.addrs)]
+	e.next++
+
+	return addr, nil
+}
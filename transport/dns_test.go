@@ -0,0 +1,241 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testResolver struct {
+	sync.Mutex
+	hosts   map[string][]string
+	lookups map[string]int
+}
+
+func (r *testResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lookups[host]++
+
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+func (r *testResolver) count(host string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.lookups[host]
+}
+
+func (r *testResolver) set(host string, addrs ...string) {
+	r.Lock()
+	defer r.Unlock()
+	r.hosts[host] = addrs
+}
+
+func TestDNSCache(t *testing.T) {
+	r := &testResolver{
+		hosts: map[string][]string{
+			"multi.local": {"10.0.0.1", "10.0.0.2"},
+		},
+		lookups: make(map[string]int),
+	}
+
+	ttl := time.Millisecond * 50
+	d := newDNSCache(ttl, r)
+
+	// cached lookups rotate through the records
+	var got []string
+	for i := 0; i < 4; i++ {
+		addr, err := d.Resolve("multi.local")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, addr)
+	}
+
+	expect := []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "10.0.0.2"}
+	for i := range expect {
+		if got[i] != expect[i] {
+			t.Fatalf("Expected addresses %v got %v", expect, got)
+		}
+	}
+
+	if n := r.count("multi.local"); n != 1 {
+		t.Fatalf("Expected 1 lookup got %d", n)
+	}
+
+	// failures are cached
+	for i := 0; i < 3; i++ {
+		_, err := d.Resolve("missing.local")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			t.Fatalf("Expected dns error got %v", err)
+		}
+	}
+
+	if n := r.count("missing.local"); n != 1 {
+		t.Fatalf("Expected failed lookup to be cached got %d lookups", n)
+	}
+
+	// once expired the stale records are served while refreshing
+	r.set("multi.local", "10.0.0.3")
+	time.Sleep(ttl * 2)
+
+	if addr, err := d.Resolve("multi.local"); err != nil || addr == "10.0.0.3" {
+		t.Fatalf("Expected a stale address got %s %v", addr, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		addr, err := d.Resolve("multi.local")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr == "10.0.0.3" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected cache to be refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := r.count("multi.local"); n != 2 {
+		t.Fatalf("Expected 2 lookups got %d", n)
+	}
+
+	// expired failures are looked up again
+	r.set("missing.local", "10.0.0.4")
+	if addr, err := d.Resolve("missing.local"); err != nil || addr != "10.0.0.4" {
+		t.Fatalf("Expected 10.0.0.4 got %s %v", addr, err)
+	}
+}
+
+func TestDNSCacheOutage(t *testing.T) {
+	r := &testResolver{
+		hosts: map[string][]string{
+			"service.local": {"10.0.0.1"},
+		},
+		lookups: make(map[string]int),
+	}
+
+	ttl := time.Millisecond * 20
+	d := newDNSCache(ttl, r)
+	d.negative = time.Minute
+
+	if _, err := d.Resolve("service.local"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the resolver goes away once the entry has expired
+	r.Lock()
+	delete(r.hosts, "service.local")
+	r.Unlock()
+	time.Sleep(ttl * 2)
+
+	// the first dial refreshes in the background
+	if addr, err := d.Resolve("service.local"); err != nil || addr != "10.0.0.1" {
+		t.Fatalf("Expected the stale address got %s %v", addr, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for r.count("service.local") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the entry to be refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the failed refresh backs off rather than looking up on every dial
+	for i := 0; i < 10; i++ {
+		if addr, err := d.Resolve("service.local"); err != nil || addr != "10.0.0.1" {
+			t.Fatalf("Expected the stale address got %s %v", addr, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := r.count("service.local"); n != 2 {
+		t.Fatalf("Expected 2 lookups during the outage got %d", n)
+	}
+}
+
+func TestHTTPTransportDNSCache(t *testing.T) {
+	r := &testResolver{
+		hosts: map[string][]string{
+			"service.local": {"127.0.0.1"},
+		},
+		lookups: make(map[string]int),
+	}
+
+	tr := NewHTTPTransport(DNSCache(time.Minute), DNSResolver(r))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock Socket) {
+		sock.Close()
+	})
+
+	_, port, _ := net.SplitHostPort(l.Addr())
+
+	for i := 0; i < 3; i++ {
+		c, err := tr.Dial(net.JoinHostPort("service.local", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+
+	if n := r.count("service.local"); n != 1 {
+		t.Fatalf("Expected 1 lookup got %d", n)
+	}
+
+	if _, err := tr.Dial(net.JoinHostPort("missing.local", port)); err == nil {
+		t.Fatal("Expected dial to an unknown host to fail")
+	}
+}
+
+func TestHTTPTransportDNSCacheInit(t *testing.T) {
+	r := &testResolver{
+		hosts: map[string][]string{
+			"service.local": {"127.0.0.1"},
+		},
+		lookups: make(map[string]int),
+	}
+
+	tr := NewHTTPTransport(DNSCache(time.Minute), DNSResolver(r))
+
+	// resolve while the cache is replaced
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := tr.resolve("service.local:8080"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := tr.Init(DNSCache(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+}
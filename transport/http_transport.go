@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -23,6 +24,10 @@ import (
 
 type httpTransport struct {
 	opts Options
+	// dns caches host lookups when enabled, it's
+	// replaced by Init while dials may be resolving
+	dmtx sync.RWMutex
+	dns  *dnsCache
 }
 
 type httpTransportClient struct {
@@ -32,6 +37,16 @@ type httpTransportClient struct {
 	dialOpts DialOptions
 	once     sync.Once
 
+	// sends being written and requests awaiting a response,
+	// drained signals pending reaching zero
+	pmtx    sync.Mutex
+	drained *sync.Cond
+	pending int
+	// requests whose write failed so no response is coming
+	failed map[*http.Request]bool
+	// stream sends which haven't been matched by a receive
+	unanswered int
+
 	sync.RWMutex
 
 	// request must be stored for response processing
@@ -43,6 +58,10 @@ type httpTransportClient struct {
 	// local/remote ip
 	local  string
 	remote string
+
+	// compressor selected by the listener
+	cmtx sync.RWMutex
+	comp Compressor
 }
 
 type httpTransportSocket struct {
@@ -62,6 +81,8 @@ type httpTransportSocket struct {
 	buf *bufio.Reader
 	// indicate if socket is closed
 	closed chan bool
+	// compressor negotiated on the first request
+	comp Compressor
 
 	// local/remote ip
 	local  string
@@ -88,7 +109,27 @@ func (h *httpTransportClient) Send(m *Message) error {
 		header.Set(k, v)
 	}
 
-	b := buf.New(bytes.NewBuffer(m.Body))
+	body := m.Body
+
+	// compress once the listener has chosen a compressor, until then offer them
+	if cs := h.ht.opts.Compressors; len(cs) > 0 {
+		h.cmtx.RLock()
+		c := h.comp
+		h.cmtx.RUnlock()
+
+		if c != nil {
+			cb, err := c.Compress(body)
+			if err != nil {
+				return err
+			}
+			body = cb
+			header.Set(compressHeader, c.Name())
+		} else {
+			header.Set(compressAcceptHeader, offerCompression(cs))
+		}
+	}
+
+	b := buf.New(bytes.NewBuffer(body))
 	defer b.Close()
 
 	req := &http.Request{
@@ -103,12 +144,16 @@ func (h *httpTransportClient) Send(m *Message) error {
 		Host:          h.addr,
 	}
 
+	h.begin()
+	defer h.done()
+
 	if !h.dialOpts.Stream {
 		h.Lock()
 		if h.closed {
 			h.Unlock()
 			return io.EOF
 		}
+		h.begin()
 		h.bl = append(h.bl, req)
 		select {
 		case h.r <- h.bl[0]:
@@ -123,7 +168,27 @@ func (h *httpTransportClient) Send(m *Message) error {
 		h.conn.SetDeadline(time.Now().Add(h.ht.opts.Timeout))
 	}
 
-	return req.Write(h.conn)
+	if err := req.Write(h.conn); err != nil {
+		// the request won't get a response
+		if !h.dialOpts.Stream {
+			h.Lock()
+			h.failed[req] = true
+			h.Unlock()
+			h.done()
+		}
+		return err
+	}
+
+	// stream connections have no request queue, so count the
+	// send as pending until a receive answers it
+	if h.dialOpts.Stream {
+		h.pmtx.Lock()
+		h.pending++
+		h.unanswered++
+		h.pmtx.Unlock()
+	}
+
+	return nil
 }
 
 func (h *httpTransportClient) Recv(m *Message) error {
@@ -145,6 +210,17 @@ func (h *httpTransportClient) Recv(m *Message) error {
 			h.Unlock()
 		}
 		r = rc
+
+		h.Lock()
+		failed := h.failed[r]
+		delete(h.failed, r)
+		h.Unlock()
+
+		if !failed {
+			defer h.done()
+		}
+	} else if h.answer() {
+		defer h.done()
 	}
 
 	// set timeout if its greater than 0
@@ -163,7 +239,7 @@ func (h *httpTransportClient) Recv(m *Message) error {
 	}
 	defer rsp.Body.Close()
 
-	b, err := io.ReadAll(rsp.Body)
+	b, err := h.ht.readBody(rsp.Body, rsp.ContentLength)
 	if err != nil {
 		return err
 	}
@@ -172,6 +248,22 @@ func (h *httpTransportClient) Recv(m *Message) error {
 		return errors.New(rsp.Status + ": " + string(b))
 	}
 
+	// the listener compresses with the compressor it chose
+	if name := rsp.Header.Get(compressHeader); len(name) > 0 {
+		c := findCompressor(h.ht.opts.Compressors, name)
+		if c == nil {
+			return fmt.Errorf("unsupported compression %s", name)
+		}
+
+		if b, err = h.ht.decompress(c, b); err != nil {
+			return err
+		}
+
+		h.cmtx.Lock()
+		h.comp = c
+		h.cmtx.Unlock()
+	}
+
 	m.Body = b
 
 	if m.Header == nil {
@@ -186,10 +278,81 @@ func (h *httpTransportClient) Recv(m *Message) error {
 		}
 	}
 
+	delete(m.Header, compressHeader)
+	delete(m.Header, compressAcceptHeader)
+
 	return nil
 }
 
+// begin tracks a pending send or response.
+func (h *httpTransportClient) begin() {
+	h.pmtx.Lock()
+	h.pending++
+	h.pmtx.Unlock()
+}
+
+// done finishes a pending send or response.
+func (h *httpTransportClient) done() {
+	h.pmtx.Lock()
+	h.pending--
+	if h.pending == 0 {
+		h.drained.Broadcast()
+	}
+	h.pmtx.Unlock()
+}
+
+// answer matches a receive on a stream connection to an unanswered
+// send, it reports whether there was one.
+func (h *httpTransportClient) answer() bool {
+	h.pmtx.Lock()
+	defer h.pmtx.Unlock()
+
+	if h.unanswered == 0 {
+		return false
+	}
+	h.unanswered--
+	return true
+}
+
+// drain waits for pending sends and responses then half closes the
+// connection so the peer reads everything written before it goes away.
+func (h *httpTransportClient) drain(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	// wake the wait once the timeout passes
+	t := time.AfterFunc(timeout, func() {
+		h.pmtx.Lock()
+		h.drained.Broadcast()
+		h.pmtx.Unlock()
+	})
+	defer t.Stop()
+
+	h.pmtx.Lock()
+	for h.pending > 0 && time.Now().Before(deadline) {
+		h.drained.Wait()
+	}
+	pending := h.pending
+	h.pmtx.Unlock()
+
+	if pending > 0 {
+		return
+	}
+
+	cw, ok := h.conn.(interface{ CloseWrite() error })
+	if !ok || cw.CloseWrite() != nil {
+		return
+	}
+
+	// wait for the peer to close its side
+	h.conn.SetReadDeadline(deadline)
+	io.Copy(io.Discard, h.conn)
+}
+
 func (h *httpTransportClient) Close() error {
+	if d := h.ht.opts.DrainTimeout; d > 0 {
+		h.drain(d)
+	}
+
 	if !h.dialOpts.Stream {
 		h.once.Do(func() {
 			h.Lock()
@@ -211,6 +374,11 @@ func (h *httpTransportClient) Close() error {
 	return err
 }
 
+// ConnectionState returns the tls state of the connection the socket is on.
+func (h *httpTransportSocket) ConnectionState() *tls.ConnectionState {
+	return h.r.TLS
+}
+
 func (h *httpTransportSocket) Local() string {
 	return h.local
 }
@@ -249,13 +417,24 @@ func (h *httpTransportSocket) Recv(m *Message) error {
 		}
 
 		// read body
-		b, err := io.ReadAll(r.Body)
+		b, err := h.ht.readBody(r.Body, r.ContentLength)
 		if err != nil {
 			return err
 		}
 
 		// set body
 		r.Body.Close()
+
+		if name := r.Header.Get(compressHeader); len(name) > 0 {
+			c := findCompressor(h.ht.opts.Compressors, name)
+			if c == nil {
+				return fmt.Errorf("unsupported compression %s", name)
+			}
+			if b, err = h.ht.decompress(c, b); err != nil {
+				return err
+			}
+		}
+
 		m.Body = b
 
 		// set headers
@@ -267,6 +446,9 @@ func (h *httpTransportSocket) Recv(m *Message) error {
 			}
 		}
 
+		delete(m.Header, compressHeader)
+		delete(m.Header, compressAcceptHeader)
+
 		// return early early
 		return nil
 	}
@@ -320,22 +502,38 @@ func (h *httpTransportSocket) Send(m *Message) error {
 		for k, v := range h.r.Header {
 			hdr[k] = v
 		}
+		hdr.Del(compressHeader)
+		hdr.Del(compressAcceptHeader)
+
+		body := m.Body
+		if h.comp != nil {
+			cb, err := h.comp.Compress(body)
+			if err != nil {
+				return err
+			}
+			body = cb
+		}
 
 		rsp := &http.Response{
 			Header:        hdr,
-			Body:          io.NopCloser(bytes.NewReader(m.Body)),
+			Body:          io.NopCloser(bytes.NewReader(body)),
 			Status:        "200 OK",
 			StatusCode:    200,
 			Proto:         "HTTP/1.1",
 			ProtoMajor:    1,
 			ProtoMinor:    1,
-			ContentLength: int64(len(m.Body)),
+			ContentLength: int64(len(body)),
 		}
 
 		for k, v := range m.Header {
 			rsp.Header.Set(k, v)
 		}
 
+		// tell the client which compressor was chosen
+		if h.comp != nil {
+			rsp.Header.Set(compressHeader, h.comp.Name())
+		}
+
 		// set timeout if its greater than 0
 		if h.ht.opts.Timeout > time.Duration(0) {
 			h.conn.SetDeadline(time.Now().Add(h.ht.opts.Timeout))
@@ -434,8 +632,11 @@ func (h *httpTransportListener) Accept(fn func(Socket)) error {
 
 		// read a regular request
 		if r.ProtoMajor == 1 {
-			b, err := io.ReadAll(r.Body)
-			if err != nil {
+			b, err := h.ht.readBody(r.Body, r.ContentLength)
+			if err == ErrFrameTooLarge {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -461,6 +662,12 @@ func (h *httpTransportListener) Accept(fn func(Socket)) error {
 		// buffered reader
 		bufr := bufio.NewReader(r.Body)
 
+		// pick the compressor from those offered, only supported over http 1
+		var comp Compressor
+		if r.ProtoMajor == 1 {
+			comp = negotiateCompression(h.ht.opts.Compressors, r.Header.Get(compressAcceptHeader))
+		}
+
 		// save the request
 		ch := make(chan *http.Request, 1)
 		ch <- r
@@ -477,6 +684,7 @@ func (h *httpTransportListener) Accept(fn func(Socket)) error {
 			local:  h.Addr(),
 			remote: r.RemoteAddr,
 			closed: make(chan bool),
+			comp:   comp,
 		}
 
 		// execute the socket
@@ -507,6 +715,45 @@ func (h *httpTransportListener) Accept(fn func(Socket)) error {
 	return srv.Serve(h.listener)
 }
 
+// readBody reads a message body enforcing the max frame size. The declared
+// length is checked first so an oversized frame is rejected before reading.
+func (h *httpTransport) readBody(r io.Reader, length int64) ([]byte, error) {
+	max := h.opts.MaxFrameSize
+	if max <= 0 {
+		return io.ReadAll(r)
+	}
+
+	if length > max {
+		return nil, ErrFrameTooLarge
+	}
+
+	// the length may be unknown e.g chunked encoding
+	b, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > max {
+		return nil, ErrFrameTooLarge
+	}
+
+	return b, nil
+}
+
+// decompress decompresses a message body enforcing the max frame size.
+func (h *httpTransport) decompress(c Compressor, b []byte) ([]byte, error) {
+	b, err := c.Decompress(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if max := h.opts.MaxFrameSize; max > 0 && int64(len(b)) > max {
+		return nil, ErrFrameTooLarge
+	}
+
+	return b, nil
+}
+
 func (h *httpTransport) Dial(addr string, opts ...DialOption) (Client, error) {
 	dopts := DialOptions{
 		Timeout: DefaultDialTimeout,
@@ -529,11 +776,24 @@ func (h *httpTransport) Dial(addr string, opts ...DialOption) (Client, error) {
 		}
 		config.NextProtos = []string{"http/1.1"}
 		conn, err = newConn(func(addr string) (net.Conn, error) {
-			return tls.DialWithDialer(&net.Dialer{Timeout: dopts.Timeout}, "tcp", addr, config)
+			raddr, err := h.resolve(addr)
+			if err != nil {
+				return nil, err
+			}
+			// verify against the host rather than the resolved address
+			if raddr != addr && len(config.ServerName) == 0 {
+				config = config.Clone()
+				config.ServerName, _, _ = net.SplitHostPort(addr)
+			}
+			return tls.DialWithDialer(&net.Dialer{Timeout: dopts.Timeout}, "tcp", raddr, config)
 		})(addr)
 	} else {
 		conn, err = newConn(func(addr string) (net.Conn, error) {
-			return net.DialTimeout("tcp", addr, dopts.Timeout)
+			raddr, err := h.resolve(addr)
+			if err != nil {
+				return nil, err
+			}
+			return net.DialTimeout("tcp", raddr, dopts.Timeout)
 		})(addr)
 	}
 
@@ -541,16 +801,20 @@ func (h *httpTransport) Dial(addr string, opts ...DialOption) (Client, error) {
 		return nil, err
 	}
 
-	return &httpTransportClient{
+	c := &httpTransportClient{
 		ht:       h,
 		addr:     addr,
 		conn:     conn,
 		buff:     bufio.NewReader(conn),
 		dialOpts: dopts,
 		r:        make(chan *http.Request, 100),
+		failed:   make(map[*http.Request]bool),
 		local:    conn.LocalAddr().String(),
 		remote:   conn.RemoteAddr().String(),
-	}, nil
+	}
+	c.drained = sync.NewCond(&c.pmtx)
+
+	return c, nil
 }
 
 func (h *httpTransport) Listen(addr string, opts ...ListenOption) (Listener, error) {
@@ -607,6 +871,10 @@ func (h *httpTransport) Listen(addr string, opts ...ListenOption) (Listener, err
 		return nil, err
 	}
 
+	if options.MaxConns > 0 {
+		l = newLimitListener(l, options.MaxConns, options.Backlog)
+	}
+
 	return &httpTransportListener{
 		ht:       h,
 		listener: l,
@@ -617,9 +885,41 @@ func (h *httpTransport) Init(opts ...Option) error {
 	for _, o := range opts {
 		o(&h.opts)
 	}
+	var dns *dnsCache
+	if h.opts.DNSCacheTTL > 0 {
+		dns = newDNSCache(h.opts.DNSCacheTTL, h.opts.Resolver)
+	}
+
+	h.dmtx.Lock()
+	h.dns = dns
+	h.dmtx.Unlock()
+
 	return nil
 }
 
+// resolve replaces the host in addr with a cached address if enabled.
+func (h *httpTransport) resolve(addr string) (string, error) {
+	h.dmtx.RLock()
+	dns := h.dns
+	h.dmtx.RUnlock()
+
+	if dns == nil {
+		return addr, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	ip, err := dns.Resolve(host)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(ip, port), nil
+}
+
 func (h *httpTransport) Options() Options {
 	return h.opts
 }
@@ -629,9 +929,15 @@ func (h *httpTransport) String() string {
 }
 
 func NewHTTPTransport(opts ...Option) *httpTransport {
-	var options Options
+	options := Options{
+		MaxFrameSize: DefaultMaxFrameSize,
+	}
 	for _, o := range opts {
 		o(&options)
 	}
-	return &httpTransport{opts: options}
+	h := &httpTransport{opts: options}
+	if options.DNSCacheTTL > 0 {
+		h.dns = newDNSCache(options.DNSCacheTTL, options.Resolver)
+	}
+	return h
 }
@@ -1,8 +1,11 @@
 package transport
 
 import (
+	"bufio"
+	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"sync"
 	"testing"
 	"time"
@@ -474,3 +477,575 @@ func TestHttpTransportListenerNetListener(t *testing.T) {
 
 	<-done
 }
+
+func TestHTTPTransportMaxFrameSize(t *testing.T) {
+	tr := NewHTTPTransport(MaxFrameSize(1024))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	fn := func(sock Socket) {
+		defer sock.Close()
+
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	}
+
+	go l.Accept(fn)
+
+	// send an absurd length header to the listener
+	conn, err := net.Dial("tcp", l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer conn.Close()
+
+	req := "POST / HTTP/1.1\r\nHost: test\r\nContent-Length: 1099511627776\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("Unexpected write err: %v", err)
+	}
+
+	rsp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Unexpected read err: %v", err)
+	}
+	rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d got %d", http.StatusRequestEntityTooLarge, rsp.StatusCode)
+	}
+
+	// a frame within the limit is accepted
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer c.Close()
+
+	m := Message{
+		Header: map[string]string{},
+		Body:   []byte(`{"message": "Hello World"}`),
+	}
+
+	if err := c.Send(&m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+
+	var rm Message
+	if err := c.Recv(&rm); err != nil {
+		t.Fatalf("Unexpected recv err: %v", err)
+	}
+
+	// a response with an absurd length header is rejected by the client
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 1099511627776\r\n\r\n"))
+	}()
+
+	c2, err := tr.Dial(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer c2.Close()
+
+	if err := c2.Send(&m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+
+	if err := c2.Recv(&rm); err != ErrFrameTooLarge {
+		t.Fatalf("Expected %v got %v", ErrFrameTooLarge, err)
+	}
+}
+
+func TestHTTPTransportDrainOnClose(t *testing.T) {
+	tr := NewHTTPTransport(DrainTimeout(time.Second))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 10)
+
+	fn := func(sock Socket) {
+		defer sock.Close()
+
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			received <- string(m.Body)
+
+			// respond slowly so the response is still in flight on close
+			time.Sleep(time.Millisecond * 50)
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	}
+
+	go l.Accept(fn)
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+
+	rsp := make(chan error, 3)
+	for _, body := range []string{"1", "2", "3"} {
+		if err := c.Send(&Message{Body: []byte(body)}); err != nil {
+			t.Fatalf("Unexpected send err: %v", err)
+		}
+	}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			var m Message
+			rsp <- c.Recv(&m)
+		}
+	}()
+
+	// close straight after the last write
+	if err := c.Close(); err != nil {
+		t.Fatalf("Unexpected close err: %v", err)
+	}
+
+	for _, body := range []string{"1", "2", "3"} {
+		select {
+		case got := <-received:
+			if got != body {
+				t.Fatalf("Expected frame %s got %s", body, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Frame %s was not delivered", body)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-rsp:
+			if err != nil {
+				t.Fatalf("Expected in flight response to be delivered, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for in flight response")
+		}
+	}
+}
+
+func TestHTTPTransportDrainFailedSend(t *testing.T) {
+	tr := NewHTTPTransport(DrainTimeout(time.Second * 5))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock Socket) {
+		defer sock.Close()
+		var m Message
+		sock.Recv(&m)
+	})
+
+	c, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+
+	// break the connection so the write fails
+	c.(*httpTransportClient).conn.Close()
+
+	if err := c.Send(&Message{Body: []byte("1")}); err == nil {
+		t.Fatal("Expected the send to fail")
+	}
+
+	// no response is awaited for the failed send
+	start := time.Now()
+	c.Close()
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected close not to wait for the failed send, took %v", d)
+	}
+}
+
+type countingCompressor struct {
+	Compressor
+
+	mtx  sync.Mutex
+	used int
+}
+
+func (c *countingCompressor) Compress(b []byte) ([]byte, error) {
+	c.mtx.Lock()
+	c.used++
+	c.mtx.Unlock()
+	return c.Compressor.Compress(b)
+}
+
+func (c *countingCompressor) count() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.used
+}
+
+func TestHTTPTransportCompression(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   []string
+		listener []string
+		// the compressor expected to be used, if any
+		expect string
+	}{
+		{"matched", []string{"deflate", "gzip"}, []string{"gzip", "deflate"}, "gzip"},
+		{"partial", []string{"gzip", "deflate"}, []string{"deflate"}, "deflate"},
+		{"mismatched", []string{"gzip"}, []string{"deflate"}, ""},
+		{"client none", nil, []string{"gzip"}, ""},
+		{"listener none", []string{"gzip"}, nil, ""},
+	}
+
+	newCompressors := func(names []string) ([]Compressor, map[string]*countingCompressor) {
+		var cs []Compressor
+		counts := make(map[string]*countingCompressor)
+		for _, name := range names {
+			c := NewGzipCompressor()
+			if name == "deflate" {
+				c = NewFlateCompressor()
+			}
+			cc := &countingCompressor{Compressor: c}
+			cs = append(cs, cc)
+			counts[name] = cc
+		}
+		return cs, counts
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lcs, lcounts := newCompressors(tc.listener)
+			ccs, ccounts := newCompressors(tc.client)
+
+			l, err := NewHTTPTransport(Compression(lcs...)).Listen("127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Unexpected listen err: %v", err)
+			}
+			defer l.Close()
+
+			go l.Accept(func(sock Socket) {
+				defer sock.Close()
+
+				for {
+					var m Message
+					if err := sock.Recv(&m); err != nil {
+						return
+					}
+					if _, ok := m.Header[compressHeader]; ok {
+						t.Error("Expected the compression header to be removed")
+					}
+					if err := sock.Send(&m); err != nil {
+						return
+					}
+				}
+			})
+
+			c, err := NewHTTPTransport(Compression(ccs...)).Dial(l.Addr())
+			if err != nil {
+				t.Fatalf("Unexpected dial err: %v", err)
+			}
+			defer c.Close()
+
+			for i := 0; i < 3; i++ {
+				m := Message{
+					Header: map[string]string{"Content-Type": "application/json"},
+					Body:   []byte(`{"message": "Hello World"}`),
+				}
+				if err := c.Send(&m); err != nil {
+					t.Fatalf("Unexpected send err: %v", err)
+				}
+
+				var rm Message
+				if err := c.Recv(&rm); err != nil {
+					t.Fatalf("Unexpected recv err: %v", err)
+				}
+				if string(rm.Body) != string(m.Body) {
+					t.Fatalf("Expected %s, got %s", m.Body, rm.Body)
+				}
+			}
+
+			for name, cc := range lcounts {
+				if n := cc.count(); name == tc.expect && n != 3 {
+					t.Fatalf("Expected the listener to compress 3 responses with %s, got %d", name, n)
+				} else if name != tc.expect && n != 0 {
+					t.Fatalf("Expected the listener not to use %s, used %d times", name, n)
+				}
+			}
+
+			// the first request is sent before the negotiation completes
+			for name, cc := range ccounts {
+				if n := cc.count(); name == tc.expect && n != 2 {
+					t.Fatalf("Expected the client to compress 2 requests with %s, got %d", name, n)
+				} else if name != tc.expect && n != 0 {
+					t.Fatalf("Expected the client not to use %s, used %d times", name, n)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPTransportMaxConns(t *testing.T) {
+	tr := NewHTTPTransport()
+
+	l, err := tr.Listen("127.0.0.1:0", MaxConns(2))
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	fn := func(sock Socket) {
+		defer sock.Close()
+
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	}
+
+	go l.Accept(fn)
+
+	m := Message{
+		Header: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: []byte(`{"message": "Hello World"}`),
+	}
+
+	echo := func(c Client) error {
+		if err := c.Send(&m); err != nil {
+			return err
+		}
+		var rm Message
+		if err := c.Recv(&rm); err != nil {
+			return err
+		}
+		if string(rm.Body) != string(m.Body) {
+			return fmt.Errorf("expected %s, got %s", m.Body, rm.Body)
+		}
+		return nil
+	}
+
+	var clients []Client
+	for i := 0; i < 2; i++ {
+		c, err := tr.Dial(l.Addr())
+		if err != nil {
+			t.Fatalf("Unexpected dial err: %v", err)
+		}
+		defer c.Close()
+
+		if err := echo(c); err != nil {
+			t.Fatalf("Unexpected echo err: %v", err)
+		}
+		clients = append(clients, c)
+	}
+
+	// the connection beyond the limit is refused
+	c, err := tr.Dial(l.Addr(), WithTimeout(time.Second))
+	if err == nil {
+		err = echo(c)
+		c.Close()
+	}
+	if err == nil {
+		t.Fatal("Expected the connection beyond the limit to be refused")
+	}
+
+	// the existing connections keep working
+	for _, c := range clients {
+		if err := echo(c); err != nil {
+			t.Fatalf("Unexpected echo err: %v", err)
+		}
+	}
+
+	// closing one makes room for another
+	clients[0].Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		c, err := tr.Dial(l.Addr(), WithTimeout(time.Second))
+		if err == nil {
+			err = echo(c)
+			c.Close()
+		}
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a connection once one closed, got %v", err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestHTTPTransportAcceptBacklog(t *testing.T) {
+	tr := NewHTTPTransport()
+
+	l, err := tr.Listen("127.0.0.1:0", MaxConns(1), AcceptBacklog(1))
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock Socket) {
+		defer sock.Close()
+
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	m := Message{
+		Header: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: []byte(`{"message": "Hello World"}`),
+	}
+
+	first, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	if err := first.Send(&m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+	var rm Message
+	if err := first.Recv(&rm); err != nil {
+		t.Fatalf("Unexpected recv err: %v", err)
+	}
+
+	// the second waits in the backlog until the first is done
+	second, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer second.Close()
+
+	got := make(chan error, 1)
+	go func() {
+		if err := second.Send(&m); err != nil {
+			got <- err
+			return
+		}
+		var rm Message
+		got <- second.Recv(&rm)
+	}()
+
+	select {
+	case err := <-got:
+		t.Fatalf("Expected the second connection to wait, got %v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	first.Close()
+
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("Expected the delayed connection to be served, got %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Expected the delayed connection to be served")
+	}
+}
+
+func TestHTTPTransportNegativeBacklog(t *testing.T) {
+	tr := NewHTTPTransport()
+
+	l, err := tr.Listen("127.0.0.1:0", MaxConns(1), AcceptBacklog(-1))
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock Socket) {
+		defer sock.Close()
+
+		for {
+			var m Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	m := Message{
+		Header: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: []byte(`{"message": "Hello World"}`),
+	}
+
+	first, err := tr.Dial(l.Addr())
+	if err != nil {
+		t.Fatalf("Unexpected dial err: %v", err)
+	}
+	defer first.Close()
+
+	if err := first.Send(&m); err != nil {
+		t.Fatalf("Unexpected send err: %v", err)
+	}
+	var rm Message
+	if err := first.Recv(&rm); err != nil {
+		t.Fatalf("Unexpected recv err: %v", err)
+	}
+
+	// a negative backlog is no backlog so the second is refused
+	second, err := tr.Dial(l.Addr(), WithTimeout(time.Second))
+	if err == nil {
+		if err = second.Send(&m); err == nil {
+			err = second.Recv(&rm)
+		}
+		second.Close()
+	}
+	if err == nil {
+		t.Fatal("Expected the connection beyond the limit to be refused")
+	}
+}
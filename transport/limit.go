@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"net"
+	"sync"
+)
+
+// limitListener bounds the connections served at once. Connections
+// beyond the limit wait in the backlog for one to close, those which
+// don't fit in the backlog are refused by closing them.
+type limitListener struct {
+	net.Listener
+	// a slot for each connection being served
+	sem     chan struct{}
+	backlog int
+	ready   chan net.Conn
+	errc    chan error
+	exit    chan bool
+
+	sync.Mutex
+	// connections waiting for a slot
+	waiting int
+}
+
+// limitConn frees its slot when closed.
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func newLimitListener(l net.Listener, max, backlog int) net.Listener {
+	if backlog < 0 {
+		backlog = 0
+	}
+
+	ll := &limitListener{
+		Listener: l,
+		sem:      make(chan struct{}, max),
+		backlog:  backlog,
+		ready:    make(chan net.Conn),
+		errc:     make(chan error, 1),
+		exit:     make(chan bool),
+	}
+
+	go ll.run()
+
+	return ll
+}
+
+// run accepts connections, handing them to Accept once there's a slot.
+func (l *limitListener) run() {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			l.errc <- err
+			return
+		}
+
+		select {
+		case l.sem <- struct{}{}:
+			go l.serve(c)
+			continue
+		default:
+		}
+
+		l.Lock()
+		if l.waiting >= l.backlog {
+			l.Unlock()
+			c.Close()
+			continue
+		}
+		l.waiting++
+		l.Unlock()
+
+		go l.wait(c)
+	}
+}
+
+// wait holds the connection in the backlog until there's a slot.
+func (l *limitListener) wait(c net.Conn) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.exit:
+		c.Close()
+		return
+	}
+
+	l.Lock()
+	l.waiting--
+	l.Unlock()
+
+	l.serve(c)
+}
+
+// serve hands the connection holding a slot to Accept.
+func (l *limitListener) serve(c net.Conn) {
+	lc := &limitConn{
+		Conn:    c,
+		release: func() { <-l.sem },
+	}
+
+	select {
+	case l.ready <- lc:
+	case <-l.exit:
+		lc.Close()
+	}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.ready:
+		return c, nil
+	case err := <-l.errc:
+		// keep the error for any later calls
+		l.errc <- err
+		return nil, err
+	}
+}
+
+func (l *limitListener) Close() error {
+	l.Lock()
+	select {
+	case <-l.exit:
+	default:
+		close(l.exit)
+	}
+	l.Unlock()
+
+	return l.Listener.Close()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
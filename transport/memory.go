@@ -101,11 +101,20 @@ func (ms *memorySocket) Send(m *Message) error {
 		return errors.New("connection closed")
 	case <-ms.lexit:
 		return errors.New("server connection closed")
-	case ms.send <- m:
+	case ms.send <- copyMessage(m):
 	}
 	return nil
 }
 
+// copyMessage copies the body like a network transport would, senders
+// such as the client codec reuse the buffer once Send has returned.
+func copyMessage(m *Message) *Message {
+	return &Message{
+		Header: m.Header,
+		Body:   append([]byte(nil), m.Body...),
+	}
+}
+
 func (ms *memorySocket) Close() error {
 	ms.Lock()
 	defer ms.Unlock()
@@ -25,6 +25,19 @@ type Options struct {
 	TLSConfig *tls.Config
 	// Timeout sets the timeout for Send/Recv
 	Timeout time.Duration
+	// MaxFrameSize is the maximum size in bytes of a message body
+	// read from the wire. A value of zero or less disables the limit.
+	MaxFrameSize int64
+	// DNSCacheTTL caches host lookups made when dialing for the
+	// duration. Zero leaves resolution to the dialer.
+	DNSCacheTTL time.Duration
+	// Resolver used for cached lookups, defaults to net.DefaultResolver
+	Resolver Resolver
+	// DrainTimeout bounds how long closing a client waits for pending
+	// sends and responses to complete. Zero closes immediately.
+	DrainTimeout time.Duration
+	// Compressors supported for message bodies, most preferred first
+	Compressors []Compressor
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -51,6 +64,12 @@ type ListenOptions struct {
 	// TODO: add tls options when listening
 	// Currently set in global options
 
+	// MaxConns limits the connections served at once, zero is unlimited
+	MaxConns int
+	// Backlog is how many connections beyond MaxConns wait for
+	// one to close, the rest are refused
+	Backlog int
+
 	// Other options for implementations of the interface
 	// can be stored in a context
 	Context context.Context
@@ -78,6 +97,50 @@ func Timeout(t time.Duration) Option {
 	}
 }
 
+// MaxFrameSize sets the maximum size of a message body read from the wire.
+// Larger messages are rejected before being read into memory.
+func MaxFrameSize(s int64) Option {
+	return func(o *Options) {
+		o.MaxFrameSize = s
+	}
+}
+
+// DNSCache caches the addresses of hosts dialed for ttl. Hosts with
+// multiple addresses are dialed in turn and failed lookups are cached
+// for DefaultDNSNegativeTTL. Expired entries are refreshed in the background,
+// if that fails the stale addresses are kept and retried after the negative ttl.
+func DNSCache(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.DNSCacheTTL = ttl
+	}
+}
+
+// DNSResolver sets the resolver used by the DNS cache.
+func DNSResolver(r Resolver) Option {
+	return func(o *Options) {
+		o.Resolver = r
+	}
+}
+
+// DrainTimeout makes client Close wait up to d for in flight
+// sends and responses before tearing down the connection.
+func DrainTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.DrainTimeout = d
+	}
+}
+
+// Compression sets the compressors supported for message bodies, most
+// preferred first. A client offers them on its first message and the
+// listener picks the first of its own the client also supports, which
+// both ends then use for the connection. Without any overlap messages
+// are sent uncompressed.
+func Compression(c ...Compressor) Option {
+	return func(o *Options) {
+		o.Compressors = c
+	}
+}
+
 // Use secure communication. If TLSConfig is not specified we
 // use InsecureSkipVerify and generate a self signed cert.
 func Secure(b bool) Option {
@@ -125,3 +188,23 @@ func NetListener(customListener net.Listener) ListenOption {
 		o.Context = context.WithValue(o.Context, netListener{}, customListener)
 	}
 }
+
+// MaxConns limits the connections a listener serves at once, protecting
+// against exhausting file descriptors. By default connections beyond the
+// limit are refused by closing them, see AcceptBacklog to delay them instead.
+// It's supported by the http transport.
+func MaxConns(n int) ListenOption {
+	return func(o *ListenOptions) {
+		o.MaxConns = n
+	}
+}
+
+// AcceptBacklog delays up to n connections beyond MaxConns until one
+// closes rather than refusing them, a negative n is treated as zero. Those
+// which don't fit in the backlog are refused. The operating system's
+// backlog of connections waiting to be accepted is left as is.
+func AcceptBacklog(n int) ListenOption {
+	return func(o *ListenOptions) {
+		o.Backlog = n
+	}
+}
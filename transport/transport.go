@@ -2,6 +2,8 @@
 package transport
 
 import (
+	"crypto/tls"
+	"errors"
 	"time"
 )
 
@@ -29,6 +31,12 @@ type Socket interface {
 	Remote() string
 }
 
+// TLSSocket is implemented by sockets which may be secured with tls.
+type TLSSocket interface {
+	// ConnectionState returns the tls state, nil for plain connections
+	ConnectionState() *tls.ConnectionState
+}
+
 type Client interface {
 	Socket
 }
@@ -49,4 +57,10 @@ var (
 	DefaultTransport Transport = NewHTTPTransport()
 
 	DefaultDialTimeout = time.Second * 5
+
+	// DefaultMaxFrameSize is the default maximum message body size of 32MiB.
+	DefaultMaxFrameSize int64 = 32 * 1024 * 1024
+
+	// ErrFrameTooLarge is returned when a message exceeds the max frame size.
+	ErrFrameTooLarge = errors.New("frame exceeds max frame size")
 )
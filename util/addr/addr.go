@@ -61,74 +61,117 @@ func IsLocal(addr string) bool {
 
 // Extract returns a real ip.
 func Extract(addr string) (string, error) {
+	return ExtractPreferred(addr)
+}
+
+// ExtractPreferred returns a real ip like Extract, trying the preferences
+// in order first. Each is the name of an interface e.g eth0 or a CIDR block
+// e.g 10.0.0.0/8. Otherwise private addresses are preferred, then public
+// ones and loopback addresses are only used as a last resort.
+func ExtractPreferred(addr string, prefer ...string) (string, error) {
 	// if addr specified then its returned
 	if len(addr) > 0 && (addr != "0.0.0.0" && addr != "[::]" && addr != "::") {
 		return addr, nil
 	}
 
-	ifaces, err := net.Interfaces()
+	ifaces, err := interfaceAddrs()
 	if err != nil {
 		return "", fmt.Errorf("Failed to get interfaces! Err: %v", err)
 	}
 
-	var addrs []net.Addr
-	var loAddrs []net.Addr
+	ip := chooseIP(ifaces, prefer)
+	if ip == nil {
+		return "", fmt.Errorf("No IP address found, and explicit IP not provided")
+	}
+
+	return ip.String(), nil
+}
+
+// ifaceAddrs are the addresses of a network interface.
+type ifaceAddrs struct {
+	name     string
+	loopback bool
+	ips      []net.IP
+}
+
+func interfaceAddrs() ([]ifaceAddrs, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]ifaceAddrs, 0, len(ifaces))
+
 	for _, iface := range ifaces {
-		ifaceAddrs, err := iface.Addrs()
+		addrs, err := iface.Addrs()
 		if err != nil {
 			// ignore error, interface can disappear from system
 			continue
 		}
-		if iface.Flags&net.FlagLoopback != 0 {
-			loAddrs = append(loAddrs, ifaceAddrs...)
-			continue
+
+		ia := ifaceAddrs{
+			name:     iface.Name,
+			loopback: iface.Flags&net.FlagLoopback != 0,
+		}
+
+		for _, rawAddr := range addrs {
+			switch addr := rawAddr.(type) {
+			case *net.IPAddr:
+				ia.ips = append(ia.ips, addr.IP)
+			case *net.IPNet:
+				ia.ips = append(ia.ips, addr.IP)
+			}
 		}
-		addrs = append(addrs, ifaceAddrs...)
+
+		list = append(list, ia)
 	}
-	addrs = append(addrs, loAddrs...)
 
-	var ipAddr string
-	var publicIP string
+	return list, nil
+}
 
-	for _, rawAddr := range addrs {
-		var ip net.IP
-		switch addr := rawAddr.(type) {
-		case *net.IPAddr:
-			ip = addr.IP
-		case *net.IPNet:
-			ip = addr.IP
-		default:
-			continue
-		}
+// chooseIP picks the address to use from the interfaces.
+func chooseIP(ifaces []ifaceAddrs, prefer []string) net.IP {
+	// the preference list wins
+	for _, p := range prefer {
+		_, block, err := net.ParseCIDR(p)
 
-		if !isPrivateIP(ip.String()) {
-			publicIP = ip.String()
-			continue
+		for _, iface := range ifaces {
+			for _, ip := range iface.ips {
+				if (err == nil && block.Contains(ip)) || (err != nil && iface.name == p) {
+					return ip
+				}
+			}
 		}
-
-		ipAddr = ip.String()
-		break
 	}
 
-	// return private ip
-	if len(ipAddr) > 0 {
-		a := net.ParseIP(ipAddr)
-		if a == nil {
-			return "", fmt.Errorf("ip addr %s is invalid", ipAddr)
+	var public, linkLocal, loopback net.IP
+
+	for _, iface := range ifaces {
+		for _, ip := range iface.ips {
+			switch {
+			case iface.loopback || ip.IsLoopback():
+				if loopback == nil {
+					loopback = ip
+				}
+			case ip.IsLinkLocalUnicast():
+				if linkLocal == nil {
+					linkLocal = ip
+				}
+			case isPrivateIP(ip.String()):
+				return ip
+			case public == nil:
+				public = ip
+			}
 		}
-		return a.String(), nil
 	}
 
-	// return public or virtual ip
-	if len(publicIP) > 0 {
-		a := net.ParseIP(publicIP)
-		if a == nil {
-			return "", fmt.Errorf("ip addr %s is invalid", publicIP)
+	for _, ip := range []net.IP{public, linkLocal, loopback} {
+		if ip != nil {
+			return ip
 		}
-		return a.String(), nil
 	}
 
-	return "", fmt.Errorf("No IP address found, and explicit IP not provided")
+	return nil
 }
 
 // IPs returns all known ips.
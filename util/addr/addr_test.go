@@ -75,3 +75,37 @@ func TestAppendPrivateBlocks(t *testing.T) {
 		})
 	}
 }
+
+func TestChooseIP(t *testing.T) {
+	ifaces := []ifaceAddrs{
+		{name: "lo", loopback: true, ips: []net.IP{net.ParseIP("127.0.0.1")}},
+		{name: "eth0", ips: []net.IP{net.ParseIP("fe80::1"), net.ParseIP("80.1.1.1")}},
+		{name: "eth1", ips: []net.IP{net.ParseIP("10.0.0.1")}},
+		{name: "docker0", ips: []net.IP{net.ParseIP("172.17.0.1")}},
+	}
+
+	testData := []struct {
+		name   string
+		ifaces []ifaceAddrs
+		prefer []string
+		expect string
+	}{
+		{"private", ifaces, nil, "10.0.0.1"},
+		{"public", ifaces[:2], nil, "80.1.1.1"},
+		{"link local", []ifaceAddrs{ifaces[0], {name: "eth0", ips: []net.IP{net.ParseIP("fe80::1")}}}, nil, "fe80::1"},
+		{"loopback", ifaces[:1], nil, "127.0.0.1"},
+		{"interface", ifaces, []string{"docker0"}, "172.17.0.1"},
+		{"cidr", ifaces, []string{"80.0.0.0/8"}, "80.1.1.1"},
+		{"preference order", ifaces, []string{"192.168.0.0/16", "eth9", "docker0", "eth1"}, "172.17.0.1"},
+		{"no match", ifaces, []string{"eth9"}, "10.0.0.1"},
+		{"none", nil, nil, "<nil>"},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			if ip := chooseIP(d.ifaces, d.prefer); ip.String() != d.expect {
+				t.Fatalf("Expected %s got %s", d.expect, ip)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package pool
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -8,39 +10,127 @@ import (
 	"go-micro.dev/v4/transport"
 )
 
+// ErrPoolClosed is returned when using a pool which has been closed.
+var ErrPoolClosed = errors.New("pool closed")
+
 type pool struct {
-	size int
-	ttl  time.Duration
-	tr   transport.Transport
+	size     int
+	ttl      time.Duration
+	tr       transport.Transport
+	blocking bool
 
 	sync.Mutex
-	conns map[string][]*poolConn
+	conns  map[string][]*poolConn
+	closed bool
+	// connections in use by address
+	active map[string]int
+	// closed and replaced whenever a connection is released
+	released chan struct{}
+
+	// stops the reaper
+	exit chan bool
+	once sync.Once
 }
 
 type poolConn struct {
 	transport.Client
 	id      string
 	created time.Time
+	// address it was got for
+	addr string
 }
 
 func newPool(options Options) *pool {
-	return &pool{
-		size:  options.Size,
-		tr:    options.Transport,
-		ttl:   options.TTL,
-		conns: make(map[string][]*poolConn),
+	p := &pool{
+		size:     options.Size,
+		tr:       options.Transport,
+		ttl:      options.TTL,
+		blocking: options.Blocking,
+		conns:    make(map[string][]*poolConn),
+		active:   make(map[string]int),
+		released: make(chan struct{}),
+		exit:     make(chan bool),
+	}
+
+	if options.ReapInterval > 0 {
+		go p.reap(options.ReapInterval)
+	}
+
+	return p
+}
+
+// closeConns closes the connections, the lock mustn't be held.
+func closeConns(conns []*poolConn) {
+	for _, conn := range conns {
+		conn.Client.Close()
+	}
+}
+
+// reap periodically closes idle connections which have outlived the ttl.
+func (p *pool) reap(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-p.exit:
+			return
+		case <-t.C:
+		}
+
+		var expired []*poolConn
+
+		p.Lock()
+		for addr, conns := range p.conns {
+			var keep []*poolConn
+			for _, conn := range conns {
+				if time.Since(conn.Created()) > p.ttl {
+					expired = append(expired, conn)
+					continue
+				}
+				keep = append(keep, conn)
+			}
+
+			if len(keep) == 0 {
+				delete(p.conns, addr)
+				continue
+			}
+			p.conns[addr] = keep
+		}
+		p.Unlock()
+
+		// close outside the lock as closing may wait on the connection
+		closeConns(expired)
 	}
 }
 
+// Close closes all pooled connections. They're closed concurrently so
+// transports which drain on close are bounded by a single drain timeout.
 func (p *pool) Close() error {
+	p.once.Do(func() {
+		close(p.exit)
+	})
+
+	var wg sync.WaitGroup
+
 	p.Lock()
+	p.closed = true
+	// wake anyone waiting on a connection
+	p.notify()
 	for k, c := range p.conns {
 		for _, conn := range c {
-			conn.Client.Close()
+			wg.Add(1)
+			go func(conn *poolConn) {
+				defer wg.Done()
+				conn.Client.Close()
+			}(conn)
 		}
 		delete(p.conns, k)
 	}
 	p.Unlock()
+
+	wg.Wait()
+
 	return nil
 }
 
@@ -58,50 +148,105 @@ func (p *poolConn) Created() time.Time {
 }
 
 func (p *pool) Get(addr string, opts ...transport.DialOption) (Conn, error) {
+	return p.GetContext(context.Background(), addr, opts...)
+}
+
+// GetContext gets a connection, if the pool is blocking and at capacity it
+// waits for one to be released until the context is done.
+func (p *pool) GetContext(ctx context.Context, addr string, opts ...transport.DialOption) (Conn, error) {
+	// old conns are closed outside the lock as closing may wait on the connection
+	var stale []*poolConn
+
 	p.Lock()
-	conns := p.conns[addr]
-
-	// while we have conns check age and then return one
-	// otherwise we'll create a new conn
-	for len(conns) > 0 {
-		conn := conns[len(conns)-1]
-		conns = conns[:len(conns)-1]
-		p.conns[addr] = conns
-
-		// if conn is old kill it and move on
-		if d := time.Since(conn.Created()); d > p.ttl {
-			conn.Client.Close()
-			continue
+
+	for {
+		if p.closed {
+			p.Unlock()
+			closeConns(stale)
+			return nil, ErrPoolClosed
+		}
+
+		conns := p.conns[addr]
+
+		// while we have conns check age and then return one
+		// otherwise we'll create a new conn
+		for len(conns) > 0 {
+			conn := conns[len(conns)-1]
+			conns = conns[:len(conns)-1]
+			p.conns[addr] = conns
+
+			// if conn is old kill it and move on
+			if d := time.Since(conn.Created()); d > p.ttl {
+				stale = append(stale, conn)
+				continue
+			}
+
+			// we got a good conn, lets unlock and return it
+			p.active[addr]++
+			p.Unlock()
+			closeConns(stale)
+
+			return conn, nil
 		}
 
-		// we got a good conn, lets unlock and return it
+		if !p.blocking || p.active[addr] < p.size {
+			break
+		}
+
+		// at capacity so wait for a release
+		released := p.released
 		p.Unlock()
 
-		return conn, nil
+		closeConns(stale)
+		stale = nil
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		p.Lock()
 	}
 
+	p.active[addr]++
 	p.Unlock()
 
+	closeConns(stale)
+
 	// create new conn
 	c, err := p.tr.Dial(addr, opts...)
 	if err != nil {
+		p.Lock()
+		p.done(addr)
+		p.Unlock()
 		return nil, err
 	}
 	return &poolConn{
 		Client:  c,
 		id:      uuid.New().String(),
 		created: time.Now(),
+		addr:    addr,
 	}, nil
 }
 
 func (p *pool) Release(conn Conn, err error) error {
+	p.Lock()
+	p.done(conn.(*poolConn).addr)
+
 	// don't store the conn if it has errored
 	if err != nil {
+		p.Unlock()
 		return conn.(*poolConn).Client.Close()
 	}
 
 	// otherwise put it back for reuse
-	p.Lock()
+	if p.closed {
+		p.Unlock()
+		conn.(*poolConn).Client.Close()
+		return ErrPoolClosed
+	}
+
 	conns := p.conns[conn.Remote()]
 	if len(conns) >= p.size {
 		p.Unlock()
@@ -112,3 +257,20 @@ func (p *pool) Release(conn Conn, err error) error {
 
 	return nil
 }
+
+// done marks a connection to the address as no longer in use,
+// the lock must be held.
+func (p *pool) done(addr string) {
+	if p.active[addr] <= 1 {
+		delete(p.active, addr)
+	} else {
+		p.active[addr]--
+	}
+	p.notify()
+}
+
+// notify wakes those waiting on a connection, the lock must be held.
+func (p *pool) notify() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
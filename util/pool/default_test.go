@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -86,3 +87,371 @@ func TestClientPool(t *testing.T) {
 	testPool(t, 0, time.Minute)
 	testPool(t, 2, time.Minute)
 }
+
+func TestPoolCloseDrain(t *testing.T) {
+	drain := time.Millisecond * 200
+	tr := transport.NewHTTPTransport(transport.DrainTimeout(drain))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan bool, 10)
+
+	// read frames but never respond
+	go l.Accept(func(s transport.Socket) {
+		defer s.Close()
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+			received <- true
+		}
+	})
+
+	p := newPool(Options{
+		TTL:       time.Minute,
+		Size:      10,
+		Transport: tr,
+	})
+
+	var conns []Conn
+	for i := 0; i < 3; i++ {
+		c, err := p.Get(l.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Send(&transport.Message{Body: []byte(`hello world`)}); err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+
+	for _, c := range conns {
+		if err := p.Release(c, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	p.Close()
+
+	// each conn waits on its unanswered request, but they drain together
+	if d := time.Since(start); d < drain || d > drain*2 {
+		t.Fatalf("expected close to take about %v, took %v", drain, d)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("expected 3 frames delivered, got %d", i)
+		}
+	}
+}
+
+func TestPoolCloseInFlight(t *testing.T) {
+	tr := transport.NewHTTPTransport(transport.DrainTimeout(time.Second))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// respond slowly so the response is still in flight on close
+	go l.Accept(func(s transport.Socket) {
+		defer s.Close()
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond * 50)
+			if err := s.Send(&msg); err != nil {
+				return
+			}
+		}
+	})
+
+	p := newPool(Options{
+		TTL:       time.Minute,
+		Size:      10,
+		Transport: tr,
+	})
+
+	// the client dials stream connections
+	c, err := p.Get(l.Addr(), transport.WithStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(&transport.Message{Body: []byte(`hello world`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the response is read after close has started
+	rsp := make(chan error, 1)
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		var msg transport.Message
+		rsp <- c.Recv(&msg)
+	}()
+
+	if err := p.Release(c, nil); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	select {
+	case err := <-rsp:
+		if err != nil {
+			t.Fatalf("expected the in flight response to be delivered, got %v", err)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("timed out waiting for the in flight response")
+	}
+}
+
+func TestPoolStaleClose(t *testing.T) {
+	drain := time.Millisecond * 200
+	tr := transport.NewHTTPTransport(transport.DrainTimeout(drain))
+
+	l, err := tr.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// read frames but never respond
+	go l.Accept(func(s transport.Socket) {
+		defer s.Close()
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				return
+			}
+		}
+	})
+
+	p := newPool(Options{
+		TTL:       time.Millisecond * 50,
+		Size:      10,
+		Transport: tr,
+	})
+	defer p.Close()
+
+	// a conn with an unanswered request drains when closed
+	c, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(&transport.Message{Body: []byte(`hello world`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Release(c, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+
+	// closes the stale conn
+	done := make(chan error, 1)
+	go func() {
+		c, err := p.Get(l.Addr())
+		if err == nil {
+			p.Release(c, nil)
+		}
+		done <- err
+	}()
+
+	time.Sleep(time.Millisecond * 20)
+
+	// isn't held up by the stale conn closing
+	start := time.Now()
+	c, err = p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > drain/2 {
+		t.Fatalf("expected get not to wait on the stale conn closing, took %v", d)
+	}
+	p.Release(c, nil)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolReap(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	closed := make(chan bool, 10)
+
+	go l.Accept(func(s transport.Socket) {
+		for {
+			var msg transport.Message
+			if err := s.Recv(&msg); err != nil {
+				closed <- true
+				return
+			}
+		}
+	})
+
+	p := newPool(Options{
+		TTL:          time.Millisecond * 50,
+		Size:         10,
+		Transport:    tr,
+		ReapInterval: time.Millisecond * 10,
+	})
+	defer p.Close()
+
+	var conns []Conn
+	for i := 0; i < 2; i++ {
+		c, err := p.Get(l.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+
+	for _, c := range conns {
+		if err := p.Release(c, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the idle connections are closed without calling Get
+	for i := 0; i < 2; i++ {
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatalf("expected 2 connections to be reaped, got %d", i)
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if n := len(p.conns[l.Addr()]); n != 0 {
+		t.Fatalf("expected no pooled connections got %d", n)
+	}
+}
+
+func TestPoolClosed(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(s transport.Socket) {
+		var msg transport.Message
+		s.Recv(&msg)
+	})
+
+	p := newPool(Options{
+		TTL:       time.Minute,
+		Size:      10,
+		Transport: tr,
+	})
+
+	// a conn still in use when the pool closes
+	c, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Get(l.Addr()); err != ErrPoolClosed {
+		t.Fatalf("Expected ErrPoolClosed from Get got %v", err)
+	}
+
+	if err := p.Release(c, nil); err != ErrPoolClosed {
+		t.Fatalf("Expected ErrPoolClosed from Release got %v", err)
+	}
+
+	// the released conn is closed rather than kept
+	if err := c.Send(&transport.Message{}); err == nil {
+		t.Fatal("Expected the released conn to be closed")
+	}
+	if len(p.conns) != 0 {
+		t.Fatalf("Expected no pooled conns got %d", len(p.conns))
+	}
+}
+
+func TestPoolBlocking(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+
+	l, err := tr.Listen(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(s transport.Socket) {})
+
+	p := newPool(Options{
+		TTL:       time.Minute,
+		Size:      1,
+		Transport: tr,
+		Blocking:  true,
+	})
+
+	c, err := p.Get(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// at capacity so waits until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	if _, err := p.GetContext(ctx, l.Addr()); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the deadline to be exceeded got %v", err)
+	}
+
+	// released connections are reused
+	if err := p.Release(c, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c2, err := p.GetContext(context.Background(), l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2.Id() != c.Id() {
+		t.Fatal("Expected the released connection to be reused")
+	}
+
+	// closing the pool wakes the waiters
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := p.GetContext(context.Background(), l.Addr())
+		errCh <- err
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+	p.Close()
+
+	select {
+	case err := <-errCh:
+		if err != ErrPoolClosed {
+			t.Fatalf("Expected ErrPoolClosed got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiter to be woken on close")
+	}
+}
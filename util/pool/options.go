@@ -10,6 +10,13 @@ type Options struct {
 	Transport transport.Transport
 	TTL       time.Duration
 	Size      int
+	// ReapInterval is how often idle connections older than the TTL
+	// are closed in the background. Zero disables the reaper and old
+	// connections are only closed when Get finds them.
+	ReapInterval time.Duration
+	// Blocking caps the connections in use per address at the Size,
+	// getting another waits for one to be released.
+	Blocking bool
 }
 
 type Option func(*Options)
@@ -31,3 +38,19 @@ func TTL(t time.Duration) Option {
 		o.TTL = t
 	}
 }
+
+// ReapInterval enables closing connections older than the TTL in the
+// background, checking every d.
+func ReapInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.ReapInterval = d
+	}
+}
+
+// Blocking makes getting a connection wait when Size connections
+// to the address are already in use.
+func Blocking(b bool) Option {
+	return func(o *Options) {
+		o.Blocking = b
+	}
+}
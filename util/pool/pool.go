@@ -2,6 +2,7 @@
 package pool
 
 import (
+	"context"
 	"time"
 
 	"go-micro.dev/v4/transport"
@@ -17,6 +18,12 @@ type Pool interface {
 	Release(c Conn, status error) error
 }
 
+// ContextGetter is implemented by pools which can stop
+// waiting for a connection when a context is done.
+type ContextGetter interface {
+	GetContext(ctx context.Context, addr string, opts ...transport.DialOption) (Conn, error)
+}
+
 type Conn interface {
 	// unique id of connection
 	Id() string
@@ -33,3 +40,15 @@ func NewPool(opts ...Option) Pool {
 	}
 	return newPool(options)
 }
+
+// GetContext gets a connection from the pool, returning the context's
+// error if it's done before one is available.
+func GetContext(ctx context.Context, p Pool, addr string, opts ...transport.DialOption) (Conn, error) {
+	if g, ok := p.(ContextGetter); ok {
+		return g.GetContext(ctx, addr, opts...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.Get(addr, opts...)
+}
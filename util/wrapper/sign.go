@@ -0,0 +1,175 @@
+package wrapper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/codec/bytes"
+	jsonCodec "go-micro.dev/v4/codec/json"
+	protoCodec "go-micro.dev/v4/codec/proto"
+	"go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/server"
+)
+
+var (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request
+	SignatureHeader = HeaderPrefix + "Signature"
+	// SignatureTimestampHeader carries the unix time the request was signed at
+	SignatureTimestampHeader = HeaderPrefix + "Signature-Timestamp"
+	// SignatureKeyHeader carries the id of the key the request was signed with
+	SignatureKeyHeader = HeaderPrefix + "Signature-Key"
+
+	// DefaultSignatureSkew is how far the signing time may be from
+	// the server's clock, in either direction.
+	DefaultSignatureSkew = time.Minute * 5
+)
+
+// SignatureKeys returns the key with the id a request was signed with,
+// the id is empty if the caller didn't send one.
+type SignatureKeys func(id string) ([]byte, error)
+
+// SharedKey returns SignatureKeys using the key for every request.
+func SharedKey(key []byte) SignatureKeys {
+	return func(string) ([]byte, error) {
+		return key, nil
+	}
+}
+
+// canonicalRequest is the string signed for a request, the lines are the
+// service, endpoint, unix timestamp and hex SHA-256 of the encoded body.
+func canonicalRequest(service, endpoint, timestamp string, body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return strings.Join([]string{
+		service,
+		endpoint,
+		timestamp,
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+}
+
+func sign(key []byte, canonical string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type signWrapper struct {
+	client.Client
+
+	id  string
+	key []byte
+}
+
+// signMarshaler returns the marshaler for content types whose codec
+// sends a raw frame as is, so the bytes signed are those sent.
+func signMarshaler(contentType string) (codec.Marshaler, bool) {
+	switch contentType {
+	case "application/json":
+		return jsonCodec.Marshaler{}, true
+	case "application/protobuf", "application/x-protobuf":
+		return protoCodec.Marshaler{}, true
+	case "application/octet-stream":
+		return bytes.Marshaler{}, true
+	default:
+		return nil, false
+	}
+}
+
+func (s *signWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	m, ok := signMarshaler(req.ContentType())
+	if !ok {
+		return errors.InternalServerError("go.micro.client", "can't sign %s requests", req.ContentType())
+	}
+
+	body, err := m.Marshal(req.Body())
+	if err != nil {
+		return errors.InternalServerError("go.micro.client", "failed to sign request: %v", err)
+	}
+
+	// the body is sent encoded so the server verifies the bytes signed
+	req = s.Client.NewRequest(req.Service(), req.Endpoint(), &bytes.Frame{Data: body},
+		client.WithContentType(req.ContentType()))
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	md := metadata.Metadata{
+		SignatureHeader:          sign(s.key, canonicalRequest(req.Service(), req.Endpoint(), ts, body)),
+		SignatureTimestampHeader: ts,
+	}
+	if len(s.id) > 0 {
+		md[SignatureKeyHeader] = s.id
+	}
+
+	return s.Client.Call(metadata.MergeContext(ctx, md, true), req, rsp, opts...)
+}
+
+// SignCalls wraps a client to sign each call with HMAC-SHA256 of the service,
+// endpoint, time and encoded request body using the key. The id, if set, is
+// sent for the server to look the key up with. Only json, protobuf and raw
+// bodies are signed, streams and publications aren't.
+func SignCalls(id string, key []byte, c client.Client) client.Client {
+	return &signWrapper{Client: c, id: id, key: key}
+}
+
+// VerifySignature wraps a server handler to reject calls without a valid
+// signature from SignCalls, or signed longer than skew ago or ahead of the
+// server's clock. DefaultSignatureSkew is used if skew is zero. Streams are
+// passed through unverified.
+func VerifySignature(keys SignatureKeys, skew time.Duration) server.HandlerWrapper {
+	if skew <= 0 {
+		skew = DefaultSignatureSkew
+	}
+
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if req.Stream() {
+				return h(ctx, req, rsp)
+			}
+
+			id := req.Service()
+
+			signature, ok := metadata.Get(ctx, SignatureHeader)
+			if !ok {
+				return errors.Unauthorized(id, "missing request signature")
+			}
+
+			ts, _ := metadata.Get(ctx, SignatureTimestampHeader)
+			unix, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return errors.Unauthorized(id, "invalid signature timestamp")
+			}
+
+			if d := time.Since(time.Unix(unix, 0)); d > skew || d < -skew {
+				return errors.Unauthorized(id, "request signature expired")
+			}
+
+			keyID, _ := metadata.Get(ctx, SignatureKeyHeader)
+			key, err := keys(keyID)
+			if err != nil || len(key) == 0 {
+				return errors.Unauthorized(id, "unknown signature key")
+			}
+
+			// the body as it was received
+			body, err := req.Read()
+			if err != nil {
+				return errors.BadRequest(id, "failed to verify request signature: %v", err)
+			}
+
+			expect := sign(key, canonicalRequest(req.Service(), req.Endpoint(), ts, body))
+			if !hmac.Equal([]byte(signature), []byte(expect)) {
+				return errors.Unauthorized(id, "invalid request signature")
+			}
+
+			return h(ctx, req, rsp)
+		}
+	}
+}
@@ -2,10 +2,17 @@ package wrapper
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
+	"golang.org/x/sync/singleflight"
+
 	"go-micro.dev/v4/auth"
 	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec"
+	"go-micro.dev/v4/codec/bytes"
+	jsonCodec "go-micro.dev/v4/codec/json"
+	protoCodec "go-micro.dev/v4/codec/proto"
 	"go-micro.dev/v4/debug/stats"
 	"go-micro.dev/v4/debug/trace"
 	"go-micro.dev/v4/metadata"
@@ -173,3 +180,84 @@ func (a *authWrapper) Call(ctx context.Context, req client.Request, rsp interfac
 	// call without an auth token
 	return a.Client.Call(ctx, req, rsp, opts...)
 }
+
+type coalesceWrapper struct {
+	client.Client
+
+	group singleflight.Group
+}
+
+// marshaler returns the marshaler used to key requests and share
+// responses for the content type.
+func marshaler(contentType string) codec.Marshaler {
+	switch contentType {
+	case "application/protobuf", "application/proto-rpc", "application/grpc", "application/grpc+proto":
+		return protoCodec.Marshaler{}
+	case "application/octet-stream":
+		return bytes.Marshaler{}
+	default:
+		return jsonCodec.Marshaler{}
+	}
+}
+
+// coalesceKey identifies identical calls. The caller's namespace and
+// authorization are included so responses are only shared between
+// callers which would see the same result. The key holds every field
+// in full, prefixed with its length, as a collision would share one
+// caller's response with another.
+func coalesceKey(ctx context.Context, req client.Request, body []byte) string {
+	ns, _ := metadata.Get(ctx, "Micro-Namespace")
+	token, _ := metadata.Get(ctx, "Authorization")
+
+	var key strings.Builder
+	for _, v := range []string{ns, token, req.Service(), req.Endpoint(), req.ContentType(), string(body)} {
+		fmt.Fprintf(&key, "%d:%s", len(v), v)
+	}
+
+	return key.String()
+}
+
+func (c *coalesceWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	m := marshaler(req.ContentType())
+
+	body, err := m.Marshal(req.Body())
+	if err != nil {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	}
+
+	var leader, called bool
+
+	v, err, _ := c.group.Do(coalesceKey(ctx, req, body), func() (interface{}, error) {
+		leader = true
+
+		if err := c.Client.Call(ctx, req, rsp, opts...); err != nil {
+			return nil, err
+		}
+		called = true
+
+		return m.Marshal(rsp)
+	})
+
+	// the response was written by our own call, only
+	// those waiting on it fail if it can't be shared
+	if leader {
+		if called {
+			return nil
+		}
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return m.Unmarshal(v.([]byte), rsp)
+}
+
+// CoalesceCalls wraps a client so identical concurrent calls, those with the
+// same service, endpoint and marshaled request, share one in flight request.
+// Each caller gets its own copy of the response. The first caller's context
+// and options are used for the shared request. Streams aren't coalesced.
+func CoalesceCalls(c client.Client) client.Client {
+	return &coalesceWrapper{Client: c}
+}
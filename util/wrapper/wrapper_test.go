@@ -2,13 +2,23 @@ package wrapper
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"go-micro.dev/v4/auth"
+	"go-micro.dev/v4/broker"
 	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/codec/bytes"
+	"go-micro.dev/v4/errors"
 	"go-micro.dev/v4/metadata"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
 	"go-micro.dev/v4/server"
+	"go-micro.dev/v4/transport"
 )
 
 func TestWrapper(t *testing.T) {
@@ -114,3 +124,261 @@ func (c *testClient) Call(ctx context.Context, req client.Request, rsp interface
 type testRsp struct {
 	value string
 }
+
+type coalesceValue struct {
+	Value string `json:"value"`
+}
+
+type blockingClient struct {
+	mtx     sync.Mutex
+	count   int
+	release chan bool
+	client.Client
+}
+
+func (c *blockingClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	c.mtx.Lock()
+	c.count++
+	c.mtx.Unlock()
+
+	<-c.release
+
+	rsp.(*coalesceValue).Value = req.Body().(*coalesceValue).Value
+	return nil
+}
+
+func TestCoalesceCalls(t *testing.T) {
+	bc := &blockingClient{
+		release: make(chan bool),
+		Client:  client.NewClient(),
+	}
+	c := CoalesceCalls(bc)
+
+	var wg sync.WaitGroup
+	rsps := make([]*coalesceValue, 10)
+
+	for i := range rsps {
+		rsps[i] = new(coalesceValue)
+		wg.Add(1)
+		go func(rsp *coalesceValue) {
+			defer wg.Done()
+			req := c.NewRequest("test.service", "Test.Call", &coalesceValue{Value: "same"})
+			if err := c.Call(context.Background(), req, rsp); err != nil {
+				t.Error(err)
+			}
+		}(rsps[i])
+	}
+
+	// a different request isn't coalesced with the others
+	wg.Add(1)
+	other := new(coalesceValue)
+	go func() {
+		defer wg.Done()
+		req := c.NewRequest("test.service", "Test.Call", &coalesceValue{Value: "other"})
+		if err := c.Call(context.Background(), req, other); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// let the calls pile up behind the in flight ones
+	time.Sleep(time.Millisecond * 100)
+	close(bc.release)
+	wg.Wait()
+
+	if bc.count != 2 {
+		t.Fatalf("Expected 2 calls to reach the client got %d", bc.count)
+	}
+
+	for i, rsp := range rsps {
+		if rsp.Value != "same" {
+			t.Fatalf("Expected response %d to be copied got %v", i, rsp)
+		}
+	}
+
+	if other.Value != "other" {
+		t.Fatalf("Expected other response got %v", other)
+	}
+}
+
+// chanValue can't be marshaled to share it.
+type chanValue struct {
+	Ch chan int
+}
+
+type chanClient struct {
+	release chan bool
+	client.Client
+}
+
+func (c *chanClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	<-c.release
+	rsp.(*chanValue).Ch = make(chan int)
+	return nil
+}
+
+func TestCoalesceCallsMarshalError(t *testing.T) {
+	cc := &chanClient{
+		release: make(chan bool),
+		Client:  client.NewClient(),
+	}
+	c := CoalesceCalls(cc)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := c.NewRequest("test.service", "Test.Call", &coalesceValue{Value: "same"})
+			errs <- c.Call(context.Background(), req, new(chanValue))
+		}()
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	close(cc.release)
+	wg.Wait()
+	close(errs)
+
+	// the leader's call succeeded, only the others can't share it
+	var failed int
+	for err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Fatalf("Expected 2 calls to fail got %d", failed)
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	c := client.NewClient()
+
+	key := func(token, body string) string {
+		ctx := metadata.NewContext(context.Background(), metadata.Metadata{"Authorization": token})
+		return coalesceKey(ctx, c.NewRequest("test.service", "Test.Call", nil), []byte(body))
+	}
+
+	// fields moving between each other aren't the same call
+	if key("ab", "c") == key("a", "bc") {
+		t.Fatal("Expected different keys")
+	}
+	if key("a", "b") != key("a", "b") {
+		t.Fatal("Expected the same key")
+	}
+}
+
+type SignValue struct {
+	Value string `json:"value"`
+}
+
+type SignHandler struct{}
+
+func (SignHandler) Echo(ctx context.Context, req *SignValue, rsp *SignValue) error {
+	rsp.Value = req.Value
+	return nil
+}
+
+// tamperClient changes the request after it's been signed.
+type tamperClient struct {
+	client.Client
+}
+
+func (c *tamperClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	req = c.NewRequest(req.Service(), req.Endpoint(), &SignValue{Value: "tampered"})
+	return c.Client.Call(ctx, req, rsp, opts...)
+}
+
+func TestSignature(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	tr := transport.NewMemoryTransport()
+
+	keys := func(id string) ([]byte, error) {
+		if id != "test" {
+			return nil, fmt.Errorf("unknown key %s", id)
+		}
+		return []byte("secret"), nil
+	}
+
+	srv := server.NewServer(
+		server.Name("test.service"),
+		server.Registry(r),
+		server.Transport(tr),
+		server.Broker(broker.NewMemoryBroker()),
+		server.WrapHandler(VerifySignature(keys, time.Minute)),
+	)
+	if err := srv.Handle(srv.NewHandler(SignHandler{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	c := client.NewClient(
+		client.Registry(r),
+		client.Transport(tr),
+		client.Selector(selector.NewSelector(selector.Registry(r))),
+	)
+
+	body := []byte(`{"value":"hello"}`)
+
+	// signed sets the headers of a request with the body signed at the time
+	signed := func(at time.Time, key string) context.Context {
+		ts := strconv.FormatInt(at.Unix(), 10)
+		return metadata.NewContext(context.Background(), metadata.Metadata{
+			SignatureHeader:          sign([]byte(key), canonicalRequest("test.service", "SignHandler.Echo", ts, body)),
+			SignatureTimestampHeader: ts,
+			SignatureKeyHeader:       "test",
+		})
+	}
+
+	testCases := []struct {
+		name   string
+		ctx    context.Context
+		client client.Client
+		body   []byte
+		err    string
+	}{
+		{"valid", context.Background(), SignCalls("test", []byte("secret"), c), nil, ""},
+		{"valid headers", signed(time.Now(), "secret"), c, body, ""},
+		{"unsigned", context.Background(), c, nil, "missing request signature"},
+		{"wrong key", context.Background(), SignCalls("test", []byte("wrong"), c), nil, "invalid request signature"},
+		{"unknown key", context.Background(), SignCalls("other", []byte("secret"), c), nil, "unknown signature key"},
+		{"tampered", context.Background(), SignCalls("test", []byte("secret"), &tamperClient{c}), nil, "invalid request signature"},
+		// the same value encoded differently isn't what was signed
+		{"reencoded", signed(time.Now(), "secret"), c, []byte(`{ "value": "hello" }`), "invalid request signature"},
+		{"expired", signed(time.Now().Add(-time.Minute*2), "secret"), c, body, "request signature expired"},
+		{"future", signed(time.Now().Add(time.Minute*2), "secret"), c, body, "request signature expired"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reqBody interface{} = &SignValue{Value: "hello"}
+			if tc.body != nil {
+				reqBody = &bytes.Frame{Data: tc.body}
+			}
+
+			req := tc.client.NewRequest("test.service", "SignHandler.Echo", reqBody, client.WithContentType("application/json"))
+
+			var rsp SignValue
+			err := tc.client.Call(tc.ctx, req, &rsp)
+
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rsp.Value != "hello" {
+					t.Fatalf("Expected hello got %s", rsp.Value)
+				}
+				return
+			}
+
+			merr := errors.FromError(err)
+			if merr.Code != 401 || merr.Detail != tc.err {
+				t.Fatalf("Expected unauthorized %q got %v", tc.err, err)
+			}
+		})
+	}
+}